@@ -0,0 +1,545 @@
+// Service for querying the analysis of fetched StackOverflow questions; served
+// by the grpc command of analyze-question-sentiment.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: analysis.proto
+
+package analysispb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListTagsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTagsRequest) Reset() {
+	*x = ListTagsRequest{}
+	mi := &file_analysis_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTagsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTagsRequest) ProtoMessage() {}
+
+func (x *ListTagsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analysis_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTagsRequest.ProtoReflect.Descriptor instead.
+func (*ListTagsRequest) Descriptor() ([]byte, []int) {
+	return file_analysis_proto_rawDescGZIP(), []int{0}
+}
+
+type ListTagsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []string               `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTagsResponse) Reset() {
+	*x = ListTagsResponse{}
+	mi := &file_analysis_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTagsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTagsResponse) ProtoMessage() {}
+
+func (x *ListTagsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_analysis_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTagsResponse.ProtoReflect.Descriptor instead.
+func (*ListTagsResponse) Descriptor() ([]byte, []int) {
+	return file_analysis_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListTagsResponse) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+// Period selects the questions to analyze. from_date and to_date are in
+// 2006-01-02 format and may be empty; by_month requires both.
+type Period struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromDate      string                 `protobuf:"bytes,1,opt,name=from_date,json=fromDate,proto3" json:"from_date,omitempty"`
+	ToDate        string                 `protobuf:"bytes,2,opt,name=to_date,json=toDate,proto3" json:"to_date,omitempty"`
+	ByMonth       bool                   `protobuf:"varint,3,opt,name=by_month,json=byMonth,proto3" json:"by_month,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Period) Reset() {
+	*x = Period{}
+	mi := &file_analysis_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Period) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Period) ProtoMessage() {}
+
+func (x *Period) ProtoReflect() protoreflect.Message {
+	mi := &file_analysis_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Period.ProtoReflect.Descriptor instead.
+func (*Period) Descriptor() ([]byte, []int) {
+	return file_analysis_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Period) GetFromDate() string {
+	if x != nil {
+		return x.FromDate
+	}
+	return ""
+}
+
+func (x *Period) GetToDate() string {
+	if x != nil {
+		return x.ToDate
+	}
+	return ""
+}
+
+func (x *Period) GetByMonth() bool {
+	if x != nil {
+		return x.ByMonth
+	}
+	return false
+}
+
+type GetMetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Period        *Period                `protobuf:"bytes,2,opt,name=period,proto3" json:"period,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricsRequest) Reset() {
+	*x = GetMetricsRequest{}
+	mi := &file_analysis_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricsRequest) ProtoMessage() {}
+
+func (x *GetMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analysis_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricsRequest.ProtoReflect.Descriptor instead.
+func (*GetMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_analysis_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetMetricsRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *GetMetricsRequest) GetPeriod() *Period {
+	if x != nil {
+		return x.Period
+	}
+	return nil
+}
+
+type CompareRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []string               `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	Period        *Period                `protobuf:"bytes,2,opt,name=period,proto3" json:"period,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompareRequest) Reset() {
+	*x = CompareRequest{}
+	mi := &file_analysis_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompareRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareRequest) ProtoMessage() {}
+
+func (x *CompareRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_analysis_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareRequest.ProtoReflect.Descriptor instead.
+func (*CompareRequest) Descriptor() ([]byte, []int) {
+	return file_analysis_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CompareRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *CompareRequest) GetPeriod() *Period {
+	if x != nil {
+		return x.Period
+	}
+	return nil
+}
+
+type CompareResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []*TagMetrics          `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompareResponse) Reset() {
+	*x = CompareResponse{}
+	mi := &file_analysis_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompareResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareResponse) ProtoMessage() {}
+
+func (x *CompareResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_analysis_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareResponse.ProtoReflect.Descriptor instead.
+func (*CompareResponse) Descriptor() ([]byte, []int) {
+	return file_analysis_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CompareResponse) GetTags() []*TagMetrics {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type TagMetrics struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	Buckets       []*Bucket              `protobuf:"bytes,2,rep,name=buckets,proto3" json:"buckets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TagMetrics) Reset() {
+	*x = TagMetrics{}
+	mi := &file_analysis_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TagMetrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TagMetrics) ProtoMessage() {}
+
+func (x *TagMetrics) ProtoReflect() protoreflect.Message {
+	mi := &file_analysis_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TagMetrics.ProtoReflect.Descriptor instead.
+func (*TagMetrics) Descriptor() ([]byte, []int) {
+	return file_analysis_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *TagMetrics) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *TagMetrics) GetBuckets() []*Bucket {
+	if x != nil {
+		return x.Buckets
+	}
+	return nil
+}
+
+// Bucket holds the metrics of a single period; date is the end of the period.
+type Bucket struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Date              string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	Total             int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	Negative          float64                `protobuf:"fixed64,3,opt,name=negative,proto3" json:"negative,omitempty"`
+	Closed            float64                `protobuf:"fixed64,4,opt,name=closed,proto3" json:"closed,omitempty"`
+	ClosedAndNegative float64                `protobuf:"fixed64,5,opt,name=closed_and_negative,json=closedAndNegative,proto3" json:"closed_and_negative,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Bucket) Reset() {
+	*x = Bucket{}
+	mi := &file_analysis_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Bucket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bucket) ProtoMessage() {}
+
+func (x *Bucket) ProtoReflect() protoreflect.Message {
+	mi := &file_analysis_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bucket.ProtoReflect.Descriptor instead.
+func (*Bucket) Descriptor() ([]byte, []int) {
+	return file_analysis_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Bucket) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *Bucket) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Bucket) GetNegative() float64 {
+	if x != nil {
+		return x.Negative
+	}
+	return 0
+}
+
+func (x *Bucket) GetClosed() float64 {
+	if x != nil {
+		return x.Closed
+	}
+	return 0
+}
+
+func (x *Bucket) GetClosedAndNegative() float64 {
+	if x != nil {
+		return x.ClosedAndNegative
+	}
+	return 0
+}
+
+var File_analysis_proto protoreflect.FileDescriptor
+
+const file_analysis_proto_rawDesc = "" +
+	"\n" +
+	"\x0eanalysis.proto\x12\banalysis\"\x11\n" +
+	"\x0fListTagsRequest\"&\n" +
+	"\x10ListTagsResponse\x12\x12\n" +
+	"\x04tags\x18\x01 \x03(\tR\x04tags\"Y\n" +
+	"\x06Period\x12\x1b\n" +
+	"\tfrom_date\x18\x01 \x01(\tR\bfromDate\x12\x17\n" +
+	"\ato_date\x18\x02 \x01(\tR\x06toDate\x12\x19\n" +
+	"\bby_month\x18\x03 \x01(\bR\abyMonth\"O\n" +
+	"\x11GetMetricsRequest\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12(\n" +
+	"\x06period\x18\x02 \x01(\v2\x10.analysis.PeriodR\x06period\"N\n" +
+	"\x0eCompareRequest\x12\x12\n" +
+	"\x04tags\x18\x01 \x03(\tR\x04tags\x12(\n" +
+	"\x06period\x18\x02 \x01(\v2\x10.analysis.PeriodR\x06period\";\n" +
+	"\x0fCompareResponse\x12(\n" +
+	"\x04tags\x18\x01 \x03(\v2\x14.analysis.TagMetricsR\x04tags\"J\n" +
+	"\n" +
+	"TagMetrics\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\x12*\n" +
+	"\abuckets\x18\x02 \x03(\v2\x10.analysis.BucketR\abuckets\"\x96\x01\n" +
+	"\x06Bucket\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\x12\x1a\n" +
+	"\bnegative\x18\x03 \x01(\x01R\bnegative\x12\x16\n" +
+	"\x06closed\x18\x04 \x01(\x01R\x06closed\x12.\n" +
+	"\x13closed_and_negative\x18\x05 \x01(\x01R\x11closedAndNegative2\xce\x01\n" +
+	"\bAnalysis\x12A\n" +
+	"\bListTags\x12\x19.analysis.ListTagsRequest\x1a\x1a.analysis.ListTagsResponse\x12?\n" +
+	"\n" +
+	"GetMetrics\x12\x1b.analysis.GetMetricsRequest\x1a\x14.analysis.TagMetrics\x12>\n" +
+	"\aCompare\x12\x18.analysis.CompareRequest\x1a\x19.analysis.CompareResponseB8Z6github.com/eliben/so-tag-sentiment-analysis/analysispbb\x06proto3"
+
+var (
+	file_analysis_proto_rawDescOnce sync.Once
+	file_analysis_proto_rawDescData []byte
+)
+
+func file_analysis_proto_rawDescGZIP() []byte {
+	file_analysis_proto_rawDescOnce.Do(func() {
+		file_analysis_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_analysis_proto_rawDesc), len(file_analysis_proto_rawDesc)))
+	})
+	return file_analysis_proto_rawDescData
+}
+
+var file_analysis_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_analysis_proto_goTypes = []any{
+	(*ListTagsRequest)(nil),   // 0: analysis.ListTagsRequest
+	(*ListTagsResponse)(nil),  // 1: analysis.ListTagsResponse
+	(*Period)(nil),            // 2: analysis.Period
+	(*GetMetricsRequest)(nil), // 3: analysis.GetMetricsRequest
+	(*CompareRequest)(nil),    // 4: analysis.CompareRequest
+	(*CompareResponse)(nil),   // 5: analysis.CompareResponse
+	(*TagMetrics)(nil),        // 6: analysis.TagMetrics
+	(*Bucket)(nil),            // 7: analysis.Bucket
+}
+var file_analysis_proto_depIdxs = []int32{
+	2, // 0: analysis.GetMetricsRequest.period:type_name -> analysis.Period
+	2, // 1: analysis.CompareRequest.period:type_name -> analysis.Period
+	6, // 2: analysis.CompareResponse.tags:type_name -> analysis.TagMetrics
+	7, // 3: analysis.TagMetrics.buckets:type_name -> analysis.Bucket
+	0, // 4: analysis.Analysis.ListTags:input_type -> analysis.ListTagsRequest
+	3, // 5: analysis.Analysis.GetMetrics:input_type -> analysis.GetMetricsRequest
+	4, // 6: analysis.Analysis.Compare:input_type -> analysis.CompareRequest
+	1, // 7: analysis.Analysis.ListTags:output_type -> analysis.ListTagsResponse
+	6, // 8: analysis.Analysis.GetMetrics:output_type -> analysis.TagMetrics
+	5, // 9: analysis.Analysis.Compare:output_type -> analysis.CompareResponse
+	7, // [7:10] is the sub-list for method output_type
+	4, // [4:7] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_analysis_proto_init() }
+func file_analysis_proto_init() {
+	if File_analysis_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_analysis_proto_rawDesc), len(file_analysis_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_analysis_proto_goTypes,
+		DependencyIndexes: file_analysis_proto_depIdxs,
+		MessageInfos:      file_analysis_proto_msgTypes,
+	}.Build()
+	File_analysis_proto = out.File
+	file_analysis_proto_goTypes = nil
+	file_analysis_proto_depIdxs = nil
+}
@@ -0,0 +1,206 @@
+// Service for querying the analysis of fetched StackOverflow questions; served
+// by the grpc command of analyze-question-sentiment.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: analysis.proto
+
+package analysispb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Analysis_ListTags_FullMethodName   = "/analysis.Analysis/ListTags"
+	Analysis_GetMetrics_FullMethodName = "/analysis.Analysis/GetMetrics"
+	Analysis_Compare_FullMethodName    = "/analysis.Analysis/Compare"
+)
+
+// AnalysisClient is the client API for Analysis service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AnalysisClient interface {
+	// ListTags returns the tags available in the data directory.
+	ListTags(ctx context.Context, in *ListTagsRequest, opts ...grpc.CallOption) (*ListTagsResponse, error)
+	// GetMetrics returns the metrics of a single tag.
+	GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*TagMetrics, error)
+	// Compare returns the metrics of several tags over the same periods.
+	Compare(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*CompareResponse, error)
+}
+
+type analysisClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAnalysisClient(cc grpc.ClientConnInterface) AnalysisClient {
+	return &analysisClient{cc}
+}
+
+func (c *analysisClient) ListTags(ctx context.Context, in *ListTagsRequest, opts ...grpc.CallOption) (*ListTagsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTagsResponse)
+	err := c.cc.Invoke(ctx, Analysis_ListTags_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisClient) GetMetrics(ctx context.Context, in *GetMetricsRequest, opts ...grpc.CallOption) (*TagMetrics, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TagMetrics)
+	err := c.cc.Invoke(ctx, Analysis_GetMetrics_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *analysisClient) Compare(ctx context.Context, in *CompareRequest, opts ...grpc.CallOption) (*CompareResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompareResponse)
+	err := c.cc.Invoke(ctx, Analysis_Compare_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AnalysisServer is the server API for Analysis service.
+// All implementations must embed UnimplementedAnalysisServer
+// for forward compatibility.
+type AnalysisServer interface {
+	// ListTags returns the tags available in the data directory.
+	ListTags(context.Context, *ListTagsRequest) (*ListTagsResponse, error)
+	// GetMetrics returns the metrics of a single tag.
+	GetMetrics(context.Context, *GetMetricsRequest) (*TagMetrics, error)
+	// Compare returns the metrics of several tags over the same periods.
+	Compare(context.Context, *CompareRequest) (*CompareResponse, error)
+	mustEmbedUnimplementedAnalysisServer()
+}
+
+// UnimplementedAnalysisServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAnalysisServer struct{}
+
+func (UnimplementedAnalysisServer) ListTags(context.Context, *ListTagsRequest) (*ListTagsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTags not implemented")
+}
+func (UnimplementedAnalysisServer) GetMetrics(context.Context, *GetMetricsRequest) (*TagMetrics, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMetrics not implemented")
+}
+func (UnimplementedAnalysisServer) Compare(context.Context, *CompareRequest) (*CompareResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Compare not implemented")
+}
+func (UnimplementedAnalysisServer) mustEmbedUnimplementedAnalysisServer() {}
+func (UnimplementedAnalysisServer) testEmbeddedByValue()                  {}
+
+// UnsafeAnalysisServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AnalysisServer will
+// result in compilation errors.
+type UnsafeAnalysisServer interface {
+	mustEmbedUnimplementedAnalysisServer()
+}
+
+func RegisterAnalysisServer(s grpc.ServiceRegistrar, srv AnalysisServer) {
+	// If the following call pancis, it indicates UnimplementedAnalysisServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Analysis_ServiceDesc, srv)
+}
+
+func _Analysis_ListTags_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTagsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServer).ListTags(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Analysis_ListTags_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServer).ListTags(ctx, req.(*ListTagsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Analysis_GetMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServer).GetMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Analysis_GetMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServer).GetMetrics(ctx, req.(*GetMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Analysis_Compare_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AnalysisServer).Compare(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Analysis_Compare_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AnalysisServer).Compare(ctx, req.(*CompareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Analysis_ServiceDesc is the grpc.ServiceDesc for Analysis service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Analysis_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "analysis.Analysis",
+	HandlerType: (*AnalysisServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListTags",
+			Handler:    _Analysis_ListTags_Handler,
+		},
+		{
+			MethodName: "GetMetrics",
+			Handler:    _Analysis_GetMetrics_Handler,
+		},
+		{
+			MethodName: "Compare",
+			Handler:    _Analysis_Compare_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "analysis.proto",
+}
@@ -0,0 +1,5 @@
+// Package analysispb contains the gRPC service definition for querying the
+// analysis, and the Go code generated from it.
+package analysispb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative analysis.proto
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// answerScoreRow is a bucket of the answer scores of a tag as presented in
+// -format json.
+type answerScoreRow struct {
+	Date               string  `json:"date"`
+	Questions          int     `json:"questions"`
+	Answered           int     `json:"answered"`
+	Answers            int     `json:"answers"`
+	MeanTopScore       float64 `json:"meanTopScore"`
+	NegativeBest       float64 `json:"negativeBest"`
+	AnswersPerQuestion float64 `json:"answersPerQuestion"`
+	Accepted           int     `json:"accepted"`
+	AcceptedNotTop     float64 `json:"acceptedNotTop"`
+}
+
+type answerScoreTag struct {
+	Tag  string           `json:"tag"`
+	Rows []answerScoreRow `json:"rows"`
+}
+
+// runAnswers implements the answers command, which reports the scores of the
+// answers to the questions of each tag in each bucket: the mean score of the
+// best answers, the ratio of questions whose best answer is negative, the
+// number of answers per question, and the ratio of accepted answers that aren't
+// the highest-voted ones; see soanalysis.Analyzer.AnswerScores. It
+// requires the answers to be fetched with fetch-all-questions -answers.
+func runAnswers(args []string) {
+	fs := newFlagSet("answers")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("answers requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.AnswerScoreSeries
+	for _, tag := range tags {
+		as, err := an.AnswerScores(ctx, tag, fromDate, toDate, af.bymonth)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, as)
+	}
+
+	if *formatFlag == "json" {
+		err = writeAnswersJSON(os.Stdout, results)
+	} else {
+		err = writeAnswersCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeAnswersCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per bucket.
+func writeAnswersCSV(w io.Writer, results []soanalysis.AnswerScoreSeries) error {
+	for _, as := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", as.Tag); err != nil {
+			return err
+		}
+		for _, b := range as.Buckets {
+			_, err := fmt.Fprintf(w, "%s,%d,%d,%d,%.2f,%.3f,%.2f,%d,%.3f\n", b.Date.Format("2006-01-02"), b.Questions, b.Answered,
+				b.Answers, b.MeanTopScore(), b.NegativeBestRatio(), b.AnswersPerQuestion(), b.Accepted, b.AcceptedNotTopRatio())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeAnswersJSON writes the results as a JSON array with an object per tag.
+func writeAnswersJSON(w io.Writer, results []soanalysis.AnswerScoreSeries) error {
+	ats := []answerScoreTag{}
+	for _, as := range results {
+		at := answerScoreTag{Tag: as.Tag, Rows: []answerScoreRow{}}
+		for _, b := range as.Buckets {
+			at.Rows = append(at.Rows, answerScoreRow{
+				Date:               b.Date.Format("2006-01-02"),
+				Questions:          b.Questions,
+				Answered:           b.Answered,
+				Answers:            b.Answers,
+				MeanTopScore:       b.MeanTopScore(),
+				NegativeBest:       b.NegativeBestRatio(),
+				AnswersPerQuestion: b.AnswersPerQuestion(),
+				Accepted:           b.Accepted,
+				AcceptedNotTop:     b.AcceptedNotTopRatio(),
+			})
+		}
+		ats = append(ats, at)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ats)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// runBaseline implements the baseline command, which saves the results of an
+// analysis as a named baseline for the check command.
+func runBaseline(args []string) {
+	fs := newFlagSet("baseline")
+	var af analysisFlags
+	af.register(fs)
+	nameFlag := fs.String("name", "", "name of the baseline")
+	baselineDirFlag := fs.String("baselinedir", "baselines", "directory to store baselines in")
+	parseFlags(fs, args)
+
+	if *nameFlag == "" {
+		log.Fatal("-name must be provided")
+	}
+	err := os.MkdirAll(*baselineDirFlag, 0777)
+	failonf(err, "creating directory %q", *baselineDirFlag)
+
+	path := filepath.Join(*baselineDirFlag, *nameFlag+".json")
+	f, err := os.Create(path)
+	failonf(err, "creating %q", path)
+	err = writeJSONResults(f, af.run())
+	failonf(err, "writing %q", path)
+	err = f.Close()
+	failonf(err, "writing %q", path)
+	fmt.Println("Saved baseline", path)
+}
+
+// runCheck implements the check command, which compares a fresh analysis with
+// a baseline saved by the baseline command, and exits with status 1 if any
+// ratio of a tag in a bucket rose above its baseline value by more than the
+// tolerance. Tags and buckets missing from the baseline are ignored.
+func runCheck(args []string) {
+	fs := newFlagSet("check")
+	var af analysisFlags
+	af.register(fs)
+	nameFlag := fs.String("name", "", "name of the baseline")
+	baselineDirFlag := fs.String("baselinedir", "baselines", "directory baselines are stored in")
+	var tol checkTolerances
+	fs.Float64Var(&tol.negative, "tolerance-negative", 0.05, "allowed rise of the negative ratio")
+	fs.Float64Var(&tol.closed, "tolerance-closed", 0.05, "allowed rise of the closed ratio")
+	fs.Float64Var(&tol.closedAndNegative, "tolerance-closed-negative", 0.05, "allowed rise of the closed & negative ratio")
+	parseFlags(fs, args)
+
+	if *nameFlag == "" {
+		log.Fatal("-name must be provided")
+	}
+	path := filepath.Join(*baselineDirFlag, *nameFlag+".json")
+	data, err := os.ReadFile(path)
+	failonf(err, "reading baseline %q", path)
+	var baseline []reportTag
+	err = json.Unmarshal(data, &baseline)
+	failonf(err, "unmarshalling %q", path)
+
+	var current []reportTag
+	for _, ts := range af.run() {
+		current = append(current, newReportTag(ts))
+	}
+
+	regressions := tol.check(baseline, current)
+	for _, r := range regressions {
+		fmt.Println(r)
+	}
+	if len(regressions) > 0 {
+		os.Exit(1)
+	}
+	fmt.Println("No regressions from baseline", *nameFlag)
+}
+
+// checkTolerances are the allowed rises of ratios from their baseline values.
+type checkTolerances struct {
+	negative          float64
+	closed            float64
+	closedAndNegative float64
+}
+
+// check returns a description of each regression of current from baseline.
+func (tol checkTolerances) check(baseline []reportTag, current []reportTag) []string {
+	baseRows := make(map[string]reportRow)
+	for _, rt := range baseline {
+		for _, r := range rt.Rows {
+			baseRows[rt.Tag+" "+r.Date] = r
+		}
+	}
+
+	var regressions []string
+	for _, rt := range current {
+		for _, r := range rt.Rows {
+			br, ok := baseRows[rt.Tag+" "+r.Date]
+			if !ok {
+				continue
+			}
+			for _, m := range []struct {
+				name           string
+				base, cur, tol float64
+			}{
+				{"negative", br.Negative, r.Negative, tol.negative},
+				{"closed", br.Closed, r.Closed, tol.closed},
+				{"closed & negative", br.ClosedAndNegative, r.ClosedAndNegative, tol.closedAndNegative},
+			} {
+				if m.cur-m.base > m.tol {
+					regressions = append(regressions, fmt.Sprintf("[%s] %s: %s ratio rose from %.3f to %.3f (tolerance %.3f)",
+						rt.Tag, r.Date, m.name, m.base, m.cur, m.tol))
+				}
+			}
+		}
+	}
+	return regressions
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// benchmarkRow is a bucket of the percentile ranks of a tag among its peers as
+// presented in -format json.
+type benchmarkRow struct {
+	Date                string  `json:"date"`
+	Peers               int     `json:"peers"`
+	Negative            float64 `json:"negative"`
+	Closed              float64 `json:"closed"`
+	ClosedAndNegative   float64 `json:"closedAndNegative"`
+	Unanswered          float64 `json:"unanswered"`
+	NegativePer10kViews float64 `json:"negativePer10kViews"`
+}
+
+type benchmarkTag struct {
+	Tag   string         `json:"tag"`
+	Peers []string       `json:"peers"`
+	Rows  []benchmarkRow `json:"rows"`
+}
+
+// runBenchmark implements the benchmark command, which reports the metrics of
+// each tag as percentile ranks among its related tags; see
+// soanalysis.Analyzer.Benchmark. It requires the related tags to be fetched
+// with fetch-all-questions -related; without -tags, the tags without related
+// tags are skipped.
+func runBenchmark(args []string) {
+	fs := newFlagSet("benchmark")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("benchmark requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.Benchmark
+	for _, tag := range tags {
+		bm, err := an.Benchmark(ctx, tag, fromDate, toDate, af.bymonth)
+		if errors.Is(err, os.ErrNotExist) {
+			if af.tags == "" {
+				continue
+			}
+			log.Fatalf("no related tags of %q in %q; fetch them with fetch-all-questions -related", tag, af.dir)
+		}
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, bm)
+	}
+
+	if *formatFlag == "json" {
+		err = writeBenchmarkJSON(os.Stdout, results)
+	} else {
+		err = writeBenchmarkCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeBenchmarkCSV writes the results of each tag as a line with the tag name
+// and its peers followed by a CSV table with a row per bucket.
+func writeBenchmarkCSV(w io.Writer, results []soanalysis.Benchmark) error {
+	for _, bm := range results {
+		if _, err := fmt.Fprintf(w, "\n%s (peers: %s)\n", bm.Tag, strings.Join(bm.Peers, ", ")); err != nil {
+			return err
+		}
+		for _, b := range bm.Buckets {
+			_, err := fmt.Fprintf(w, "%s,%d,%.0f,%.0f,%.0f,%.0f,%.0f\n", b.Date.Format("2006-01-02"), b.Peers,
+				b.Negative, b.Closed, b.ClosedAndNegative, b.Unanswered, b.NegativePer10kViews)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeBenchmarkJSON writes the results as a JSON array with an object per tag.
+func writeBenchmarkJSON(w io.Writer, results []soanalysis.Benchmark) error {
+	bts := []benchmarkTag{}
+	for _, bm := range results {
+		bt := benchmarkTag{Tag: bm.Tag, Peers: bm.Peers, Rows: []benchmarkRow{}}
+		if bt.Peers == nil {
+			bt.Peers = []string{}
+		}
+		for _, b := range bm.Buckets {
+			bt.Rows = append(bt.Rows, benchmarkRow{
+				Date:                b.Date.Format("2006-01-02"),
+				Peers:               b.Peers,
+				Negative:            b.Negative,
+				Closed:              b.Closed,
+				ClosedAndNegative:   b.ClosedAndNegative,
+				Unanswered:          b.Unanswered,
+				NegativePer10kViews: b.NegativePer10kViews,
+			})
+		}
+		bts = append(bts, bt)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bts)
+}
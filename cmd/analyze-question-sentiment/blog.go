@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// blogData is what the blog template is executed with: the report, and the
+// methodology of the analysis filled in from the run.
+type blogData struct {
+	reportData
+
+	// Tags are the tags of the report with their charts and coverage, in the
+	// order of reportData.Tags.
+	Tags []blogTag
+
+	// From and To are the period of the analysis, as given by -fromdate and
+	// -todate (empty if not given), and Buckets describes its buckets.
+	From    string
+	To      string
+	Buckets string
+
+	// Filters describes the flags selecting the questions analyzed.
+	Filters []string
+
+	// GapDays is the number of days without questions reported as gaps in
+	// the coverage of the data.
+	GapDays int
+}
+
+// blogTag is a tag of the blog report.
+type blogTag struct {
+	reportTag
+
+	// Chart is the file name of the chart of the tag, empty if it has none.
+	Chart string
+
+	// Coverage describes the stored data of the tag, if it was read from a
+	// data directory.
+	Coverage *soanalysis.TagStats
+}
+
+// blogGapDays is the default of blogData.GapDays.
+const blogGapDays = 30
+
+// writeBlog writes the analysis into the directory dir as a markdown article
+// skeleton, index.md, along with a PNG chart per tag of a series with more than
+// one bucket, for publishing the results. The methodology section is filled
+// in from af and, if the questions were read from a data directory, from the
+// coverage of its data.
+func writeBlog(dir string, rd reportData, results []soanalysis.Series, af *analysisFlags) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	bd := blogData{reportData: rd, From: af.fromDate, To: af.toDate, Buckets: "a single period", GapDays: blogGapDays}
+	switch {
+	case af.window != "":
+		bd.Buckets = fmt.Sprintf("sliding windows of %s every %s", af.window, af.step)
+	case af.bymonth:
+		bd.Buckets = "calendar months"
+	}
+	for _, f := range []struct {
+		value string
+		desc  string
+	}{
+		{af.licenses, "only questions under the content licenses %s"},
+		{af.keywords, "only questions matching the full-text query %q"},
+		{fmt.Sprint(af.trim), "means of scores and view counts trimmed by %s on each side"},
+		{fmt.Sprint(af.winsorize), "means of scores and view counts winsorized by %s on each side"},
+	} {
+		if f.value != "" && f.value != "0" {
+			bd.Filters = append(bd.Filters, fmt.Sprintf(f.desc, f.value))
+		}
+	}
+
+	ds := &soanalysis.Dataset{Dir: af.dir}
+	for i, rt := range rd.Tags {
+		bt := blogTag{reportTag: rt}
+		if len(results[i].Buckets) > 1 {
+			bt.Chart = rt.Tag + ".png"
+			if err := plotTag(filepath.Join(dir, bt.Chart), rt.Tag, results[i].Buckets); err != nil {
+				return fmt.Errorf("plotting tag %q: %w", rt.Tag, err)
+			}
+		}
+		if !cli.IsStream(af.dir) {
+			st, err := ds.Stats(rt.Tag, time.Duration(bd.GapDays)*24*time.Hour)
+			if err != nil {
+				return fmt.Errorf("reading the coverage of tag %q: %w", rt.Tag, err)
+			}
+			bt.Coverage = &st
+		}
+		bd.Tags = append(bd.Tags, bt)
+	}
+
+	f, err := os.Create(filepath.Join(dir, "index.md"))
+	if err != nil {
+		return err
+	}
+	if err := blogTemplate.Execute(f, bd); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// blogDir returns the directory the blog report is written into for -out: its
+// path without the .html extension.
+func blogDir(out string) string {
+	return strings.TrimSuffix(out, ".html")
+}
+
+var blogTemplate = template.Must(template.New("blog").Funcs(template.FuncMap{
+	"date": func(t time.Time) string { return t.Format("2006-01-02") },
+}).Parse(blogMarkdown))
+
+const blogMarkdown = `---
+title: "StackOverflow sentiment of {{range $i, $t := .Tags}}{{if $i}}, {{end}}[{{$t.Tag}}]{{end}}"
+date: {{slice .Generated 0 10}}
+---
+
+<!-- A skeleton generated by analyze-question-sentiment report -style blog;
+     replace the TODOs with your own words. -->
+
+TODO: introduction — what was compared and why.
+
+{{range .Tags}}
+## [{{.Tag}}]
+
+{{if .Chart}}![The ratios of negative and closed questions of [{{.Tag}}]]({{.Chart}})
+{{end}}
+TODO: what stands out for [{{.Tag}}].
+
+| date | questions | negative | closed | closed & negative |
+|------|----------:|---------:|-------:|------------------:|
+{{range .Rows}}| {{.Date}} | {{.Total}} | {{printf "%.3f" .Negative}} | {{printf "%.3f" .Closed}} | {{printf "%.3f" .ClosedAndNegative}} |
+{{end}}{{with .Forecast}}
+Forecast, with 95% prediction intervals:
+
+| date | questions | negative | closed |
+|------|----------:|---------:|-------:|
+{{range .}}| {{.Date}} | {{printf "%.0f" .Total.Value}} ({{printf "%.0f" .Total.Lower}}–{{printf "%.0f" .Total.Upper}}) | {{printf "%.3f" .Negative.Value}} ({{printf "%.3f" .Negative.Lower}}–{{printf "%.3f" .Negative.Upper}}) | {{printf "%.3f" .Closed.Value}} ({{printf "%.3f" .Closed.Lower}}–{{printf "%.3f" .Closed.Upper}}) |
+{{end}}{{end}}{{end}}
+## Conclusions
+
+TODO: conclusions.
+
+## Methodology
+
+The questions were fetched from the StackExchange API with fetch-all-questions
+and analyzed with analyze-question-sentiment on {{.Generated}}.
+
+- Period: {{if .From}}{{.From}}{{else}}the first question{{end}} to {{if .To}}{{.To}}{{else}}the last question{{end}}, analyzed by {{.Buckets}}.
+{{range .Filters}}- Filter: {{.}}.
+{{end}}- A question counts as negative if its score is below 0, and as closed if it was
+  closed at the time it was fetched; scores and closings keep changing after
+  fetching.
+{{range .Tags}}{{with .Coverage}}- Data of [{{.Tag}}]: {{.UniqueQuestions}} questions asked from {{date .MinDate}} to {{date .MaxDate}}, last fetched {{date .LastFetch}}{{with .Gaps}}; periods of {{$.GapDays}} days or more without questions, which may not have been fetched:{{range $i, $g := .}}{{if $i}},{{end}} {{date $g.From}} to {{date $g.To}}{{end}}{{end}}.
+{{end}}{{end}}`
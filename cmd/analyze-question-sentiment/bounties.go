@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// bountyGroupRow is a group of questions of a tag as presented in -format
+// json.
+type bountyGroupRow struct {
+	Questions           int     `json:"questions"`
+	AnswerRate          float64 `json:"answerRate"`
+	AcceptRate          float64 `json:"acceptRate"`
+	MedianHoursToAccept float64 `json:"medianHoursToAccept"`
+	TimedAccepted       int     `json:"timedAccepted"`
+}
+
+type bountyTag struct {
+	Tag        string         `json:"tag"`
+	MeanAmount float64        `json:"meanAmount"`
+	Bountied   bountyGroupRow `json:"bountied"`
+	Other      bountyGroupRow `json:"other"`
+}
+
+// runBounties implements the bounties command, which compares the answer and
+// accept rates and the times to accept of the questions of each tag with a
+// bounty with those of the other questions; see soanalysis.Analyzer.Bounties.
+// The times to accept require the answers to be fetched with
+// fetch-all-questions -answers. The API only reports the bounties open when the
+// questions are fetched, so recently fetched periods are the most telling.
+func runBounties(args []string) {
+	fs := newFlagSet("bounties")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("bounties requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.BountyStats
+	for _, tag := range tags {
+		bs, err := an.Bounties(ctx, tag, fromDate, toDate)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, bs)
+	}
+
+	if *formatFlag == "json" {
+		err = writeBountiesJSON(os.Stdout, results)
+	} else {
+		err = writeBountiesCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeBountiesCSV writes the results of each tag as a line with the tag name
+// and the mean amount of its bounties followed by a CSV table with a row for
+// the questions with a bounty, named "bountied", and one for the others, named
+// "other".
+func writeBountiesCSV(w io.Writer, results []soanalysis.BountyStats) error {
+	for _, bs := range results {
+		if _, err := fmt.Fprintf(w, "\n%s (mean bounty %.0f)\n", bs.Tag, bs.MeanAmount); err != nil {
+			return err
+		}
+		for _, g := range []struct {
+			name  string
+			group soanalysis.BountyGroup
+		}{{"bountied", bs.Bountied}, {"other", bs.Other}} {
+			_, err := fmt.Fprintf(w, "%s,%d,%.3f,%.3f,%.1f,%d\n", g.name, g.group.Questions, g.group.AnswerRate(),
+				g.group.AcceptRate(), g.group.MedianTimeToAccept.Hours(), g.group.TimedAccepted)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeBountiesJSON writes the results as a JSON array with an object per tag.
+func writeBountiesJSON(w io.Writer, results []soanalysis.BountyStats) error {
+	newRow := func(g soanalysis.BountyGroup) bountyGroupRow {
+		return bountyGroupRow{
+			Questions:           g.Questions,
+			AnswerRate:          g.AnswerRate(),
+			AcceptRate:          g.AcceptRate(),
+			MedianHoursToAccept: g.MedianTimeToAccept.Hours(),
+			TimedAccepted:       g.TimedAccepted,
+		}
+	}
+	bts := []bountyTag{}
+	for _, bs := range results {
+		bts = append(bts, bountyTag{
+			Tag:        bs.Tag,
+			MeanAmount: bs.MeanAmount,
+			Bountied:   newRow(bs.Bountied),
+			Other:      newRow(bs.Other),
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bts)
+}
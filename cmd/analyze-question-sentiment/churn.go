@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// churnRow is a bucket of the moderation churn of a tag as presented in
+// -format json.
+type churnRow struct {
+	Date      string  `json:"date"`
+	Questions int     `json:"questions"`
+	Closed    int     `json:"closed"`
+	Reopened  int     `json:"reopened"`
+	Deleted   int     `json:"deleted"`
+	Churn     float64 `json:"churn"`
+
+	// ChurnSmoothed is the moving average of the churn over velocityWindow
+	// buckets.
+	ChurnSmoothed float64 `json:"churnSmoothed"`
+}
+
+type churnTag struct {
+	Tag  string     `json:"tag"`
+	Rows []churnRow `json:"rows"`
+}
+
+// runChurn implements the churn command, which combines the closing, reopening
+// and deletion of the questions of each tag into a single measure of how
+// contested its moderation is in each bucket, with its moving average for the
+// trend; see soanalysis.Analyzer.Churn. Reopenings and deletions are only
+// detected across fetches of the same windows.
+func runChurn(args []string) {
+	fs := newFlagSet("churn")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("churn requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.ChurnSeries
+	for _, tag := range tags {
+		cs, err := an.Churn(ctx, tag, fromDate, toDate, af.bymonth)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, cs)
+	}
+
+	if *formatFlag == "json" {
+		err = writeChurnJSON(os.Stdout, results)
+	} else {
+		err = writeChurnCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeChurnCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per bucket.
+func writeChurnCSV(w io.Writer, results []soanalysis.ChurnSeries) error {
+	for _, cs := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", cs.Tag); err != nil {
+			return err
+		}
+		smoothed := cs.Smoothed(velocityWindow)
+		for i, b := range cs.Buckets {
+			_, err := fmt.Fprintf(w, "%s,%d,%d,%d,%d,%.3f,%.3f\n", b.Date.Format("2006-01-02"), b.Questions, b.Closed,
+				b.Reopened, b.Deleted, b.Churn(), smoothed[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeChurnJSON writes the results as a JSON array with an object per tag.
+func writeChurnJSON(w io.Writer, results []soanalysis.ChurnSeries) error {
+	cts := []churnTag{}
+	for _, cs := range results {
+		ct := churnTag{Tag: cs.Tag, Rows: []churnRow{}}
+		smoothed := cs.Smoothed(velocityWindow)
+		for i, b := range cs.Buckets {
+			ct.Rows = append(ct.Rows, churnRow{
+				Date:          b.Date.Format("2006-01-02"),
+				Questions:     b.Questions,
+				Closed:        b.Closed,
+				Reopened:      b.Reopened,
+				Deleted:       b.Deleted,
+				Churn:         b.Churn(),
+				ChurnSmoothed: smoothed[i],
+			})
+		}
+		cts = append(cts, ct)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cts)
+}
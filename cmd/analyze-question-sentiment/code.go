@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// codeBandRow is the result of a band of code ratios as presented in -format
+// json.
+type codeBandRow struct {
+	Band       string  `json:"band"`
+	Questions  int     `json:"questions"`
+	Negative   float64 `json:"negative"`
+	Closed     float64 `json:"closed"`
+	Unanswered float64 `json:"unanswered"`
+	MeanScore  float64 `json:"meanScore"`
+}
+
+type codeTag struct {
+	Tag               string        `json:"tag"`
+	Questions         int           `json:"questions"`
+	WithoutBodies     int           `json:"withoutBodies"`
+	MeanRatio         float64       `json:"meanRatio"`
+	WithCode          float64       `json:"withCode"`
+	ScoreCorrelation  float64       `json:"scoreCorrelation"`
+	ClosedCorrelation float64       `json:"closedCorrelation"`
+	Bands             []codeBandRow `json:"bands"`
+}
+
+// runCode implements the code command, which reports the share of code blocks
+// in the bodies of the questions of each tag and how it correlates with their
+// scores and closing, as a proxy for the effort put into the questions; see
+// soanalysis.Analyzer.CodeRatios. It requires the bodies of the questions to
+// be fetched with fetch-all-questions -bodies.
+func runCode(args []string) {
+	fs := newFlagSet("code")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("code requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.CodeRatioStats
+	for _, tag := range tags {
+		cs, err := an.CodeRatios(ctx, tag, fromDate, toDate)
+		failonf(err, "analyzing tag %q", tag)
+		if cs.Questions == 0 && cs.WithoutBodies > 0 {
+			log.Printf("no bodies of the questions of %q; fetch them with fetch-all-questions -bodies", tag)
+		}
+		results = append(results, cs)
+	}
+
+	if *formatFlag == "json" {
+		err = writeCodeJSON(os.Stdout, results)
+	} else {
+		err = writeCodeCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// codeBandName returns the name of a band of code ratios in the output, like
+// "0.25-0.50", or "none" for the questions without code.
+func codeBandName(b soanalysis.CodeRatioBand) string {
+	if b.Max == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%.2f-%.2f", b.Min, b.Max)
+}
+
+// writeCodeCSV writes the results of each tag as a line with the tag name and
+// its code ratio statistics followed by a CSV table with a row per band of code
+// ratios.
+func writeCodeCSV(w io.Writer, results []soanalysis.CodeRatioStats) error {
+	for _, cs := range results {
+		_, err := fmt.Fprintf(w, "\n%s (%d questions, mean code ratio %.3f, with code %.3f, score correlation %.3f, closed correlation %.3f)\n",
+			cs.Tag, cs.Questions, cs.MeanRatio, cs.WithCode, cs.ScoreCorrelation, cs.ClosedCorrelation)
+		if err != nil {
+			return err
+		}
+		for _, b := range cs.Bands {
+			r := b.Result
+			_, err := fmt.Fprintf(w, "%s,%d,%.3f,%.3f,%.3f,%.2f\n", codeBandName(b), r.Total, r.NegativeRatio(), r.ClosedRatio(),
+				r.UnansweredRatio(), r.MeanScore())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeCodeJSON writes the results as a JSON array with an object per tag.
+func writeCodeJSON(w io.Writer, results []soanalysis.CodeRatioStats) error {
+	cts := []codeTag{}
+	for _, cs := range results {
+		ct := codeTag{
+			Tag:               cs.Tag,
+			Questions:         cs.Questions,
+			WithoutBodies:     cs.WithoutBodies,
+			MeanRatio:         cs.MeanRatio,
+			WithCode:          cs.WithCode,
+			ScoreCorrelation:  cs.ScoreCorrelation,
+			ClosedCorrelation: cs.ClosedCorrelation,
+			Bands:             []codeBandRow{},
+		}
+		for _, b := range cs.Bands {
+			r := b.Result
+			ct.Bands = append(ct.Bands, codeBandRow{
+				Band:       codeBandName(b),
+				Questions:  r.Total,
+				Negative:   r.NegativeRatio(),
+				Closed:     r.ClosedRatio(),
+				Unanswered: r.UnansweredRatio(),
+				MeanScore:  r.MeanScore(),
+			})
+		}
+		cts = append(cts, ct)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cts)
+}
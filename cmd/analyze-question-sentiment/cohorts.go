@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// cohortRow is a cohort of askers as presented in -format json.
+type cohortRow struct {
+	Month             string    `json:"month"`
+	Askers            int       `json:"askers"`
+	Retention         []float64 `json:"retention"`
+	NegativeAskers    int       `json:"negativeAskers"`
+	NegativeRetention []float64 `json:"negativeRetention"`
+}
+
+type cohortTag struct {
+	Tag     string      `json:"tag"`
+	Cohorts []cohortRow `json:"cohorts"`
+}
+
+// runCohorts implements the cohorts command, which groups the askers of each
+// tag by the month of their first question and reports the ratio of each
+// cohort asking again in each of the following -months months, for all the
+// askers of the cohort and for the ones whose first question had a negative
+// score; see soanalysis.Analyzer.Cohorts.
+func runCohorts(args []string) {
+	fs := newFlagSet("cohorts")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	fs.StringVar(&af.fromDate, "fromdate", "", "start date in 2006-01-02 format")
+	fs.StringVar(&af.toDate, "todate", "", "end date in 2006-01-02 format")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) separated by commas")
+	fs.StringVar(&af.timezone, "timezone", "UTC", "time zone of the dates, e.g. America/New_York")
+	fs.StringVar(&af.licenses, "licenses", "", "only analyze questions under these content licenses, separated by commas, e.g. 'CC BY-SA 4.0'")
+	monthsFlag := fs.Int("months", 6, "number of months to follow each cohort for")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("cohorts requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	if fromDate.IsZero() {
+		// Months start in the time zone of fromDate.
+		loc, err := time.LoadLocation(af.timezone)
+		failonf(err, "parsing -timezone")
+		fromDate = fromDate.In(loc)
+	}
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	results := make(map[string][]soanalysis.Cohort)
+	for _, tag := range tags {
+		cohorts, err := an.Cohorts(ctx, tag, fromDate, toDate, *monthsFlag)
+		failonf(err, "analyzing tag %q", tag)
+		results[tag] = cohorts
+	}
+
+	if *formatFlag == "json" {
+		err = writeCohortsJSON(os.Stdout, tags, results)
+	} else {
+		err = writeCohortsCSV(os.Stdout, tags, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeCohortsCSV writes the cohorts of each tag as a line with the tag name
+// followed by a CSV table with two rows per cohort: the month, "all" or
+// "negative" for the askers whose first question was negative, the number of
+// askers and the retention ratio in each following month.
+func writeCohortsCSV(w io.Writer, tags []string, results map[string][]soanalysis.Cohort) error {
+	for _, tag := range tags {
+		if _, err := fmt.Fprintf(w, "\n%s\n", tag); err != nil {
+			return err
+		}
+		for _, c := range results[tag] {
+			month := c.Month.Format("2006-01")
+			rows := []struct {
+				group  string
+				askers int
+				ratio  func(i int) float64
+			}{
+				{"all", c.Askers, c.RetentionRatio},
+				{"negative", c.NegativeAskers, c.NegativeRetentionRatio},
+			}
+			for _, row := range rows {
+				if _, err := fmt.Fprintf(w, "%s,%s,%d", month, row.group, row.askers); err != nil {
+					return err
+				}
+				for i := range c.Retained {
+					if _, err := fmt.Fprintf(w, ",%.3f", row.ratio(i)); err != nil {
+						return err
+					}
+				}
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeCohortsJSON writes the cohorts as a JSON array with an object per tag.
+func writeCohortsJSON(w io.Writer, tags []string, results map[string][]soanalysis.Cohort) error {
+	cts := []cohortTag{}
+	for _, tag := range tags {
+		ct := cohortTag{Tag: tag, Cohorts: []cohortRow{}}
+		for _, c := range results[tag] {
+			row := cohortRow{
+				Month:             c.Month.Format("2006-01"),
+				Askers:            c.Askers,
+				Retention:         []float64{},
+				NegativeAskers:    c.NegativeAskers,
+				NegativeRetention: []float64{},
+			}
+			for i := range c.Retained {
+				row.Retention = append(row.Retention, c.RetentionRatio(i))
+				row.NegativeRetention = append(row.NegativeRetention, c.NegativeRetentionRatio(i))
+			}
+			ct.Cohorts = append(ct.Cohorts, row)
+		}
+		cts = append(cts, ct)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cts)
+}
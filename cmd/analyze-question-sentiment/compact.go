@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+)
+
+// runCompact implements the compact command, which merges the pages of each
+// tag in the data directory into larger files, to speed up analyzing data
+// directories with many small pages; see soanalysis.Dataset.Compact.
+func runCompact(args []string) {
+	fs := newFlagSet("compact")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) separated by commas; all tags by default")
+	sizeFlag := fs.Int("size", 10000, "maximal number of questions per merged file")
+	parseFlags(fs, args)
+
+	if cli.IsStream(af.dir) {
+		log.Fatal("-dir - can't be compacted")
+	}
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	ds := af.analyzer().Dataset
+	ctx, cancel := af.context()
+	defer cancel()
+	for _, tag := range tags {
+		before, after, err := ds.Compact(ctx, tag, *sizeFlag)
+		failonf(err, "compacting tag %q", tag)
+		fmt.Printf("%s: merged %d files into %d\n", tag, before, after)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// completing is set while running the hidden __complete command, making the
+// usage of commands print the names of their flags.
+var completing bool
+
+// completionScripts maps shell names to their completion scripts. The scripts
+// call the __complete command with the words of the command line, and fall
+// back to completing file names when it has no candidates.
+var completionScripts = map[string]string{
+	"bash": `_analyze_question_sentiment() {
+	local IFS=$'\n'
+	COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)" -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -o default -F _analyze_question_sentiment analyze-question-sentiment
+`,
+	"zsh": `#compdef analyze-question-sentiment
+
+_analyze_question_sentiment() {
+	local -a candidates
+	candidates=("${(@f)$("${words[1]}" __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	if [[ -n "${candidates[1]}" ]]; then
+		compadd -a candidates
+	else
+		_files
+	fi
+}
+
+compdef _analyze_question_sentiment analyze-question-sentiment
+`,
+	"fish": `function __analyze_question_sentiment_complete
+	set -l candidates (analyze-question-sentiment __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)
+	if test (count $candidates) -gt 0
+		printf '%s\n' $candidates
+	else
+		__fish_complete_path (commandline -ct)
+	end
+end
+complete -c analyze-question-sentiment -f -a '(__analyze_question_sentiment_complete)'
+`,
+}
+
+// runCompletion implements the completion command, which prints the completion
+// script for a shell.
+func runCompletion(args []string) {
+	fs := newFlagSet("completion")
+	parseFlags(fs, args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	script, ok := completionScripts[fs.Arg(0)]
+	if !ok {
+		log.Fatalf("unknown shell %q", fs.Arg(0))
+	}
+	fmt.Print(script)
+}
+
+// runComplete implements the hidden __complete command called by the
+// completion scripts. args are the words of the command line following the
+// program name, the last one being the word to complete; the candidates are
+// printed one per line, leaving their filtering by prefix to the shell.
+func runComplete(args []string) {
+	if len(args) == 0 {
+		return
+	}
+	words, word := args[:len(args)-1], args[len(args)-1]
+
+	name := ""
+	if len(words) > 0 {
+		if _, ok := commandHelp[words[0]]; ok {
+			name = words[0]
+		}
+	}
+
+	var prev string
+	if len(words) > 0 {
+		prev = strings.TrimLeft(words[len(words)-1], "-")
+	}
+
+	switch {
+	case len(words) == 0 && !strings.HasPrefix(word, "-"):
+		// The command itself
+		for _, n := range commandNames() {
+			fmt.Println(n)
+		}
+	case name == "help" && len(words) == 1:
+		for _, n := range commandNames() {
+			fmt.Println(n)
+		}
+	case name == "completion" && len(words) == 1:
+		fmt.Println("bash\nzsh\nfish")
+	case prev == "tags":
+		// Complete the last of the tags separated by commas, keeping the ones
+		// before it.
+		done := word[:strings.LastIndex(word, ",")+1]
+		tags, err := (&soanalysis.Dataset{Dir: completionDir(name, words)}).Tags()
+		if err != nil {
+			return
+		}
+		for _, tag := range tags {
+			fmt.Println(done + tag)
+		}
+	case prev == "profile":
+		// The profiles are listed even if the selected one is unknown.
+		c, _ := readConfig("")
+		for _, p := range c.profiles {
+			fmt.Println(p)
+		}
+	case prev == "language" && name == "terms":
+		for _, l := range soanalysis.TermLanguages() {
+			fmt.Println(l)
+		}
+	case prev == "format" && name == "":
+		for f := range formatters {
+			fmt.Println(f)
+		}
+		fmt.Println("gnuplot")
+	case strings.HasPrefix(word, "-"):
+		// The usage of the command prints its flags and exits.
+		completing = true
+		switch name {
+		case "":
+			runAnalysis([]string{"-help"})
+		case "completion":
+			runCompletion([]string{"-help"})
+		case "help":
+			runHelp([]string{"-help"})
+		default:
+			commands[name]([]string{"-help"})
+		}
+	}
+}
+
+// completionDir returns the value of the -dir flag in words, or the default of
+// -dir for the command name from the config (with the profile selected in
+// words, if any), or "" if there is none.
+func completionDir(name string, words []string) string {
+	if dir, ok := flagArg(words, "dir"); ok {
+		return dir
+	}
+	p, ok := flagArg(words, "profile")
+	if !ok {
+		p = os.Getenv(cli.EnvVar("", "profile"))
+	}
+	c, err := readConfig(p)
+	if err != nil {
+		return ""
+	}
+	if dir, ok := c.commands[name]["dir"]; ok {
+		return dir
+	}
+	return c.flags["dir"]
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// concentrationRow is a bucket of the concentration of answers as presented in
+// -format json.
+type concentrationRow struct {
+	Date      string  `json:"date"`
+	Answers   int     `json:"answers"`
+	Answerers int     `json:"answerers"`
+	Gini      float64 `json:"gini"`
+	TopShare  float64 `json:"topShare"`
+}
+
+type concentrationTag struct {
+	Tag  string             `json:"tag"`
+	Rows []concentrationRow `json:"rows"`
+}
+
+// runConcentration implements the concentration command, which reports how
+// concentrated the answers of each tag and bucket are among the answerers: the
+// Gini coefficient of their numbers of answers and the share of the answers
+// given by the top 10% of them; see soanalysis.Analyzer.Concentration. It
+// requires the answers to be fetched with fetch-all-questions -answers.
+func runConcentration(args []string) {
+	fs := newFlagSet("concentration")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("concentration requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.ConcentrationSeries
+	for _, tag := range tags {
+		ts, err := an.Concentration(ctx, tag, fromDate, toDate, af.bymonth)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, ts)
+	}
+
+	if *formatFlag == "json" {
+		err = writeConcentrationJSON(os.Stdout, results)
+	} else {
+		err = writeConcentrationCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeConcentrationCSV writes the results of each tag as a line with the tag
+// name followed by a CSV table with a row per bucket.
+func writeConcentrationCSV(w io.Writer, results []soanalysis.ConcentrationSeries) error {
+	for _, ts := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ts.Tag); err != nil {
+			return err
+		}
+		for _, b := range ts.Buckets {
+			c := b.Concentration
+			_, err := fmt.Fprintf(w, "%s,%d,%d,%.3f,%.3f\n", b.Date.Format("2006-01-02"), c.Answers, c.Answerers, c.Gini, c.TopShare)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeConcentrationJSON writes the results as a JSON array with an object per
+// tag.
+func writeConcentrationJSON(w io.Writer, results []soanalysis.ConcentrationSeries) error {
+	cts := []concentrationTag{}
+	for _, ts := range results {
+		ct := concentrationTag{Tag: ts.Tag, Rows: []concentrationRow{}}
+		for _, b := range ts.Buckets {
+			c := b.Concentration
+			ct.Rows = append(ct.Rows, concentrationRow{
+				Date:      b.Date.Format("2006-01-02"),
+				Answers:   c.Answers,
+				Answerers: c.Answerers,
+				Gini:      c.Gini,
+				TopShare:  c.TopShare,
+			})
+		}
+		cts = append(cts, ct)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cts)
+}
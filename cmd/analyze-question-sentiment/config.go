@@ -0,0 +1,321 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// config holds the defaults of flags read from the config file, sotrends.toml
+// in the user's config directory (e.g. ~/.config/sotrends.toml):
+//
+//	dir = "/home/me/so-data"
+//	format = "json"
+//	timezone = "America/Los_Angeles"
+//
+//	[serve]
+//	addr = "0.0.0.0:8080"
+//
+//	[groups]
+//	systems = ["go", "rust", "c++"]
+//
+//	[events]
+//	"Go 1.18" = 2022-03-15
+//	"ChatGPT launch" = 2022-11-30
+//
+//	[profiles.frameworks]
+//	dir = "/home/me/so-frameworks"
+//	tags = ["django", "rails", "react"]
+//
+//	[profiles.frameworks.groups]
+//	python = ["django", "flask"]
+//
+// Top-level keys set the default of the flag with that name in every command
+// that has it, and keys in a table named after a command only in that command.
+// Flags given on the command line override these defaults. The groups table
+// defines named groups of tags, which can be used in -tags instead of listing
+// the tags of the group. The events table names dates that may have changed the
+// reception of questions; they are marked on the charts and reports, and
+// compared before and after by the events command.
+//
+// The profiles table holds named profiles, for working with several datasets:
+// the keys of the profile selected with -profile (or the top-level profile key)
+// override the ones outside of profiles, and its tables (such as groups or the
+// tables of commands) add to the ones outside of profiles.
+type config struct {
+	flags    map[string]string
+	commands map[string]map[string]string
+	groups   map[string][]string
+
+	// profiles are the names of the profiles, sorted.
+	profiles []string
+
+	// events are sorted by date.
+	events []soanalysis.Event
+}
+
+// cfg is the config loaded from the config file by parseFlags, with the profile
+// selected by -profile or $SOTRENDS_PROFILE.
+var cfg config
+
+// profile is the profile of the config given by -profile.
+var profile string
+
+// configPath returns the path of the config file.
+func configPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "sotrends.toml")
+}
+
+// readConfig reads the config file with the given profile (or the default one
+// if it's empty); a missing config file is the same as an empty one.
+func readConfig(profile string) (config, error) {
+	c := config{
+		flags:    make(map[string]string),
+		commands: make(map[string]map[string]string),
+		groups:   make(map[string][]string),
+	}
+	path := configPath()
+	if path == "" {
+		return c, nil
+	}
+
+	var raw map[string]interface{}
+	_, err := toml.DecodeFile(path, &raw)
+	if errors.Is(err, os.ErrNotExist) {
+		if profile != "" {
+			return c, fmt.Errorf("unknown profile %q", profile)
+		}
+		return c, nil
+	} else if err != nil {
+		return c, err
+	}
+	if err := selectProfile(&c, raw, profile); err != nil {
+		return c, err
+	}
+
+	for key, value := range raw {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if key == "groups" {
+				for name, tags := range v {
+					c.groups[name] = strings.Split(configValue(tags), ",")
+				}
+				continue
+			}
+			if key == "events" {
+				for name, value := range v {
+					date, err := configDate(value)
+					if err != nil {
+						return c, fmt.Errorf("date of event %q: %w", name, err)
+					}
+					c.events = append(c.events, soanalysis.Event{Name: name, Date: date})
+				}
+				slices.SortFunc(c.events, func(a, b soanalysis.Event) int {
+					return a.Date.Compare(b.Date)
+				})
+				continue
+			}
+			c.commands[key] = make(map[string]string)
+			for name, value := range v {
+				c.commands[key][name] = configValue(value)
+			}
+		default:
+			c.flags[key] = configValue(value)
+		}
+	}
+	return c, nil
+}
+
+// selectProfile merges the profile of raw (the decoded config file) with the
+// given name, or the one named by its profile key if name is empty, into raw,
+// and removes the profiles from it.
+func selectProfile(c *config, raw map[string]interface{}, name string) error {
+	profiles, _ := raw["profiles"].(map[string]interface{})
+	delete(raw, "profiles")
+	c.profiles = slices.Sorted(maps.Keys(profiles))
+	if name == "" {
+		name, _ = raw["profile"].(string)
+	}
+	if name == "" {
+		return nil
+	}
+	p, ok := profiles[name].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unknown profile %q; the profiles are: %s", name, strings.Join(c.profiles, ", "))
+	}
+	raw["profile"] = name
+	for key, value := range p {
+		table, ok := value.(map[string]interface{})
+		outer, outerOK := raw[key].(map[string]interface{})
+		if !ok || !outerOK {
+			raw[key] = value
+			continue
+		}
+		merged := maps.Clone(outer)
+		maps.Copy(merged, table)
+		raw[key] = merged
+	}
+	return nil
+}
+
+// configValue converts a value from the config file to a flag value; arrays
+// become lists separated by commas, like -tags.
+func configValue(value interface{}) string {
+	if values, ok := value.([]interface{}); ok {
+		var s []string
+		for _, v := range values {
+			s = append(s, fmt.Sprint(v))
+		}
+		return strings.Join(s, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// configDate converts a date from the config file, either a TOML date or a
+// string in 2006-01-02 format, to a time in UTC.
+func configDate(value interface{}) (time.Time, error) {
+	if t, ok := value.(time.Time); ok {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	return cli.ParseDate(configValue(value), time.UTC)
+}
+
+// parseFlags sets the defaults of the flags in fs from the config, and then
+// from the environment, and then parses the command-line arguments of the
+// command. In both the config and the environment, defaults for the specific
+// command take precedence over the ones for all commands.
+//
+// Errors in the config or the environment are reported only after parsing the
+// arguments, so that -help works even with a malformed config file.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	name, ok := flagArg(args, "profile")
+	if !ok {
+		name = os.Getenv(cli.EnvVar("", "profile"))
+	}
+	c, cfgErr := readConfig(name)
+	if cfgErr != nil {
+		c = config{}
+	}
+	cfg = c
+	parsed.fs = fs
+	parsed.args = args
+	parsed.defaults = make(map[string]string)
+	defaultsErr := setDefaults(fs)
+	fs.Parse(args)
+	failonf(cfgErr, "reading config file %q", configPath())
+	if defaultsErr != nil {
+		log.Fatal(defaultsErr)
+	}
+	setupTelemetry()
+}
+
+// flagArg returns the value of the flag with the given name in args, for
+// flags needed before parsing them, such as -profile, which selects the profile
+// of the config setting the defaults of the other flags.
+func flagArg(args []string, name string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		flagName, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || flagName != name {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// parsed records the flags of the command parsed by parseFlags, for
+// reloadConfig to parse them again.
+var parsed struct {
+	fs   *flag.FlagSet
+	args []string
+
+	// defaults are the defaults of the flags set from the config or the
+	// environment, before they were.
+	defaults map[string]string
+}
+
+// setDefaults sets the defaults of the flags in fs from the config and the
+// environment; see parseFlags.
+func setDefaults(fs *flag.FlagSet) error {
+	var errs []error
+	setDefault := func(f *flag.Flag, value string, source string) {
+		if _, ok := parsed.defaults[f.Name]; !ok {
+			parsed.defaults[f.Name] = f.DefValue
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: -%s: %w", source, f.Name, err))
+		}
+		f.DefValue = value
+	}
+
+	for _, values := range []map[string]string{cfg.flags, cfg.commands[fs.Name()]} {
+		for name, value := range values {
+			if f := fs.Lookup(name); f != nil {
+				setDefault(f, value, fmt.Sprintf("config file %q", configPath()))
+			}
+		}
+	}
+
+	cli.VisitEnv(fs, fs.Name(), func(f *flag.Flag, name string, value string) {
+		setDefault(f, value, "$"+name)
+	})
+	return errors.Join(errs...)
+}
+
+// reloadConfig reads the config file again, for long-running commands to pick
+// up changes to it (such as tags added to -tags or to a group) without
+// restarting: it replaces cfg, and sets the flags parsed by parseFlags to their
+// new defaults, unless they're given on the command line. On errors, such as a
+// malformed config file, the config and the flags are left as they are.
+func reloadConfig() error {
+	c, err := readConfig(profile)
+	if err != nil {
+		return fmt.Errorf("reading config file %q: %w", configPath(), err)
+	}
+	old := cfg
+	cfg = c
+	if err := resetFlags(); err != nil {
+		cfg = old
+		resetFlags()
+		return err
+	}
+	return nil
+}
+
+// resetFlags sets the flags parsed by parseFlags to their defaults from cfg and
+// the environment, and parses the command-line arguments again.
+func resetFlags() error {
+	fs := parsed.fs
+	for name, value := range parsed.defaults {
+		fs.Set(name, value)
+		fs.Lookup(name).DefValue = value
+	}
+	parsed.defaults = make(map[string]string)
+	if err := setDefaults(fs); err != nil {
+		return err
+	}
+	return fs.Parse(parsed.args)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// deletionRow is a bucket of the deletions of a tag as presented in -format
+// json.
+type deletionRow struct {
+	Date            string  `json:"date"`
+	Questions       int     `json:"questions"`
+	Deleted         int     `json:"deleted"`
+	DeletedRatio    float64 `json:"deletedRatio"`
+	DeletedNegative int     `json:"deletedNegative"`
+}
+
+type deletionTag struct {
+	Tag  string        `json:"tag"`
+	Rows []deletionRow `json:"rows"`
+}
+
+// runDeletions implements the deletions command, which reports how many of the
+// questions of each tag asked in each bucket were deleted, as detected when
+// their windows were fetched again; see soanalysis.Analyzer.Deletions.
+func runDeletions(args []string) {
+	fs := newFlagSet("deletions")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("deletions requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.DeletionSeries
+	for _, tag := range tags {
+		ds, err := an.Deletions(ctx, tag, fromDate, toDate, af.bymonth)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, ds)
+	}
+
+	if *formatFlag == "json" {
+		err = writeDeletionsJSON(os.Stdout, results)
+	} else {
+		err = writeDeletionsCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeDeletionsCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per bucket.
+func writeDeletionsCSV(w io.Writer, results []soanalysis.DeletionSeries) error {
+	for _, ds := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ds.Tag); err != nil {
+			return err
+		}
+		for _, b := range ds.Buckets {
+			_, err := fmt.Fprintf(w, "%s,%d,%d,%.3f,%d\n", b.Date.Format("2006-01-02"), b.Questions, b.Deleted,
+				b.DeletedRatio(), b.DeletedNegative)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeDeletionsJSON writes the results as a JSON array with an object per tag.
+func writeDeletionsJSON(w io.Writer, results []soanalysis.DeletionSeries) error {
+	dts := []deletionTag{}
+	for _, ds := range results {
+		dt := deletionTag{Tag: ds.Tag, Rows: []deletionRow{}}
+		for _, b := range ds.Buckets {
+			dt.Rows = append(dt.Rows, deletionRow{
+				Date:            b.Date.Format("2006-01-02"),
+				Questions:       b.Questions,
+				Deleted:         b.Deleted,
+				DeletedRatio:    b.DeletedRatio(),
+				DeletedNegative: b.DeletedNegative,
+			})
+		}
+		dts = append(dts, dt)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dts)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"text/tabwriter"
+)
+
+// runDiff implements the diff command, which compares two analysis runs and
+// prints the change of each metric per tag and bucket, marking significant
+// changes with '*'. Each of the two runs is either a result file written with
+// -format json, or a data directory which is then analyzed with the given
+// flags (e.g. two snapshots of the same period fetched at different times).
+func runDiff(args []string) {
+	fs := newFlagSet("diff")
+	var af analysisFlags
+	af.register(fs)
+	thresholdFlag := fs.Float64("threshold", 0.05, "absolute change in a ratio considered significant")
+	parseFlags(fs, args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	load := func(path string) []reportTag {
+		info, err := os.Stat(path)
+		failonf(err, "reading %q", path)
+		if info.IsDir() {
+			daf := af
+			daf.dir = path
+			var rts []reportTag
+			for _, ts := range daf.run() {
+				rts = append(rts, newReportTag(ts))
+			}
+			return rts
+		}
+		data, err := os.ReadFile(path)
+		failonf(err, "reading %q", path)
+		var rts []reportTag
+		err = json.Unmarshal(data, &rts)
+		failonf(err, "unmarshalling %q", path)
+		return rts
+	}
+	err := writeDiff(os.Stdout, load(fs.Arg(0)), load(fs.Arg(1)), *thresholdFlag)
+	failonf(err, "writing diff")
+}
+
+// writeDiff writes the differences between the results before and after; changes of
+// ratios by at least threshold are marked as significant.
+func writeDiff(w io.Writer, before []reportTag, after []reportTag, threshold float64) error {
+	oldTags := make(map[string]reportTag)
+	for _, rt := range before {
+		oldTags[rt.Tag] = rt
+	}
+	newTags := make(map[string]bool)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, nrt := range after {
+		newTags[nrt.Tag] = true
+		ort, ok := oldTags[nrt.Tag]
+		if !ok {
+			fmt.Fprintf(tw, "\n%s\tonly in new\n", nrt.Tag)
+			continue
+		}
+
+		fmt.Fprintf(tw, "\n%s\n", nrt.Tag)
+		fmt.Fprintln(tw, "date\tmetric\told\tnew\tchange\t")
+		oldRows := make(map[string]reportRow)
+		for _, r := range ort.Rows {
+			oldRows[r.Date] = r
+		}
+		for _, nr := range nrt.Rows {
+			or, ok := oldRows[nr.Date]
+			if !ok {
+				fmt.Fprintf(tw, "%s\tonly in new\t\t\t\t\n", nr.Date)
+				continue
+			}
+			delete(oldRows, nr.Date)
+
+			fmt.Fprintf(tw, "%s\tquestions\t%d\t%d\t%+d\t\n", nr.Date, or.Total, nr.Total, nr.Total-or.Total)
+			for _, m := range []struct {
+				name     string
+				old, new float64
+			}{
+				{"negative", or.Negative, nr.Negative},
+				{"closed", or.Closed, nr.Closed},
+				{"closed & negative", or.ClosedAndNegative, nr.ClosedAndNegative},
+			} {
+				mark := ""
+				if math.Abs(m.new-m.old) >= threshold {
+					mark = "*"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%.3f\t%.3f\t%+.3f\t%s\n", nr.Date, m.name, m.old, m.new, m.new-m.old, mark)
+			}
+		}
+		for _, or := range ort.Rows {
+			if _, ok := oldRows[or.Date]; ok {
+				fmt.Fprintf(tw, "%s\tonly in old\t\t\t\t\n", or.Date)
+			}
+		}
+	}
+	for _, ort := range before {
+		if !newTags[ort.Tag] {
+			fmt.Fprintf(tw, "\n%s\tonly in old\n", ort.Tag)
+		}
+	}
+	return tw.Flush()
+}
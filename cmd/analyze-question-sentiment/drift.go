@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// driftRow is a bucket of the drift of the scores of a tag as presented in
+// -format json.
+type driftRow struct {
+	Date          string  `json:"date"`
+	Tracked       int     `json:"tracked"`
+	MeanChange    float64 `json:"meanChange"`
+	Negative      int     `json:"negative"`
+	Recovered     int     `json:"recovered"`
+	RecoveryRatio float64 `json:"recoveryRatio"`
+}
+
+// driftWeek is a week of the age of the questions as presented in -format
+// json.
+type driftWeek struct {
+	Week      int     `json:"week"`
+	Snapshots int     `json:"snapshots"`
+	MeanScore float64 `json:"meanScore"`
+	Negative  float64 `json:"negative"`
+}
+
+type driftTag struct {
+	Tag   string      `json:"tag"`
+	Rows  []driftRow  `json:"rows"`
+	Weeks []driftWeek `json:"weeks"`
+}
+
+// runDrift implements the drift command, which reports how the scores of the
+// questions of each tag drift after they are asked, comparing the scores
+// logged by successive fetches: per bucket, the mean change of the scores and
+// how many initially negative questions recovered, and per week of the age of
+// the questions, their mean score. See soanalysis.Analyzer.ScoreDrift.
+func runDrift(args []string) {
+	fs := newFlagSet("drift")
+	var af analysisFlags
+	af.register(fs)
+	weeksFlag := fs.Int("weeks", 12, "number of weeks after asking to report the scores of")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("drift requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.DriftSeries
+	for _, tag := range tags {
+		dr, err := an.ScoreDrift(ctx, tag, fromDate, toDate, af.bymonth)
+		failonf(err, "analyzing tag %q", tag)
+		if len(dr.Weeks) > *weeksFlag {
+			dr.Weeks = dr.Weeks[:*weeksFlag]
+		}
+		results = append(results, dr)
+	}
+
+	if *formatFlag == "json" {
+		err = writeDriftJSON(os.Stdout, results)
+	} else {
+		err = writeDriftCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeDriftCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per bucket, and then a line with the tag
+// name followed by a CSV table with a row per week.
+func writeDriftCSV(w io.Writer, results []soanalysis.DriftSeries) error {
+	for _, dr := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", dr.Tag); err != nil {
+			return err
+		}
+		for _, b := range dr.Buckets {
+			_, err := fmt.Fprintf(w, "%s,%d,%.3f,%d,%d,%.3f\n", b.Date.Format("2006-01-02"), b.Tracked, b.MeanChange,
+				b.Negative, b.Recovered, b.RecoveryRatio())
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "\n%s by week\n", dr.Tag); err != nil {
+			return err
+		}
+		for _, wk := range dr.Weeks {
+			_, err := fmt.Fprintf(w, "%d,%d,%.3f,%.3f\n", wk.Week, wk.Snapshots, wk.MeanScore, wk.NegativeRatio())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeDriftJSON writes the results as a JSON array with an object per tag.
+func writeDriftJSON(w io.Writer, results []soanalysis.DriftSeries) error {
+	dts := []driftTag{}
+	for _, dr := range results {
+		dt := driftTag{Tag: dr.Tag, Rows: []driftRow{}, Weeks: []driftWeek{}}
+		for _, b := range dr.Buckets {
+			dt.Rows = append(dt.Rows, driftRow{
+				Date:          b.Date.Format("2006-01-02"),
+				Tracked:       b.Tracked,
+				MeanChange:    b.MeanChange,
+				Negative:      b.Negative,
+				Recovered:     b.Recovered,
+				RecoveryRatio: b.RecoveryRatio(),
+			})
+		}
+		for _, wk := range dr.Weeks {
+			dt.Weeks = append(dt.Weeks, driftWeek{
+				Week:      wk.Week,
+				Snapshots: wk.Snapshots,
+				MeanScore: wk.MeanScore,
+				Negative:  wk.NegativeRatio(),
+			})
+		}
+		dts = append(dts, dt)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dts)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// duplicateRow is a bucket of the duplicates of a tag as presented in -format
+// json.
+type duplicateRow struct {
+	Date            string  `json:"date"`
+	Closed          int     `json:"closed"`
+	Duplicates      int     `json:"duplicates"`
+	Clusters        int     `json:"clusters"`
+	MeanClusterSize float64 `json:"meanClusterSize"`
+	Largest         int     `json:"largest"`
+}
+
+// duplicateCluster is a cluster of duplicates as presented in -format json.
+type duplicateCluster struct {
+	Original   int    `json:"original"`
+	Link       string `json:"link"`
+	Title      string `json:"title"`
+	Duplicates []int  `json:"duplicates"`
+}
+
+type duplicateTag struct {
+	Tag      string             `json:"tag"`
+	Rows     []duplicateRow     `json:"rows"`
+	Clusters []duplicateCluster `json:"clusters"`
+}
+
+// runDuplicates implements the duplicates command, which reports how the
+// questions closed as duplicates of each tag cluster by their original
+// questions in each bucket, and the largest clusters of the whole period;
+// large clusters point at frequently asked questions that drive closures. See
+// soanalysis.Analyzer.Duplicates. It requires the questions to be fetched with
+// fetch-all-questions -closeddetails.
+func runDuplicates(args []string) {
+	fs := newFlagSet("duplicates")
+	var af analysisFlags
+	af.register(fs)
+	topFlag := fs.Int("top", 10, "number of largest clusters to list for each tag")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("duplicates requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.DuplicateSeries
+	for _, tag := range tags {
+		ds, err := an.Duplicates(ctx, tag, fromDate, toDate, af.bymonth)
+		failonf(err, "analyzing tag %q", tag)
+		if len(ds.Total.Clusters) > *topFlag {
+			ds.Total.Clusters = ds.Total.Clusters[:*topFlag]
+		}
+		results = append(results, ds)
+	}
+
+	if *formatFlag == "json" {
+		err = writeDuplicatesJSON(os.Stdout, results)
+	} else {
+		err = writeDuplicatesCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// questionLink returns the link to the question with the given ID.
+func questionLink(id int) string {
+	return fmt.Sprintf("https://stackoverflow.com/q/%d", id)
+}
+
+// largestCluster returns the size of the largest cluster of b.
+func largestCluster(b soanalysis.DuplicateBucket) int {
+	if len(b.Clusters) == 0 {
+		return 0
+	}
+	return len(b.Clusters[0].Questions)
+}
+
+// writeDuplicatesCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per bucket, and then a line with the tag
+// name followed by a CSV table with a row per largest cluster.
+func writeDuplicatesCSV(w io.Writer, results []soanalysis.DuplicateSeries) error {
+	for _, ds := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ds.Tag); err != nil {
+			return err
+		}
+		for _, b := range ds.Buckets {
+			_, err := fmt.Fprintf(w, "%s,%d,%d,%d,%.2f,%d\n", b.Date.Format("2006-01-02"), b.Closed, b.Duplicates,
+				len(b.Clusters), b.MeanClusterSize(), largestCluster(b))
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "\n%s largest clusters\n", ds.Tag); err != nil {
+			return err
+		}
+		cw := csv.NewWriter(w)
+		for _, c := range ds.Total.Clusters {
+			cw.Write([]string{strconv.Itoa(len(c.Questions)), strconv.Itoa(c.Original), questionLink(c.Original), c.Title})
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeDuplicatesJSON writes the results as a JSON array with an object per
+// tag.
+func writeDuplicatesJSON(w io.Writer, results []soanalysis.DuplicateSeries) error {
+	dts := []duplicateTag{}
+	for _, ds := range results {
+		dt := duplicateTag{Tag: ds.Tag, Rows: []duplicateRow{}, Clusters: []duplicateCluster{}}
+		for _, b := range ds.Buckets {
+			dt.Rows = append(dt.Rows, duplicateRow{
+				Date:            b.Date.Format("2006-01-02"),
+				Closed:          b.Closed,
+				Duplicates:      b.Duplicates,
+				Clusters:        len(b.Clusters),
+				MeanClusterSize: b.MeanClusterSize(),
+				Largest:         largestCluster(b),
+			})
+		}
+		for _, c := range ds.Total.Clusters {
+			dt.Clusters = append(dt.Clusters, duplicateCluster{
+				Original:   c.Original,
+				Link:       questionLink(c.Original),
+				Title:      c.Title,
+				Duplicates: c.Questions,
+			})
+		}
+		dts = append(dts, dt)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(dts)
+}
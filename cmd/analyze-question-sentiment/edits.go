@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// editRow is a bucket of the edit activity of a tag as presented in -format
+// json.
+type editRow struct {
+	Date              string  `json:"date"`
+	Questions         int     `json:"questions"`
+	Edited            int     `json:"edited"`
+	EditedRatio       float64 `json:"editedRatio"`
+	MedianHoursToEdit float64 `json:"medianHoursToEdit"`
+	NegativeEdited    int     `json:"negativeEdited"`
+	Recovered         int     `json:"recovered"`
+	RecoveryRatio     float64 `json:"recoveryRatio"`
+}
+
+type editTag struct {
+	Tag  string    `json:"tag"`
+	Rows []editRow `json:"rows"`
+}
+
+// runEdits implements the edits command, which reports the edit activity of
+// the questions of each tag in each bucket as a measure of the effort of the
+// community to curate them: the fraction of the questions that were edited,
+// the median time to edit them, and how many of the negative questions edited
+// after being downvoted recovered. See soanalysis.Analyzer.Edits; the latter
+// needs several snapshots of the questions, fetched with fetch-all-questions
+// -refresh.
+func runEdits(args []string) {
+	fs := newFlagSet("edits")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("edits requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.EditSeries
+	for _, tag := range tags {
+		es, err := an.Edits(ctx, tag, fromDate, toDate, af.bymonth)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, es)
+	}
+
+	if *formatFlag == "json" {
+		err = writeEditsJSON(os.Stdout, results)
+	} else {
+		err = writeEditsCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeEditsCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per bucket.
+func writeEditsCSV(w io.Writer, results []soanalysis.EditSeries) error {
+	for _, es := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", es.Tag); err != nil {
+			return err
+		}
+		for _, b := range es.Buckets {
+			_, err := fmt.Fprintf(w, "%s,%d,%d,%.3f,%.1f,%d,%d,%.3f\n", b.Date.Format("2006-01-02"), b.Questions, b.Edited,
+				b.EditedRatio(), b.MedianTimeToEdit.Hours(), b.NegativeEdited, b.Recovered, b.RecoveryRatio())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeEditsJSON writes the results as a JSON array with an object per tag.
+func writeEditsJSON(w io.Writer, results []soanalysis.EditSeries) error {
+	ets := []editTag{}
+	for _, es := range results {
+		et := editTag{Tag: es.Tag, Rows: []editRow{}}
+		for _, b := range es.Buckets {
+			et.Rows = append(et.Rows, editRow{
+				Date:              b.Date.Format("2006-01-02"),
+				Questions:         b.Questions,
+				Edited:            b.Edited,
+				EditedRatio:       b.EditedRatio(),
+				MedianHoursToEdit: b.MedianTimeToEdit.Hours(),
+				NegativeEdited:    b.NegativeEdited,
+				Recovered:         b.Recovered,
+				RecoveryRatio:     b.RecoveryRatio(),
+			})
+		}
+		ets = append(ets, et)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ets)
+}
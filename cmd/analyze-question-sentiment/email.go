@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// runEmail implements the email command, which sends the monthly report of
+// the last -months months by email: an HTML summary with a table per tag, and
+// the results attached as CSV. It's meant to be run on a schedule, like notify.
+// The SMTP credentials are taken from the SMTP_USERNAME and SMTP_PASSWORD env
+// vars, if set.
+func runEmail(args []string) {
+	fs := newFlagSet("email")
+	var af analysisFlags
+	af.register(fs)
+	monthsFlag := fs.Int("months", 12, "number of months to report, when -fromdate isn't provided")
+	smtpFlag := fs.String("smtp", "localhost:25", "SMTP server address, host:port")
+	fromFlag := fs.String("from", "", "sender address")
+	toFlag := fs.String("to", "", "recipient addresses, separated by commas")
+	subjectFlag := fs.String("subject", "StackOverflow tag sentiment report", "subject of the email")
+	parseFlags(fs, args)
+
+	if *fromFlag == "" || *toFlag == "" {
+		log.Fatal("-from and -to must be provided")
+	}
+	af.setRecentMonths(*monthsFlag)
+	rd := newReportData(fs, af.run())
+
+	var html bytes.Buffer
+	err := reportTemplates.ExecuteTemplate(&html, "email", rd)
+	failonf(err, "rendering report")
+
+	var attachment bytes.Buffer
+	err = writeReportCSV(&attachment, rd)
+	failonf(err, "writing CSV")
+
+	to := strings.Split(*toFlag, ",")
+	msg, err := composeEmail(*fromFlag, to, *subjectFlag, html.Bytes(), "sentiment.csv", attachment.Bytes())
+	failonf(err, "composing email")
+
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		host, _, _ := net.SplitHostPort(*smtpFlag)
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	err = smtp.SendMail(*smtpFlag, auth, *fromFlag, to, msg)
+	failonf(err, "sending email")
+	fmt.Println("Sent report to", *toFlag)
+}
+
+// writeReportCSV writes the rows of all tags in rd as a CSV table.
+func writeReportCSV(w io.Writer, rd reportData) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"tag", "date", "questions", "negative", "closed", "closed_and_negative"})
+	for _, rt := range rd.Tags {
+		for _, r := range rt.Rows {
+			cw.Write([]string{rt.Tag, r.Date, strconv.Itoa(r.Total),
+				strconv.FormatFloat(r.Negative, 'f', 3, 64),
+				strconv.FormatFloat(r.Closed, 'f', 3, 64),
+				strconv.FormatFloat(r.ClosedAndNegative, 'f', 3, 64)})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// composeEmail builds a MIME message with an HTML body and a single CSV file
+// attached.
+func composeEmail(from string, to []string, subject string, html []byte, filename string, attachment []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	pw, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	qw := quotedprintable.NewWriter(pw)
+	if _, err := qw.Write(html); err != nil {
+		return nil, err
+	}
+	if err := qw.Close(); err != nil {
+		return nil, err
+	}
+
+	pw, err = mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/csv; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", filename)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Wrap the base64 lines at 76 characters, as required by MIME
+	encoded := base64.StdEncoding.EncodeToString(attachment)
+	for len(encoded) > 76 {
+		fmt.Fprintf(pw, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	fmt.Fprintf(pw, "%s\r\n", encoded)
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// errorRow is a bucket of the most frequent error signatures as presented in
+// -format json.
+type errorRow struct {
+	Date       string           `json:"date"`
+	Questions  int              `json:"questions"`
+	WithErrors int              `json:"withErrors"`
+	Top        []errorCountJSON `json:"top"`
+}
+
+type errorCountJSON struct {
+	Signature string  `json:"signature"`
+	Questions int     `json:"questions"`
+	Share     float64 `json:"share"`
+}
+
+type errorTag struct {
+	Tag  string     `json:"tag"`
+	Rows []errorRow `json:"rows"`
+}
+
+// runErrors implements the errors command, which reports the most frequent
+// error signatures (quoted error messages, exception names, exit codes...) in
+// the titles of the questions of each tag in each bucket; see
+// soanalysis.ErrorSignatures.
+func runErrors(args []string) {
+	fs := newFlagSet("errors")
+	var af analysisFlags
+	af.register(fs)
+	nFlag := fs.Int("n", 10, "number of error signatures listed per bucket")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("errors requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.ErrorSeries
+	for _, tag := range tags {
+		es, err := an.TopErrors(ctx, tag, fromDate, toDate, af.bymonth, *nFlag)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, es)
+	}
+
+	if *formatFlag == "json" {
+		err = writeErrorsJSON(os.Stdout, results)
+	} else {
+		err = writeErrorsCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeErrorsCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per error signature of each bucket: its
+// rank, the signature, and the number and share of the questions of the
+// bucket with it.
+func writeErrorsCSV(w io.Writer, results []soanalysis.ErrorSeries) error {
+	for _, es := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", es.Tag); err != nil {
+			return err
+		}
+		cw := csv.NewWriter(w)
+		for _, b := range es.Buckets {
+			for i, ec := range b.Top {
+				cw.Write([]string{b.Date.Format("2006-01-02"), strconv.Itoa(i + 1), ec.Signature,
+					strconv.Itoa(ec.Questions), strconv.FormatFloat(b.Share(ec), 'f', 3, 64)})
+			}
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeErrorsJSON writes the results as a JSON array with an object per tag.
+func writeErrorsJSON(w io.Writer, results []soanalysis.ErrorSeries) error {
+	ets := []errorTag{}
+	for _, es := range results {
+		et := errorTag{Tag: es.Tag, Rows: []errorRow{}}
+		for _, b := range es.Buckets {
+			row := errorRow{
+				Date:       b.Date.Format("2006-01-02"),
+				Questions:  b.Questions,
+				WithErrors: b.WithErrors,
+				Top:        []errorCountJSON{},
+			}
+			for _, ec := range b.Top {
+				row.Top = append(row.Top, errorCountJSON{Signature: ec.Signature, Questions: ec.Questions, Share: b.Share(ec)})
+			}
+			et.Rows = append(et.Rows, row)
+		}
+		ets = append(ets, et)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ets)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+type eventTag struct {
+	Tag    string     `json:"tag"`
+	Events []eventRow `json:"events"`
+}
+
+// eventImpacts are the comparisons around the events of a tag.
+type eventImpacts struct {
+	tag     string
+	impacts []soanalysis.EventImpact
+}
+
+// runEvents implements the events command, which compares the questions of
+// each tag asked shortly before each event of the config file with the ones
+// asked shortly after it; see soanalysis.Analyzer.EventImpact.
+func runEvents(args []string) {
+	fs := newFlagSet("events")
+	var af analysisFlags
+	af.register(fs)
+	daysFlag := fs.Int("days", 90, "number of days before and after each event to compare")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("events requires a data directory, not -dir -")
+	}
+	if len(cfg.events) == 0 {
+		log.Fatalf("no events in the config file %q; add them to its events table", configPath())
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []eventImpacts
+	for _, tag := range tags {
+		ei := eventImpacts{tag: tag}
+		for _, ev := range cfg.events {
+			if (!fromDate.IsZero() && ev.Date.Before(fromDate)) || (!toDate.IsZero() && ev.Date.After(toDate)) {
+				continue
+			}
+			impact, err := an.EventImpact(ctx, tag, ev, *daysFlag)
+			failonf(err, "analyzing tag %q around %q", tag, ev.Name)
+			ei.impacts = append(ei.impacts, impact)
+		}
+		results = append(results, ei)
+	}
+
+	if *formatFlag == "json" {
+		err = writeEventsJSON(os.Stdout, results)
+	} else {
+		err = writeEventsCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeEventsCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per event: the number of questions and the
+// negative, closed and closed & negative ratios before and after it.
+func writeEventsCSV(w io.Writer, results []eventImpacts) error {
+	for _, ei := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ei.tag); err != nil {
+			return err
+		}
+		for _, impact := range ei.impacts {
+			b, a := impact.Before, impact.After
+			_, err := fmt.Fprintf(w, "%s,%s,%d,%d,%.3f,%.3f,%.3f,%.3f,%.3f,%.3f\n", impact.Event.Name,
+				impact.Event.Date.Format("2006-01-02"), b.Total, a.Total, b.NegativeRatio(), a.NegativeRatio(),
+				b.ClosedRatio(), a.ClosedRatio(), b.ClosedAndNegativeRatio(), a.ClosedAndNegativeRatio())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeEventsJSON writes the results as a JSON array with an object per tag.
+func writeEventsJSON(w io.Writer, results []eventImpacts) error {
+	ets := []eventTag{}
+	for _, ei := range results {
+		et := eventTag{Tag: ei.tag, Events: []eventRow{}}
+		for _, impact := range ei.impacts {
+			et.Events = append(et.Events, eventRow{
+				Name:   impact.Event.Name,
+				Date:   impact.Event.Date.Format("2006-01-02"),
+				Before: newEventStats(impact.Before),
+				After:  newEventStats(impact.After),
+			})
+		}
+		ets = append(ets, et)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ets)
+}
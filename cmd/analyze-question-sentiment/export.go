@@ -0,0 +1,100 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// runExport implements the export command, which copies the questions of the
+// tags in -dir between -fromdate and -todate (and under -licenses, if given)
+// into a new data directory, merged into large files like the compact command
+// does. With -anonymize, the personal data of the owners of the questions is
+// removed on the way (see soanalysis.Anonymize), so the exported data can be
+// shared; it can still be analyzed like any other data directory.
+//
+// With -notebook, the analysis of the questions is exported instead, as a
+// data bundle for custom analyses: a panel of the metrics of each tag and
+// bucket (as written by -format panel) along with a Jupyter or R Markdown
+// notebook loading it and reproducing the standard charts; see writeNotebook.
+func runExport(args []string) {
+	fs := newFlagSet("export")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	fs.StringVar(&af.fromDate, "fromdate", "", "start date in 2006-01-02 format")
+	fs.StringVar(&af.toDate, "todate", "", "end date in 2006-01-02 format")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) separated by commas; all tags by default")
+	fs.StringVar(&af.timezone, "timezone", "UTC", "time zone of the dates, e.g. America/New_York")
+	fs.BoolVar(&af.bymonth, "bymonth", false, "analyze by month, with -notebook")
+	fs.StringVar(&af.licenses, "licenses", "", "only export questions under these content licenses, separated by commas, e.g. 'CC BY-SA 4.0'")
+	outFlag := fs.String("out", "", "directory to export into")
+	anonymizeFlag := fs.Bool("anonymize", false, "remove the personal data of the owners of questions")
+	keyFlag := fs.String("key", "", "secret key for pseudonymizing user IDs with -anonymize; random by default, so the pseudonyms differ between exports")
+	sizeFlag := fs.Int("size", 10000, "maximal number of questions per exported file")
+	notebookFlag := fs.String("notebook", "", "export the analysis as a data bundle with a notebook reproducing its charts instead of the questions: jupyter or rmarkdown")
+	parseFlags(fs, args)
+
+	if cli.IsStream(af.dir) {
+		log.Fatal("-dir - can't be exported")
+	}
+	if *outFlag == "" {
+		log.Fatal("-out must be provided")
+	}
+	if *notebookFlag != "" {
+		if *notebookFlag != "jupyter" && *notebookFlag != "rmarkdown" {
+			log.Fatalf("unknown -notebook %q", *notebookFlag)
+		}
+		if *anonymizeFlag {
+			log.Fatal("-notebook exports no personal data to remove with -anonymize")
+		}
+		if _, err := os.Stat(filepath.Join(*outFlag, notebookPanel)); err == nil {
+			log.Fatalf("%s already holds a bundle; export into a fresh -out", *outFlag)
+		}
+		err := writeNotebook(*outFlag, *notebookFlag, af.run(), &af)
+		failonf(err, "writing notebook bundle")
+		fmt.Println("Wrote notebook bundle into", *outFlag)
+		return
+	}
+	if af.bymonth {
+		log.Fatal("-bymonth only applies to -notebook")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	key := []byte(*keyFlag)
+	if *anonymizeFlag && len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	out := &soanalysis.Dataset{Dir: *outFlag}
+	for _, tag := range tags {
+		dir := out.TagDir(tag)
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+			log.Fatalf("Directory %s is not empty; export into a fresh -out", dir)
+		}
+
+		var items []soapi.Item
+		err := an.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+			if *anonymizeFlag {
+				soanalysis.Anonymize(item, key)
+			}
+			items = append(items, *item)
+		})
+		failonf(err, "reading tag %q", tag)
+		paths, err := out.WriteItems(tag, items, *sizeFlag)
+		failonf(err, "exporting tag %q", tag)
+		fmt.Printf("%s: exported %d questions into %d files\n", tag, len(items), len(paths))
+	}
+}
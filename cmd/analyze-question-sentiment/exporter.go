@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// runExporter implements the exporter command, which serves the metrics of
+// each tag in -dir over a trailing window as Prometheus gauges on /metrics.
+// The metrics are recomputed from the data on every scrape.
+func runExporter(args []string) {
+	fs := newFlagSet("exporter")
+	dirFlag := fs.String("dir", "", "base directory with results")
+	addrFlag := fs.String("addr", "localhost:9191", "address to listen on")
+	windowFlag := fs.Int("window", 30, "trailing window to compute the metrics for, in days")
+	sentimentsFlag := fs.String("sentiments", "", "CSV or JSON file mapping question IDs to sentiment scores from an external model, to export the mean of")
+	parseFlags(fs, args)
+
+	if len(*dirFlag) == 0 {
+		log.Fatal("-dir must be provided and cannot be empty. Please use the folder where the data was fetched.")
+	}
+
+	an := soanalysis.NewAnalyzer(*dirFlag)
+	an.Sentiments = loadSentimentScores(*sentimentsFlag)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(&tagCollector{analyzer: an, windowDays: *windowFlag})
+
+	http.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	log.Println("Serving metrics on", *addrFlag)
+	log.Fatal(http.ListenAndServe(*addrFlag, nil))
+}
+
+var (
+	questionsDesc = prometheus.NewDesc("so_tag_questions",
+		"Number of questions asked in the trailing window.", []string{"tag"}, nil)
+	negativeRatioDesc = prometheus.NewDesc("so_tag_negative_ratio",
+		"Ratio of questions with a negative score in the trailing window.", []string{"tag"}, nil)
+	closedRatioDesc = prometheus.NewDesc("so_tag_closed_ratio",
+		"Ratio of closed questions in the trailing window.", []string{"tag"}, nil)
+	unansweredRatioDesc = prometheus.NewDesc("so_tag_unanswered_ratio",
+		"Ratio of unanswered questions in the trailing window.", []string{"tag"}, nil)
+	negativePer10kViewsDesc = prometheus.NewDesc("so_tag_negative_per_10k_views",
+		"Questions with a negative score per 10,000 views in the trailing window.", []string{"tag"}, nil)
+	meanSentimentDesc = prometheus.NewDesc("so_tag_mean_sentiment",
+		"Mean external sentiment score of the questions having one in the trailing window.", []string{"tag"}, nil)
+)
+
+// tagCollector is a prometheus.Collector analyzing the tags of analyzer over
+// the last windowDays days.
+type tagCollector struct {
+	analyzer   *soanalysis.Analyzer
+	windowDays int
+}
+
+func (tc *tagCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- questionsDesc
+	ch <- negativeRatioDesc
+	ch <- closedRatioDesc
+	ch <- unansweredRatioDesc
+	ch <- negativePer10kViewsDesc
+	ch <- meanSentimentDesc
+}
+
+func (tc *tagCollector) Collect(ch chan<- prometheus.Metric) {
+	toDate := time.Now()
+	fromDate := toDate.AddDate(0, 0, -tc.windowDays)
+	tags, err := tc.analyzer.Dataset.Tags()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(questionsDesc, err)
+		return
+	}
+	for _, tag := range tags {
+		tr, err := tc.analyzer.Analyze(context.Background(), tag, fromDate, toDate)
+		if err != nil {
+			ch <- prometheus.NewInvalidMetric(questionsDesc, err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(questionsDesc, prometheus.GaugeValue, float64(tr.Total), tag)
+		ch <- prometheus.MustNewConstMetric(negativeRatioDesc, prometheus.GaugeValue, tr.NegativeRatio(), tag)
+		ch <- prometheus.MustNewConstMetric(closedRatioDesc, prometheus.GaugeValue, tr.ClosedRatio(), tag)
+		ch <- prometheus.MustNewConstMetric(unansweredRatioDesc, prometheus.GaugeValue, tr.UnansweredRatio(), tag)
+		ch <- prometheus.MustNewConstMetric(negativePer10kViewsDesc, prometheus.GaugeValue, tr.NegativePer10kViews(), tag)
+		if len(tr.Sentiments) > 0 {
+			ch <- prometheus.MustNewConstMetric(meanSentimentDesc, prometheus.GaugeValue, tr.MeanSentiment(), tag)
+		}
+	}
+}
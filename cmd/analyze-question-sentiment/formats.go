@@ -0,0 +1,778 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/xuri/excelize/v2"
+)
+
+// formatters maps the names of output formats to functions writing analysis
+// results in that format.
+var formatters = map[string]func(w io.Writer, results []soanalysis.Series) error{
+	"csv":      writeCSV,
+	"influx":   writeInflux,
+	"vegalite": writeVegaLite,
+	"xlsx":     writeXLSX,
+	"json":     writeJSONResults,
+	"arrow":    writeArrow,
+	"cbor":     writeCBOR,
+	"msgpack":  writeMsgpack,
+	"licenses": writeLicenses,
+	"owners":   writeOwners,
+	"panel":    writePanel,
+}
+
+// writeCSV writes the results of each tag as a line with the tag name followed
+// by a CSV table with a row per bucket. With external sentiment scores, the
+// rows end with the mean score and the ratio of the questions having one.
+func writeCSV(w io.Writer, results []soanalysis.Series) error {
+	sentiments := hasSentiments(results)
+	for _, ts := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ts.Tag); err != nil {
+			return err
+		}
+		for _, b := range ts.Buckets {
+			tr := b.Result
+			row := fmt.Sprintf("%s,%d,%.3f,%.3f,%.3f", b.Date.Format("2006-01-02"), tr.Total, tr.NegativeRatio(), tr.ClosedRatio(), tr.ClosedAndNegativeRatio())
+			if sentiments {
+				row += fmt.Sprintf(",%.3f,%.3f", tr.MeanSentiment(), tr.SentimentCoverage())
+			}
+			if _, err := fmt.Fprintln(w, row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasSentiments reports whether any question of the results has an external
+// sentiment score (see -sentiments), for the outputs with columns of their
+// own for them.
+func hasSentiments(results []soanalysis.Series) bool {
+	for _, ts := range results {
+		for _, b := range ts.Buckets {
+			if len(b.Result.Sentiments) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writePanel writes the results as a balanced panel for fixed-effects
+// regressions in R or Stata: a single long CSV table with a header and a row
+// per tag and bucket, the tags identified by tag_id (their rank in
+// alphabetical order, so the same tags get the same IDs whatever the order of
+// -tags) and the buckets by period (their rank from 1). Metrics undefined for
+// a bucket without questions, or without site counts for site_share, are left
+// empty, which both read as missing.
+func writePanel(w io.Writer, results []soanalysis.Series) error {
+	if len(results) == 0 {
+		return nil
+	}
+	periods := results[0].Buckets
+	for _, ts := range results {
+		if len(ts.Buckets) != len(periods) {
+			return fmt.Errorf("tag %q has %d buckets instead of %d; the panel wouldn't be balanced", ts.Tag, len(ts.Buckets), len(periods))
+		}
+	}
+	results = slices.Clone(results)
+	slices.SortFunc(results, func(a, b soanalysis.Series) int { return strings.Compare(a.Tag, b.Tag) })
+
+	sentiments := hasSentiments(results)
+	header := []string{"tag_id", "tag", "period", "period_start", "period_end", "questions", "questions_per_day",
+		"negative", "closed", "closed_negative", "unanswered", "mean_score", "mean_views", "negative_per_10k_views", "site_share"}
+	if sentiments {
+		header = append(header, "mean_sentiment", "sentiment_coverage")
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	decimal := func(f float64) string { return strconv.FormatFloat(f, 'f', 6, 64) }
+	for id, ts := range results {
+		perDay := ts.QuestionsPerDay(1)
+		for i, b := range ts.Buckets {
+			start := ""
+			if !periods[i].Start.IsZero() {
+				start = periods[i].Start.Format("2006-01-02")
+			}
+			r := b.Result
+			row := []string{strconv.Itoa(id + 1), ts.Tag, strconv.Itoa(i + 1), start,
+				periods[i].Date.Format("2006-01-02"), strconv.Itoa(r.Total), decimal(perDay[i])}
+			metrics := []string{decimal(r.NegativeRatio()), decimal(r.ClosedRatio()), decimal(r.ClosedAndNegativeRatio()),
+				decimal(r.UnansweredRatio()), decimal(r.MeanScore()), decimal(r.MeanViews()), decimal(r.NegativePer10kViews())}
+			if r.Total == 0 {
+				metrics = make([]string, len(metrics))
+			}
+			row = append(row, metrics...)
+			if b.SiteTotal > 0 {
+				row = append(row, decimal(b.SiteShare()))
+			} else {
+				row = append(row, "")
+			}
+			if sentiments {
+				if len(r.Sentiments) > 0 {
+					row = append(row, decimal(r.MeanSentiment()), decimal(r.SentimentCoverage()))
+				} else {
+					row = append(row, "", decimal(r.SentimentCoverage()))
+				}
+			}
+			cw.Write(row)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeDeltaCSV writes the results like writeCSV, appending to each row the
+// change of each metric since the previous row, absolute and in percent. The
+// columns of the changes of the first row, and the percentages of changes from
+// 0, are empty.
+func writeDeltaCSV(w io.Writer, results []soanalysis.Series) error {
+	for _, ts := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ts.Tag); err != nil {
+			return err
+		}
+		rt := newReportTag(ts)
+		addDeltas(&rt)
+		for _, r := range rt.Rows {
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "%s,%d,%.3f,%.3f,%.3f", r.Date, r.Total, r.Negative, r.Closed, r.ClosedAndNegative)
+			if r.Deltas == nil {
+				sb.WriteString(",,,,,,,,")
+			} else {
+				for i, d := range []delta{r.Deltas.Total, r.Deltas.Negative, r.Deltas.Closed, r.Deltas.ClosedAndNegative} {
+					if i == 0 {
+						fmt.Fprintf(&sb, ",%.0f,", d.Change)
+					} else {
+						fmt.Fprintf(&sb, ",%.3f,", d.Change)
+					}
+					if d.Percent != nil {
+						fmt.Fprintf(&sb, "%.1f", *d.Percent)
+					}
+				}
+			}
+			sb.WriteString("\n")
+			if _, err := io.WriteString(w, sb.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeDeltaJSON writes the results like writeJSONResults, with the changes of
+// the metrics of each row since the previous one.
+func writeDeltaJSON(w io.Writer, results []soanalysis.Series) error {
+	rts := []reportTag{}
+	for _, ts := range results {
+		rt := newReportTag(ts)
+		addDeltas(&rt)
+		rts = append(rts, rt)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rts)
+}
+
+// writeForecastCSV writes the results like writeCSV, followed for each tag by
+// a line with the tag name and a CSV table of the forecasts of the n buckets
+// after them: a row per bucket with the forecast questions and ratios, each
+// followed by the bounds of its 95% prediction interval.
+func writeForecastCSV(w io.Writer, results []soanalysis.Series, n int) error {
+	if err := writeCSV(w, results); err != nil {
+		return err
+	}
+	for _, ts := range results {
+		rows, err := forecastRows(ts, n)
+		if err != nil {
+			return fmt.Errorf("forecasting tag %q: %w", ts.Tag, err)
+		}
+		if _, err := fmt.Fprintf(w, "\n%s forecast\n", ts.Tag); err != nil {
+			return err
+		}
+		for _, r := range rows {
+			_, err := fmt.Fprintf(w, "%s,%.0f,%.0f,%.0f,%.3f,%.3f,%.3f,%.3f,%.3f,%.3f,%.3f,%.3f,%.3f\n", r.Date,
+				r.Total.Value, r.Total.Lower, r.Total.Upper,
+				r.Negative.Value, r.Negative.Lower, r.Negative.Upper,
+				r.Closed.Value, r.Closed.Lower, r.Closed.Upper,
+				r.ClosedAndNegative.Value, r.ClosedAndNegative.Lower, r.ClosedAndNegative.Upper)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeForecastJSON writes the results like writeJSONResults, with the
+// forecasts of the n buckets after them.
+func writeForecastJSON(w io.Writer, results []soanalysis.Series, n int) error {
+	rts := []reportTag{}
+	for _, ts := range results {
+		rts = append(rts, newReportTag(ts))
+	}
+	if err := addForecasts(rts, results, n); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rts)
+}
+
+// writeLicenses writes the distribution of the content licenses of the
+// questions of each tag: a line with the tag name followed by a CSV table with
+// the number and ratio of the questions under each license in each bucket.
+func writeLicenses(w io.Writer, results []soanalysis.Series) error {
+	for _, ts := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ts.Tag); err != nil {
+			return err
+		}
+		for _, b := range ts.Buckets {
+			tr := b.Result
+			for _, license := range slices.Sorted(maps.Keys(tr.Licenses)) {
+				n := tr.Licenses[license]
+				_, err := fmt.Fprintf(w, "%s,%s,%d,%.3f\n", b.Date.Format("2006-01-02"), license, n, float64(n)/float64(tr.Total))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeOwners writes the metrics of the questions of each tag broken down by
+// the type of the accounts of their owners: a line with the tag name followed
+// by a CSV table with the number and share of the questions of each type in
+// each bucket, their negative and closed ratios, and the share of the negative
+// questions of the bucket asked by that type.
+func writeOwners(w io.Writer, results []soanalysis.Series) error {
+	for _, ts := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ts.Tag); err != nil {
+			return err
+		}
+		for _, b := range ts.Buckets {
+			tr := b.Result
+			for _, userType := range slices.Sorted(maps.Keys(tr.OwnerTypes)) {
+				oc := tr.OwnerTypes[userType]
+				_, err := fmt.Fprintf(w, "%s,%s,%d,%.3f,%.3f,%.3f,%.3f\n", b.Date.Format("2006-01-02"), userType, oc.Total,
+					float64(oc.Total)/float64(tr.Total), float64(oc.Negative)/float64(oc.Total),
+					float64(oc.Closed)/float64(oc.Total), negativeShare(oc, tr))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// negativeShare returns the share of the negative questions of tr asked by
+// owners of the type counted in oc.
+func negativeShare(oc soanalysis.OwnerTypeCounts, tr soanalysis.Result) float64 {
+	if tr.Negative == 0 {
+		return 0
+	}
+	return float64(oc.Negative) / float64(tr.Negative)
+}
+
+// writeChart writes the results like writeCSV, adding a bar of the negative
+// ratio to each row and sparklines of the metrics of each tag, for viewing in a
+// terminal.
+func writeChart(w io.Writer, results []soanalysis.Series) error {
+	for _, ts := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ts.Tag); err != nil {
+			return err
+		}
+		for _, b := range ts.Buckets {
+			tr := b.Result
+			_, err := fmt.Fprintf(w, "%s,%d,%.3f,%.3f,%.3f  %s\n", b.Date.Format("2006-01-02"), tr.Total, tr.NegativeRatio(), tr.ClosedRatio(), tr.ClosedAndNegativeRatio(), bar(tr.NegativeRatio(), 40))
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(ts.Buckets) > 1 {
+			metrics := []struct {
+				name   string
+				metric func(soanalysis.Result) float64
+			}{
+				{"questions", func(tr soanalysis.Result) float64 { return float64(tr.Total) }},
+				{"negative", soanalysis.Result.NegativeRatio},
+				{"closed", soanalysis.Result.ClosedRatio},
+				{"closed & negative", soanalysis.Result.ClosedAndNegativeRatio},
+			}
+			if hasSentiments(results) {
+				metrics = append(metrics, struct {
+					name   string
+					metric func(soanalysis.Result) float64
+				}{"sentiment", soanalysis.Result.MeanSentiment})
+			}
+			for _, m := range metrics {
+				values := make([]float64, len(ts.Buckets))
+				for i, b := range ts.Buckets {
+					values[i] = m.metric(b.Result)
+				}
+				if _, err := fmt.Fprintf(w, "%-18s %s\n", m.name, sparkline(values)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// bar returns a horizontal bar of Unicode blocks for a ratio between 0 and 1;
+// width is the length of the bar for a ratio of 1.
+func bar(ratio float64, width int) string {
+	const eighths = " ▏▎▍▌▋▊▉"
+	n := int(math.Round(ratio * float64(width) * 8))
+	b := strings.Repeat("█", n/8)
+	if n%8 > 0 {
+		b += string([]rune(eighths)[n%8])
+	}
+	return b
+}
+
+// sparkline returns a line of Unicode blocks, one per value, with heights
+// scaled between the minimal and maximal values.
+func sparkline(values []float64) string {
+	ticks := []rune("▁▂▃▄▅▆▇█")
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if max > min {
+			i = int((v - min) / (max - min) * float64(len(ticks)-1))
+		}
+		sb.WriteRune(ticks[i])
+	}
+	return sb.String()
+}
+
+// writeJSONResults writes the results as a JSON array with an object per tag;
+// this is the format of result files read by the diff command.
+func writeJSONResults(w io.Writer, results []soanalysis.Series) error {
+	rts := []reportTag{}
+	for _, ts := range results {
+		rts = append(rts, newReportTag(ts))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rts)
+}
+
+// writeArrow writes the results as an Arrow IPC file (also known as Feather
+// v2), with a row per bucket.
+func writeArrow(w io.Writer, results []soanalysis.Series) error {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "tag", Type: arrow.BinaryTypes.String},
+		{Name: "date", Type: arrow.FixedWidthTypes.Date32},
+		{Name: "total", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "negative", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "closed", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "closed_and_negative", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "unanswered", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "negative_per_10k_views", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "questions_per_day", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "questions_per_day_smoothed", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "site_share", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "mean_score", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "mean_views", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "mean_sentiment", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "sentiment_coverage", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+
+	rb := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer rb.Release()
+	for _, ts := range results {
+		smoothed := ts.QuestionsPerDay(velocityWindow)
+		for i, b := range ts.Buckets {
+			rb.Field(0).(*array.StringBuilder).Append(ts.Tag)
+			rb.Field(1).(*array.Date32Builder).Append(arrow.Date32FromTime(b.Date))
+			rb.Field(2).(*array.Int64Builder).Append(int64(b.Result.Total))
+			rb.Field(3).(*array.Float64Builder).Append(b.Result.NegativeRatio())
+			rb.Field(4).(*array.Float64Builder).Append(b.Result.ClosedRatio())
+			rb.Field(5).(*array.Float64Builder).Append(b.Result.ClosedAndNegativeRatio())
+			rb.Field(6).(*array.Float64Builder).Append(b.Result.UnansweredRatio())
+			rb.Field(7).(*array.Float64Builder).Append(b.Result.NegativePer10kViews())
+			rb.Field(8).(*array.Float64Builder).Append(b.QuestionsPerDay())
+			rb.Field(9).(*array.Float64Builder).Append(smoothed[i])
+			if b.SiteTotal > 0 {
+				rb.Field(10).(*array.Float64Builder).Append(b.SiteShare())
+			} else {
+				rb.Field(10).AppendNull()
+			}
+			rb.Field(11).(*array.Float64Builder).Append(b.Result.MeanScore())
+			rb.Field(12).(*array.Float64Builder).Append(b.Result.MeanViews())
+			if len(b.Result.Sentiments) > 0 {
+				rb.Field(13).(*array.Float64Builder).Append(b.Result.MeanSentiment())
+			} else {
+				rb.Field(13).AppendNull()
+			}
+			rb.Field(14).(*array.Float64Builder).Append(b.Result.SentimentCoverage())
+		}
+	}
+	return writeArrowRecord(w, rb)
+}
+
+// writeArrowItems writes the questions selected by af, flattened to a row per
+// question, into an Arrow IPC file at path.
+func writeArrowItems(ctx context.Context, path string, af analysisFlags) error {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "tag", Type: arrow.BinaryTypes.String},
+		{Name: "question_id", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "creation_date", Type: arrow.FixedWidthTypes.Timestamp_s},
+		{Name: "score", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "view_count", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "answer_count", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "is_answered", Type: arrow.FixedWidthTypes.Boolean},
+		{Name: "closed_date", Type: arrow.FixedWidthTypes.Timestamp_s, Nullable: true},
+		{Name: "owner_user_type", Type: arrow.BinaryTypes.String},
+		{Name: "title", Type: arrow.BinaryTypes.String},
+		{Name: "link", Type: arrow.BinaryTypes.String},
+		{Name: "content_license", Type: arrow.BinaryTypes.String},
+	}, nil)
+
+	tags, err := af.tagList()
+	if err != nil {
+		return err
+	}
+	fromDate, toDate, err := af.dates()
+	if err != nil {
+		return err
+	}
+
+	rb := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer rb.Release()
+	an := af.analyzer()
+	for _, tag := range tags {
+		err := an.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+			rb.Field(0).(*array.StringBuilder).Append(tag)
+			rb.Field(1).(*array.Int64Builder).Append(int64(item.QuestionID))
+			rb.Field(2).(*array.TimestampBuilder).Append(arrow.Timestamp(item.CreationDate))
+			rb.Field(3).(*array.Int64Builder).Append(int64(item.Score))
+			rb.Field(4).(*array.Int64Builder).Append(int64(item.ViewCount))
+			rb.Field(5).(*array.Int64Builder).Append(int64(item.AnswerCount))
+			rb.Field(6).(*array.BooleanBuilder).Append(item.IsAnswered)
+			if item.ClosedDate > 0 {
+				rb.Field(7).(*array.TimestampBuilder).Append(arrow.Timestamp(item.ClosedDate))
+			} else {
+				rb.Field(7).AppendNull()
+			}
+			rb.Field(8).(*array.StringBuilder).Append(item.Owner.UserType)
+			rb.Field(9).(*array.StringBuilder).Append(item.Title)
+			rb.Field(10).(*array.StringBuilder).Append(item.Link)
+			rb.Field(11).(*array.StringBuilder).Append(item.ContentLicense)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := writeArrowRecord(f, rb); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writeArrowRecord writes the contents of rb as a single record batch of an
+// Arrow IPC file.
+func writeArrowRecord(w io.Writer, rb *array.RecordBuilder) error {
+	rec := rb.NewRecordBatch()
+	defer rec.Release()
+
+	fw, err := ipc.NewFileWriter(w, ipc.WithSchema(rb.Schema()))
+	if err != nil {
+		return err
+	}
+	if err := fw.Write(rec); err != nil {
+		return err
+	}
+	return fw.Close()
+}
+
+// writeCBOR writes the results in CBOR, with the same structure as the json
+// format.
+func writeCBOR(w io.Writer, results []soanalysis.Series) error {
+	rts := []reportTag{}
+	for _, ts := range results {
+		rts = append(rts, newReportTag(ts))
+	}
+	return cbor.NewEncoder(w).Encode(rts)
+}
+
+// writeMsgpack writes the results in MessagePack, with the same structure as
+// the json format.
+func writeMsgpack(w io.Writer, results []soanalysis.Series) error {
+	rts := []reportTag{}
+	for _, ts := range results {
+		rts = append(rts, newReportTag(ts))
+	}
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(rts)
+}
+
+// writeInflux writes the results in InfluxDB line protocol, one point per
+// bucket with the tag name as an Influx tag, timestamped with the bucket date.
+func writeInflux(w io.Writer, results []soanalysis.Series) error {
+	escaper := strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	for _, ts := range results {
+		smoothed := ts.QuestionsPerDay(velocityWindow)
+		for i, b := range ts.Buckets {
+			tr := b.Result
+			_, err := fmt.Fprintf(w, "so_tag_sentiment,tag=%s total=%di,negative=%g,closed=%g,closed_and_negative=%g,unanswered=%g,negative_per_10k_views=%g,questions_per_day=%g,questions_per_day_smoothed=%g,mean_score=%g,mean_views=%g %d\n",
+				escaper.Replace(ts.Tag), tr.Total, tr.NegativeRatio(), tr.ClosedRatio(), tr.ClosedAndNegativeRatio(), tr.UnansweredRatio(), tr.NegativePer10kViews(), b.QuestionsPerDay(), smoothed[i], tr.MeanScore(), tr.MeanViews(), b.Date.UnixNano())
+			if err != nil {
+				return err
+			}
+			if b.SiteTotal > 0 {
+				_, err := fmt.Fprintf(w, "so_tag_sentiment,tag=%s site_share=%g %d\n", escaper.Replace(ts.Tag), b.SiteShare(), b.Date.UnixNano())
+				if err != nil {
+					return err
+				}
+			}
+			if len(tr.Sentiments) > 0 {
+				_, err := fmt.Fprintf(w, "so_tag_sentiment,tag=%s mean_sentiment=%g,sentiment_coverage=%g %d\n", escaper.Replace(ts.Tag), tr.MeanSentiment(), tr.SentimentCoverage(), b.Date.UnixNano())
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeVegaLite writes a Vega-Lite specification charting the ratio metrics of
+// each tag over time, with the results inlined as its data.
+func writeVegaLite(w io.Writer, results []soanalysis.Series) error {
+	type value struct {
+		Tag    string  `json:"tag"`
+		Date   string  `json:"date"`
+		Metric string  `json:"metric"`
+		Value  float64 `json:"value"`
+	}
+	var values []value
+	for _, ts := range results {
+		for _, b := range ts.Buckets {
+			date := b.Date.Format("2006-01-02")
+			values = append(values,
+				value{ts.Tag, date, "negative", b.Result.NegativeRatio()},
+				value{ts.Tag, date, "closed", b.Result.ClosedRatio()},
+				value{ts.Tag, date, "closed & negative", b.Result.ClosedAndNegativeRatio()})
+		}
+	}
+
+	type m = map[string]interface{}
+	spec := m{
+		"$schema":     "https://vega.github.io/schema/vega-lite/v5.json",
+		"description": "StackOverflow tag sentiment",
+		"data":        m{"values": values},
+		"mark":        m{"type": "line", "point": true, "tooltip": true},
+		"width":       600,
+		"height":      200,
+		"encoding": m{
+			"x":     m{"field": "date", "type": "temporal", "title": "date"},
+			"y":     m{"field": "value", "type": "quantitative", "title": "ratio"},
+			"color": m{"field": "metric", "type": "nominal"},
+			"row":   m{"field": "tag", "type": "nominal"},
+		},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(spec)
+}
+
+// writeGnuplot writes a gnuplot script to w that charts the ratio metrics of
+// each tag into <tag>.png, and the data it plots into dataFile, as a block per
+// tag.
+func writeGnuplot(w io.Writer, results []soanalysis.Series, dataFile string) error {
+	f, err := os.Create(dataFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fmt.Fprintln(f, "# date total negative closed closed_and_negative")
+	for i, ts := range results {
+		if i > 0 {
+			// two blank lines separate the blocks selected with 'index' in gnuplot
+			fmt.Fprint(f, "\n\n")
+		}
+		fmt.Fprintf(f, "# %s\n", ts.Tag)
+		for _, b := range ts.Buckets {
+			tr := b.Result
+			fmt.Fprintf(f, "%s %d %.3f %.3f %.3f\n", b.Date.Format("2006-01-02"), tr.Total, tr.NegativeRatio(), tr.ClosedRatio(), tr.ClosedAndNegativeRatio())
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, `# Generated by analyze-question-sentiment; run with: gnuplot <this file>
+set terminal pngcairo size 1000,400
+set xdata time
+set timefmt "%%Y-%%m-%%d"
+set format x "%%Y-%%m"
+set ylabel "ratio"
+set yrange [0:*]
+set grid
+set key top left
+`)
+	for i, ts := range results {
+		fmt.Fprintf(w, `
+set output %q
+set title %q
+plot %q index %d using 1:3 with linespoints title "negative", \
+     '' index %d using 1:4 with linespoints title "closed", \
+     '' index %d using 1:5 with linespoints title "closed & negative"
+`, ts.Tag+".png", ts.Tag, dataFile, i, i, i)
+	}
+	return nil
+}
+
+// writeXLSX writes the results as an Excel workbook: a sheet per tag with a row
+// per bucket, and a summary sheet with a row per tag over the whole period and
+// charts comparing the tags over time.
+func writeXLSX(w io.Writer, results []soanalysis.Series) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const summary = "Summary"
+	if err := f.SetSheetName("Sheet1", summary); err != nil {
+		return err
+	}
+	header := []interface{}{"questions", "negative", "closed", "closed & negative", "unanswered", "negative per 10k views"}
+	sentiments := hasSentiments(results)
+	if sentiments {
+		header = append(header, "mean sentiment", "sentiment coverage")
+	}
+	row := func(tr soanalysis.Result) []interface{} {
+		cells := []interface{}{tr.Total, tr.NegativeRatio(), tr.ClosedRatio(), tr.ClosedAndNegativeRatio(), tr.UnansweredRatio(), tr.NegativePer10kViews()}
+		if sentiments {
+			cells = append(cells, tr.MeanSentiment(), tr.SentimentCoverage())
+		}
+		return cells
+	}
+
+	err := f.SetSheetRow(summary, "A1", &[]interface{}{"tag"})
+	if err == nil {
+		err = f.SetSheetRow(summary, "B1", &header)
+	}
+	if err != nil {
+		return err
+	}
+
+	used := map[string]bool{strings.ToLower(summary): true}
+	var negativeSeries, closedSeries []excelize.ChartSeries
+	for i, ts := range results {
+		sheet := xlsxSheetName(ts.Tag, used)
+		if _, err := f.NewSheet(sheet); err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, "A1", &[]interface{}{"date"}); err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, "B1", &header); err != nil {
+			return err
+		}
+
+		var all soanalysis.Result
+		for j, b := range ts.Buckets {
+			all.Merge(b.Result)
+			cells := append([]interface{}{b.Date.Format("2006-01-02")}, row(b.Result)...)
+			if err := f.SetSheetRow(sheet, fmt.Sprintf("A%d", j+2), &cells); err != nil {
+				return err
+			}
+		}
+
+		cells := append([]interface{}{ts.Tag}, row(all)...)
+		if err := f.SetSheetRow(summary, fmt.Sprintf("A%d", i+2), &cells); err != nil {
+			return err
+		}
+
+		if len(ts.Buckets) > 0 {
+			last := len(ts.Buckets) + 1
+			series := func(col string) excelize.ChartSeries {
+				return excelize.ChartSeries{
+					Name:       fmt.Sprintf("'%s'!$A$%d", summary, i+2),
+					Categories: fmt.Sprintf("'%s'!$A$2:$A$%d", sheet, last),
+					Values:     fmt.Sprintf("'%s'!$%s$2:$%s$%d", sheet, col, col, last),
+				}
+			}
+			negativeSeries = append(negativeSeries, series("C"))
+			closedSeries = append(closedSeries, series("D"))
+		}
+	}
+
+	if len(negativeSeries) > 0 {
+		chart := func(title string, series []excelize.ChartSeries) *excelize.Chart {
+			return &excelize.Chart{
+				Type:      excelize.Line,
+				Series:    series,
+				Title:     excelize.ChartTitle{Paragraph: []excelize.RichTextRun{{Text: title}}},
+				Dimension: excelize.ChartDimension{Width: 720, Height: 300},
+			}
+		}
+		chartRow := len(results) + 3
+		err := f.AddChart(summary, fmt.Sprintf("A%d", chartRow), chart("negative ratio", negativeSeries))
+		if err == nil {
+			err = f.AddChart(summary, fmt.Sprintf("A%d", chartRow+16), chart("closed ratio", closedSeries))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = f.WriteTo(w)
+	return err
+}
+
+// xlsxSheetName returns a valid and unique (among the names in used) Excel
+// sheet name for tag, and adds it to used.
+func xlsxSheetName(tag string, used map[string]bool) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\'`, r) {
+			return '_'
+		}
+		return r
+	}, tag)
+	if len(name) > 28 {
+		name = name[:28]
+	}
+	unique := name
+	for i := 2; used[strings.ToLower(unique)]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	used[strings.ToLower(unique)] = true
+	return unique
+}
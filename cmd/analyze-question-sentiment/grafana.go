@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// grafanaMetrics maps the metric names available to Grafana to their values.
+var grafanaMetrics = map[string]func(soanalysis.Result) float64{
+	"total":                  func(tr soanalysis.Result) float64 { return float64(tr.Total) },
+	"negative":               soanalysis.Result.NegativeRatio,
+	"closed":                 soanalysis.Result.ClosedRatio,
+	"closed_and_negative":    soanalysis.Result.ClosedAndNegativeRatio,
+	"unanswered":             soanalysis.Result.UnansweredRatio,
+	"negative_per_10k_views": soanalysis.Result.NegativePer10kViews,
+	"mean_sentiment":         soanalysis.Result.MeanSentiment,
+	"sentiment_coverage":     soanalysis.Result.SentimentCoverage,
+}
+
+// handleGrafanaTest answers the connection test of the datasource.
+func (s *server) handleGrafanaTest(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "OK")
+}
+
+// handleGrafanaSearch returns all the available targets.
+func (s *server) handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	tags, err := s.analyzer.Dataset.Tags()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var targets []string
+	for _, tag := range tags {
+		for metric := range grafanaMetrics {
+			targets = append(targets, tag+"."+metric)
+		}
+	}
+	sort.Strings(targets)
+	writeJSONResponse(w, targets)
+}
+
+// handleGrafanaQuery returns the time series of the requested targets, by
+// month over the requested range.
+func (s *server) handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Range struct {
+			From time.Time `json:"from"`
+			To   time.Time `json:"to"`
+		} `json:"range"`
+		Targets []struct {
+			Target string `json:"target"`
+		} `json:"targets"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type timeSeries struct {
+		Target     string       `json:"target"`
+		Datapoints [][2]float64 `json:"datapoints"`
+	}
+	series := []timeSeries{}
+
+	from := req.Range.From.Format("2006-01-02")
+	to := req.Range.To.Format("2006-01-02")
+	for _, t := range req.Targets {
+		tag, metricName, _ := strings.Cut(t.Target, ".")
+		metric, ok := grafanaMetrics[metricName]
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown metric in target %q", t.Target), http.StatusBadRequest)
+			return
+		}
+		results, err := s.query(r.Context(), []string{tag}, from, to, true)
+		if err != nil {
+			http.Error(w, err.Error(), queryStatus(err))
+			return
+		}
+
+		ts := timeSeries{Target: t.Target, Datapoints: [][2]float64{}}
+		for _, b := range results[0].Buckets {
+			ts.Datapoints = append(ts.Datapoints, [2]float64{metric(b.Result), float64(b.Date.UnixMilli())})
+		}
+		series = append(series, ts)
+	}
+	writeJSONResponse(w, series)
+}
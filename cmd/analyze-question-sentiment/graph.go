@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// graphML is a GraphML document with a single graph, as read by Gephi.
+type graphML struct {
+	XMLName xml.Name     `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+// graphMLKey declares an attribute of the nodes or edges.
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// nodeAttr is an attribute of the nodes of the tag graph.
+type nodeAttr struct {
+	name  string
+	typ   string
+	value func(r soanalysis.Result) string
+}
+
+// nodeAttrs returns the attributes of the nodes of the tag graph: the number
+// of questions of the tag and their metrics, and their mean sentiment with
+// sentiments.
+func nodeAttrs(sentiments bool) []nodeAttr {
+	decimal := func(f func(r soanalysis.Result) float64) func(r soanalysis.Result) string {
+		return func(r soanalysis.Result) string { return strconv.FormatFloat(f(r), 'f', 4, 64) }
+	}
+	attrs := []nodeAttr{
+		{"questions", "int", func(r soanalysis.Result) string { return strconv.Itoa(r.Total) }},
+		{"negative", "double", decimal(soanalysis.Result.NegativeRatio)},
+		{"closed", "double", decimal(soanalysis.Result.ClosedRatio)},
+		{"unanswered", "double", decimal(soanalysis.Result.UnansweredRatio)},
+		{"meanScore", "double", decimal(soanalysis.Result.MeanScore)},
+	}
+	if sentiments {
+		attrs = append(attrs,
+			nodeAttr{"meanSentiment", "double", decimal(soanalysis.Result.MeanSentiment)},
+			nodeAttr{"sentimentCoverage", "double", decimal(soanalysis.Result.SentimentCoverage)})
+	}
+	return attrs
+}
+
+// runGraph implements the graph command, which exports the co-occurrence
+// network of the tags of the questions of the given tags, for visualizing it
+// with Gephi (GraphML) or Graphviz (DOT); see soanalysis.Analyzer.TagGraph.
+func runGraph(args []string) {
+	fs := newFlagSet("graph")
+	var af analysisFlags
+	af.register(fs)
+	minQuestionsFlag := fs.Int("minquestions", 10, "minimal number of questions of the tags in the graph")
+	minEdgeFlag := fs.Int("minedge", 5, "minimal number of questions with both tags of the edges of the graph")
+	formatFlag := fs.String("format", "graphml", "output format: graphml or dot")
+	parseFlags(fs, args)
+
+	if *formatFlag != "graphml" && *formatFlag != "dot" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("graph requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	g, err := an.TagGraph(ctx, tags, fromDate, toDate, *minQuestionsFlag, *minEdgeFlag)
+	failonf(err, "analyzing tags")
+
+	attrs := nodeAttrs(an.Sentiments != nil)
+	if *formatFlag == "dot" {
+		err = writeGraphDOT(os.Stdout, g, attrs)
+	} else {
+		err = writeGraphML(os.Stdout, g, attrs)
+	}
+	failonf(err, "writing graph")
+}
+
+// writeGraphML writes the graph as an undirected GraphML graph, with the
+// attributes of the nodes and the number of questions of the edges as their
+// weight.
+func writeGraphML(w io.Writer, g soanalysis.TagGraph, attrs []nodeAttr) error {
+	doc := graphML{Graph: graphMLGraph{ID: "tags", EdgeDefault: "undirected"}}
+	doc.Keys = append(doc.Keys, graphMLKey{ID: "label", For: "node", Name: "label", Type: "string"})
+	for _, a := range attrs {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: a.name, For: "node", Name: a.name, Type: a.typ})
+	}
+	doc.Keys = append(doc.Keys, graphMLKey{ID: "weight", For: "edge", Name: "weight", Type: "int"})
+
+	for _, n := range g.Nodes {
+		node := graphMLNode{ID: n.Tag, Data: []graphMLData{{Key: "label", Value: n.Tag}}}
+		for _, a := range attrs {
+			node.Data = append(node.Data, graphMLData{Key: a.name, Value: a.value(n.Result)})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			Source: e.From,
+			Target: e.To,
+			Data:   []graphMLData{{Key: "weight", Value: strconv.Itoa(e.Questions)}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// writeGraphDOT writes the graph as an undirected Graphviz graph, with the
+// attributes of the nodes and the number of questions of the edges as their
+// weight.
+func writeGraphDOT(w io.Writer, g soanalysis.TagGraph, attrs []nodeAttr) error {
+	if _, err := fmt.Fprintln(w, "graph tags {"); err != nil {
+		return err
+	}
+	for _, n := range g.Nodes {
+		line := "  " + strconv.Quote(n.Tag) + " ["
+		for i, a := range attrs {
+			if i > 0 {
+				line += ", "
+			}
+			line += a.name + "=" + a.value(n.Result)
+		}
+		if _, err := fmt.Fprintln(w, line+"];"); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges {
+		if _, err := fmt.Fprintf(w, "  %s -- %s [weight=%d];\n", strconv.Quote(e.From), strconv.Quote(e.To), e.Questions); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// graphqlSchema is the schema of the GraphQL endpoint of the serve command.
+// Dates are in 2006-01-02 format.
+const graphqlSchema = `
+schema {
+	query: Query
+}
+
+type Query {
+	tags: [Tag!]!
+	tag(name: String!): Tag
+}
+
+type Tag {
+	name: String!
+	# byMonth requires both from and to.
+	metrics(from: String, to: String, byMonth: Boolean = false): [Bucket!]!
+	# Questions are ordered by creation date.
+	questions(from: String, to: String, minScore: Int, maxScore: Int, closed: Boolean, titleContains: String, first: Int = 100): [Question!]!
+}
+
+# The metrics of a period ending at date.
+type Bucket {
+	date: String!
+	total: Int!
+	negative: Float!
+	closed: Float!
+	closedAndNegative: Float!
+	unanswered: Float!
+	negativePer10kViews: Float!
+	# The mean of the external sentiment scores (see -sentiments), null if
+	# no question has one.
+	meanSentiment: Float
+	sentimentCoverage: Float!
+}
+
+type Question {
+	id: Int!
+	title: String!
+	link: String!
+	score: Int!
+	viewCount: Int!
+	answerCount: Int!
+	isAnswered: Boolean!
+	creationDate: String!
+	closedDate: String
+	tags: [String!]!
+}
+`
+
+// gqlResolver is the root resolver of graphqlSchema.
+type gqlResolver struct {
+	s *server
+}
+
+func (r *gqlResolver) Tags() ([]*gqlTag, error) {
+	names, err := r.s.analyzer.Dataset.Tags()
+	if err != nil {
+		return nil, err
+	}
+	var tags []*gqlTag
+	for _, name := range names {
+		tags = append(tags, &gqlTag{s: r.s, name: name})
+	}
+	return tags, nil
+}
+
+func (r *gqlResolver) Tag(args struct{ Name string }) (*gqlTag, error) {
+	if err := r.s.checkTag(args.Name); errors.Is(err, errUnknownTag) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return &gqlTag{s: r.s, name: args.Name}, nil
+}
+
+type gqlTag struct {
+	s    *server
+	name string
+}
+
+func (t *gqlTag) Name() string {
+	return t.name
+}
+
+func (t *gqlTag) Metrics(ctx context.Context, args struct {
+	From    *string
+	To      *string
+	ByMonth bool
+}) ([]*gqlBucket, error) {
+	results, err := t.s.query(ctx, []string{t.name}, derefString(args.From), derefString(args.To), args.ByMonth)
+	if err != nil {
+		return nil, err
+	}
+	var buckets []*gqlBucket
+	for _, b := range results[0].Buckets {
+		buckets = append(buckets, &gqlBucket{b})
+	}
+	return buckets, nil
+}
+
+func (t *gqlTag) Questions(ctx context.Context, args struct {
+	From          *string
+	To            *string
+	MinScore      *int32
+	MaxScore      *int32
+	Closed        *bool
+	TitleContains *string
+	First         int32
+}) ([]*gqlQuestion, error) {
+	from, to := derefString(args.From), derefString(args.To)
+	for _, date := range []string{from, to} {
+		if err := checkDate(date); err != nil {
+			return nil, err
+		}
+	}
+	if args.First < 0 {
+		return nil, fmt.Errorf("bad first %d, expecting a number of questions of at least 0", args.First)
+	}
+
+	ctx, cancel := t.s.context(ctx)
+	defer cancel()
+	var questions []*gqlQuestion
+	err := t.s.analyzer.ForEachItem(ctx, t.name, parseDate(from), parseDate(to), func(item *soapi.Item) {
+		switch {
+		case args.MinScore != nil && item.Score < int(*args.MinScore),
+			args.MaxScore != nil && item.Score > int(*args.MaxScore),
+			args.Closed != nil && (item.ClosedDate > 0) != *args.Closed,
+			args.TitleContains != nil && !strings.Contains(strings.ToLower(item.Title), strings.ToLower(*args.TitleContains)):
+			return
+		}
+		questions = append(questions, &gqlQuestion{*item})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(questions, func(i, j int) bool {
+		return questions[i].item.CreationDate < questions[j].item.CreationDate
+	})
+	if len(questions) > int(args.First) {
+		questions = questions[:args.First]
+	}
+	return questions, nil
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+type gqlBucket struct {
+	b soanalysis.Bucket
+}
+
+func (b *gqlBucket) Date() string                 { return b.b.Date.Format("2006-01-02") }
+func (b *gqlBucket) Total() int32                 { return int32(b.b.Result.Total) }
+func (b *gqlBucket) Negative() float64            { return b.b.Result.NegativeRatio() }
+func (b *gqlBucket) Closed() float64              { return b.b.Result.ClosedRatio() }
+func (b *gqlBucket) ClosedAndNegative() float64   { return b.b.Result.ClosedAndNegativeRatio() }
+func (b *gqlBucket) Unanswered() float64          { return b.b.Result.UnansweredRatio() }
+func (b *gqlBucket) NegativePer10kViews() float64 { return b.b.Result.NegativePer10kViews() }
+func (b *gqlBucket) SentimentCoverage() float64   { return b.b.Result.SentimentCoverage() }
+
+func (b *gqlBucket) MeanSentiment() *float64 {
+	if len(b.b.Result.Sentiments) == 0 {
+		return nil
+	}
+	mean := b.b.Result.MeanSentiment()
+	return &mean
+}
+
+type gqlQuestion struct {
+	item soapi.Item
+}
+
+func (q *gqlQuestion) ID() int32          { return int32(q.item.QuestionID) }
+func (q *gqlQuestion) Title() string      { return q.item.Title }
+func (q *gqlQuestion) Link() string       { return q.item.Link }
+func (q *gqlQuestion) Score() int32       { return int32(q.item.Score) }
+func (q *gqlQuestion) ViewCount() int32   { return int32(q.item.ViewCount) }
+func (q *gqlQuestion) AnswerCount() int32 { return int32(q.item.AnswerCount) }
+func (q *gqlQuestion) IsAnswered() bool   { return q.item.IsAnswered }
+func (q *gqlQuestion) Tags() []string     { return q.item.Tags }
+
+func (q *gqlQuestion) CreationDate() string {
+	return time.Unix(int64(q.item.CreationDate), 0).UTC().Format("2006-01-02")
+}
+
+func (q *gqlQuestion) ClosedDate() *string {
+	if q.item.ClosedDate == 0 {
+		return nil
+	}
+	date := time.Unix(q.item.ClosedDate, 0).UTC().Format("2006-01-02")
+	return &date
+}
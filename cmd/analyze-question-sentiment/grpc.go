@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/analysispb"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// runGRPC implements the grpc command, which serves the analysis of the data
+// in -dir with the gRPC service defined in analysispb, keeping the questions in
+// memory as the serve command does (see cacheFlags).
+func runGRPC(args []string) {
+	fs := newFlagSet("grpc")
+	dirFlag := fs.String("dir", "", "base directory with results")
+	addrFlag := fs.String("addr", "localhost:8081", "address to listen on")
+	timeoutFlag := fs.Duration("timeout", 30*time.Second, "maximal duration of the analysis of a call, unless the client sets a shorter deadline; 0 for no limit")
+	var cf cacheFlags
+	cf.register(fs)
+	parseFlags(fs, args)
+
+	if len(*dirFlag) == 0 {
+		log.Fatal("-dir must be provided and cannot be empty. Please use the folder where the data was fetched.")
+	}
+
+	lis, err := net.Listen("tcp", *addrFlag)
+	failonf(err, "listening on %q", *addrFlag)
+
+	gs := grpc.NewServer(grpc.UnaryInterceptor(traceUnary))
+	s := server{analyzer: soanalysis.NewAnalyzer(*dirFlag), timeout: *timeoutFlag}
+	cf.setUp(s.analyzer.Dataset)
+	analysispb.RegisterAnalysisServer(gs, &grpcServer{server: s})
+	log.Println("Serving gRPC on", *addrFlag)
+	log.Fatal(gs.Serve(lis))
+}
+
+// traceUnary traces each call, continuing the traces of clients, along with the
+// analysis it runs (with -otel-endpoint).
+func traceUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	}
+	ctx, span := otel.Tracer("github.com/eliben/so-tag-sentiment-analysis/cmd/analyze-question-sentiment").Start(ctx, info.FullMethod, trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	resp, err := handler(ctx, req)
+	if err != nil {
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.String("rpc.grpc.status_code", status.Code(err).String()))
+	return resp, err
+}
+
+// metadataCarrier adapts gRPC metadata to propagation.TextMapCarrier.
+type metadataCarrier metadata.MD
+
+func (mc metadataCarrier) Get(key string) string {
+	if values := metadata.MD(mc).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (mc metadataCarrier) Set(key string, value string) {
+	metadata.MD(mc).Set(key, value)
+}
+
+func (mc metadataCarrier) Keys() []string {
+	var keys []string
+	for k := range mc {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// grpcServer implements analysispb.AnalysisServer on top of server.
+type grpcServer struct {
+	analysispb.UnimplementedAnalysisServer
+	server server
+}
+
+func (gs *grpcServer) ListTags(ctx context.Context, req *analysispb.ListTagsRequest) (*analysispb.ListTagsResponse, error) {
+	tags, err := gs.server.analyzer.Dataset.Tags()
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &analysispb.ListTagsResponse{Tags: tags}, nil
+}
+
+func (gs *grpcServer) GetMetrics(ctx context.Context, req *analysispb.GetMetricsRequest) (*analysispb.TagMetrics, error) {
+	results, err := gs.query(ctx, []string{req.GetTag()}, req.GetPeriod())
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+func (gs *grpcServer) Compare(ctx context.Context, req *analysispb.CompareRequest) (*analysispb.CompareResponse, error) {
+	if len(req.GetTags()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "no tags to compare")
+	}
+	results, err := gs.query(ctx, req.GetTags(), req.GetPeriod())
+	if err != nil {
+		return nil, err
+	}
+	return &analysispb.CompareResponse{Tags: results}, nil
+}
+
+// query runs server.query and converts its results and errors to their gRPC
+// counterparts.
+func (gs *grpcServer) query(ctx context.Context, tags []string, period *analysispb.Period) ([]*analysispb.TagMetrics, error) {
+	results, err := gs.server.query(ctx, tags, period.GetFromDate(), period.GetToDate(), period.GetByMonth())
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return nil, status.FromContextError(err).Err()
+	} else if err != nil {
+		code := codes.Internal
+		switch queryStatus(err) {
+		case http.StatusNotFound:
+			code = codes.NotFound
+		case http.StatusBadRequest:
+			code = codes.InvalidArgument
+		}
+		return nil, status.Error(code, err.Error())
+	}
+
+	var tms []*analysispb.TagMetrics
+	for _, ts := range results {
+		tm := &analysispb.TagMetrics{Tag: ts.Tag}
+		for _, b := range ts.Buckets {
+			tm.Buckets = append(tm.Buckets, &analysispb.Bucket{
+				Date:              b.Date.Format("2006-01-02"),
+				Total:             int64(b.Result.Total),
+				Negative:          b.Result.NegativeRatio(),
+				Closed:            b.Result.ClosedRatio(),
+				ClosedAndNegative: b.Result.ClosedAndNegativeRatio(),
+			})
+		}
+		tms = append(tms, tm)
+	}
+	return tms, nil
+}
@@ -0,0 +1,552 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const progName = "analyze-question-sentiment"
+
+// commandHelp holds the help of each command: the default analysis command
+// under the empty name, and the subcommands. args describes the positional
+// arguments following the flags, and details what the summary leaves out, such
+// as the data the command requires.
+var commandHelp = map[string]struct {
+	args     string
+	summary  string
+	details  string
+	examples []string
+}{
+	"": {
+		summary: "Analyze the questions fetched with fetch-all-questions and print the results.",
+		details: "Tags that can't be analyzed are reported, and the results of the others printed. With -bymonth, the results have a row per month; -window buckets them by sliding windows instead, for smoother series. The results are printed as CSV by default, or in another format with -format: json, cbor and msgpack; influx line protocol; a vegalite chart or a gnuplot script (writing its data into -gnuplotdata); an xlsx workbook; an arrow file (with -arrowitems for the questions themselves); a panel table for econometric analysis; or the distribution of the content licenses or the types of the owners of the questions. -trim and -winsorize make the means robust to a few viral questions. -sentiments reads the sentiment scores of the questions from a CSV or JSON file (see the score command), and -sentimentmodel computes them with a local ONNX model, in builds with -tags onnx. -keywords analyzes only the questions matching a full-text query, which needs the index built by the index command. -cumulative, -deltas and -forecast accumulate the buckets, append their changes and forecast the months after the period. With -dir -, pages are read from stdin, e.g. from fetch-all-questions -dir -. With -watch, the analysis is rerun whenever the data or the config file changes. The defaults of the flags of all commands can be set in ~/.config/sotrends.toml or with environment variables such as SOTRENDS_DIR.",
+		examples: []string{
+			progName + " -dir data",
+			progName + " -dir data -tags go,rust -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " -dir data -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -format xlsx > sentiment.xlsx",
+			progName + " -dir data -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -format panel > panel.csv",
+			progName + " -dir data -tags go -fromdate 2020-01-01 -todate 2021-01-01 -window 90d -step 7d",
+			progName + " -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -sentimentmodel distilbert-sst2-onnx",
+			progName + " -dir data -watch -chart",
+		},
+	},
+	"report": {
+		summary: "Render the analysis into a single HTML file with charts and tables.",
+		details: "Takes the flags of the default command. With -style blog, it writes a markdown article skeleton instead, with a chart image, a table of the metrics per tag and methodology notes, into the directory named by -out without .html.",
+		examples: []string{
+			progName + " report -dir data -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -out report.html",
+			progName + " report -dir data -bymonth -fromdate 2019-01-01 -todate 2021-01-01 -forecast 6",
+			progName + " report -dir data -bymonth -fromdate 2019-01-01 -todate 2021-01-01 -eventdays 90",
+			progName + " report -dir data -tags go,rust -bymonth -fromdate 2019-01-01 -todate 2021-01-01 -style blog -out go-vs-rust",
+		},
+	},
+	"site": {
+		summary: "Generate a static site with a page per tag and JSON data files.",
+		details: "Writes an index page, a page per tag and the data as JSON files into -out, e.g. for GitHub Pages. Rerun it after fetching more data to update the site in place.",
+		examples: []string{
+			progName + " site -dir data -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -out site",
+		},
+	},
+	"serve": {
+		summary: "Serve the analysis as an HTTP JSON API, Atom feeds, badges and GraphQL.",
+		details: "Serves GET /tags, /tags/{tag}/metrics?from=&to=&by=month, /tags/{tag}/feed (Atom), /badge/{tag}/negativity (shields.io), /cache, POST /graphql and the Grafana simple JSON datasource under /grafana/. With -cache, the questions are kept in memory between requests and reloaded when their files change.",
+		examples: []string{
+			progName + " serve -dir data -addr localhost:8080",
+			"curl 'localhost:8080/tags/go/metrics?from=2020-01-01&to=2021-01-01&by=month'",
+		},
+	},
+	"grpc": {
+		summary: "Serve the analysis with the gRPC service defined in analysispb.",
+		details: "Keeps the questions in memory between requests, as the serve command does.",
+		examples: []string{
+			progName + " grpc -dir data -addr localhost:8081",
+		},
+	},
+	"exporter": {
+		summary: "Serve the current metrics of each tag as Prometheus gauges.",
+		details: "Serves the metrics of each tag over the trailing -window days on /metrics, recomputed on every scrape.",
+		examples: []string{
+			progName + " exporter -dir data -window 30",
+		},
+	},
+	"sheets": {
+		summary: "Append the results to a Google Sheet, skipping rows published before.",
+		details: "Buckets already in the sheet are skipped, so running it periodically appends only the new ones. It authenticates with a service account, which the spreadsheet has to be shared with.",
+		examples: []string{
+			progName + " sheets -dir data -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -spreadsheet <id> -credentials key.json",
+		},
+	},
+	"notify": {
+		summary: "Post to a webhook when the latest month of a tag crosses a threshold.",
+		details: "Meant to run on a schedule after fetching fresh data; without -todate, the last month ends today.",
+		examples: []string{
+			progName + " notify -dir data -webhook https://hooks.slack.com/services/... -max-negative 0.3",
+		},
+	},
+	"email": {
+		summary: "Email the monthly report, with the results attached as CSV.",
+		details: "Meant to run on a schedule, like notify. The SMTP credentials are taken from $SMTP_USERNAME and $SMTP_PASSWORD.",
+		examples: []string{
+			progName + " email -dir data -smtp smtp.example.com:587 -from bot@example.com -to team@example.com",
+		},
+	},
+	"tui": {
+		summary: "Explore the data interactively in the terminal.",
+		details: "Pick a tag, browse its monthly table and drill down into the most negative questions of a month.",
+		examples: []string{
+			progName + " tui -dir data -months 36",
+		},
+	},
+	"diff": {
+		args:    " <old> <new>",
+		summary: "Compare two analysis runs, given as -format json result files or data directories.",
+		details: "Each run is either a result file written with -format json or a data directory, which is analyzed with the given flags. Changes beyond -threshold are marked with '*'.",
+		examples: []string{
+			progName + " diff -threshold 0.05 old.json new.json",
+			progName + " diff -fromdate 2020-01-01 -todate 2021-01-01 data-january data-june",
+		},
+	},
+	"baseline": {
+		summary: "Save the results of an analysis as a named baseline.",
+		details: "Check fresh analyses against the baseline with the check command.",
+		examples: []string{
+			progName + " baseline -dir data -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -name 2020",
+		},
+	},
+	"check": {
+		summary: "Compare a fresh analysis with a baseline, failing if a ratio regressed.",
+		details: "Exits with status 1 if any ratio of a tag in a bucket rose above the baseline by more than its tolerance, e.g. for automated checks.",
+		examples: []string{
+			progName + " check -dir data -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -name 2020",
+		},
+	},
+	"run": {
+		summary: "Fetch the questions of tags, analyze them as they arrive and write a report.",
+		details: "Needs no data directory: with -dir, the fetched pages are also saved there, and otherwise only the report is written. Set $STACK_KEY for the increased API quota.",
+		examples: []string{
+			progName + " run -tags go,rust -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -out report.html",
+			progName + " run -tags go -fromdate 2021-01-01 -todate 2021-02-01 -dir data -out ''",
+		},
+	},
+	"survival": {
+		summary: "Estimate the ratio of questions answered within hours of being asked.",
+		details: "The ratios are Kaplan-Meier estimates per tag and bucket. Requires the answers, fetched with fetch-all-questions -answers.",
+		examples: []string{
+			progName + " survival -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " survival -dir data -hours 1,6,24 -format json -plot charts",
+		},
+	},
+	"concentration": {
+		summary: "Report how concentrated the answers of tags are among the answerers.",
+		details: "Reports the Gini coefficient of the numbers of answers of the answerers and the share of the top 10% of them. Requires the answers, fetched with fetch-all-questions -answers.",
+		examples: []string{
+			progName + " concentration -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+		},
+	},
+	"reputation": {
+		summary: "Report the reputation of the users whose answers to tags get accepted.",
+		details: "Also reports the share of the accepted answers given by veterans, whose minimal reputation is set by -veteran. Requires the answers, fetched with fetch-all-questions -answers.",
+		examples: []string{
+			progName + " reputation -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " reputation -dir data -veteran 20000 -format json",
+		},
+	},
+	"answers": {
+		summary: "Report the scores of the best and accepted answers and the answers per question of tags.",
+		details: "Reports the mean score of the best answers, the ratio of questions whose best answer is negative, the answers per question and how often the accepted answer isn't the highest-voted one. Requires the answers, fetched with fetch-all-questions -answers.",
+		examples: []string{
+			progName + " answers -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " answers -dir data -format json",
+		},
+	},
+	"bounties": {
+		summary: "Compare the answer rates and times to accept of questions with and without bounties.",
+		details: "The API only reports the bounties open when the questions were fetched, so recently fetched periods are the most telling. The times to accept require the answers, fetched with fetch-all-questions -answers.",
+		examples: []string{
+			progName + " bounties -dir data -tags go,rust -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " bounties -dir data -format json",
+		},
+	},
+	"duplicates": {
+		summary: "Cluster the duplicates of tags by the questions they duplicate.",
+		details: "Large clusters point at frequently asked questions driving closures. Requires the details of closing, fetched with fetch-all-questions -closeddetails.",
+		examples: []string{
+			progName + " duplicates -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " duplicates -dir data -top 20 -format json",
+		},
+	},
+	"protection": {
+		summary: "Report the ratios of protected and locked questions of tags.",
+		details: "Also reports the median time to protect the questions. Only the protections and locks in place when the questions were fetched are known.",
+		examples: []string{
+			progName + " protection -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " protection -dir data -format json",
+		},
+	},
+	"neardups": {
+		summary: "Estimate the share of questions of tags with titles similar to older questions.",
+		details: "Titles are similar by the Jaccard similarity of their words, estimated with MinHash, whether or not the questions were closed as duplicates.",
+		examples: []string{
+			progName + " neardups -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " neardups -dir data -similarity 0.8 -top 20 -format json",
+		},
+	},
+	"graph": {
+		summary: "Export the co-occurrence network of the tags of questions as GraphML or DOT.",
+		details: "A node per tag, with the metrics of its questions as attributes, and an edge per pair of tags, weighted by the number of questions with both.",
+		examples: []string{
+			progName + " graph -dir data -tags go,rust -minquestions 20 > tags.graphml",
+			progName + " graph -dir data -tags go -format dot | sfdp -Tsvg > tags.svg",
+		},
+	},
+	"versions": {
+		summary: "Report the metrics of tags rolled up and split by their versioned tags, like python-3.x.",
+		details: "Versioned tags given to -tags or fetched into -dir are rolled up into their family.",
+		examples: []string{
+			progName + " versions -dir data -tags python,java -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " versions -dir data -tags angular -format json",
+		},
+	},
+	"code": {
+		summary: "Report the share of code in the bodies of questions and how it correlates with their reception.",
+		details: "Also reports the metrics of the questions by how much code they have. Requires the bodies of the questions, fetched with fetch-all-questions -bodies.",
+		examples: []string{
+			progName + " code -dir data -tags go,rust -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " code -dir data -tags go -format json",
+		},
+	},
+	"sites": {
+		summary: "Compare tags across Stack Exchange sites fetched into separate data directories.",
+		details: "Each site is fetched into its own data directory with fetch-all-questions -site. Directories are named by the site they were fetched from, or explicitly with name=dir.",
+		examples: []string{
+			progName + " sites -dirs data-so,data-sf,data-devops -tags docker -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " sites -dirs so=data,sf=data-sf -tags docker,nginx -todate 2021-01-01 -format json",
+		},
+	},
+	"edits": {
+		summary: "Report how much the questions of tags are edited, and whether negative ones recover.",
+		details: "Reports the fraction of edited questions, the median time to edit them, and how many negative questions edited after being downvoted recover, which needs several fetches of the questions, e.g. with fetch-all-questions -refresh.",
+		examples: []string{
+			progName + " edits -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " edits -dir data -format json",
+		},
+	},
+	"deletions": {
+		summary: "Report the rates of deletion of the questions of tags, detected by fetching again.",
+		details: "Deletions are detected when fetching windows again, e.g. with fetch-all-questions -refresh; they bias the other metrics towards the questions that survive.",
+		examples: []string{
+			progName + " deletions -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " deletions -dir data -format json",
+		},
+	},
+	"drift": {
+		summary: "Report how the scores of questions of tags drift after asking, across fetches.",
+		details: "Compares the scores logged by successive fetches of the windows: the mean change of the scores, how many initially negative questions recover, and the mean score by the age of the questions in weeks.",
+		examples: []string{
+			progName + " drift -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " drift -dir data -weeks 8 -format json",
+		},
+	},
+	"views": {
+		summary: "Report how the view counts of questions of tags grow after asking, across fetches.",
+		details: "From the view counts logged by successive fetches, e.g. with fetch-all-questions -refresh; reports whether negative questions stop accumulating views sooner, by how long they take to get half of their views.",
+		examples: []string{
+			progName + " views -dir data -tags go -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " views -dir data -weeks 12 -format json",
+		},
+	},
+	"churn": {
+		summary: "Report the moderation churn of tags: closings, reopenings and deletions per question.",
+		details: "The number of closings, reopenings and deletions per question of each bucket, with its moving average. Reopenings and deletions are detected by fetching windows again.",
+		examples: []string{
+			progName + " churn -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " churn -dir data -format json",
+		},
+	},
+	"seasonal": {
+		summary: "Decompose a metric of tags into trend, seasonal and residual components.",
+		details: "Seasonality, such as that of the academic calendar, isn't then misread as a trend; -plot charts the components.",
+		examples: []string{
+			progName + " seasonal -dir data -tags go -bymonth -fromdate 2018-01-01 -todate 2021-01-01",
+			progName + " seasonal -dir data -bymonth -fromdate 2018-01-01 -todate 2021-01-01 -metric unanswered -plot charts",
+		},
+	},
+	"events": {
+		summary: "Compare the questions of tags before and after the events of the config file.",
+		details: "Events are named with their dates in the events table of ~/.config/sotrends.toml, and marked on the charts of -plot and of the report command.",
+		examples: []string{
+			progName + " events -dir data -tags go",
+			progName + " events -dir data -days 30 -format json",
+		},
+	},
+	"lifecycle": {
+		summary: "Summarize the lifecycle stage of tags by their volume of questions.",
+		details: "Reports the date of the first question of each tag, its peak month, its recent monthly volume and whether it's growing, on a plateau or declining.",
+		examples: []string{
+			progName + " lifecycle -dir data",
+			progName + " lifecycle -dir data -todate 2021-01-01 -window 12 -format json",
+		},
+	},
+	"benchmark": {
+		summary: "Rank the metrics of tags among the ones of their related tags.",
+		details: "Requires the related tags, fetched with fetch-all-questions -related. A negative rank of 90 means that the tag has more negative questions than 90% of its peers.",
+		examples: []string{
+			progName + " benchmark -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " benchmark -dir data -format json",
+		},
+	},
+	"cohorts": {
+		summary: "Report how many askers of each month ask again in the following months.",
+		details: "Reported separately for the askers whose first question got a negative score.",
+		examples: []string{
+			progName + " cohorts -dir data -tags go -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " cohorts -dir data -months 12 -format json",
+		},
+	},
+	"quota": {
+		summary: "Report the API quota spent fetching the data directory per tag, run and day.",
+		details: "From the quota log written by fetch-all-questions; helps planning large fetches within the daily quota.",
+		examples: []string{
+			progName + " quota -dir data",
+			progName + " quota -dir data -format json",
+		},
+	},
+	"stats": {
+		summary: "Summarize the data of tags in the data directory, as a health check.",
+		details: "Reports the pages, questions, dates, size and last fetch of each tag, and the gaps of more than -gap days without questions, which may be windows that weren't fetched.",
+		examples: []string{
+			progName + " stats -dir data",
+			progName + " stats -dir data -tags go -gap 7 -format json",
+		},
+	},
+	"inspect": {
+		summary: "Print everything stored about a question in the data directory.",
+		details: "Prints each copy of the question in the pages of any tag, as returned by the API, and its answers if they were fetched.",
+		examples: []string{
+			progName + " inspect -dir data -id 12345678",
+		},
+	},
+	"search": {
+		summary: "List the stored questions matching filters on titles, scores, dates and tags.",
+		details: "Filters on titles (-title, -regex), scores (-minscore, -maxscore), dates and tags, or a full-text query with -keywords. A question stored under several tags is listed once.",
+		examples: []string{
+			progName + " search -dir data -tags go -title panic -maxscore -1",
+			progName + " search -dir data -regex '(?i)^why .*goroutine' -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " search -dir data -withtags go,generics -limit 20 -format csv",
+		},
+	},
+	"terms": {
+		summary: "List the characteristic terms of the titles of tags, ranked by TF-IDF.",
+		details: "Stop words of -language and words common to all questions are dropped, and words are counted by their stem. With -ngram, terms are phrases of several words, and -bymonth follows the share of the titles with each term month by month.",
+		examples: []string{
+			progName + " terms -dir data -tags go,rust,python -n 10",
+			progName + " terms -dir data -tags go,rust -stopwords stopwords.txt -format csv",
+			progName + " terms -dir data -tags c++ -ngram 2-3 -bymonth -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " terms -dir data -tags go -language '' -questionstopwords=false",
+		},
+	},
+	"intents": {
+		summary: "Report the metrics of questions by the intent of their titles, such as how-to or debugging.",
+		details: "Classifies questions by heuristics on their phrasing as how-to, debugging, conceptual, opinion, resource requests or other.",
+		examples: []string{
+			progName + " intents -dir data -tags go,rust -fromdate 2020-01-01 -todate 2021-01-01",
+			progName + " intents -dir data -tags go -sentiments data/sentiments.csv -format json",
+		},
+	},
+	"errors": {
+		summary: "List the most frequent error messages, exceptions and exit codes in the titles of questions.",
+		details: "Signatures are quoted error messages (with the names, numbers and paths in them normalized), exception names, exit codes and compiler error codes.",
+		examples: []string{
+			progName + " errors -dir data -tags rust -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -n 5",
+			progName + " errors -dir data -tags python -format json",
+		},
+	},
+	"score": {
+		summary: "Score the sentiment of questions with an external service, for -sentiments.",
+		details: "Posts each question, as JSON with its question_id, title, body and tags, to -endpoint, which replies with {\"sentiment\": score}. The scores are cached in a CSV file (sentiments.csv in -dir by default), so each question is only posted once.",
+		examples: []string{
+			progName + ` score -dir data -tags go -endpoint https://sentiment.example.com/score -header "Authorization: Bearer $TOKEN"`,
+			progName + " -dir data -tags go -bymonth -fromdate 2020-01-01 -todate 2021-01-01 -sentiments data/sentiments.csv",
+		},
+	},
+	"index": {
+		summary: "Build the full-text index of the questions of tags, for -keywords.",
+		details: "fetch-all-questions -fulltext builds the index while fetching; rerun this after fetching without it.",
+		examples: []string{
+			progName + " index -dir data -tags go",
+			progName + " search -dir data -tags go -keywords '+panic -goroutine'",
+		},
+	},
+	"compact": {
+		summary: "Merge the pages of tags in the data directory into larger files.",
+		details: "Makes analyzing data directories with many small pages faster.",
+		examples: []string{
+			progName + " compact -dir data",
+			progName + " compact -dir data -tags go -size 50000",
+		},
+	},
+	"validate": {
+		summary: "Check the stored pages of tags against the JSON Schema of the API's replies.",
+		details: "Catches changes of the API that would otherwise decode as zero values: unknown fields, wrong types and missing required fields. fetch-all-questions -validate checks pages as they're fetched.",
+		examples: []string{
+			progName + " validate -dir data",
+			progName + " validate -schema > reply.schema.json",
+		},
+	},
+	"migrate": {
+		summary: "Upgrade the data directory to the current storage format.",
+		details: "Older data directories can still be analyzed; migrating them keeps the same results.",
+		examples: []string{
+			progName + " migrate -dir data",
+			progName + " migrate -dir data -check",
+		},
+	},
+	"export": {
+		summary: "Copy the data of tags into a new data directory, optionally anonymized.",
+		details: "With -anonymize, the personal data of the owners of the questions is removed, for sharing the data. With -notebook, the analysis is exported instead, as a panel of the metrics along with a Jupyter or R Markdown notebook reproducing the standard charts.",
+		examples: []string{
+			progName + " export -dir data -tags go,rust -fromdate 2020-01-01 -todate 2021-01-01 -out data-2020",
+			progName + " export -dir data -anonymize -out shared",
+			progName + " export -dir data -tags go,rust -bymonth -notebook rmarkdown -out go-rust",
+		},
+	},
+	"purge": {
+		summary: "Remove the questions and answers of a user from the data directory.",
+		details: "Rewrites the pages and the files of answers they were in, e.g. to honor a request to delete their data. Fetching the same questions again brings them back.",
+		examples: []string{
+			progName + " purge -dir data -user 12345",
+			progName + " purge -dir data -link https://stackoverflow.com/users/12345/name",
+		},
+	},
+	"completion": {
+		args:    " bash|zsh|fish",
+		summary: "Print a shell completion script, completing commands, flags and tag names.",
+		details: "The scripts also complete the tag names in -dir for -tags.",
+		examples: []string{
+			"source <(" + progName + " completion bash)",
+			progName + " completion zsh > \"${fpath[1]}/_" + progName + "\"",
+			progName + " completion fish > ~/.config/fish/completions/" + progName + ".fish",
+		},
+	},
+	"help": {
+		args:    " [command]",
+		summary: "Show the help of a command.",
+	},
+}
+
+// newFlagSet creates the flag set of the named command, with its help as usage.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		usage(name, fs)
+	}
+	fs.StringVar(&otelEndpoint, "otel-endpoint", "", "OTLP/HTTP collector to export traces and metrics to, e.g. http://localhost:4318")
+	fs.StringVar(&profile, "profile", "", "profile of the config file to use, setting e.g. -dir; see config")
+	return fs
+}
+
+// usage prints the help of the named command, with the flags of fs. While
+// completing, it prints just the names of the flags instead.
+func usage(name string, fs *flag.FlagSet) {
+	if completing {
+		fs.VisitAll(func(f *flag.Flag) {
+			fmt.Println("-" + f.Name)
+		})
+		return
+	}
+
+	h := commandHelp[name]
+	w := fs.Output()
+	cmdline := progName
+	if name != "" {
+		cmdline += " " + name
+	}
+	fmt.Fprintf(w, "usage: %s [flags]%s\n\n%s\n", cmdline, h.args, h.summary)
+	if h.details != "" {
+		fmt.Fprintf(w, "\n%s\n", wrapText(h.details, 80))
+	}
+
+	if name == "" {
+		fmt.Fprintf(w, "\nCommands (run '%s help <command>' for details):\n", progName)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, n := range commandNames() {
+			fmt.Fprintf(tw, "  %s\t%s\n", n, commandHelp[n].summary)
+		}
+		tw.Flush()
+	}
+
+	if len(h.examples) > 0 {
+		fmt.Fprintln(w, "\nExamples:")
+		for _, ex := range h.examples {
+			fmt.Fprintf(w, "  %s\n", ex)
+		}
+	}
+
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(w, "\nFlags:")
+		fs.PrintDefaults()
+	}
+}
+
+// wrapText wraps text into lines of at most width characters, breaking it
+// between words.
+func wrapText(text string, width int) string {
+	var b strings.Builder
+	n := 0
+	for _, word := range strings.Fields(text) {
+		switch {
+		case n == 0:
+		case n+1+len(word) > width:
+			b.WriteByte('\n')
+			n = 0
+		default:
+			b.WriteByte(' ')
+			n++
+		}
+		b.WriteString(word)
+		n += len(word)
+	}
+	return b.String()
+}
+
+// commandNames returns the sorted names of the subcommands.
+func commandNames() []string {
+	var names []string
+	for name := range commandHelp {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runHelp implements the help command, which shows the help of the given
+// command, or of the default analysis command.
+func runHelp(args []string) {
+	fs := newFlagSet("help")
+	// Help doesn't read the config, so it works with a malformed one.
+	fs.Parse(args)
+
+	switch {
+	case fs.NArg() == 0:
+		runAnalysis([]string{"-help"})
+	case fs.Arg(0) == "completion":
+		runCompletion([]string{"-help"})
+	case fs.Arg(0) == "help":
+		fs.Usage()
+	default:
+		cmd, ok := commands[fs.Arg(0)]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n", fs.Arg(0))
+			os.Exit(2)
+		}
+		cmd([]string{"-help"})
+	}
+}
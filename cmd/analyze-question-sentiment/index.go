@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+)
+
+// runIndex implements the index command, which builds the full-text index of
+// the titles and bodies of the questions of each tag in the data directory,
+// for -keywords; see soanalysis.Dataset.BuildFullTextIndex. Rerun it after
+// fetching more questions without fetch-all-questions -fulltext.
+func runIndex(args []string) {
+	fs := newFlagSet("index")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) separated by commas; all tags by default")
+	parseFlags(fs, args)
+
+	if cli.IsStream(af.dir) {
+		log.Fatal("-dir - can't be indexed")
+	}
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	ds := af.analyzer().Dataset
+	ctx, cancel := af.context()
+	defer cancel()
+	for _, tag := range tags {
+		n, err := ds.BuildFullTextIndex(ctx, tag)
+		failonf(err, "indexing tag %q", tag)
+		fmt.Printf("%s: indexed %d questions\n", tag, n)
+	}
+}
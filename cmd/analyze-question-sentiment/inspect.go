@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// inspectCopy is a stored copy of the inspected question as presented by the
+// inspect command.
+type inspectCopy struct {
+	Tag  string `json:"tag"`
+	Path string `json:"path"`
+
+	// Analyzed tells whether this copy is the one analyzed for the tag.
+	Analyzed bool            `json:"analyzed"`
+	Question json.RawMessage `json:"question"`
+}
+
+// inspectQuestion is everything stored about a question as presented by the
+// inspect command.
+type inspectQuestion struct {
+	ID      int            `json:"id"`
+	Copies  []inspectCopy  `json:"copies"`
+	Answers []soapi.Answer `json:"answers"`
+
+	// AnswersFetched lists the tags the answers were fetched with.
+	AnswersFetched []string `json:"answersFetched"`
+}
+
+// runInspect implements the inspect command, which prints everything stored
+// about a question in the data directory as JSON, for debugging surprising
+// numbers: each copy of it in the pages of any tag, as returned by the API, and
+// its answers if they were fetched. Comments, timelines and revisions aren't
+// fetched, so they can't be shown.
+func runInspect(args []string) {
+	fs := newFlagSet("inspect")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	idFlag := fs.Int("id", 0, "ID of the question to inspect")
+	parseFlags(fs, args)
+
+	if af.dir == "" || cli.IsStream(af.dir) {
+		log.Fatal("inspect requires a data directory with -dir")
+	}
+	if *idFlag <= 0 {
+		log.Fatal("-id must be provided")
+	}
+
+	ds := af.analyzer().Dataset
+	copies, err := ds.FindQuestion(*idFlag)
+	failonf(err, "finding question %d", *idFlag)
+	if len(copies) == 0 {
+		log.Fatalf("question %d not found in %q", *idFlag, af.dir)
+	}
+
+	iq := inspectQuestion{ID: *idFlag, Answers: []soapi.Answer{}, AnswersFetched: []string{}}
+	for i, c := range copies {
+		analyzed := i == 0 || copies[i-1].Tag != c.Tag
+		iq.Copies = append(iq.Copies, inspectCopy{Tag: c.Tag, Path: c.Path, Analyzed: analyzed, Question: c.Raw})
+		if !analyzed || !ds.HasAnswers(c.Tag) {
+			continue
+		}
+
+		iq.AnswersFetched = append(iq.AnswersFetched, c.Tag)
+		seen := make(map[int]bool)
+		for _, a := range iq.Answers {
+			seen[a.AnswerID] = true
+		}
+		err := ds.ForEachAnswer(c.Tag, func(answer *soapi.Answer) {
+			if answer.QuestionID == *idFlag && !seen[answer.AnswerID] {
+				iq.Answers = append(iq.Answers, *answer)
+			}
+		})
+		failonf(err, "reading answers of tag %q", c.Tag)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	failonf(enc.Encode(iq), "writing results")
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// intentRow is the result of the questions of an intent as presented in
+// -format json.
+type intentRow struct {
+	Intent            string   `json:"intent"`
+	Total             int      `json:"total"`
+	Share             float64  `json:"share"`
+	Negative          float64  `json:"negative"`
+	Closed            float64  `json:"closed"`
+	ClosedAndNegative float64  `json:"closedAndNegative"`
+	Unanswered        float64  `json:"unanswered"`
+	MeanScore         float64  `json:"meanScore"`
+	MeanSentiment     *float64 `json:"meanSentiment,omitempty"`
+	SentimentCoverage float64  `json:"sentimentCoverage,omitempty"`
+}
+
+type intentTag struct {
+	Tag     string      `json:"tag"`
+	Intents []intentRow `json:"intents"`
+}
+
+// tagIntents are the results by intent of the questions of a tag.
+type tagIntents struct {
+	tag     string
+	results []soanalysis.IntentResult
+}
+
+// runIntents implements the intents command, which classifies the questions of
+// each tag by the intent of their titles (how-to, debugging, conceptual,
+// opinion, resource or other) and reports the metrics of the questions of each
+// intent; see soanalysis.ClassifyIntent.
+func runIntents(args []string) {
+	fs := newFlagSet("intents")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("intents requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []tagIntents
+	for _, tag := range tags {
+		irs, err := an.IntentResults(ctx, tag, fromDate, toDate)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, tagIntents{tag: tag, results: irs})
+	}
+
+	if *formatFlag == "json" {
+		err = writeIntentsJSON(os.Stdout, results, an.Sentiments != nil)
+	} else {
+		err = writeIntentsCSV(os.Stdout, results, an.Sentiments != nil)
+	}
+	failonf(err, "writing results")
+}
+
+// writeIntentsCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per intent: the number of questions, their
+// share of the questions of the tag, their negative, closed, closed & negative
+// and unanswered ratios and their mean score, along with their mean sentiment
+// and its coverage with sentiments.
+func writeIntentsCSV(w io.Writer, results []tagIntents, sentiments bool) error {
+	for _, ti := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ti.tag); err != nil {
+			return err
+		}
+		for _, ir := range ti.results {
+			r := ir.Result
+			_, err := fmt.Fprintf(w, "%s,%d,%.3f,%.3f,%.3f,%.3f,%.3f,%.2f", ir.Intent, r.Total, ir.Share,
+				r.NegativeRatio(), r.ClosedRatio(), r.ClosedAndNegativeRatio(), r.UnansweredRatio(), r.MeanScore())
+			if err != nil {
+				return err
+			}
+			if sentiments {
+				if _, err := fmt.Fprintf(w, ",%.3f,%.3f", r.MeanSentiment(), r.SentimentCoverage()); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeIntentsJSON writes the results as a JSON array with an object per tag.
+func writeIntentsJSON(w io.Writer, results []tagIntents, sentiments bool) error {
+	its := []intentTag{}
+	for _, ti := range results {
+		it := intentTag{Tag: ti.tag, Intents: []intentRow{}}
+		for _, ir := range ti.results {
+			r := ir.Result
+			row := intentRow{
+				Intent:            string(ir.Intent),
+				Total:             r.Total,
+				Share:             ir.Share,
+				Negative:          r.NegativeRatio(),
+				Closed:            r.ClosedRatio(),
+				ClosedAndNegative: r.ClosedAndNegativeRatio(),
+				Unanswered:        r.UnansweredRatio(),
+				MeanScore:         r.MeanScore(),
+			}
+			if sentiments && len(r.Sentiments) > 0 {
+				mean := r.MeanSentiment()
+				row.MeanSentiment = &mean
+				row.SentimentCoverage = r.SentimentCoverage()
+			}
+			it.Intents = append(it.Intents, row)
+		}
+		its = append(its, it)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(its)
+}
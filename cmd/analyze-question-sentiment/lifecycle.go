@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// lifecycleRow is the lifecycle of a tag as presented in -format json.
+type lifecycleRow struct {
+	Tag           string  `json:"tag"`
+	First         string  `json:"first"`
+	PeakMonth     string  `json:"peakMonth"`
+	PeakQuestions int     `json:"peakQuestions"`
+	Trailing      float64 `json:"trailing"`
+	Previous      float64 `json:"previous"`
+	Stage         string  `json:"stage"`
+}
+
+// runLifecycle implements the lifecycle command, which summarizes the history
+// of the volume of questions of each tag in a single table: the date of its
+// first question, its peak month, its recent monthly volume and its stage
+// (growing, plateau or declining); see soanalysis.Analyzer.Lifecycle.
+func runLifecycle(args []string) {
+	fs := newFlagSet("lifecycle")
+	var af analysisFlags
+	af.register(fs)
+	windowFlag := fs.Int("window", 6, "number of recent months whose volume is compared with the months before them")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("lifecycle requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.Lifecycle
+	for _, tag := range tags {
+		lc, err := an.Lifecycle(ctx, tag, fromDate, toDate, *windowFlag)
+		failonf(err, "analyzing tag %q", tag)
+		if lc.First.IsZero() {
+			continue
+		}
+		results = append(results, lc)
+	}
+
+	if *formatFlag == "json" {
+		err = writeLifecycleJSON(os.Stdout, results)
+	} else {
+		err = writeLifecycleCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeLifecycleCSV writes the results as a CSV table with a header and a row
+// per tag.
+func writeLifecycleCSV(w io.Writer, results []soanalysis.Lifecycle) error {
+	if _, err := fmt.Fprintln(w, "tag,first,peakMonth,peakQuestions,trailing,previous,stage"); err != nil {
+		return err
+	}
+	for _, lc := range results {
+		_, err := fmt.Fprintf(w, "%s,%s,%s,%d,%.1f,%.1f,%s\n", lc.Tag, lc.First.Format("2006-01-02"), lc.PeakMonth.Format("2006-01"),
+			lc.PeakQuestions, lc.Trailing, lc.Previous, lc.Stage)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLifecycleJSON writes the results as a JSON array with an object per tag.
+func writeLifecycleJSON(w io.Writer, results []soanalysis.Lifecycle) error {
+	rows := []lifecycleRow{}
+	for _, lc := range results {
+		rows = append(rows, lifecycleRow{
+			Tag:           lc.Tag,
+			First:         lc.First.Format("2006-01-02"),
+			PeakMonth:     lc.PeakMonth.Format("2006-01"),
+			PeakQuestions: lc.PeakQuestions,
+			Trailing:      lc.Trailing,
+			Previous:      lc.Previous,
+			Stage:         string(lc.Stage),
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
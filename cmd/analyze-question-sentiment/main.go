@@ -0,0 +1,786 @@
+// Before running this program, first fetch the data with fetch-all-questions
+// into some base directory. Pass this base directory with the -dir flag to
+// this program.
+//
+// By default, the program analyzes the questions of the tags in -dir and prints
+// their metrics, such as the ratios of negative, closed and unanswered
+// questions, month by month with -bymonth; see runAnalysis. Its commands
+// report on the data in other ways (e.g. report, serve or survival) or manage
+// the data directory (e.g. compact, migrate or purge). Run
+// "analyze-question-sentiment help" for the list of commands, and
+// "analyze-question-sentiment help <command>" for the details, flags and
+// examples of each. The completion command prints a completion script for bash,
+// zsh or fish:
+//
+//	source <(analyze-question-sentiment completion bash)
+//
+// Defaults for the flags of all commands, such as -dir and -format, as well as
+// named groups of tags can be put in ~/.config/sotrends.toml, or set with
+// environment variables such as SOTRENDS_DIR; see config and cli.EnvVar.
+//
+// Eli Bendersky [https://eli.thegreenplace.net]
+// This code is in the public domain.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/internal/telemetry"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// failonf exits with a message if err is not nil.
+func failonf(err error, pattern string, args ...interface{}) {
+	cli.Exit(cli.Wrapf(err, pattern, args...))
+}
+
+// analysisFlags holds the flags shared by all commands that analyze the
+// fetched data.
+type analysisFlags struct {
+	dir      string
+	fromDate string
+	toDate   string
+	tags     string
+	bymonth  bool
+	timezone string
+	licenses string
+	keywords string
+
+	// sentiments is the sidecar file of external sentiment scores given by
+	// -sentiments (see soanalysis.ReadSentiments), and sentimentModel the
+	// directory of the model given by -sentimentmodel to score them with
+	// instead.
+	sentiments     string
+	sentimentModel string
+
+	trim      float64
+	winsorize float64
+
+	parallelism int
+	timeout     time.Duration
+
+	// window and step are the sliding windows given by -window and -step;
+	// only registered by the commands taking them (see registerWindows).
+	window string
+	step   string
+}
+
+func (af *analysisFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&af.dir, "dir", "", "base directory with results, or - to read pages from stdin")
+	fs.StringVar(&af.fromDate, "fromdate", "", "start date in 2006-01-02 format")
+	fs.StringVar(&af.toDate, "todate", "", "end date in 2006-01-02 format")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) separated by commas")
+	fs.BoolVar(&af.bymonth, "bymonth", false, "analyze by month")
+	fs.StringVar(&af.timezone, "timezone", "UTC", "time zone of the dates, e.g. America/New_York")
+	fs.StringVar(&af.licenses, "licenses", "", "only analyze questions under these content licenses, separated by commas, e.g. 'CC BY-SA 4.0'")
+	fs.StringVar(&af.keywords, "keywords", "", "only analyze questions matching this full-text query, e.g. panic or '+panic -goroutine'")
+	fs.StringVar(&af.sentiments, "sentiments", "", "CSV or JSON file mapping question IDs to sentiment scores from an external model, to aggregate along with the other metrics")
+	fs.StringVar(&af.sentimentModel, "sentimentmodel", "", "directory of an ONNX sentiment classifier to score the titles and bodies of the questions with, caching the scores in -dir")
+	fs.Float64Var(&af.trim, "trim", 0, "fraction of the lowest and of the highest scores and view counts to drop from their means")
+	fs.Float64Var(&af.winsorize, "winsorize", 0, "fraction of the lowest and of the highest scores and view counts to clamp in their means")
+	fs.IntVar(&af.parallelism, "parallelism", 0, "number of pages to decode concurrently; 0 for the number of CPUs")
+	fs.DurationVar(&af.timeout, "timeout", 0, "maximal duration of the analysis, e.g. 30s; 0 for no limit")
+}
+
+// context returns the context to analyze in: it's cancelled on an interrupt
+// (Ctrl-C), so that the analysis stops between pages, and after -timeout if
+// it's set.
+func (af *analysisFlags) context() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	if af.timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, af.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// registerWindows registers -window and -step, for analysis by sliding windows
+// instead of calendar months.
+func (af *analysisFlags) registerWindows(fs *flag.FlagSet) {
+	fs.StringVar(&af.window, "window", "", "analyze by sliding windows of this size, e.g. 90d or 12w (requires -fromdate and -todate)")
+	fs.StringVar(&af.step, "step", "7d", "distance between the starts of successive -window windows, e.g. 7d")
+}
+
+// windows returns the size and step of the sliding windows given by -window and
+// -step; the size is 0 without -window.
+func (af *analysisFlags) windows() (time.Duration, time.Duration, error) {
+	if af.window == "" {
+		return 0, 0, nil
+	}
+	if af.bymonth {
+		return 0, 0, errors.New("-window can't be combined with -bymonth")
+	}
+	size, err := parseDays(af.window)
+	if err != nil {
+		return 0, 0, fmt.Errorf("-window: %w", err)
+	}
+	step, err := parseDays(af.step)
+	if err != nil {
+		return 0, 0, fmt.Errorf("-step: %w", err)
+	}
+	return size, step, nil
+}
+
+// parseDays parses a duration given in days (90d) or weeks (12w), or as
+// accepted by time.ParseDuration (36h).
+func parseDays(s string) (time.Duration, error) {
+	day := 24 * time.Hour
+	for suffix, unit := range map[string]time.Duration{"d": day, "w": 7 * day} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			if count, err := strconv.Atoi(n); err == nil {
+				return time.Duration(count) * unit, nil
+			}
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, want e.g. 90d or 12w", s)
+	}
+	return d, nil
+}
+
+// setRecentMonths sets up analysis by month of the given number of months up to
+// the end date; without explicit dates, the period ends today. This is useful
+// for commands running on a schedule.
+func (af *analysisFlags) setRecentMonths(months int) {
+	if af.toDate == "" {
+		af.toDate = time.Now().Format("2006-01-02")
+	}
+	if af.fromDate == "" {
+		af.fromDate = parseDate(af.toDate).AddDate(0, -months, 0).Format("2006-01-02")
+	}
+	af.bymonth = true
+}
+
+// dates returns the dates given by -fromdate and -todate in the time zone given
+// by -timezone; a date that isn't given is zero.
+func (af *analysisFlags) dates() (time.Time, time.Time, error) {
+	loc, err := time.LoadLocation(af.timezone)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	var dates [2]time.Time
+	for i, date := range []string{af.fromDate, af.toDate} {
+		dates[i], err = cli.ParseDate(date, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	return dates[0], dates[1], nil
+}
+
+// analyzer returns an analyzer of the data directory.
+func (af *analysisFlags) analyzer() *soanalysis.Analyzer {
+	an := soanalysis.NewAnalyzer(af.dir)
+	an.Dataset.Parallelism = af.parallelism
+	an.Filter = af.filter()
+	an.Robust = af.robust()
+	an.Sentiments = af.sentimentScores()
+	return an
+}
+
+// sentimentScores returns the external sentiment scores read from the file
+// given by -sentiments or computed by -sentimentmodel, or nil without either.
+func (af *analysisFlags) sentimentScores() soanalysis.Sentiments {
+	if af.sentimentModel != "" {
+		if af.sentiments != "" {
+			log.Fatal("-sentiments can't be combined with -sentimentmodel")
+		}
+		return af.modelSentiments()
+	}
+	return loadSentimentScores(af.sentiments)
+}
+
+// loadSentimentScores returns the external sentiment scores read from the file
+// at path, or nil if path is empty.
+func loadSentimentScores(path string) soanalysis.Sentiments {
+	if path == "" {
+		return nil
+	}
+	s, err := soanalysis.ReadSentiments(path)
+	failonf(err, "reading sentiment scores from %q", path)
+	return s
+}
+
+// robust returns the handling of extreme values selected by -trim and
+// -winsorize.
+func (af *analysisFlags) robust() soanalysis.Robust {
+	for _, f := range []float64{af.trim, af.winsorize} {
+		if f < 0 || f >= 0.5 {
+			log.Fatalf("-trim and -winsorize must be between 0 and 0.5, got %g", f)
+		}
+	}
+	return soanalysis.Robust{Trim: af.trim, Winsorize: af.winsorize}
+}
+
+// filter returns the filter of questions selected by -licenses and -keywords,
+// or nil if all questions are selected.
+func (af *analysisFlags) filter() func(item *soapi.Item) bool {
+	var filters []func(item *soapi.Item) bool
+	if af.licenses != "" {
+		licenses := strings.Split(af.licenses, ",")
+		filters = append(filters, func(item *soapi.Item) bool {
+			return slices.Contains(licenses, item.ContentLicense)
+		})
+	}
+	if af.keywords != "" {
+		ids := af.keywordMatches()
+		filters = append(filters, func(item *soapi.Item) bool {
+			return ids[item.QuestionID]
+		})
+	}
+
+	if len(filters) == 0 {
+		return nil
+	}
+	return func(item *soapi.Item) bool {
+		for _, f := range filters {
+			if !f(item) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// keywordMatches returns the IDs of the questions matching -keywords in the
+// full-text indexes of the tags requested by the flags.
+func (af *analysisFlags) keywordMatches() map[int]bool {
+	if cli.IsStream(af.dir) {
+		log.Fatal("-keywords requires a data directory, not -dir -")
+	}
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	ds := &soanalysis.Dataset{Dir: af.dir}
+	ids := make(map[int]bool)
+	for _, tag := range tags {
+		if !ds.HasFullTextIndex(tag) {
+			log.Fatalf("no full-text index of tag %q; build it with the index command or fetch-all-questions -fulltext", tag)
+		}
+		matches, err := ds.SearchFullText(tag, af.keywords)
+		failonf(err, "searching tag %q for %q", tag, af.keywords)
+		for _, id := range matches {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// tagList returns the tags requested by the flags: the ones listed in -tags,
+// with groups expanded to their tags, or all the tags in the data directory.
+func (af *analysisFlags) tagList() ([]string, error) {
+	if len(af.dir) == 0 {
+		return nil, errors.New("-dir must be provided and cannot be empty. Please use the folder where the data was fetched.")
+	}
+
+	if af.tags == "" {
+		// No explicit tags specified by user => then discover
+		// the subfolders of the results base directory
+		ds := &soanalysis.Dataset{Dir: af.dir}
+		return ds.Tags()
+	}
+	return af.expandTags(), nil
+}
+
+// expandTags returns the tags listed in -tags, with groups expanded to their
+// tags.
+func (af *analysisFlags) expandTags() []string {
+	var tags []string
+	for _, tag := range cli.SplitList(af.tags) {
+		if group, ok := cfg.groups[tag]; ok {
+			tags = append(tags, group...)
+		} else {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// analyze analyzes the data as requested by the flags. A tag that can't be
+// analyzed (e.g. missing from -dir) doesn't stop the analysis of the other
+// tags: their results are returned with a *cli.PartialError holding the errors
+// of the failed tags.
+func (af *analysisFlags) analyze(ctx context.Context) ([]soanalysis.Series, error) {
+	fromDate, toDate, err := af.dates()
+	if err != nil {
+		return nil, err
+	}
+	size, step, err := af.windows()
+	if err != nil {
+		return nil, err
+	}
+	if cli.IsStream(af.dir) {
+		return af.analyzeReader(os.Stdin, fromDate, toDate)
+	}
+	tags, err := af.tagList()
+	if err != nil {
+		return nil, err
+	}
+
+	an := af.analyzer()
+	var results []soanalysis.Series
+	var errs []error
+	for _, tag := range tags {
+		var ts soanalysis.Series
+		if size > 0 {
+			ts, err = an.WindowSeries(ctx, tag, fromDate, toDate, size, step)
+		} else {
+			ts, err = an.Series(ctx, tag, fromDate, toDate, af.bymonth)
+		}
+		if ctx.Err() != nil {
+			// Interrupted or timed out: the other tags would fail too.
+			return nil, fmt.Errorf("analyzing tag %q: %w", tag, err)
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("analyzing tag %q: %w", tag, err))
+			continue
+		}
+		results = append(results, ts)
+	}
+	return results, cli.Partial(errs)
+}
+
+// stdinTag is the name of the series of all the questions read from stdin when
+// no -tags are given.
+const stdinTag = "stdin"
+
+// analyzeReader analyzes the questions in the stream of API replies read from r
+// (for -dir -). Each question is analyzed for the tags in -tags it has; without
+// -tags, all the questions are analyzed together as stdinTag.
+func (af *analysisFlags) analyzeReader(r io.Reader, fromDate time.Time, toDate time.Time) ([]soanalysis.Series, error) {
+	tags := []string{stdinTag}
+	if af.tags != "" {
+		tags = af.expandTags()
+	}
+
+	size, step, err := af.windows()
+	if err != nil {
+		return nil, err
+	}
+	sentiments := af.sentimentScores()
+	var ags []*soanalysis.Aggregator
+	for _, tag := range tags {
+		var ag *soanalysis.Aggregator
+		if size > 0 {
+			ag, err = soanalysis.NewWindowAggregator(tag, fromDate, toDate, size, step)
+		} else {
+			ag, err = soanalysis.NewAggregator(tag, fromDate, toDate, af.bymonth)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ag.Robust = af.robust()
+		ag.Sentiments = sentiments
+		ags = append(ags, ag)
+	}
+
+	filter := af.filter()
+	err = soapi.DecodeReplies(r, func(reply *soapi.Reply) error {
+		for i := range reply.Items {
+			if filter != nil && !filter(&reply.Items[i]) {
+				continue
+			}
+			for j, tag := range tags {
+				if af.tags == "" || slices.Contains(reply.Items[i].Tags, tag) {
+					ags[j].Add(&reply.Items[i])
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading stdin: %w", err)
+	}
+
+	var results []soanalysis.Series
+	for _, ag := range ags {
+		results = append(results, ag.Series())
+	}
+	return results, nil
+}
+
+// run is like analyze, but exits on errors. If only some of the tags failed,
+// their errors are logged and the results of the other tags returned, and the
+// program exits with exitStatus 1 once the command is done.
+func (af *analysisFlags) run() []soanalysis.Series {
+	ctx, cancel := af.context()
+	defer cancel()
+	results, err := af.analyze(ctx)
+	var pe *cli.PartialError
+	if errors.As(err, &pe) && len(results) > 0 {
+		cli.Warn(pe)
+		exitStatus = 1
+		return results
+	}
+	failonf(err, "analyzing %q", af.dir)
+	return results
+}
+
+// exitStatus is the status the program exits with once the command is done;
+// it's set when some of the results are missing, e.g. because some tags
+// couldn't be analyzed.
+var exitStatus int
+
+// otelEndpoint is the OTLP/HTTP collector given by -otel-endpoint, which all the
+// commands take, to export the traces and metrics of the analysis to.
+var otelEndpoint string
+
+// flushTelemetry flushes the traces and metrics not exported yet; main calls it
+// before exiting.
+var flushTelemetry = func() {}
+
+// setupTelemetry starts exporting traces and metrics to otelEndpoint, if it's
+// set; see telemetry.Setup.
+func setupTelemetry() {
+	if otelEndpoint == "" {
+		return
+	}
+	shutdown, err := telemetry.Setup(otelEndpoint, progName)
+	failonf(err, "setting up telemetry")
+	flushTelemetry = func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			log.Println("flushing telemetry:", err)
+		}
+	}
+}
+
+// cumulative returns the cumulative series of results; see
+// soanalysis.Series.Cumulative.
+func cumulative(results []soanalysis.Series) []soanalysis.Series {
+	var cs []soanalysis.Series
+	for _, ts := range results {
+		cs = append(cs, ts.Cumulative())
+	}
+	return cs
+}
+
+// eventsIn returns the events of the config file in the period of buckets, from
+// the start of the first to the end of the last.
+func eventsIn(buckets []soanalysis.Bucket) []soanalysis.Event {
+	if len(buckets) == 0 {
+		return nil
+	}
+	var events []soanalysis.Event
+	for _, ev := range cfg.events {
+		if !ev.Date.Before(buckets[0].Start) && !ev.Date.After(buckets[len(buckets)-1].Date) {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// commands maps the names of subcommands to their entry points; each is
+// invoked with the command-line arguments following its name.
+var commands = map[string]func(args []string){
+	"report":        runReport,
+	"site":          runSite,
+	"serve":         runServe,
+	"grpc":          runGRPC,
+	"exporter":      runExporter,
+	"sheets":        runSheets,
+	"notify":        runNotify,
+	"email":         runEmail,
+	"tui":           runTUI,
+	"diff":          runDiff,
+	"baseline":      runBaseline,
+	"check":         runCheck,
+	"run":           runPipeline,
+	"compact":       runCompact,
+	"validate":      runValidate,
+	"migrate":       runMigrate,
+	"export":        runExport,
+	"purge":         runPurge,
+	"survival":      runSurvival,
+	"cohorts":       runCohorts,
+	"concentration": runConcentration,
+	"reputation":    runReputation,
+	"answers":       runAnswers,
+	"bounties":      runBounties,
+	"benchmark":     runBenchmark,
+	"quota":         runQuota,
+	"stats":         runStats,
+	"inspect":       runInspect,
+	"search":        runSearch,
+	"index":         runIndex,
+	"score":         runScore,
+	"terms":         runTerms,
+	"intents":       runIntents,
+	"errors":        runErrors,
+	"lifecycle":     runLifecycle,
+	"duplicates":    runDuplicates,
+	"protection":    runProtection,
+	"neardups":      runNearDuplicates,
+	"graph":         runGraph,
+	"versions":      runVersions,
+	"sites":         runSites,
+	"code":          runCode,
+	"edits":         runEdits,
+	"deletions":     runDeletions,
+	"drift":         runDrift,
+	"views":         runViews,
+	"churn":         runChurn,
+	"seasonal":      runSeasonal,
+	"events":        runEvents,
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "help":
+			runHelp(os.Args[2:])
+			return
+		case "completion":
+			runCompletion(os.Args[2:])
+			return
+		case "__complete":
+			runComplete(os.Args[2:])
+			return
+		}
+		if cmd, ok := commands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			flushTelemetry()
+			os.Exit(exitStatus)
+		}
+	}
+	runAnalysis(os.Args[1:])
+	flushTelemetry()
+	os.Exit(exitStatus)
+}
+
+// runAnalysis implements the default command, which prints the analysis in the
+// format selected by -format.
+//
+// A tag that can't be analyzed, e.g. because it's missing from -dir, doesn't
+// stop the analysis of the others: its error is logged, the results of the
+// other tags are printed, and the program exits with status 1.
+//
+// The analysis stops on Ctrl-C or once -timeout passes. The serve and grpc
+// commands limit the analysis of each request with their own -timeout.
+//
+// With -otel-endpoint, which all the commands take, the analysis of each tag and
+// the decoding of its pages are traced, and their metrics recorded, with
+// OpenTelemetry, and exported to the OTLP/HTTP collector at the given URL; serve
+// and grpc also trace each request, continuing the traces of their clients.
+//
+// To get a month-by-month breakdown from start date to end date, use the
+// -bymonth flag. The results are printed as CSV by default; -format selects
+// other formats, e.g. -format influx for InfluxDB line protocol or -format
+// vegalite for a Vega-Lite chart specification with the data inlined.
+// -format gnuplot prints a gnuplot script and writes the data it plots into the
+// file named by -gnuplotdata. -format xlsx writes an Excel workbook with a sheet
+// per tag and a summary sheet with charts; redirect it to a .xlsx file.
+// -format arrow writes an Arrow IPC (Feather) file, and -arrowitems writes the
+// analyzed questions themselves into another one, for loading into data frames.
+// -format cbor and -format msgpack are compact binary encodings of -format json.
+//
+// -format panel writes a balanced panel for econometric analysis: a single
+// long CSV table with a row per tag and bucket (typically month), with
+// consistent numeric identifiers of the tags and periods and missing values
+// left empty, ready for fixed-effects regressions in R or Stata.
+//
+// Besides the ratios, -format json, arrow and influx report the number of
+// questions per day in each bucket, also smoothed over the last 3 buckets, to
+// track the volume of questions along with their reception. If the site counts
+// were fetched into -dir with fetch-all-questions -sitecounts for the same
+// periods, they also report the share of the questions of each tag out of all
+// the questions on the site, telling the decline of a tag from that of the
+// site.
+//
+// With -trim or -winsorize, that fraction of the lowest and of the highest
+// scores and view counts is dropped or clamped before computing their means,
+// so that a few viral questions don't dominate the means of a small tag; the
+// means are reported by -format json, arrow and influx, and the robust mean of
+// the view counts is used for the negative questions per 10,000 views.
+//
+// With -sentiments, the per-question sentiment scores of an external model are
+// read from a sidecar file, CSV rows of question_id,sentiment or JSON, and
+// their mean (trimmed and winsorized like the other means) and the ratio of
+// the questions having one become metrics of each bucket, reported by every
+// output format (as csv columns following the ratios), serve and its GraphQL
+// API, and the TUI; the scores are taken as they are, so any scale works.
+//
+// -sentimentmodel scores the questions offline instead, running the titles and
+// bodies through a local BERT-family sentiment classifier exported to ONNX (a
+// directory with model.onnx and vocab.txt, e.g. a DistilBERT fine-tuned on
+// SST-2) in batches; the scores are between -1 and 1 and cached in -dir, so
+// only new questions are scored on later runs. This needs a build with
+// -tags onnx and the ONNX Runtime library, found through ONNXRUNTIME_LIB.
+//
+// With -keywords, only the questions matching a full-text query on their titles
+// and bodies are analyzed, e.g. -keywords panic for the negativity of questions
+// mentioning panics; this needs the full-text index of the tags, built by the
+// index command or fetch-all-questions -fulltext.
+//
+// -format licenses prints the distribution of the content licenses of the
+// questions instead of the metrics; with -licenses, only questions under the
+// given licenses are analyzed, e.g. -licenses 'CC BY-SA 4.0'. Similarly,
+// -format owners breaks the metrics down by the type of the accounts of the
+// askers (registered, unregistered, or deleted: does_not_exist), including
+// their share of the negative questions.
+//
+// With -cumulative, each bucket holds all the questions from the start of the
+// period to its end rather than the ones of its own period, e.g. for the total
+// number of questions and closures to date with -bymonth; the report command
+// takes -cumulative too.
+//
+// With -deltas, the change of each metric since the previous bucket is
+// appended to each row, both absolute and in percent, e.g. for monthly
+// changes with -bymonth.
+//
+// With -forecast N, the metrics of the N months after the period are forecast
+// with exponential smoothing (Holt-Winters, with yearly seasons given two
+// years of data), with their 95% prediction intervals; the report command
+// takes -forecast too, for a section about where each tag is heading:
+//
+//	analyze-question-sentiment -dir data -bymonth -fromdate ... -todate ... -forecast 6
+//
+// With -window, the questions are bucketed by sliding windows of the given
+// size, a -step apart, instead of by calendar months; overlapping windows make
+// for much smoother series for noisy tags than disjoint months. The buckets are
+// dated by the ends of their windows, and the report command takes -window
+// too:
+//
+//	analyze-question-sentiment -dir data -tags go -fromdate ... -todate ... -window 90d -step 7d
+//
+// The pages of questions are decoded in parallel, by as many workers as there
+// are CPUs or as set with -parallelism; -parallelism 1 decodes them one at a
+// time, using the least memory.
+//
+// With -chart, bars and sparklines of the metrics are shown in the terminal
+// alongside the numbers. With -plot, a chart of the monthly series is rendered
+// for each tag into the given directory.
+//
+// With -dir -, pages of questions as returned by the API are read from stdin
+// instead, e.g. as written by fetch-all-questions -dir -:
+//
+//	fetch-all-questions -dir - -tags go ... | analyze-question-sentiment -dir - -tags go
+//
+// With -watch, the program keeps running and reruns the analysis whenever the
+// data in -dir changes, e.g. while fetch-all-questions is running in another
+// terminal. The report and site commands support -watch as well. While
+// watching, the config file is reloaded when it changes or on SIGHUP, and the
+// analysis rerun, so that e.g. tags added to -tags or to a group in the config
+// file are picked up without restarting; flags given on the command line keep
+// their values, and flags such as -format only take effect on restarting.
+func runAnalysis(args []string) {
+	fs := newFlagSet("")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv, json, cbor, msgpack, influx, vegalite, gnuplot, xlsx, arrow, panel, licenses or owners")
+	gnuplotDataFlag := fs.String("gnuplotdata", "sentiment.dat", "data file to write for the script emitted by -format gnuplot")
+	chartFlag := fs.Bool("chart", false, "add bars and sparklines of the metrics to the csv output")
+	plotFlag := fs.String("plot", "", "directory to write a chart per tag into (requires -bymonth or -window)")
+	plotFormatFlag := fs.String("plotformat", "png", "chart image format: png or svg")
+	watchFlag := fs.Bool("watch", false, "keep watching -dir and rerun the analysis when the data changes")
+	af.registerWindows(fs)
+	arrowItemsFlag := fs.String("arrowitems", "", "also write the analyzed questions as rows into this Arrow IPC file")
+	cumulativeFlag := fs.Bool("cumulative", false, "make each bucket hold all the questions from the start of the period to its end")
+	deltasFlag := fs.Bool("deltas", false, "append the change of each metric since the previous bucket, absolute and in percent, with -format csv or json")
+	forecastFlag := fs.Int("forecast", 0, "number of months after the period to forecast the metrics of, with -format csv or json (requires -bymonth)")
+
+	parseFlags(fs, args)
+
+	formatters["gnuplot"] = func(w io.Writer, results []soanalysis.Series) error {
+		return writeGnuplot(w, results, *gnuplotDataFlag)
+	}
+	formatter, ok := formatters[*formatFlag]
+	if !ok {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if *chartFlag {
+		if *formatFlag != "csv" {
+			log.Fatal("-chart only applies to -format csv")
+		}
+		formatter = writeChart
+	}
+	if *deltasFlag {
+		switch {
+		case *formatFlag == "csv" && !*chartFlag:
+			formatter = writeDeltaCSV
+		case *formatFlag == "json":
+			formatter = writeDeltaJSON
+		default:
+			log.Fatal("-deltas only applies to -format csv and json, without -chart")
+		}
+	}
+	if *forecastFlag > 0 {
+		if *deltasFlag {
+			log.Fatal("-forecast can't be combined with -deltas")
+		}
+		if !af.bymonth {
+			log.Fatal("-forecast requires -bymonth")
+		}
+		switch *formatFlag {
+		case "csv":
+			formatter = func(w io.Writer, results []soanalysis.Series) error {
+				return writeForecastCSV(w, results, *forecastFlag)
+			}
+		case "json":
+			formatter = func(w io.Writer, results []soanalysis.Series) error {
+				return writeForecastJSON(w, results, *forecastFlag)
+			}
+		default:
+			log.Fatal("-forecast only applies to -format csv and json")
+		}
+	}
+
+	if *arrowItemsFlag != "" && cli.IsStream(af.dir) {
+		log.Fatal("-arrowitems requires a data directory, not -dir -")
+	}
+
+	if *plotFlag != "" {
+		if !af.bymonth && af.window == "" {
+			log.Fatal("-plot requires -bymonth or -window")
+		}
+		if *plotFormatFlag != "png" && *plotFormatFlag != "svg" {
+			log.Fatalf("unknown -plotformat %q", *plotFormatFlag)
+		}
+		// Try to create the directory; ignore error (if it already exists, etc.)
+		_ = os.Mkdir(*plotFlag, 0777)
+	}
+
+	rerunOnChange(*watchFlag, af.dir, func() {
+		results := af.run()
+		if *cumulativeFlag {
+			results = cumulative(results)
+		}
+		err := formatter(os.Stdout, results)
+		failonf(err, "writing results")
+
+		if *arrowItemsFlag != "" {
+			ctx, cancel := af.context()
+			err := writeArrowItems(ctx, *arrowItemsFlag, af)
+			cancel()
+			failonf(err, "writing %q", *arrowItemsFlag)
+		}
+
+		if *plotFlag != "" {
+			for _, ts := range results {
+				filename := filepath.Join(*plotFlag, ts.Tag+"."+*plotFormatFlag)
+				err := plotTag(filename, ts.Tag, ts.Buckets)
+				failonf(err, "plotting tag %q", ts.Tag)
+				log.Println("Wrote", filename)
+			}
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// runMigrate implements the migrate command, which upgrades the data directory
+// from the format it was written in to soanalysis.CurrentFormat; see
+// soanalysis.Dataset.Migrate.
+func runMigrate(args []string) {
+	fs := newFlagSet("migrate")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	checkFlag := fs.Bool("check", false, "print the format of the data directory and exit")
+	parseFlags(fs, args)
+
+	if cli.IsStream(af.dir) {
+		log.Fatal("-dir - can't be migrated")
+	}
+	ds := af.analyzer().Dataset
+	if *checkFlag {
+		format, err := ds.Format()
+		failonf(err, "reading the format of %q", af.dir)
+		fmt.Printf("%s: format %d, current format %d\n", af.dir, format, soanalysis.CurrentFormat)
+		return
+	}
+
+	from, err := ds.Migrate(func(tag string, from int) {
+		fmt.Printf("%s: migrating from format %d to %d\n", tag, from, from+1)
+	})
+	failonf(err, "migrating %q", af.dir)
+	if from == soanalysis.CurrentFormat {
+		fmt.Printf("%s: already in format %d\n", af.dir, from)
+	} else {
+		fmt.Printf("%s: migrated from format %d to %d\n", af.dir, from, soanalysis.CurrentFormat)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// nearDuplicateRow is a bucket of the near-duplicates of a tag as presented in
+// -format json.
+type nearDuplicateRow struct {
+	Date           string  `json:"date"`
+	Questions      int     `json:"questions"`
+	NearDuplicates int     `json:"nearDuplicates"`
+	Ratio          float64 `json:"ratio"`
+}
+
+// nearDuplicateCluster is a cluster of near-duplicates as presented in -format
+// json.
+type nearDuplicateCluster struct {
+	First     int    `json:"first"`
+	Link      string `json:"link"`
+	Title     string `json:"title"`
+	Questions []int  `json:"questions"`
+}
+
+type nearDuplicateTag struct {
+	Tag      string                 `json:"tag"`
+	Rows     []nearDuplicateRow     `json:"rows"`
+	Clusters []nearDuplicateCluster `json:"clusters"`
+}
+
+// runNearDuplicates implements the neardups command, which estimates how much
+// of the volume of each tag in each bucket is questions with titles similar to
+// older questions, and lists the largest clusters of similar titles of the
+// whole period; see soanalysis.Analyzer.NearDuplicates. Unlike the duplicates
+// command, it doesn't depend on moderators closing the questions.
+func runNearDuplicates(args []string) {
+	fs := newFlagSet("neardups")
+	var af analysisFlags
+	af.register(fs)
+	similarityFlag := fs.Float64("similarity", 0.7, "minimal Jaccard similarity of the words of two titles for them to be near-duplicates, between 0 and 1")
+	topFlag := fs.Int("top", 10, "number of largest clusters to list for each tag")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if *similarityFlag <= 0 || *similarityFlag > 1 {
+		log.Fatalf("-similarity must be between 0 and 1, got %g", *similarityFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("neardups requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.NearDuplicateSeries
+	for _, tag := range tags {
+		ns, err := an.NearDuplicates(ctx, tag, fromDate, toDate, af.bymonth, *similarityFlag)
+		failonf(err, "analyzing tag %q", tag)
+		if len(ns.Clusters) > *topFlag {
+			ns.Clusters = ns.Clusters[:*topFlag]
+		}
+		results = append(results, ns)
+	}
+
+	if *formatFlag == "json" {
+		err = writeNearDuplicatesJSON(os.Stdout, results)
+	} else {
+		err = writeNearDuplicatesCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeNearDuplicatesCSV writes the results of each tag as a line with the tag
+// name followed by a CSV table with a row per bucket, and then a line with the
+// tag name followed by a CSV table with a row per largest cluster.
+func writeNearDuplicatesCSV(w io.Writer, results []soanalysis.NearDuplicateSeries) error {
+	for _, ns := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ns.Tag); err != nil {
+			return err
+		}
+		for _, b := range ns.Buckets {
+			_, err := fmt.Fprintf(w, "%s,%d,%d,%.3f\n", b.Date.Format("2006-01-02"), b.Questions, b.NearDuplicates, b.NearDuplicateRatio())
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(w, "\n%s largest clusters\n", ns.Tag); err != nil {
+			return err
+		}
+		cw := csv.NewWriter(w)
+		for _, c := range ns.Clusters {
+			cw.Write([]string{strconv.Itoa(len(c.Questions)), strconv.Itoa(c.Questions[0]), questionLink(c.Questions[0]), c.Title})
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeNearDuplicatesJSON writes the results as a JSON array with an object per
+// tag.
+func writeNearDuplicatesJSON(w io.Writer, results []soanalysis.NearDuplicateSeries) error {
+	nts := []nearDuplicateTag{}
+	for _, ns := range results {
+		nt := nearDuplicateTag{Tag: ns.Tag, Rows: []nearDuplicateRow{}, Clusters: []nearDuplicateCluster{}}
+		for _, b := range ns.Buckets {
+			nt.Rows = append(nt.Rows, nearDuplicateRow{
+				Date:           b.Date.Format("2006-01-02"),
+				Questions:      b.Questions,
+				NearDuplicates: b.NearDuplicates,
+				Ratio:          b.NearDuplicateRatio(),
+			})
+		}
+		for _, c := range ns.Clusters {
+			nt.Clusters = append(nt.Clusters, nearDuplicateCluster{
+				First:     c.Questions[0],
+				Link:      questionLink(c.Questions[0]),
+				Title:     c.Title,
+				Questions: c.Questions,
+			})
+		}
+		nts = append(nts, nt)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(nts)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// notebookData is what the notebook templates are executed with.
+type notebookData struct {
+	Generated string
+	Tags      []string
+
+	// From and To are the period of the analysis, as given by -fromdate and
+	// -todate (empty if not given), and Buckets describes its buckets.
+	From    string
+	To      string
+	Buckets string
+}
+
+// notebookPanel is the name of the data file of notebook bundles.
+const notebookPanel = "panel.csv"
+
+// writeNotebook writes the results into the directory dir as a data bundle
+// for custom analyses: the results as a panel (see writePanel) and a notebook
+// loading it and reproducing the standard charts, analysis.ipynb for Jupyter
+// (kind "jupyter") or analysis.Rmd for R Markdown (kind "rmarkdown").
+func writeNotebook(dir string, kind string, results []soanalysis.Series, af *analysisFlags) error {
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, notebookPanel))
+	if err != nil {
+		return err
+	}
+	if err := writePanel(f, results); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	nd := notebookData{Generated: time.Now().UTC().Format(time.RFC3339), From: af.fromDate, To: af.toDate, Buckets: "a single period"}
+	if af.bymonth {
+		nd.Buckets = "calendar months"
+	}
+	for _, ts := range results {
+		nd.Tags = append(nd.Tags, ts.Tag)
+	}
+
+	var path string
+	var text strings.Builder
+	switch kind {
+	case "jupyter":
+		path = filepath.Join(dir, "analysis.ipynb")
+		err = writeJupyter(&text, nd)
+	case "rmarkdown":
+		path = filepath.Join(dir, "analysis.Rmd")
+		err = rmarkdownTemplate.Execute(&text, nd)
+	default:
+		return fmt.Errorf("unknown kind of notebook %q", kind)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(text.String()), 0644)
+}
+
+// writeJupyter writes a Jupyter notebook (nbformat 4) of the bundle, with a
+// markdown cell describing the data and a code cell per chart, without
+// outputs; running it renders the charts.
+func writeJupyter(w io.Writer, nd notebookData) error {
+	var intro strings.Builder
+	if err := notebookIntroTemplate.Execute(&intro, nd); err != nil {
+		return err
+	}
+	cells := []map[string]any{jupyterCell("markdown", intro.String())}
+	for _, source := range jupyterCode {
+		cells = append(cells, jupyterCell("code", source))
+	}
+	nb := map[string]any{
+		"cells": cells,
+		"metadata": map[string]any{
+			"kernelspec":    map[string]string{"name": "python3", "display_name": "Python 3", "language": "python"},
+			"language_info": map[string]string{"name": "python"},
+		},
+		"nbformat":       4,
+		"nbformat_minor": 5,
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(nb)
+}
+
+// jupyterCell returns a cell of a Jupyter notebook of the given type with the
+// given source, split into lines as Jupyter stores it.
+func jupyterCell(cellType string, source string) map[string]any {
+	lines := strings.SplitAfter(strings.TrimSpace(source), "\n")
+	cell := map[string]any{"cell_type": cellType, "metadata": map[string]any{}, "source": lines}
+	if cellType == "code" {
+		cell["execution_count"] = nil
+		cell["outputs"] = []any{}
+	}
+	return cell
+}
+
+var notebookIntroTemplate = template.Must(template.New("intro").Parse(`
+# StackOverflow sentiment of {{range $i, $t := .Tags}}{{if $i}}, {{end}}[{{$t}}]{{end}}
+
+Exported by analyze-question-sentiment export -notebook on {{.Generated}}, for
+the period from {{if .From}}{{.From}}{{else}}the first question{{end}} to {{if .To}}{{.To}}{{else}}the last question{{end}}, analyzed by {{.Buckets}}.
+
+` + "`" + notebookPanel + "`" + ` holds a row per tag and period, identified by ` + "`tag_id`" + ` and ` + "`period`" + `; metrics
+undefined for a period without questions are empty. A question counts as
+negative if its score is below 0, and as closed if it was closed at the time it
+was fetched.
+`))
+
+// jupyterCode are the code cells of Jupyter notebooks, loading the panel and
+// reproducing the standard charts with pandas and matplotlib.
+var jupyterCode = []string{`
+import pandas as pd
+import matplotlib.pyplot as plt
+
+panel = pd.read_csv("` + notebookPanel + `", parse_dates=["period_start", "period_end"])
+panel.head()
+`, `
+# The metrics of each tag over the whole period, weighting the periods by their
+# numbers of questions.
+def summarize(g):
+    w = g["questions"]
+    return pd.Series({
+        "questions": w.sum(),
+        **{m: (g[m] * w).sum() / w.sum() for m in ["negative", "closed", "closed_negative", "unanswered"]},
+    })
+
+panel.groupby("tag").apply(summarize)
+`, `
+# The ratios of negative and closed questions of each tag over time.
+fig, axes = plt.subplots(1, 2, figsize=(12, 4), sharey=True)
+for ax, metric in zip(axes, ["negative", "closed"]):
+    panel.pivot(index="period_end", columns="tag", values=metric).plot(ax=ax, marker="o", title=metric + " ratio")
+plt.show()
+`, `
+# The number of questions per day of each tag over time.
+panel.pivot(index="period_end", columns="tag", values="questions_per_day").plot(marker="o", title="questions per day")
+plt.show()
+`, `
+# A fixed-effects regression of the negative ratio on tags and periods, weighted
+# by the numbers of questions; needs statsmodels.
+# import statsmodels.formula.api as smf
+# smf.wls("negative ~ C(tag) + C(period)", data=panel.dropna(subset=["negative"]), weights=panel["questions"]).fit().summary()
+`}
+
+var rmarkdownTemplate = template.Must(template.New("rmarkdown").Parse(`---
+title: "StackOverflow sentiment of {{range $i, $t := .Tags}}{{if $i}}, {{end}}[{{$t}}]{{end}}"
+date: "{{.Generated}}"
+output: html_document
+---
+
+Exported by analyze-question-sentiment export -notebook, for the period from
+{{if .From}}{{.From}}{{else}}the first question{{end}} to {{if .To}}{{.To}}{{else}}the last question{{end}}, analyzed by {{.Buckets}}.
+
+` + "`" + notebookPanel + "`" + ` holds a row per tag and period, identified by ` + "`tag_id`" + ` and ` + "`period`" + `; metrics
+undefined for a period without questions are empty. A question counts as
+negative if its score is below 0, and as closed if it was closed at the time it
+was fetched.
+
+` + "```{r setup, message=FALSE}" + `
+library(readr)
+library(dplyr)
+library(tidyr)
+library(ggplot2)
+
+panel <- read_csv("` + notebookPanel + `", show_col_types = FALSE)
+head(panel)
+` + "```" + `
+
+The metrics of each tag over the whole period, weighting the periods by their
+numbers of questions:
+
+` + "```{r summary}" + `
+panel %>%
+  group_by(tag) %>%
+  summarise(across(c(negative, closed, closed_negative, unanswered), ~ weighted.mean(.x, questions, na.rm = TRUE)),
+            questions = sum(questions))
+` + "```" + `
+
+The ratios of negative and closed questions of each tag over time:
+
+` + "```{r ratios}" + `
+panel %>%
+  pivot_longer(c(negative, closed), names_to = "metric") %>%
+  ggplot(aes(period_end, value, colour = tag)) +
+  geom_line() + geom_point() +
+  facet_wrap(~ metric) +
+  labs(x = NULL, y = "ratio")
+` + "```" + `
+
+The number of questions per day of each tag over time:
+
+` + "```{r volume}" + `
+ggplot(panel, aes(period_end, questions_per_day, colour = tag)) +
+  geom_line() + geom_point() +
+  labs(x = NULL, y = "questions per day")
+` + "```" + `
+
+A fixed-effects regression of the negative ratio on tags and periods, weighted
+by the numbers of questions:
+
+` + "```{r regression}" + `
+summary(lm(negative ~ factor(tag) + factor(period), data = panel, weights = questions))
+` + "```" + `
+`))
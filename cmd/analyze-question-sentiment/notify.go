@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// runNotify implements the notify command, which checks the latest month of
+// each tag against thresholds and posts a Slack-compatible message to a webhook
+// for every crossing. It's meant to be run on a schedule (e.g. from cron) after
+// fetching fresh data; without -todate, the last month ends today.
+func runNotify(args []string) {
+	fs := newFlagSet("notify")
+	var af analysisFlags
+	af.register(fs)
+	webhookFlag := fs.String("webhook", "", "URL to post notifications to")
+	var nt notifyThresholds
+	fs.Float64Var(&nt.maxNegative, "max-negative", 0, "notify when the negative ratio exceeds this (0 to disable)")
+	fs.Float64Var(&nt.maxClosed, "max-closed", 0, "notify when the closed ratio exceeds this (0 to disable)")
+	fs.Float64Var(&nt.maxDeviation, "max-deviation", 0.5, "notify when a ratio deviates from its trailing average by more than this fraction (0 to disable)")
+	fs.IntVar(&nt.trailing, "trailing", 6, "number of months before the latest one to average")
+	parseFlags(fs, args)
+
+	if *webhookFlag == "" {
+		log.Fatal("-webhook must be provided")
+	}
+	af.setRecentMonths(nt.trailing + 1)
+
+	for _, ts := range af.run() {
+		for _, msg := range nt.check(ts) {
+			log.Println(msg)
+			err := postWebhook(*webhookFlag, msg)
+			failonf(err, "posting to webhook")
+		}
+	}
+}
+
+// notifyThresholds configures which changes in the metrics of a tag are
+// notified about.
+type notifyThresholds struct {
+	maxNegative  float64
+	maxClosed    float64
+	maxDeviation float64
+	trailing     int
+}
+
+// check returns a message for every threshold crossed by the latest bucket of
+// ts.
+func (nt notifyThresholds) check(ts soanalysis.Series) []string {
+	if len(ts.Buckets) == 0 {
+		return nil
+	}
+	latest := ts.Buckets[len(ts.Buckets)-1]
+	previous := ts.Buckets[:len(ts.Buckets)-1]
+	if len(previous) > nt.trailing {
+		previous = previous[len(previous)-nt.trailing:]
+	}
+
+	var msgs []string
+	date := latest.Date.Format("2006-01-02")
+	for _, m := range []struct {
+		name   string
+		metric func(soanalysis.Result) float64
+		max    float64
+	}{
+		{"negative", soanalysis.Result.NegativeRatio, nt.maxNegative},
+		{"closed", soanalysis.Result.ClosedRatio, nt.maxClosed},
+	} {
+		value := m.metric(latest.Result)
+		if m.max > 0 && value > m.max {
+			msgs = append(msgs, fmt.Sprintf("[%s] %s ratio for the month ending %s is %.3f, above the threshold of %.3f",
+				ts.Tag, m.name, date, value, m.max))
+		}
+
+		if nt.maxDeviation > 0 && len(previous) > 0 {
+			var sum float64
+			for _, b := range previous {
+				sum += m.metric(b.Result)
+			}
+			avg := sum / float64(len(previous))
+			if avg > 0 && math.Abs(value-avg)/avg > nt.maxDeviation {
+				msgs = append(msgs, fmt.Sprintf("[%s] %s ratio for the month ending %s is %.3f, vs. a trailing average of %.3f",
+					ts.Tag, m.name, date, value, avg))
+			}
+		}
+	}
+	return msgs
+}
+
+// postWebhook posts text to a webhook URL, with a Slack-compatible payload.
+func postWebhook(url string, text string) error {
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
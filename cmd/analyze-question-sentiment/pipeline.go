@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// runPipeline implements the run command, which fetches the questions of the
+// tags in -tags between -fromdate and -todate, analyzes them as they are
+// fetched, and then prints the results and writes a report like the report
+// command. With -dir, the fetched pages are also saved there, so the data can
+// be analyzed again later without fetching; otherwise nothing but the report is
+// written to disk.
+//
+// To get the increased API quota, set the env var STACK_KEY=<key> as for
+// fetch-all-questions.
+func runPipeline(args []string) {
+	fs := newFlagSet("run")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv, json, cbor, msgpack, influx, vegalite, xlsx, arrow, licenses or owners")
+	outFlag := fs.String("out", "report.html", "output HTML file; empty for no report")
+	parseFlags(fs, args)
+
+	if af.tags == "" {
+		log.Fatal("-tags must be provided")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	if fromDate.IsZero() || toDate.IsZero() {
+		log.Fatal("-fromdate and -todate must be provided")
+	}
+	formatter, ok := formatters[*formatFlag]
+	if !ok {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+
+	var ds *soanalysis.Dataset
+	if af.dir != "" {
+		ds = &soanalysis.Dataset{Dir: af.dir}
+	}
+	fetcher := soapi.NewFetcher(os.Getenv("STACK_KEY"))
+	ctx, cancel := af.context()
+	defer cancel()
+
+	filter := af.filter()
+	sentiments := af.sentimentScores()
+	var results []soanalysis.Series
+	for _, tag := range af.expandTags() {
+		ag, err := soanalysis.NewAggregator(tag, fromDate, toDate, af.bymonth)
+		failonf(err, "analyzing tag %q", tag)
+		ag.Robust = af.robust()
+		ag.Sentiments = sentiments
+
+		log.Printf("Fetching tag %q", tag)
+		err = fetcher.FetchTag(ctx, tag, fromDate, toDate, func(page int, body []byte, reply *soapi.Reply) error {
+			for i := range reply.Items {
+				if filter == nil || filter(&reply.Items[i]) {
+					ag.Add(&reply.Items[i])
+				}
+			}
+			if ds != nil {
+				if _, err := ds.StorePage(tag, fromDate, toDate, page, body); err != nil {
+					return err
+				}
+			}
+			log.Printf("Fetched page %d, quota remaining: %d", page, reply.QuotaRemaining)
+			return nil
+		})
+		failonf(err, "fetching tag %q", tag)
+		results = append(results, ag.Series())
+	}
+
+	err = formatter(os.Stdout, results)
+	failonf(err, "writing results")
+
+	if *outFlag != "" {
+		f, err := os.Create(*outFlag)
+		failonf(err, "creating %q", *outFlag)
+		err = reportTemplates.ExecuteTemplate(f, "report", newReportData(fs, results))
+		failonf(err, "writing report")
+		err = f.Close()
+		failonf(err, "writing report")
+		fmt.Fprintln(os.Stderr, "Wrote", *outFlag)
+	}
+}
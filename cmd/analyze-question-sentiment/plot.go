@@ -0,0 +1,123 @@
+package main
+
+import (
+	"image/color"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// plotTag renders the metric series of a tag into a chart saved at filename.
+// The image format is chosen by the extension of filename (png, svg, etc.)
+func plotTag(filename string, tag string, buckets []soanalysis.Bucket) error {
+	p := plot.New()
+	p.Title.Text = tag
+	p.X.Tick.Marker = plot.TimeTicks{Format: "2006-01"}
+	p.Y.Label.Text = "ratio"
+	p.Add(plotter.NewGrid())
+
+	series := func(metric func(soanalysis.Result) float64) plotter.XYs {
+		pts := make(plotter.XYs, len(buckets))
+		for i, b := range buckets {
+			pts[i].X = float64(b.Date.Unix())
+			pts[i].Y = metric(b.Result)
+		}
+		return pts
+	}
+
+	err := plotutil.AddLinePoints(p,
+		"negative", series(soanalysis.Result.NegativeRatio),
+		"closed", series(soanalysis.Result.ClosedRatio),
+		"closed & negative", series(soanalysis.Result.ClosedAndNegativeRatio))
+	if err != nil {
+		return err
+	}
+	if err := addEventMarkers(p, buckets); err != nil {
+		return err
+	}
+	return p.Save(8*vg.Inch, 4*vg.Inch, filename)
+}
+
+// plotDecomposition renders the values of a metric of the buckets of ts and
+// their trend, seasonal and residual components d into a chart saved at
+// filename.
+func plotDecomposition(filename string, ts soanalysis.Series, metric string, values []float64, d soanalysis.Decomposition) error {
+	p := plot.New()
+	p.Title.Text = ts.Tag
+	p.X.Tick.Marker = plot.TimeTicks{Format: "2006-01"}
+	p.Y.Label.Text = metric
+	p.Add(plotter.NewGrid())
+
+	series := func(ys []float64) plotter.XYs {
+		pts := make(plotter.XYs, len(ts.Buckets))
+		for i, b := range ts.Buckets {
+			pts[i].X = float64(b.Date.Unix())
+			pts[i].Y = ys[i]
+		}
+		return pts
+	}
+
+	err := plotutil.AddLinePoints(p,
+		metric, series(values),
+		"trend", series(d.Trend),
+		"seasonal", series(d.Seasonal),
+		"residual", series(d.Residual))
+	if err != nil {
+		return err
+	}
+	if err := addEventMarkers(p, ts.Buckets); err != nil {
+		return err
+	}
+	return p.Save(8*vg.Inch, 4*vg.Inch, filename)
+}
+
+// addEventMarkers adds a labeled vertical line to p for each event of the config
+// file in the period of buckets; it has to be called after the series are
+// added, for the range of the Y axis.
+func addEventMarkers(p *plot.Plot, buckets []soanalysis.Bucket) error {
+	for _, ev := range eventsIn(buckets) {
+		x := float64(ev.Date.Unix())
+		line, err := plotter.NewLine(plotter.XYs{{X: x, Y: p.Y.Min}, {X: x, Y: p.Y.Max}})
+		if err != nil {
+			return err
+		}
+		line.Color = color.Gray{Y: 128}
+		line.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+		label, err := plotter.NewLabels(plotter.XYLabels{
+			XYs:    plotter.XYs{{X: x, Y: p.Y.Max}},
+			Labels: []string{ev.Name},
+		})
+		if err != nil {
+			return err
+		}
+		p.Add(line, label)
+	}
+	return nil
+}
+
+// plotSurvival renders the survival curves of the buckets of a tag into a chart
+// saved at filename, with a line per bucket.
+func plotSurvival(filename string, ts soanalysis.SurvivalSeries) error {
+	p := plot.New()
+	p.Title.Text = ts.Tag
+	p.X.Label.Text = "hours"
+	p.Y.Label.Text = "ratio answered"
+	p.Add(plotter.NewGrid())
+
+	var lines []interface{}
+	for _, b := range ts.Buckets {
+		pts := make(plotter.XYs, len(b.Points))
+		for i, pt := range b.Points {
+			pts[i].X = pt.Hours
+			pts[i].Y = pt.Answered
+		}
+		lines = append(lines, b.Date.Format("2006-01-02"), pts)
+	}
+	if err := plotutil.AddLinePoints(p, lines...); err != nil {
+		return err
+	}
+	return p.Save(8*vg.Inch, 4*vg.Inch, filename)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// protectionRow is a bucket of the protection of the questions of a tag as
+// presented in -format json.
+type protectionRow struct {
+	Date                 string  `json:"date"`
+	Questions            int     `json:"questions"`
+	Protected            int     `json:"protected"`
+	ProtectedRatio       float64 `json:"protectedRatio"`
+	MedianHoursToProtect float64 `json:"medianHoursToProtect"`
+	Locked               int     `json:"locked"`
+	LockedRatio          float64 `json:"lockedRatio"`
+}
+
+type protectionTag struct {
+	Tag  string          `json:"tag"`
+	Rows []protectionRow `json:"rows"`
+}
+
+// runProtection implements the protection command, which reports how many of
+// the questions of each tag in each bucket are protected or locked, and the
+// median time to protect them; see soanalysis.Analyzer.Protection. Only the
+// protections and locks in place when the questions were fetched are known.
+func runProtection(args []string) {
+	fs := newFlagSet("protection")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("protection requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.ProtectionSeries
+	for _, tag := range tags {
+		ps, err := an.Protection(ctx, tag, fromDate, toDate, af.bymonth)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, ps)
+	}
+
+	if *formatFlag == "json" {
+		err = writeProtectionJSON(os.Stdout, results)
+	} else {
+		err = writeProtectionCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeProtectionCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per bucket.
+func writeProtectionCSV(w io.Writer, results []soanalysis.ProtectionSeries) error {
+	for _, ps := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ps.Tag); err != nil {
+			return err
+		}
+		for _, b := range ps.Buckets {
+			_, err := fmt.Fprintf(w, "%s,%d,%d,%.4f,%.1f,%d,%.4f\n", b.Date.Format("2006-01-02"), b.Questions, b.Protected,
+				b.ProtectedRatio(), b.MedianTimeToProtect.Hours(), b.Locked, b.LockedRatio())
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeProtectionJSON writes the results as a JSON array with an object per
+// tag.
+func writeProtectionJSON(w io.Writer, results []soanalysis.ProtectionSeries) error {
+	pts := []protectionTag{}
+	for _, ps := range results {
+		pt := protectionTag{Tag: ps.Tag, Rows: []protectionRow{}}
+		for _, b := range ps.Buckets {
+			pt.Rows = append(pt.Rows, protectionRow{
+				Date:                 b.Date.Format("2006-01-02"),
+				Questions:            b.Questions,
+				Protected:            b.Protected,
+				ProtectedRatio:       b.ProtectedRatio(),
+				MedianHoursToProtect: b.MedianTimeToProtect.Hours(),
+				Locked:               b.Locked,
+				LockedRatio:          b.LockedRatio(),
+			})
+		}
+		pts = append(pts, pt)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(pts)
+}
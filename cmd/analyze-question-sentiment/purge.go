@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// runPurge implements the purge command, which removes the questions and
+// answers of a user, given by -user or -link, from all the tags in the data
+// directory, e.g. to honor a request to delete their data. Note that fetching
+// the same questions again brings them back.
+func runPurge(args []string) {
+	fs := newFlagSet("purge")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) separated by commas; all tags by default")
+	userFlag := fs.Int("user", 0, "ID of the user whose questions and answers to remove")
+	linkFlag := fs.String("link", "", "link to the account of the user whose questions and answers to remove, e.g. https://stackoverflow.com/users/123/name")
+	parseFlags(fs, args)
+
+	if cli.IsStream(af.dir) {
+		log.Fatal("-dir - can't be purged")
+	}
+	if *userFlag == 0 && *linkFlag == "" {
+		log.Fatal("-user or -link must be provided")
+	}
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	isUser := func(userID int, link string) bool {
+		return (*userFlag != 0 && userID == *userFlag) || (*linkFlag != "" && link == *linkFlag)
+	}
+	match := func(item *soapi.Item) bool { return isUser(item.Owner.UserID, item.Owner.Link) }
+	matchAnswer := func(answer *soapi.Answer) bool { return isUser(answer.Owner.UserID, answer.Owner.Link) }
+	ds := af.analyzer().Dataset
+	ctx, cancel := af.context()
+	defer cancel()
+	for _, tag := range tags {
+		questions, answers, err := ds.Purge(ctx, tag, match, matchAnswer)
+		failonf(err, "purging tag %q", tag)
+		fmt.Printf("%s: removed %d questions and %d answers\n", tag, questions, answers)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// quotaRow is the quota spent by a group of requests as presented in -format
+// json.
+type quotaRow struct {
+	Key          string `json:"key"`
+	Requests     int    `json:"requests"`
+	Spent        int    `json:"spent"`
+	MinRemaining int    `json:"minRemaining"`
+}
+
+// quotaReport is the quota usage as presented in -format json.
+type quotaReport struct {
+	ByTag []quotaRow `json:"byTag"`
+	ByRun []quotaRow `json:"byRun"`
+	ByDay []quotaRow `json:"byDay"`
+}
+
+// runQuota implements the quota command, which reports the API quota spent
+// fetching the data directory per tag, per run of fetch-all-questions and per
+// day (in UTC), from the quota log written by fetch-all-questions; see
+// soanalysis.QuotaUsageBy.
+func runQuota(args []string) {
+	fs := newFlagSet("quota")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if af.dir == "" || cli.IsStream(af.dir) {
+		log.Fatal("quota requires a data directory with -dir")
+	}
+
+	records, err := af.analyzer().Dataset.QuotaLog()
+	failonf(err, "reading the quota log")
+	if len(records) == 0 {
+		log.Fatalf("no quota log in %q; it's written by fetch-all-questions", af.dir)
+	}
+
+	report := [3][]soanalysis.QuotaUsage{
+		soanalysis.QuotaUsageBy(records, func(rec soanalysis.QuotaRecord) string { return rec.Tag }),
+		soanalysis.QuotaUsageBy(records, func(rec soanalysis.QuotaRecord) string { return rec.Run }),
+		soanalysis.QuotaUsageBy(records, func(rec soanalysis.QuotaRecord) string { return rec.Time.UTC().Format("2006-01-02") }),
+	}
+	if *formatFlag == "json" {
+		err = writeQuotaJSON(os.Stdout, report)
+	} else {
+		err = writeQuotaCSV(os.Stdout, report)
+	}
+	failonf(err, "writing results")
+}
+
+// quotaGroups names the groupings of the quota report, in order.
+var quotaGroups = [3]string{"tag", "run", "day"}
+
+// writeQuotaCSV writes each grouping of the report as a line with its name
+// followed by a CSV table with a row per group.
+func writeQuotaCSV(w io.Writer, report [3][]soanalysis.QuotaUsage) error {
+	for i, usages := range report {
+		if _, err := fmt.Fprintf(w, "\n%s\n", quotaGroups[i]); err != nil {
+			return err
+		}
+		for _, u := range usages {
+			if _, err := fmt.Fprintf(w, "%s,%d,%d,%d\n", u.Key, u.Requests, u.Spent, u.MinRemaining); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeQuotaJSON writes the report as a JSON object with an array per
+// grouping.
+func writeQuotaJSON(w io.Writer, report [3][]soanalysis.QuotaUsage) error {
+	var rows [3][]quotaRow
+	for i, usages := range report {
+		rows[i] = []quotaRow{}
+		for _, u := range usages {
+			rows[i] = append(rows[i], quotaRow{Key: u.Key, Requests: u.Requests, Spent: u.Spent, MinRemaining: u.MinRemaining})
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(quotaReport{ByTag: rows[0], ByRun: rows[1], ByDay: rows[2]})
+}
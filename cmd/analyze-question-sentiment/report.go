@@ -0,0 +1,677 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// reportRow is a single bucket of a tag as presented in a report.
+type reportRow struct {
+	Date              string  `json:"date"`
+	Total             int     `json:"total"`
+	Negative          float64 `json:"negative"`
+	Closed            float64 `json:"closed"`
+	ClosedAndNegative float64 `json:"closedAndNegative"`
+
+	// NegativePer10kViews is the number of negative questions per 10,000
+	// views.
+	NegativePer10kViews float64 `json:"negativePer10kViews"`
+
+	// QuestionsPerDay is the average number of questions per day, and
+	// QuestionsPerDaySmoothed its moving average over velocityWindow buckets.
+	QuestionsPerDay         float64 `json:"questionsPerDay"`
+	QuestionsPerDaySmoothed float64 `json:"questionsPerDaySmoothed"`
+
+	// MeanScore and MeanViews are the means of the scores and view counts,
+	// trimmed or winsorized as requested.
+	MeanScore float64 `json:"meanScore"`
+	MeanViews float64 `json:"meanViews"`
+
+	// MeanSentiment is the mean of the external sentiment scores of the
+	// questions (see -sentiments), if any has one, and SentimentCoverage the
+	// ratio of the questions having one.
+	MeanSentiment     *float64 `json:"meanSentiment,omitempty"`
+	SentimentCoverage float64  `json:"sentimentCoverage,omitempty"`
+
+	// SiteShare is the share of the questions out of all the questions on
+	// the site, if the site counts were fetched.
+	SiteShare float64 `json:"siteShare,omitempty"`
+
+	// Licenses is the number of questions under each content license.
+	Licenses map[string]int `json:"licenses,omitempty"`
+
+	// OwnerTypes is the breakdown of the questions by the type of the
+	// accounts of their owners.
+	OwnerTypes map[string]ownerTypeRow `json:"ownerTypes,omitempty"`
+
+	// Deltas are the changes of the metrics since the previous row, with
+	// -deltas; the first row has none.
+	Deltas *reportDeltas `json:"deltas,omitempty"`
+}
+
+// delta is the change of a metric since the previous bucket, absolute and in
+// percent of its previous value; Percent is nil if the previous value is 0.
+type delta struct {
+	Change  float64  `json:"change"`
+	Percent *float64 `json:"percent"`
+}
+
+func newDelta(prev float64, cur float64) delta {
+	d := delta{Change: cur - prev}
+	if prev != 0 {
+		p := 100 * (cur - prev) / prev
+		d.Percent = &p
+	}
+	return d
+}
+
+// reportDeltas are the changes of the metrics of a row since the previous one.
+type reportDeltas struct {
+	Total             delta `json:"total"`
+	Negative          delta `json:"negative"`
+	Closed            delta `json:"closed"`
+	ClosedAndNegative delta `json:"closedAndNegative"`
+}
+
+// addDeltas sets the changes of the metrics since the previous row to each row
+// of rt but the first.
+func addDeltas(rt *reportTag) {
+	for i := 1; i < len(rt.Rows); i++ {
+		prev, cur := rt.Rows[i-1], &rt.Rows[i]
+		cur.Deltas = &reportDeltas{
+			Total:             newDelta(float64(prev.Total), float64(cur.Total)),
+			Negative:          newDelta(prev.Negative, cur.Negative),
+			Closed:            newDelta(prev.Closed, cur.Closed),
+			ClosedAndNegative: newDelta(prev.ClosedAndNegative, cur.ClosedAndNegative),
+		}
+	}
+}
+
+// ownerTypeRow is the breakdown of a bucket for a type of owners.
+type ownerTypeRow struct {
+	Total         int     `json:"total"`
+	Negative      float64 `json:"negative"`
+	Closed        float64 `json:"closed"`
+	NegativeShare float64 `json:"negativeShare"`
+}
+
+// newOwnerTypeRows returns the breakdown of tr by the types of owners.
+func newOwnerTypeRows(tr soanalysis.Result) map[string]ownerTypeRow {
+	if len(tr.OwnerTypes) == 0 {
+		return nil
+	}
+	rows := make(map[string]ownerTypeRow)
+	for userType, oc := range tr.OwnerTypes {
+		rows[userType] = ownerTypeRow{
+			Total:         oc.Total,
+			Negative:      float64(oc.Negative) / float64(oc.Total),
+			Closed:        float64(oc.Closed) / float64(oc.Total),
+			NegativeShare: negativeShare(oc, tr),
+		}
+	}
+	return rows
+}
+
+type reportTag struct {
+	Tag  string      `json:"tag"`
+	Rows []reportRow `json:"rows"`
+
+	// Forecast holds the forecasts of the buckets after Rows, with -forecast.
+	Forecast []forecastRow `json:"forecast,omitempty"`
+
+	// Events are the events of the config file in the period of Rows.
+	Events []eventRow `json:"events,omitempty"`
+}
+
+// eventRow is an event as presented in a report, with the questions of the tag
+// before and after it with -eventdays.
+type eventRow struct {
+	Name   string      `json:"name"`
+	Date   string      `json:"date"`
+	Before *eventStats `json:"before,omitempty"`
+	After  *eventStats `json:"after,omitempty"`
+
+	event soanalysis.Event
+}
+
+// eventStats are the metrics of the questions on one side of an event.
+type eventStats struct {
+	Total             int     `json:"total"`
+	Negative          float64 `json:"negative"`
+	Closed            float64 `json:"closed"`
+	ClosedAndNegative float64 `json:"closedAndNegative"`
+}
+
+func newEventStats(tr soanalysis.Result) *eventStats {
+	return &eventStats{
+		Total:             tr.Total,
+		Negative:          tr.NegativeRatio(),
+		Closed:            tr.ClosedRatio(),
+		ClosedAndNegative: tr.ClosedAndNegativeRatio(),
+	}
+}
+
+// addEventImpacts sets the metrics of the questions in the given number of
+// days before and after each event of rts; see soanalysis.Analyzer.EventImpact.
+func addEventImpacts(ctx context.Context, rts []reportTag, an *soanalysis.Analyzer, days int) error {
+	for i := range rts {
+		for j := range rts[i].Events {
+			er := &rts[i].Events[j]
+			impact, err := an.EventImpact(ctx, rts[i].Tag, er.event, days)
+			if err != nil {
+				return fmt.Errorf("comparing tag %q around %q: %w", rts[i].Tag, er.Name, err)
+			}
+			er.Before = newEventStats(impact.Before)
+			er.After = newEventStats(impact.After)
+		}
+	}
+	return nil
+}
+
+// prediction is a forecast value with its 95% prediction interval.
+type prediction struct {
+	Value float64 `json:"value"`
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// forecastRow is a forecast bucket of a tag as presented in a report.
+type forecastRow struct {
+	Date              string     `json:"date"`
+	Total             prediction `json:"total"`
+	Negative          prediction `json:"negative"`
+	Closed            prediction `json:"closed"`
+	ClosedAndNegative prediction `json:"closedAndNegative"`
+}
+
+// forecastRows returns the forecasts of the n monthly buckets after the ones
+// of ts; see soanalysis.Forecast.
+func forecastRows(ts soanalysis.Series, n int) ([]forecastRow, error) {
+	if len(ts.Buckets) == 0 {
+		return nil, nil
+	}
+	rows := make([]forecastRow, n)
+	last := ts.Buckets[len(ts.Buckets)-1].Date
+	for i := range rows {
+		rows[i].Date = last.AddDate(0, i+1, 0).Format("2006-01-02")
+	}
+	for _, m := range []struct {
+		metric func(soanalysis.Result) float64
+		field  func(*forecastRow) *prediction
+	}{
+		{func(tr soanalysis.Result) float64 { return float64(tr.Total) }, func(r *forecastRow) *prediction { return &r.Total }},
+		{soanalysis.Result.NegativeRatio, func(r *forecastRow) *prediction { return &r.Negative }},
+		{soanalysis.Result.ClosedRatio, func(r *forecastRow) *prediction { return &r.Closed }},
+		{soanalysis.Result.ClosedAndNegativeRatio, func(r *forecastRow) *prediction { return &r.ClosedAndNegative }},
+	} {
+		values := make([]float64, len(ts.Buckets))
+		for i, b := range ts.Buckets {
+			values[i] = m.metric(b.Result)
+		}
+		predictions, err := soanalysis.Forecast(values, n, 12)
+		if err != nil {
+			return nil, err
+		}
+		for i, p := range predictions {
+			*m.field(&rows[i]) = prediction{Value: p.Value, Lower: p.Lower, Upper: p.Upper}
+		}
+	}
+	return rows, nil
+}
+
+// addForecasts sets the forecasts of the n buckets after the ones of results
+// to the corresponding tags of rts.
+func addForecasts(rts []reportTag, results []soanalysis.Series, n int) error {
+	for i := range rts {
+		rows, err := forecastRows(results[i], n)
+		if err != nil {
+			return fmt.Errorf("forecasting tag %q: %w", rts[i].Tag, err)
+		}
+		rts[i].Forecast = rows
+	}
+	return nil
+}
+
+// Last returns the latest bucket of the tag, or nil if there are none.
+func (rt reportTag) Last() *reportRow {
+	if len(rt.Rows) == 0 {
+		return nil
+	}
+	return &rt.Rows[len(rt.Rows)-1]
+}
+
+type reportParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// reportData is what the report template is executed with.
+type reportData struct {
+	Generated string
+	Params    []reportParam
+	Tags      []reportTag
+}
+
+func newReportData(fs *flag.FlagSet, results []soanalysis.Series) reportData {
+	var rd reportData
+	rd.Generated = time.Now().Format("2006-01-02 15:04:05")
+	fs.VisitAll(func(f *flag.Flag) {
+		rd.Params = append(rd.Params, reportParam{f.Name, f.Value.String()})
+	})
+	for _, ts := range results {
+		rd.Tags = append(rd.Tags, newReportTag(ts))
+	}
+	return rd
+}
+
+// velocityWindow is the number of buckets the questions per day are smoothed
+// over.
+const velocityWindow = 3
+
+func newReportTag(ts soanalysis.Series) reportTag {
+	rt := reportTag{Tag: ts.Tag}
+	smoothed := ts.QuestionsPerDay(velocityWindow)
+	for i, b := range ts.Buckets {
+		rt.Rows = append(rt.Rows, reportRow{
+			Date:              b.Date.Format("2006-01-02"),
+			Total:             b.Result.Total,
+			Negative:          b.Result.NegativeRatio(),
+			Closed:            b.Result.ClosedRatio(),
+			ClosedAndNegative: b.Result.ClosedAndNegativeRatio(),
+			Licenses:          b.Result.Licenses,
+			OwnerTypes:        newOwnerTypeRows(b.Result),
+
+			NegativePer10kViews: b.Result.NegativePer10kViews(),
+
+			QuestionsPerDay:         b.QuestionsPerDay(),
+			QuestionsPerDaySmoothed: smoothed[i],
+			MeanScore:               b.Result.MeanScore(),
+			MeanViews:               b.Result.MeanViews(),
+			SiteShare:               b.SiteShare(),
+		})
+	}
+	for i, b := range ts.Buckets {
+		if len(b.Result.Sentiments) > 0 {
+			mean := b.Result.MeanSentiment()
+			rt.Rows[i].MeanSentiment = &mean
+			rt.Rows[i].SentimentCoverage = b.Result.SentimentCoverage()
+		}
+	}
+	for _, ev := range eventsIn(ts.Buckets) {
+		rt.Events = append(rt.Events, eventRow{Name: ev.Name, Date: ev.Date.Format("2006-01-02"), event: ev})
+	}
+	return rt
+}
+
+// runReport implements the report command, which renders the analysis into a
+// single self-contained HTML file, or with -style blog into a markdown article
+// skeleton with chart images (see writeBlog).
+func runReport(args []string) {
+	fs := newFlagSet("report")
+	var af analysisFlags
+	af.register(fs)
+	outFlag := fs.String("out", "report.html", "output HTML file; with -style blog, the directory named like it without .html")
+	styleFlag := fs.String("style", "html", "style of the report: html, or blog for a markdown article with chart images")
+	watchFlag := fs.Bool("watch", false, "keep watching -dir and regenerate the report when the data changes")
+	af.registerWindows(fs)
+	forecastFlag := fs.Int("forecast", 0, "number of months after the period to forecast the metrics of (requires -bymonth)")
+	cumulativeFlag := fs.Bool("cumulative", false, "make each bucket hold all the questions from the start of the period to its end")
+	eventDaysFlag := fs.Int("eventdays", 0, "compare the questions in this many days before and after each event of the config file")
+	parseFlags(fs, args)
+
+	if *forecastFlag > 0 && !af.bymonth {
+		log.Fatal("-forecast requires -bymonth")
+	}
+	if *eventDaysFlag > 0 && cli.IsStream(af.dir) {
+		log.Fatal("-eventdays requires a data directory, not -dir -")
+	}
+	if *styleFlag != "html" && *styleFlag != "blog" {
+		log.Fatalf("unknown -style %q", *styleFlag)
+	}
+
+	rerunOnChange(*watchFlag, af.dir, func() {
+		results := af.run()
+		if *cumulativeFlag {
+			results = cumulative(results)
+		}
+		rd := newReportData(fs, results)
+		if *forecastFlag > 0 {
+			err := addForecasts(rd.Tags, results, *forecastFlag)
+			failonf(err, "forecasting")
+		}
+		if *eventDaysFlag > 0 {
+			ctx, cancel := af.context()
+			err := addEventImpacts(ctx, rd.Tags, af.analyzer(), *eventDaysFlag)
+			cancel()
+			failonf(err, "comparing events")
+		}
+
+		if *styleFlag == "blog" {
+			dir := blogDir(*outFlag)
+			err := writeBlog(dir, rd, results, &af)
+			failonf(err, "writing blog report")
+			fmt.Println("Wrote", filepath.Join(dir, "index.md"))
+			return
+		}
+		f, err := os.Create(*outFlag)
+		failonf(err, "creating %q", *outFlag)
+		err = reportTemplates.ExecuteTemplate(f, "report", rd)
+		failonf(err, "writing report")
+		err = f.Close()
+		failonf(err, "writing report")
+		fmt.Println("Wrote", *outFlag)
+	})
+}
+
+// runSite implements the site command, which generates a static site from the
+// analysis: an index page of all tags, a page per tag and the underlying data
+// as JSON files. Re-running it over the same output directory updates the site
+// in place.
+func runSite(args []string) {
+	fs := newFlagSet("site")
+	var af analysisFlags
+	af.register(fs)
+	outFlag := fs.String("out", "site", "output directory")
+	watchFlag := fs.Bool("watch", false, "keep watching -dir and regenerate the site when the data changes")
+	parseFlags(fs, args)
+
+	rerunOnChange(*watchFlag, af.dir, func() {
+		rd := newReportData(fs, af.run())
+
+		for _, sub := range []string{"tags", "data"} {
+			dir := filepath.Join(*outFlag, sub)
+			err := os.MkdirAll(dir, 0777)
+			failonf(err, "creating directory %q", dir)
+		}
+
+		writeFile := func(name string, write func(w io.Writer) error) {
+			path := filepath.Join(*outFlag, name)
+			f, err := os.Create(path)
+			failonf(err, "creating %q", path)
+			err = write(f)
+			failonf(err, "writing %q", path)
+			err = f.Close()
+			failonf(err, "writing %q", path)
+		}
+		writeJSON := func(name string, v interface{}) {
+			writeFile(name, func(w io.Writer) error {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				return enc.Encode(v)
+			})
+		}
+
+		writeFile("index.html", func(w io.Writer) error {
+			return reportTemplates.ExecuteTemplate(w, "site-index", rd)
+		})
+
+		index := struct {
+			Generated string        `json:"generated"`
+			Params    []reportParam `json:"params"`
+			Tags      []string      `json:"tags"`
+		}{Generated: rd.Generated, Params: rd.Params}
+
+		for _, rt := range rd.Tags {
+			index.Tags = append(index.Tags, rt.Tag)
+			page := sitePage{Report: rd, Tag: rt, Tags: []reportTag{rt}}
+			writeFile(filepath.Join("tags", rt.Tag+".html"), func(w io.Writer) error {
+				return reportTemplates.ExecuteTemplate(w, "site-tag", page)
+			})
+			writeJSON(filepath.Join("data", rt.Tag+".json"), rt)
+		}
+		writeJSON(filepath.Join("data", "tags.json"), index)
+		fmt.Println("Wrote site to", *outFlag)
+	})
+}
+
+// sitePage is what the page of a single tag in the site is executed with.
+type sitePage struct {
+	Report reportData
+	Tag    reportTag
+	// Tags holds just Tag, for the chart script
+	Tags []reportTag
+}
+
+// reportTemplates holds the templates of the report and of the static site
+// pages; charts are drawn by inline JS from the data embedded in each page, so
+// the pages have no external dependencies.
+var reportTemplates = template.Must(template.New("").Parse(reportHTML))
+
+const reportHTML = `
+{{define "style"}}<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; color: #222; }
+table { border-collapse: collapse; margin: 1em 0; }
+td, th { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: right; }
+th { background: #eee; }
+.params td { text-align: left; }
+.chart { position: relative; }
+.tooltip { position: absolute; background: #fff; border: 1px solid #888;
+           padding: 0.3em; font-size: 0.8em; pointer-events: none; display: none; }
+.legend span { margin-right: 1.5em; }
+</style>{{end}}
+
+{{define "params"}}
+<h2>Parameters</h2>
+<p>Generated {{.Generated}}</p>
+<table class="params">
+{{range .Params}}<tr><td>-{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{define "table"}}
+<table>
+<tr><th>date</th><th>questions</th><th>negative</th><th>closed</th><th>closed &amp; negative</th></tr>
+{{range .Rows}}<tr><td>{{.Date}}</td><td>{{.Total}}</td><td>{{printf "%.3f" .Negative}}</td><td>{{printf "%.3f" .Closed}}</td><td>{{printf "%.3f" .ClosedAndNegative}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{define "forecast"}}
+<h3>Forecast</h3>
+<p>Forecast values with their 95% prediction intervals.</p>
+<table>
+<tr><th>date</th><th>questions</th><th>negative</th><th>closed</th><th>closed &amp; negative</th></tr>
+{{range .}}<tr><td>{{.Date}}</td><td>{{printf "%.0f" .Total.Value}} ({{printf "%.0f" .Total.Lower}}&ndash;{{printf "%.0f" .Total.Upper}})</td><td>{{printf "%.3f" .Negative.Value}} ({{printf "%.3f" .Negative.Lower}}&ndash;{{printf "%.3f" .Negative.Upper}})</td><td>{{printf "%.3f" .Closed.Value}} ({{printf "%.3f" .Closed.Lower}}&ndash;{{printf "%.3f" .Closed.Upper}})</td><td>{{printf "%.3f" .ClosedAndNegative.Value}} ({{printf "%.3f" .ClosedAndNegative.Lower}}&ndash;{{printf "%.3f" .ClosedAndNegative.Upper}})</td></tr>
+{{end}}</table>
+{{end}}
+
+{{define "events"}}
+<h3>Events</h3>
+<table>
+<tr><th>event</th><th>date</th><th>questions before</th><th>after</th><th>negative before</th><th>after</th><th>closed before</th><th>after</th></tr>
+{{range .}}<tr><td>{{.Name}}</td><td>{{.Date}}</td>{{if .Before}}<td>{{.Before.Total}}</td><td>{{.After.Total}}</td><td>{{printf "%.3f" .Before.Negative}}</td><td>{{printf "%.3f" .After.Negative}}</td><td>{{printf "%.3f" .Before.Closed}}</td><td>{{printf "%.3f" .After.Closed}}</td>{{else}}<td colspan="6"></td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+
+{{define "tag"}}
+<h2 id="tag-{{.Tag}}">{{.Tag}}</h2>
+<div class="chart" id="chart-{{.Tag}}"></div>
+{{template "table" .}}
+{{with .Forecast}}{{template "forecast" .}}{{end}}
+{{with .Events}}{{template "events" .}}{{end}}
+{{end}}
+
+{{define "email"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+{{template "style"}}
+</head>
+<body>
+<h1>StackOverflow tag sentiment report</h1>
+{{range .Tags}}<h2>{{.Tag}}</h2>
+{{template "table" .}}
+{{end}}
+{{template "params" .}}
+</body>
+</html>
+{{end}}
+
+{{define "script"}}<script>
+const tags = {{.}};
+const metrics = [
+  {key: "negative", label: "negative", color: "#d62728"},
+  {key: "closed", label: "closed", color: "#1f77b4"},
+  {key: "closedAndNegative", label: "closed & negative", color: "#9467bd"},
+];
+
+function drawChart(el, rows, events) {
+  const W = 900, H = 300, pad = 40;
+  const ns = "http://www.w3.org/2000/svg";
+  const svg = document.createElementNS(ns, "svg");
+  svg.setAttribute("width", W);
+  svg.setAttribute("height", H);
+  let ymax = 0;
+  for (const r of rows) {
+    for (const m of metrics) ymax = Math.max(ymax, r[m.key] || 0);
+  }
+  ymax = ymax > 0 ? ymax : 1;
+  const x = i => pad + (rows.length > 1 ? i * (W - 2 * pad) / (rows.length - 1) : (W - 2 * pad) / 2);
+  const y = v => H - pad - (v || 0) / ymax * (H - 2 * pad);
+
+  const line = (x1, y1, x2, y2) => {
+    const l = document.createElementNS(ns, "line");
+    l.setAttribute("x1", x1); l.setAttribute("y1", y1);
+    l.setAttribute("x2", x2); l.setAttribute("y2", y2);
+    l.setAttribute("stroke", "#999");
+    svg.appendChild(l);
+  };
+  const text = (tx, ty, s, anchor) => {
+    const t = document.createElementNS(ns, "text");
+    t.setAttribute("x", tx); t.setAttribute("y", ty);
+    t.setAttribute("font-size", "11");
+    t.setAttribute("text-anchor", anchor);
+    t.textContent = s;
+    svg.appendChild(t);
+  };
+  line(pad, H - pad, W - pad, H - pad);
+  line(pad, pad, pad, H - pad);
+  text(pad - 4, y(0) + 4, "0", "end");
+  text(pad - 4, y(ymax) + 4, ymax.toFixed(2), "end");
+  if (rows.length > 0) {
+    text(x(0), H - pad + 16, rows[0].date, "middle");
+    text(x(rows.length - 1), H - pad + 16, rows[rows.length - 1].date, "middle");
+  }
+
+  // events are placed between the rows dated around them
+  const times = rows.map(r => Date.parse(r.date));
+  for (const ev of events) {
+    const t = Date.parse(ev.date);
+    const i = times.findIndex(rt => rt >= t);
+    if (i < 0) continue;
+    const ex = i == 0 ? x(0) : x(i - 1 + (t - times[i - 1]) / (times[i] - times[i - 1]));
+    line(ex, pad, ex, H - pad);
+    svg.lastChild.setAttribute("stroke-dasharray", "4 2");
+    text(ex, pad - 6, ev.name, "middle");
+  }
+
+  for (const m of metrics) {
+    const pl = document.createElementNS(ns, "polyline");
+    pl.setAttribute("points", rows.map((r, i) => x(i) + "," + y(r[m.key])).join(" "));
+    pl.setAttribute("fill", "none");
+    pl.setAttribute("stroke", m.color);
+    pl.setAttribute("stroke-width", "2");
+    svg.appendChild(pl);
+  }
+
+  const tip = document.createElement("div");
+  tip.className = "tooltip";
+  svg.addEventListener("mousemove", ev => {
+    const rect = svg.getBoundingClientRect();
+    const px = ev.clientX - rect.left;
+    let i = rows.length > 1 ? Math.round((px - pad) / ((W - 2 * pad) / (rows.length - 1))) : 0;
+    i = Math.max(0, Math.min(rows.length - 1, i));
+    const r = rows[i];
+    if (!r) return;
+    tip.innerHTML = "";
+    const lines = [r.date, "questions: " + r.total].concat(
+      metrics.map(m => m.label + ": " + (r[m.key] || 0).toFixed(3)));
+    for (const s of lines) {
+      const d = document.createElement("div");
+      d.textContent = s;
+      tip.appendChild(d);
+    }
+    tip.style.left = (x(i) + 10) + "px";
+    tip.style.top = "10px";
+    tip.style.display = "block";
+  });
+  svg.addEventListener("mouseleave", () => { tip.style.display = "none"; });
+
+  const legend = document.createElement("div");
+  legend.className = "legend";
+  for (const m of metrics) {
+    const s = document.createElement("span");
+    s.style.color = m.color;
+    s.textContent = "\u25a0 " + m.label;
+    legend.appendChild(s);
+  }
+  el.appendChild(svg);
+  el.appendChild(tip);
+  el.appendChild(legend);
+}
+
+tags.forEach(t => drawChart(document.getElementById("chart-" + t.tag), t.rows || [], t.events || []));
+</script>{{end}}
+
+{{define "report"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>StackOverflow tag sentiment report</title>
+{{template "style"}}
+</head>
+<body>
+<h1>StackOverflow tag sentiment report</h1>
+{{template "params" .}}
+{{range .Tags}}{{template "tag" .}}{{end}}
+{{template "script" .Tags}}
+</body>
+</html>
+{{end}}
+
+{{define "site-index"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>StackOverflow tag sentiment</title>
+{{template "style"}}
+</head>
+<body>
+<h1>StackOverflow tag sentiment</h1>
+<table>
+<tr><th>tag</th><th>date</th><th>questions</th><th>negative</th><th>closed</th><th>closed &amp; negative</th></tr>
+{{range .Tags}}{{$tag := .Tag}}{{with .Last}}<tr><td><a href="tags/{{$tag}}.html">{{$tag}}</a></td><td>{{.Date}}</td><td>{{.Total}}</td><td>{{printf "%.3f" .Negative}}</td><td>{{printf "%.3f" .Closed}}</td><td>{{printf "%.3f" .ClosedAndNegative}}</td></tr>
+{{end}}{{end}}</table>
+<p>Raw data: <a href="data/tags.json">data/tags.json</a></p>
+{{template "params" .}}
+</body>
+</html>
+{{end}}
+
+{{define "site-tag"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Tag.Tag}} - StackOverflow tag sentiment</title>
+{{template "style"}}
+</head>
+<body>
+<p><a href="../index.html">&larr; all tags</a></p>
+{{template "tag" .Tag}}
+<p>Raw data: <a href="../data/{{.Tag.Tag}}.json">{{.Tag.Tag}}.json</a></p>
+{{template "params" .Report}}
+{{template "script" .Tags}}
+</body>
+</html>
+{{end}}
+`
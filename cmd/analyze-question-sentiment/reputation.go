@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// reputationRow is a bucket of the reputation of accepted answerers as
+// presented in -format json.
+type reputationRow struct {
+	Date         string  `json:"date"`
+	Accepted     int     `json:"accepted"`
+	Answerers    int     `json:"answerers"`
+	P25          int     `json:"p25"`
+	Median       int     `json:"median"`
+	P75          int     `json:"p75"`
+	P90          int     `json:"p90"`
+	VeteranShare float64 `json:"veteranShare"`
+}
+
+type reputationTag struct {
+	Tag  string          `json:"tag"`
+	Rows []reputationRow `json:"rows"`
+}
+
+// runReputation implements the reputation command, which reports the
+// distribution of the reputation of the users whose answers to the questions
+// of each tag were accepted in each bucket, and the share of the accepted
+// answers given by veterans; see soanalysis.Analyzer.AcceptedReputation. It
+// requires the answers to be fetched with fetch-all-questions -answers.
+func runReputation(args []string) {
+	fs := newFlagSet("reputation")
+	var af analysisFlags
+	af.register(fs)
+	veteranFlag := fs.Int("veteran", 10000, "minimal reputation of veteran answerers")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("reputation requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.ReputationSeries
+	for _, tag := range tags {
+		rs, err := an.AcceptedReputation(ctx, tag, fromDate, toDate, af.bymonth, *veteranFlag)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, rs)
+	}
+
+	if *formatFlag == "json" {
+		err = writeReputationJSON(os.Stdout, results)
+	} else {
+		err = writeReputationCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeReputationCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per bucket.
+func writeReputationCSV(w io.Writer, results []soanalysis.ReputationSeries) error {
+	for _, rs := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", rs.Tag); err != nil {
+			return err
+		}
+		for _, b := range rs.Buckets {
+			r := b.Reputation
+			_, err := fmt.Fprintf(w, "%s,%d,%d,%d,%d,%d,%d,%.3f\n", b.Date.Format("2006-01-02"), r.Accepted, r.Answerers,
+				r.P25, r.Median, r.P75, r.P90, r.VeteranShare)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeReputationJSON writes the results as a JSON array with an object per
+// tag.
+func writeReputationJSON(w io.Writer, results []soanalysis.ReputationSeries) error {
+	rts := []reputationTag{}
+	for _, rs := range results {
+		rt := reputationTag{Tag: rs.Tag, Rows: []reputationRow{}}
+		for _, b := range rs.Buckets {
+			r := b.Reputation
+			rt.Rows = append(rt.Rows, reputationRow{
+				Date:         b.Date.Format("2006-01-02"),
+				Accepted:     r.Accepted,
+				Answerers:    r.Answerers,
+				P25:          r.P25,
+				Median:       r.Median,
+				P75:          r.P75,
+				P90:          r.P90,
+				VeteranShare: r.VeteranShare,
+			})
+		}
+		rts = append(rts, rt)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rts)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// headerFlags is a flag.Value collecting the "Name: value" HTTP headers given
+// by a repeated flag.
+type headerFlags http.Header
+
+func (h headerFlags) String() string {
+	var hs []string
+	for name, values := range h {
+		for _, v := range values {
+			hs = append(hs, name+": "+v)
+		}
+	}
+	return strings.Join(hs, ", ")
+}
+
+func (h headerFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return errors.New(`want "Name: value"`)
+	}
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	return nil
+}
+
+// runScore implements the score command, which scores the sentiment of the
+// questions of the data directory with an external service (see
+// soanalysis.SentimentHook), caching the scores in a CSV file for -sentiments.
+// Questions already in the file aren't scored again, so rerunning it after
+// fetching more questions, or after an interruption, only scores the new ones.
+func runScore(args []string) {
+	fs := newFlagSet("score")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	fs.StringVar(&af.fromDate, "fromdate", "", "start date in 2006-01-02 format")
+	fs.StringVar(&af.toDate, "todate", "", "end date in 2006-01-02 format")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) separated by commas; all tags by default")
+	fs.StringVar(&af.timezone, "timezone", "UTC", "time zone of the dates, e.g. America/New_York")
+	fs.DurationVar(&af.timeout, "timeout", 0, "maximal duration of the scoring, e.g. 30m; 0 for no limit")
+	endpointFlag := fs.String("endpoint", "", "URL of the sentiment service to post the questions to")
+	header := headerFlags{}
+	fs.Var(header, "header", `header of the requests to the service, e.g. "Authorization: Bearer $TOKEN"; may be repeated`)
+	outFlag := fs.String("out", "", "CSV file caching the scores, to pass to -sentiments (default sentiments.csv in -dir)")
+	parseFlags(fs, args)
+
+	if *endpointFlag == "" {
+		log.Fatal("-endpoint must be provided")
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("score requires a data directory, not -dir -")
+	}
+	if *outFlag == "" {
+		*outFlag = filepath.Join(af.dir, "sentiments.csv")
+	}
+
+	cache, err := soanalysis.OpenSentimentCache(*outFlag)
+	failonf(err, "opening %q", *outFlag)
+	defer cache.Close()
+
+	ctx, cancel := af.context()
+	defer cancel()
+	items := af.unscored(ctx, cache)
+	hook := &soanalysis.SentimentHook{URL: *endpointFlag, Header: http.Header(header)}
+	log.Printf("Scoring %d questions (%d already in %s)", len(items), len(cache.Scores), *outFlag)
+	for i := range items {
+		score, err := hook.Score(ctx, &items[i])
+		failonf(err, "scoring question %d (%d of %d scored)", items[i].QuestionID, i, len(items))
+		err = cache.Add(items[i].QuestionID, score)
+		failonf(err, "writing %q", *outFlag)
+	}
+	fmt.Printf("Scored %d questions into %s\n", len(items), *outFlag)
+}
+
+// unscored returns the questions selected by the flags (once, if they have
+// several of the tags) that have no score in cache.
+func (af *analysisFlags) unscored(ctx context.Context, cache *soanalysis.SentimentCache) []soapi.Item {
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	// Not af.analyzer(), which reads the sentiment scores
+	an := soanalysis.NewAnalyzer(af.dir)
+	an.Dataset.Parallelism = af.parallelism
+	an.Filter = af.filter()
+	var items []soapi.Item
+	seen := make(map[int]bool)
+	for _, tag := range tags {
+		err := an.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+			if _, ok := cache.Scores[item.QuestionID]; !ok && !seen[item.QuestionID] {
+				seen[item.QuestionID] = true
+				items = append(items, *item)
+			}
+		})
+		failonf(err, "reading tag %q", tag)
+	}
+	return items
+}
+
+// modelSentiments returns the scores of the questions selected by the flags by
+// the sentiment model given by -sentimentmodel, along with all the other scores
+// of the model cached in the data directory. Only the questions without a
+// cached score are run through the model, so the model is only loaded if there
+// are new questions.
+func (af *analysisFlags) modelSentiments() soanalysis.Sentiments {
+	if cli.IsStream(af.dir) {
+		log.Fatal("-sentimentmodel requires a data directory, not -dir -")
+	}
+	path := filepath.Join(af.dir, "sentiments-"+filepath.Base(filepath.Clean(af.sentimentModel))+".csv")
+	cache, err := soanalysis.OpenSentimentCache(path)
+	failonf(err, "opening %q", path)
+	defer cache.Close()
+
+	ctx, cancel := af.context()
+	defer cancel()
+	items := af.unscored(ctx, cache)
+	if len(items) == 0 {
+		return cache.Scores
+	}
+
+	model, err := soanalysis.LoadSentimentModel(af.sentimentModel)
+	failonf(err, "loading sentiment model %q", af.sentimentModel)
+	defer model.Close()
+	log.Printf("Scoring %d questions with %s", len(items), af.sentimentModel)
+	for start := 0; start < len(items); start += model.BatchSize {
+		batch := items[start:min(start+model.BatchSize, len(items))]
+		texts := make([]string, len(batch))
+		for i := range batch {
+			texts[i] = soanalysis.ItemText(&batch[i])
+		}
+		scores, err := model.Score(ctx, texts)
+		failonf(err, "scoring questions with %q", af.sentimentModel)
+		for i, score := range scores {
+			err := cache.Add(batch[i].QuestionID, score)
+			failonf(err, "writing %q", path)
+		}
+	}
+	return cache.Scores
+}
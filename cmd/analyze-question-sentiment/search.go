@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// searchRow is a question found by the search command as presented in -format
+// json.
+type searchRow struct {
+	ID     int      `json:"id"`
+	Date   string   `json:"date"`
+	Score  int      `json:"score"`
+	Closed bool     `json:"closed"`
+	Tags   []string `json:"tags"`
+	Title  string   `json:"title"`
+	Link   string   `json:"link"`
+}
+
+// searchQuery holds the filters of the search command.
+type searchQuery struct {
+	title    string
+	regex    *regexp.Regexp
+	minScore *int
+	maxScore *int
+	withTags []string
+}
+
+// match reports whether item passes all the filters of q.
+func (q *searchQuery) match(item *soapi.Item) bool {
+	switch {
+	case q.title != "" && !strings.Contains(strings.ToLower(item.Title), strings.ToLower(q.title)),
+		q.regex != nil && !q.regex.MatchString(item.Title),
+		q.minScore != nil && item.Score < *q.minScore,
+		q.maxScore != nil && item.Score > *q.maxScore:
+		return false
+	}
+	for _, tag := range q.withTags {
+		if !slices.Contains(item.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// optionalInt is a flag.Value for an int flag that may be left unset.
+type optionalInt struct {
+	p **int
+}
+
+func (o optionalInt) String() string {
+	if o.p == nil || *o.p == nil {
+		return ""
+	}
+	return strconv.Itoa(**o.p)
+}
+
+func (o optionalInt) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*o.p = &n
+	return nil
+}
+
+// runSearch implements the search command, which lists the questions of the
+// data directory matching filters on their titles, scores, dates and tags,
+// with their links, or matching a full-text query with -keywords. A question
+// stored under several tags is listed once.
+func runSearch(args []string) {
+	fs := newFlagSet("search")
+	var af analysisFlags
+	var q searchQuery
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	fs.StringVar(&af.fromDate, "fromdate", "", "start date in 2006-01-02 format")
+	fs.StringVar(&af.toDate, "todate", "", "end date in 2006-01-02 format")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) to search, separated by commas; all tags by default")
+	fs.StringVar(&af.timezone, "timezone", "UTC", "time zone of the dates, e.g. America/New_York")
+	fs.StringVar(&q.title, "title", "", "only list questions whose titles contain this text, ignoring case")
+	fs.StringVar(&af.keywords, "keywords", "", "only list questions matching this full-text query on their titles and bodies; see the index command")
+	regexFlag := fs.String("regex", "", "only list questions whose titles match this regular expression")
+	fs.Var(optionalInt{&q.minScore}, "minscore", "only list questions with at least this score")
+	fs.Var(optionalInt{&q.maxScore}, "maxscore", "only list questions with at most this score")
+	withTagsFlag := fs.String("withtags", "", "only list questions that have all these tags, separated by commas")
+	limitFlag := fs.Int("limit", 0, "list at most this many questions, the latest ones (0 for all)")
+	formatFlag := fs.String("format", "text", "output format: text, csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "text" && *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("search requires a data directory, not -dir -")
+	}
+	if *regexFlag != "" {
+		var err error
+		q.regex, err = regexp.Compile(*regexFlag)
+		failonf(err, "parsing -regex")
+	}
+	if *withTagsFlag != "" {
+		q.withTags = strings.Split(*withTagsFlag, ",")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	seen := make(map[int]bool)
+	var items []soapi.Item
+	for _, tag := range tags {
+		err := an.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+			if !seen[item.QuestionID] && q.match(item) {
+				seen[item.QuestionID] = true
+				items = append(items, *item)
+			}
+		})
+		failonf(err, "searching tag %q", tag)
+	}
+
+	slices.SortStableFunc(items, func(a, b soapi.Item) int {
+		return a.CreationDate - b.CreationDate
+	})
+	if *limitFlag > 0 && len(items) > *limitFlag {
+		items = items[len(items)-*limitFlag:]
+	}
+
+	switch *formatFlag {
+	case "json":
+		err = writeSearchJSON(os.Stdout, items)
+	case "csv":
+		err = writeSearchCSV(os.Stdout, items)
+	default:
+		err = writeSearchText(os.Stdout, items)
+	}
+	failonf(err, "writing results")
+}
+
+// itemDate returns the creation date of item in 2006-01-02 format, in UTC.
+func itemDate(item *soapi.Item) string {
+	return time.Unix(int64(item.CreationDate), 0).UTC().Format("2006-01-02")
+}
+
+// writeSearchText writes a line per question with its score, date, title and
+// link.
+func writeSearchText(w io.Writer, items []soapi.Item) error {
+	for i := range items {
+		item := &items[i]
+		if _, err := fmt.Fprintf(w, "%4d  %s  %s  %s\n", item.Score, itemDate(item), item.Title, item.Link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSearchCSV writes a CSV table with a header and a row per question.
+func writeSearchCSV(w io.Writer, items []soapi.Item) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "date", "score", "closed", "tags", "title", "link"})
+	for i := range items {
+		item := &items[i]
+		cw.Write([]string{
+			strconv.Itoa(item.QuestionID),
+			itemDate(item),
+			strconv.Itoa(item.Score),
+			strconv.FormatBool(item.ClosedDate > 0),
+			strings.Join(item.Tags, ";"),
+			item.Title,
+			item.Link,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeSearchJSON writes the questions as a JSON array.
+func writeSearchJSON(w io.Writer, items []soapi.Item) error {
+	rows := []searchRow{}
+	for i := range items {
+		item := &items[i]
+		rows = append(rows, searchRow{
+			ID:     item.QuestionID,
+			Date:   itemDate(item),
+			Score:  item.Score,
+			Closed: item.ClosedDate > 0,
+			Tags:   item.Tags,
+			Title:  item.Title,
+			Link:   item.Link,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
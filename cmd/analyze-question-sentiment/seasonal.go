@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// seasonalRow is a bucket of the decomposition of a metric as presented in
+// -format json.
+type seasonalRow struct {
+	Date     string  `json:"date"`
+	Value    float64 `json:"value"`
+	Trend    float64 `json:"trend"`
+	Seasonal float64 `json:"seasonal"`
+	Residual float64 `json:"residual"`
+}
+
+type seasonalTag struct {
+	Tag    string        `json:"tag"`
+	Metric string        `json:"metric"`
+	Rows   []seasonalRow `json:"rows"`
+}
+
+// seasonalSeries is the decomposition of a metric of the series of a tag.
+type seasonalSeries struct {
+	series soanalysis.Series
+	values []float64
+	d      soanalysis.Decomposition
+}
+
+// runSeasonal implements the seasonal command, which decomposes a metric of the
+// monthly series of each tag into its trend, seasonal and residual components,
+// so seasonality (e.g. of the academic calendar) isn't misread as a trend; see
+// soanalysis.Decompose.
+func runSeasonal(args []string) {
+	fs := newFlagSet("seasonal")
+	var af analysisFlags
+	af.register(fs)
+	metrics := slices.Sorted(maps.Keys(grafanaMetrics))
+	metricFlag := fs.String("metric", "negative", "metric to decompose: "+strings.Join(metrics, ", "))
+	periodFlag := fs.Int("period", 12, "number of months in a season")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	plotFlag := fs.String("plot", "", "directory to write a chart of the components per tag into")
+	plotFormatFlag := fs.String("plotformat", "png", "chart image format: png or svg")
+	parseFlags(fs, args)
+
+	metric, ok := grafanaMetrics[*metricFlag]
+	if !ok {
+		log.Fatalf("unknown -metric %q", *metricFlag)
+	}
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if !af.bymonth {
+		log.Fatal("seasonal requires -bymonth")
+	}
+	if *plotFlag != "" {
+		if *plotFormatFlag != "png" && *plotFormatFlag != "svg" {
+			log.Fatalf("unknown -plotformat %q", *plotFormatFlag)
+		}
+		// Try to create the directory; ignore error (if it already exists, etc.)
+		_ = os.Mkdir(*plotFlag, 0777)
+	}
+
+	var results []seasonalSeries
+	for _, ts := range af.run() {
+		values := make([]float64, len(ts.Buckets))
+		for i, b := range ts.Buckets {
+			values[i] = metric(b.Result)
+		}
+		d, err := soanalysis.Decompose(values, *periodFlag)
+		if errors.Is(err, soanalysis.ErrSeasonalLength) {
+			log.Fatalf("%v: -fromdate and -todate must span at least %d months", err, *periodFlag*2)
+		}
+		failonf(err, "decomposing tag %q", ts.Tag)
+		results = append(results, seasonalSeries{series: ts, values: values, d: d})
+	}
+
+	var err error
+	if *formatFlag == "json" {
+		err = writeSeasonalJSON(os.Stdout, *metricFlag, results)
+	} else {
+		err = writeSeasonalCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+
+	if *plotFlag != "" {
+		for _, ss := range results {
+			filename := filepath.Join(*plotFlag, ss.series.Tag+"."+*plotFormatFlag)
+			err := plotDecomposition(filename, ss.series, *metricFlag, ss.values, ss.d)
+			failonf(err, "plotting tag %q", ss.series.Tag)
+			log.Println("Wrote", filename)
+		}
+	}
+}
+
+// writeSeasonalCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per bucket: the value of the metric and
+// its trend, seasonal and residual components.
+func writeSeasonalCSV(w io.Writer, results []seasonalSeries) error {
+	for _, ss := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ss.series.Tag); err != nil {
+			return err
+		}
+		for i, b := range ss.series.Buckets {
+			_, err := fmt.Fprintf(w, "%s,%.3f,%.3f,%.3f,%.3f\n", b.Date.Format("2006-01-02"), ss.values[i], ss.d.Trend[i],
+				ss.d.Seasonal[i], ss.d.Residual[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeSeasonalJSON writes the results as a JSON array with an object per tag.
+func writeSeasonalJSON(w io.Writer, metric string, results []seasonalSeries) error {
+	sts := []seasonalTag{}
+	for _, ss := range results {
+		st := seasonalTag{Tag: ss.series.Tag, Metric: metric, Rows: []seasonalRow{}}
+		for i, b := range ss.series.Buckets {
+			st.Rows = append(st.Rows, seasonalRow{
+				Date:     b.Date.Format("2006-01-02"),
+				Value:    ss.values[i],
+				Trend:    ss.d.Trend[i],
+				Seasonal: ss.d.Seasonal[i],
+				Residual: ss.d.Residual[i],
+			})
+		}
+		sts = append(sts, st)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sts)
+}
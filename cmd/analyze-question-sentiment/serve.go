@@ -0,0 +1,440 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// runServe implements the serve command, which exposes the analysis of the data
+// in -dir as an HTTP JSON API:
+//
+//	GET /tags                                      list of tags
+//	GET /tags/{tag}/metrics?from=&to=&by=month     metrics of a tag
+//	GET /tags/{tag}/feed                           Atom feed of a tag
+//	GET /badge/{tag}/negativity                    shields.io badge of a tag
+//	POST /graphql                                  GraphQL queries; see graphqlSchema
+//	GET /cache                                     statistics of the cache
+//
+// from and to are dates in 2006-01-02 format; by=month requires both.
+//
+// In addition, /grafana/ implements the Grafana simple JSON datasource
+// protocol, with targets named <tag>.<metric>, e.g. go.negative.
+//
+// See cacheFlags for how the questions are kept in memory between requests.
+func runServe(args []string) {
+	fs := newFlagSet("serve")
+	dirFlag := fs.String("dir", "", "base directory with results")
+	addrFlag := fs.String("addr", "localhost:8080", "address to listen on")
+	feedMonthsFlag := fs.Int("feedmonths", 12, "number of months in feeds")
+	badgeDaysFlag := fs.Int("badgedays", 90, "trailing window of badges, in days")
+	timeoutFlag := fs.Duration("timeout", 30*time.Second, "maximal duration of the analysis of a request; 0 for no limit")
+	sentimentsFlag := fs.String("sentiments", "", "CSV or JSON file mapping question IDs to sentiment scores from an external model, to serve the means of")
+	var cf cacheFlags
+	cf.register(fs)
+	parseFlags(fs, args)
+
+	if len(*dirFlag) == 0 {
+		log.Fatal("-dir must be provided and cannot be empty. Please use the folder where the data was fetched.")
+	}
+
+	s := &server{analyzer: soanalysis.NewAnalyzer(*dirFlag), feedMonths: *feedMonthsFlag, badgeDays: *badgeDaysFlag, timeout: *timeoutFlag}
+	s.analyzer.Sentiments = loadSentimentScores(*sentimentsFlag)
+	cf.setUp(s.analyzer.Dataset)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tags", s.handleTags)
+	mux.HandleFunc("GET /tags/{tag}/metrics", s.handleMetrics)
+	mux.HandleFunc("GET /tags/{tag}/feed", s.handleFeed)
+	mux.HandleFunc("GET /badge/{tag}/negativity", s.handleBadge)
+	mux.Handle("POST /graphql", &relay.Handler{Schema: graphql.MustParseSchema(graphqlSchema, &gqlResolver{s: s})})
+	mux.HandleFunc("GET /grafana/{$}", s.handleGrafanaTest)
+	mux.HandleFunc("POST /grafana/search", s.handleGrafanaSearch)
+	mux.HandleFunc("POST /grafana/query", s.handleGrafanaQuery)
+	mux.HandleFunc("GET /cache", s.handleCache)
+
+	log.Println("Serving on", *addrFlag)
+	// Traces each request, continuing the traces of clients, along with the
+	// analysis it runs (with -otel-endpoint).
+	log.Fatal(http.ListenAndServe(*addrFlag, otelhttp.NewHandler(mux, "serve")))
+}
+
+// server serves the analysis of the data in dir over HTTP.
+type server struct {
+	analyzer *soanalysis.Analyzer
+
+	// number of months in feeds
+	feedMonths int
+
+	// trailing window of badges, in days
+	badgeDays int
+
+	// maximal duration of the analysis of a request, if positive
+	timeout time.Duration
+}
+
+// cacheFlags are the flags of the servers controlling the cache of the
+// questions: with -cache, the questions of all the tags are loaded into memory
+// when the server starts, rather than read from their files for each request,
+// and reloaded in the background when the files of a tag change (checking every
+// -cacherefresh). -cachemb bounds the memory the questions take, evicting the
+// least recently used tags beyond it, which are read from their files again
+// when queried.
+type cacheFlags struct {
+	enabled  bool
+	maxMB    int64
+	interval time.Duration
+}
+
+func (cf *cacheFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&cf.enabled, "cache", true, "keep the questions in memory between requests")
+	fs.Int64Var(&cf.maxMB, "cachemb", 0, "with -cache, maximal memory taken by the cached questions, in MB; 0 for no limit")
+	fs.DurationVar(&cf.interval, "cacherefresh", time.Minute, "with -cache, how often to check for changed files to reload; 0 not to reload")
+}
+
+// setUp sets up the cache of ds as selected by the flags, loading the
+// questions of its tags in the background.
+func (cf *cacheFlags) setUp(ds *soanalysis.Dataset) {
+	if !cf.enabled {
+		return
+	}
+	ds.Cache = &soanalysis.Cache{MaxBytes: cf.maxMB << 20}
+	go func() {
+		start := time.Now()
+		tags, err := ds.Tags()
+		if err != nil {
+			log.Println("loading the cache:", err)
+			return
+		}
+		for _, tag := range tags {
+			err := ds.ForEachItem(context.Background(), tag, time.Time{}, time.Time{}, func(item *soapi.Item) {})
+			if err != nil {
+				log.Printf("loading tag %q into the cache: %v", tag, err)
+			}
+		}
+		stats := ds.Cache.Stats()
+		log.Printf("Cached %d tags (%s) in %v", stats.Tags, soanalysis.FormatBytes(stats.Bytes), time.Since(start).Round(time.Millisecond))
+		if cf.interval > 0 {
+			ds.Cache.RefreshEvery(context.Background(), ds, cf.interval)
+		}
+	}()
+}
+
+// context returns the context of the analysis of a request with context
+// parent, cancelled after the timeout of the server.
+func (s *server) context(parent context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, s.timeout)
+}
+
+// handleCache serves the statistics of the cache of the questions, or 404 if
+// the server runs without one.
+func (s *server) handleCache(w http.ResponseWriter, r *http.Request) {
+	if s.analyzer.Dataset.Cache == nil {
+		http.Error(w, "running without -cache", http.StatusNotFound)
+		return
+	}
+	writeJSONResponse(w, s.analyzer.Dataset.Cache.Stats())
+}
+
+func (s *server) handleTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := s.analyzer.Dataset.Tags()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSONResponse(w, tags)
+}
+
+func (s *server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var byMonth bool
+	switch r.FormValue("by") {
+	case "":
+	case "month":
+		byMonth = true
+	default:
+		http.Error(w, fmt.Sprintf("unknown by=%q", r.FormValue("by")), http.StatusBadRequest)
+		return
+	}
+
+	results, err := s.query(r.Context(), []string{r.PathValue("tag")}, r.FormValue("from"), r.FormValue("to"), byMonth)
+	if err != nil {
+		http.Error(w, err.Error(), queryStatus(err))
+		return
+	}
+	writeJSONResponse(w, newReportTag(results[0]))
+}
+
+var (
+	errUnknownTag = errors.New("unknown tag")
+	errBadDate    = errors.New("bad date")
+)
+
+// queryStatus returns the HTTP status code reporting an error returned by
+// query: client errors are reported as such, analyses cut short by the timeout
+// of the server as the server being unavailable, and anything else (such as
+// failing to read the data) as an internal error.
+func queryStatus(err error) int {
+	switch {
+	case errors.Is(err, errUnknownTag):
+		return http.StatusNotFound
+	case errors.Is(err, errBadDate), errors.Is(err, soanalysis.ErrMonthlyDates):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// query validates the parameters of a query from a client and runs the
+// analysis within the timeout of the server. from and to are dates in
+// 2006-01-02 format and may be empty.
+func (s *server) query(ctx context.Context, tags []string, from string, to string, byMonth bool) ([]soanalysis.Series, error) {
+	ctx, cancel := s.context(ctx)
+	defer cancel()
+
+	for _, tag := range tags {
+		if err := s.checkTag(tag); err != nil {
+			return nil, err
+		}
+	}
+	for _, date := range []string{from, to} {
+		if err := checkDate(date); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []soanalysis.Series
+	for _, tag := range tags {
+		ts, err := s.analyzer.Series(ctx, tag, parseDate(from), parseDate(to), byMonth)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, ts)
+	}
+	return results, nil
+}
+
+// checkTag returns an error wrapping errUnknownTag if tag is not one of the tags
+// in the data directory.
+func (s *server) checkTag(tag string) error {
+	ok, err := s.analyzer.Dataset.HasTag(tag)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w %q", errUnknownTag, tag)
+	}
+	return nil
+}
+
+// checkDate returns an error wrapping errBadDate if date is neither empty nor
+// in 2006-01-02 format.
+func checkDate(date string) error {
+	if _, err := cli.ParseDate(date, time.UTC); err != nil {
+		return fmt.Errorf("%w %q, expecting %s format", errBadDate, date, cli.DateLayout)
+	}
+	return nil
+}
+
+// parseDate parses a date checked with checkDate; an empty date is the zero
+// time.
+func parseDate(date string) time.Time {
+	t, _ := cli.ParseDate(date, time.UTC)
+	return t
+}
+
+// atomFeed and atomEntry are the parts of the Atom format used by handleFeed.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// handleFeed serves an Atom feed for a tag with an entry per completed month,
+// summarizing its metrics and listing its most negative questions.
+func (s *server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	tag := r.PathValue("tag")
+	if err := s.checkTag(tag); err != nil {
+		http.Error(w, err.Error(), queryStatus(err))
+		return
+	}
+
+	now := time.Now().UTC()
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	feed := atomFeed{
+		ID:      "urn:so-tag-sentiment:" + url.PathEscape(tag),
+		Title:   fmt.Sprintf("StackOverflow sentiment for [%s]", tag),
+		Updated: thisMonth.Format(time.RFC3339),
+		Link:    atomLink{Href: r.URL.String(), Rel: "self"},
+	}
+
+	// All the months are analyzed in a single pass over the questions, which
+	// also collects the negative ones to list in the entries.
+	ctx, cancel := s.context(r.Context())
+	defer cancel()
+	start := thisMonth.AddDate(0, -s.feedMonths, 0)
+	ag, err := soanalysis.NewAggregator(tag, start, thisMonth, true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ag.Robust = s.analyzer.Robust
+	ag.Sentiments = s.analyzer.Sentiments
+	var negatives []soapi.Item
+	err = s.analyzer.ForEachItem(ctx, tag, start, thisMonth, func(item *soapi.Item) {
+		ag.Add(item)
+		if item.Score < 0 {
+			negatives = append(negatives, *item)
+		}
+	})
+	if err != nil {
+		http.Error(w, err.Error(), queryStatus(err))
+		return
+	}
+
+	// Newest month first
+	buckets := ag.Series().Buckets
+	for i := len(buckets) - 1; i >= 0; i-- {
+		from, to := buckets[i].Start, buckets[i].Date
+		tr := buckets[i].Result
+		negative := mostNegative(negatives, from, to, 5)
+
+		var body strings.Builder
+		fmt.Fprintf(&body, "<p>%d questions; negative %.3f, closed %.3f, closed &amp; negative %.3f, unanswered %.3f</p>",
+			tr.Total, tr.NegativeRatio(), tr.ClosedRatio(), tr.ClosedAndNegativeRatio(), tr.UnansweredRatio())
+		if len(negative) > 0 {
+			body.WriteString("<p>Most negative questions:</p><ul>")
+			for _, item := range negative {
+				fmt.Fprintf(&body, `<li><a href="%s">%s</a> (%d)</li>`,
+					template.HTMLEscapeString(item.Link), template.HTMLEscapeString(item.Title), item.Score)
+			}
+			body.WriteString("</ul>")
+		}
+
+		month := from.Format("2006-01")
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      feed.ID + ":" + month,
+			Title:   fmt.Sprintf("[%s] %s", tag, month),
+			Updated: to.Format(time.RFC3339),
+			Content: atomContent{Type: "html", Body: body.String()},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml")
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		log.Println(err)
+	}
+}
+
+// mostNegative returns up to n of the lowest scored of items created between
+// from and to (inclusive), like Analyzer.MostNegative.
+func mostNegative(items []soapi.Item, from time.Time, to time.Time, n int) []soapi.Item {
+	var inMonth []soapi.Item
+	for _, item := range items {
+		date := time.Unix(int64(item.CreationDate), 0)
+		if !date.Before(from) && !date.After(to) {
+			inMonth = append(inMonth, item)
+		}
+	}
+	slices.SortStableFunc(inMonth, func(a, b soapi.Item) int {
+		return cmp.Compare(a.Score, b.Score)
+	})
+	return inMonth[:min(n, len(inMonth))]
+}
+
+// handleBadge serves the negative ratio of a tag over the trailing window in the
+// JSON format of shields.io endpoint badges, e.g. for a README:
+//
+//	https://img.shields.io/endpoint?url=<server>/badge/go/negativity
+func (s *server) handleBadge(w http.ResponseWriter, r *http.Request) {
+	tag := r.PathValue("tag")
+	if err := s.checkTag(tag); err != nil {
+		http.Error(w, err.Error(), queryStatus(err))
+		return
+	}
+
+	ctx, cancel := s.context(r.Context())
+	defer cancel()
+	toDate := time.Now()
+	tr, err := s.analyzer.Analyze(ctx, tag, toDate.AddDate(0, 0, -s.badgeDays), toDate)
+	if err != nil {
+		http.Error(w, err.Error(), queryStatus(err))
+		return
+	}
+	negative := tr.NegativeRatio()
+
+	badge := struct {
+		SchemaVersion int    `json:"schemaVersion"`
+		Label         string `json:"label"`
+		Message       string `json:"message"`
+		Color         string `json:"color"`
+	}{
+		SchemaVersion: 1,
+		Label:         fmt.Sprintf("SO negativity [%s]", tag),
+		Message:       fmt.Sprintf("%.1f%%", negative*100),
+	}
+	switch {
+	case tr.Total == 0:
+		badge.Message = "no data"
+		badge.Color = "lightgrey"
+	case negative < 0.2:
+		badge.Color = "green"
+	case negative < 0.35:
+		badge.Color = "yellow"
+	default:
+		badge.Color = "red"
+	}
+	writeJSONResponse(w, badge)
+}
+
+func writeJSONResponse(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// runSheets implements the sheets command, which appends the analysis results
+// to a Google Sheet as rows of tag, date and metrics. Buckets already in the
+// sheet (by tag and date) are skipped, so running it periodically over the
+// same data directory keeps appending only the new buckets.
+//
+// Authentication is done with a service account; the spreadsheet has to be
+// shared with the service account's email.
+func runSheets(args []string) {
+	fs := newFlagSet("sheets")
+	var af analysisFlags
+	af.register(fs)
+	spreadsheetFlag := fs.String("spreadsheet", "", "ID of the spreadsheet to publish to")
+	sheetFlag := fs.String("sheet", "Sheet1", "name of the sheet within the spreadsheet")
+	credentialsFlag := fs.String("credentials", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"), "service account key file")
+	parseFlags(fs, args)
+
+	if *spreadsheetFlag == "" || *credentialsFlag == "" {
+		log.Fatal("-spreadsheet and -credentials must be provided")
+	}
+
+	results := af.run()
+
+	ctx := context.Background()
+	srv, err := sheets.NewService(ctx,
+		option.WithAuthCredentialsFile(option.ServiceAccount, *credentialsFlag),
+		option.WithScopes(sheets.SpreadsheetsScope))
+	failonf(err, "creating Sheets client")
+
+	existing, err := srv.Spreadsheets.Values.Get(*spreadsheetFlag, *sheetFlag+"!A:B").Context(ctx).Do()
+	failonf(err, "reading sheet %q", *sheetFlag)
+
+	// Keys of the buckets already published, as "tag date"
+	published := make(map[string]bool)
+	for _, row := range existing.Values {
+		if len(row) >= 2 {
+			published[fmt.Sprintf("%v %v", row[0], row[1])] = true
+		}
+	}
+
+	var rows [][]interface{}
+	if len(existing.Values) == 0 {
+		rows = append(rows, []interface{}{"tag", "date", "questions", "negative", "closed", "closed & negative", "unanswered"})
+	}
+	for _, ts := range results {
+		for _, b := range ts.Buckets {
+			date := b.Date.Format("2006-01-02")
+			if published[ts.Tag+" "+date] {
+				continue
+			}
+			tr := b.Result
+			rows = append(rows, []interface{}{ts.Tag, date, tr.Total, tr.NegativeRatio(), tr.ClosedRatio(), tr.ClosedAndNegativeRatio(), tr.UnansweredRatio()})
+		}
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("Nothing new to publish")
+		return
+	}
+	_, err = srv.Spreadsheets.Values.Append(*spreadsheetFlag, *sheetFlag+"!A1", &sheets.ValueRange{Values: rows}).
+		ValueInputOption("RAW").InsertDataOption("INSERT_ROWS").Context(ctx).Do()
+	failonf(err, "appending to sheet %q", *sheetFlag)
+	fmt.Printf("Appended %d rows to sheet %q\n", len(rows), *sheetFlag)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// siteResult is the result of a tag on a site in a bucket as presented in
+// -format json.
+type siteResult struct {
+	Site       string  `json:"site"`
+	Questions  int     `json:"questions"`
+	Negative   float64 `json:"negative"`
+	Closed     float64 `json:"closed"`
+	Unanswered float64 `json:"unanswered"`
+	MeanScore  float64 `json:"meanScore"`
+}
+
+type siteRow struct {
+	Tag   string       `json:"tag"`
+	Date  string       `json:"date"`
+	Sites []siteResult `json:"sites"`
+}
+
+// siteData is a data directory fetched from a site, named by the site.
+type siteData struct {
+	name string
+	dir  string
+}
+
+// parseSiteDirs parses the -dirs of the sites command: data directories
+// separated by commas, each optionally named with name=dir. Directories are
+// named by the site they were fetched from by default.
+func parseSiteDirs(s string) ([]siteData, error) {
+	var sites []siteData
+	for _, entry := range cli.SplitList(s) {
+		name, dir, named := strings.Cut(entry, "=")
+		if !named {
+			dir = entry
+		}
+		if cli.IsStream(dir) {
+			return nil, errors.New("sites requires data directories, not -")
+		}
+		if !named {
+			site, err := (&soanalysis.Dataset{Dir: dir}).Site()
+			if err != nil {
+				return nil, err
+			}
+			name = site
+		}
+		if slices.ContainsFunc(sites, func(sd siteData) bool { return sd.name == name }) {
+			return nil, fmt.Errorf("two directories of site %q; name them with name=dir", name)
+		}
+		sites = append(sites, siteData{name: name, dir: dir})
+	}
+	return sites, nil
+}
+
+// runSites implements the sites command, which compares the same tags across
+// Stack Exchange sites (e.g. docker on stackoverflow, serverfault and devops),
+// each fetched into its own data directory with fetch-all-questions -site. The
+// buckets of all the sites span the same periods, so they're reported side by
+// side in a single table.
+func runSites(args []string) {
+	fs := newFlagSet("sites")
+	var af analysisFlags
+	af.register(fs)
+	dirsFlag := fs.String("dirs", "", "data directories of the sites separated by commas, each optionally named with name=dir")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if af.dir != "" {
+		log.Fatal("sites reads the data directories of the sites from -dirs, not -dir")
+	}
+	if af.toDate == "" {
+		log.Fatal("-todate must be provided, for the buckets of the sites to end on the same date")
+	}
+	sites, err := parseSiteDirs(*dirsFlag)
+	failonf(err, "parsing -dirs")
+	if len(sites) < 2 {
+		log.Fatal("provide the data directories of at least two sites with -dirs")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	if af.tags == "" {
+		log.Fatal("provide the tags to compare with -tags")
+	}
+	tags := af.expandTags()
+
+	ctx, cancel := af.context()
+	defer cancel()
+	results := make([][]soanalysis.Series, len(tags))
+	for _, sd := range sites {
+		siteFlags := af
+		siteFlags.dir = sd.dir
+		an := siteFlags.analyzer()
+		for i, tag := range tags {
+			ts, err := an.Series(ctx, tag, fromDate, toDate, af.bymonth)
+			failonf(err, "analyzing tag %q of site %q", tag, sd.name)
+			results[i] = append(results[i], ts)
+		}
+	}
+
+	if *formatFlag == "json" {
+		err = writeSitesJSON(os.Stdout, sites, results)
+	} else {
+		err = writeSitesCSV(os.Stdout, sites, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeSitesCSV writes the results as a CSV table with a header and a row per
+// tag and bucket, with the number of questions on each site and their
+// negative, closed and unanswered ratios and mean score.
+func writeSitesCSV(w io.Writer, sites []siteData, results [][]soanalysis.Series) error {
+	header := []string{"tag", "date"}
+	for _, sd := range sites {
+		for _, col := range []string{"questions", "negative", "closed", "unanswered", "meanScore"} {
+			header = append(header, sd.name+"."+col)
+		}
+	}
+	if _, err := fmt.Fprintln(w, strings.Join(header, ",")); err != nil {
+		return err
+	}
+	for _, tagSeries := range results {
+		for i, b := range tagSeries[0].Buckets {
+			line := tagSeries[0].Tag + "," + b.Date.Format("2006-01-02")
+			for _, ts := range tagSeries {
+				r := ts.Buckets[i].Result
+				line += fmt.Sprintf(",%d,%.3f,%.3f,%.3f,%.2f", r.Total, r.NegativeRatio(), r.ClosedRatio(), r.UnansweredRatio(), r.MeanScore())
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeSitesJSON writes the results as a JSON array with an object per tag and
+// bucket.
+func writeSitesJSON(w io.Writer, sites []siteData, results [][]soanalysis.Series) error {
+	rows := []siteRow{}
+	for _, tagSeries := range results {
+		for i, b := range tagSeries[0].Buckets {
+			row := siteRow{Tag: tagSeries[0].Tag, Date: b.Date.Format("2006-01-02")}
+			for j, ts := range tagSeries {
+				r := ts.Buckets[i].Result
+				row.Sites = append(row.Sites, siteResult{
+					Site:       sites[j].name,
+					Questions:  r.Total,
+					Negative:   r.NegativeRatio(),
+					Closed:     r.ClosedRatio(),
+					Unanswered: r.UnansweredRatio(),
+					MeanScore:  r.MeanScore(),
+				})
+			}
+			rows = append(rows, row)
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
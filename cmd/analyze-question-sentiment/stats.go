@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// statsGap is a gap in the questions of a tag as presented in -format json.
+type statsGap struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// statsTag is the summary of a tag as presented in -format json.
+type statsTag struct {
+	Tag             string     `json:"tag"`
+	Pages           int        `json:"pages"`
+	FetchedPages    int        `json:"fetchedPages"`
+	Questions       int        `json:"questions"`
+	UniqueQuestions int        `json:"uniqueQuestions"`
+	MinDate         string     `json:"minDate,omitempty"`
+	MaxDate         string     `json:"maxDate,omitempty"`
+	Size            int64      `json:"size"`
+	LastFetch       string     `json:"lastFetch,omitempty"`
+	Gaps            []statsGap `json:"gaps"`
+}
+
+// runStats implements the stats command, which summarizes the data of each tag
+// in the data directory as a quick health check before analyzing it; see
+// soanalysis.Dataset.Stats.
+func runStats(args []string) {
+	fs := newFlagSet("stats")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) separated by commas; all tags by default")
+	gapFlag := fs.Int("gap", 30, "report periods of at least this many days without questions as gaps")
+	formatFlag := fs.String("format", "text", "output format: text or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "text" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("stats requires a data directory, not -dir -")
+	}
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	ds := af.analyzer().Dataset
+	var stats []soanalysis.TagStats
+	for _, tag := range tags {
+		st, err := ds.Stats(tag, time.Duration(*gapFlag)*24*time.Hour)
+		failonf(err, "summarizing tag %q", tag)
+		stats = append(stats, st)
+	}
+
+	if *formatFlag == "json" {
+		err = writeStatsJSON(os.Stdout, stats)
+	} else {
+		err = writeStatsText(os.Stdout, stats)
+	}
+	failonf(err, "writing results")
+}
+
+// formatSize formats a size in bytes for humans.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// writeStatsText writes the summary of each tag as a few lines of text.
+func writeStatsText(w io.Writer, stats []soanalysis.TagStats) error {
+	for _, st := range stats {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "\n%s\n", st.Tag)
+		fmt.Fprintf(&sb, "  pages:      %d files holding %d fetched pages\n", st.Pages, st.FetchedPages)
+		fmt.Fprintf(&sb, "  questions:  %d (%d unique)\n", st.Questions, st.UniqueQuestions)
+		if st.UniqueQuestions > 0 {
+			fmt.Fprintf(&sb, "  dates:      %s to %s\n", st.MinDate.Format("2006-01-02"), st.MaxDate.Format("2006-01-02"))
+		}
+		fmt.Fprintf(&sb, "  size:       %s\n", formatSize(st.Size))
+		if !st.LastFetch.IsZero() {
+			fmt.Fprintf(&sb, "  last fetch: %s\n", st.LastFetch.Format("2006-01-02 15:04:05"))
+		}
+		if len(st.Gaps) == 0 {
+			fmt.Fprintf(&sb, "  gaps:       none\n")
+		}
+		for i, g := range st.Gaps {
+			label := ""
+			if i == 0 {
+				label = "gaps:"
+			}
+			fmt.Fprintf(&sb, "  %-11s %s to %s (%.0f days)\n", label, g.From.Format("2006-01-02"), g.To.Format("2006-01-02"), g.To.Sub(g.From).Hours()/24)
+		}
+		if _, err := io.WriteString(w, sb.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeStatsJSON writes the summaries as a JSON array with an object per tag.
+func writeStatsJSON(w io.Writer, stats []soanalysis.TagStats) error {
+	formatDate := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	sts := []statsTag{}
+	for _, st := range stats {
+		s := statsTag{
+			Tag:             st.Tag,
+			Pages:           st.Pages,
+			FetchedPages:    st.FetchedPages,
+			Questions:       st.Questions,
+			UniqueQuestions: st.UniqueQuestions,
+			MinDate:         formatDate(st.MinDate),
+			MaxDate:         formatDate(st.MaxDate),
+			Size:            st.Size,
+			LastFetch:       formatDate(st.LastFetch),
+			Gaps:            []statsGap{},
+		}
+		for _, g := range st.Gaps {
+			s.Gaps = append(s.Gaps, statsGap{From: formatDate(g.From), To: formatDate(g.To)})
+		}
+		sts = append(sts, s)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sts)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// survivalRow is a point of the survival curve of a bucket as presented in
+// -format json.
+type survivalRow struct {
+	Date      string  `json:"date"`
+	Questions int     `json:"questions"`
+	Hours     float64 `json:"hours"`
+	Answered  float64 `json:"answered"`
+}
+
+type survivalTag struct {
+	Tag  string        `json:"tag"`
+	Rows []survivalRow `json:"rows"`
+}
+
+// runSurvival implements the survival command, which estimates the ratio of
+// questions answered within each of -hours of being asked, for each tag and
+// bucket; see soanalysis.Analyzer.Survival. It requires the answers to be
+// fetched with fetch-all-questions -answers.
+func runSurvival(args []string) {
+	fs := newFlagSet("survival")
+	var af analysisFlags
+	af.register(fs)
+	hoursFlag := fs.String("hours", "1,2,4,8,12,24,48,72,168", "numbers of hours to estimate the ratio of answered questions at, separated by commas")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	plotFlag := fs.String("plot", "", "directory to write a chart of the curves per tag into")
+	parseFlags(fs, args)
+
+	var hours []float64
+	for _, h := range strings.Split(*hoursFlag, ",") {
+		v, err := strconv.ParseFloat(h, 64)
+		failonf(err, "parsing -hours")
+		hours = append(hours, v)
+	}
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("survival requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.SurvivalSeries
+	for _, tag := range tags {
+		ts, err := an.Survival(ctx, tag, fromDate, toDate, af.bymonth, hours)
+		failonf(err, "analyzing tag %q", tag)
+		results = append(results, ts)
+	}
+
+	if *formatFlag == "json" {
+		err = writeSurvivalJSON(os.Stdout, results)
+	} else {
+		err = writeSurvivalCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+
+	if *plotFlag != "" {
+		// Try to create the directory; ignore error (if it already exists, etc.)
+		_ = os.Mkdir(*plotFlag, 0777)
+		for _, ts := range results {
+			filename := filepath.Join(*plotFlag, ts.Tag+"-survival.png")
+			err := plotSurvival(filename, ts)
+			failonf(err, "plotting tag %q", ts.Tag)
+			log.Println("Wrote", filename)
+		}
+	}
+}
+
+// writeSurvivalCSV writes the results of each tag as a line with the tag name
+// followed by a CSV table with a row per point of the curve of each bucket.
+func writeSurvivalCSV(w io.Writer, results []soanalysis.SurvivalSeries) error {
+	for _, ts := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", ts.Tag); err != nil {
+			return err
+		}
+		for _, b := range ts.Buckets {
+			for _, p := range b.Points {
+				_, err := fmt.Fprintf(w, "%s,%d,%g,%.3f\n", b.Date.Format("2006-01-02"), b.Questions, p.Hours, p.Answered)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeSurvivalJSON writes the results as a JSON array with an object per tag.
+func writeSurvivalJSON(w io.Writer, results []soanalysis.SurvivalSeries) error {
+	sts := []survivalTag{}
+	for _, ts := range results {
+		st := survivalTag{Tag: ts.Tag}
+		for _, b := range ts.Buckets {
+			for _, p := range b.Points {
+				st.Rows = append(st.Rows, survivalRow{
+					Date:      b.Date.Format("2006-01-02"),
+					Questions: b.Questions,
+					Hours:     p.Hours,
+					Answered:  p.Answered,
+				})
+			}
+		}
+		sts = append(sts, st)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sts)
+}
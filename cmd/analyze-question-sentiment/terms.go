@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// tagTerms are the top terms of the titles of the questions of a tag.
+type tagTerms struct {
+	Tag   string                 `json:"tag"`
+	Terms []soanalysis.TermStats `json:"terms"`
+
+	// Dates are the dates of the buckets of the trends of the terms, with
+	// -bymonth.
+	Dates []string `json:"dates,omitempty"`
+}
+
+// runTerms implements the terms command, which lists the terms characteristic
+// of the titles of the questions of each tag, ranked by TF-IDF against the
+// titles of all the tags given. Stop words of the language (and the words
+// common to all programming questions, like "how" and "error") are left out,
+// and words are counted by their stem. Terms may be phrases of several words
+// (-ngram), and with -bymonth the share of the titles with each term is
+// followed month by month.
+func runTerms(args []string) {
+	fs := newFlagSet("terms")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	fs.StringVar(&af.fromDate, "fromdate", "", "start date in 2006-01-02 format")
+	fs.StringVar(&af.toDate, "todate", "", "end date in 2006-01-02 format")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) separated by commas; all tags by default")
+	fs.StringVar(&af.timezone, "timezone", "UTC", "time zone of the dates, e.g. America/New_York")
+	fs.BoolVar(&af.bymonth, "bymonth", false, "follow the share of the titles with each term by month")
+	ngramFlag := fs.String("ngram", "1", "number of words of the terms, e.g. 2 for phrases of two words, or 2-3 for phrases of two or three")
+	nFlag := fs.Int("n", 20, "number of terms listed per tag")
+	languageFlag := fs.String("language", "en", "language of the titles, whose stop words are dropped and whose stemmer is used ("+strings.Join(soanalysis.TermLanguages(), ", ")+"); empty for none")
+	stemFlag := fs.Bool("stem", true, "count words by their stem, e.g. \"import\" and \"importing\" as one term")
+	questionStopFlag := fs.Bool("questionstopwords", true, "also drop words common to all programming questions, like \"how\", \"using\" and \"error\"")
+	stopwordsFlag := fs.String("stopwords", "", "file with more words to drop, one per line")
+	formatFlag := fs.String("format", "text", "output format: text, csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "text" && *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("terms requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	minWords, maxWords, err := parseNGram(*ngramFlag)
+	failonf(err, "parsing -ngram")
+	opts := soanalysis.TermOptions{
+		Language:          *languageFlag,
+		Stem:              *stemFlag,
+		QuestionStopwords: *questionStopFlag,
+		MinWords:          minWords,
+		MaxWords:          maxWords,
+	}
+	if *stopwordsFlag != "" {
+		opts.Stopwords, err = readWords(*stopwordsFlag)
+		failonf(err, "reading %q", *stopwordsFlag)
+	}
+	ta, err := soanalysis.NewTermAnalyzer(opts)
+	failonf(err, "setting up terms")
+
+	an := soanalysis.NewAnalyzer(af.dir)
+	an.Filter = af.filter()
+	ctx, cancel := af.context()
+	defer cancel()
+	tc, err := soanalysis.NewTermCounter(ta, fromDate, toDate, af.bymonth)
+	failonf(err, "counting terms")
+	for _, tag := range tags {
+		err := an.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+			tc.Add(tag, time.Unix(int64(item.CreationDate), 0), html.UnescapeString(item.Title))
+		})
+		failonf(err, "reading tag %q", tag)
+	}
+
+	var dates []string
+	for _, d := range tc.Dates() {
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	var results []tagTerms
+	for _, tag := range tags {
+		if terms := tc.Top(tag, *nFlag); len(terms) > 0 {
+			results = append(results, tagTerms{Tag: tag, Terms: terms, Dates: dates})
+		}
+	}
+	switch *formatFlag {
+	case "csv":
+		err = writeTermsCSV(os.Stdout, results)
+	case "json":
+		err = writeTermsJSON(os.Stdout, results)
+	default:
+		err = writeTermsText(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// parseNGram parses the value of -ngram: a number of words, or a range of them
+// such as 2-3.
+func parseNGram(s string) (int, int, error) {
+	from, to, isRange := strings.Cut(s, "-")
+	minWords, err := strconv.Atoi(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxWords := minWords
+	if isRange {
+		if maxWords, err = strconv.Atoi(to); err != nil {
+			return 0, 0, err
+		}
+	}
+	if minWords < 1 || maxWords < minWords {
+		return 0, 0, fmt.Errorf("invalid range of words %q", s)
+	}
+	return minWords, maxWords, nil
+}
+
+// readWords reads a file of words, one per line, skipping empty lines and
+// lines starting with #.
+func readWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word != "" && !strings.HasPrefix(word, "#") {
+			words = append(words, word)
+		}
+	}
+	return words, scanner.Err()
+}
+
+// writeTermsText writes the terms of each tag as an aligned list under the tag,
+// with a sparkline of their trends if they have any.
+func writeTermsText(w io.Writer, results []tagTerms) error {
+	for i, r := range results {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, r.Tag)
+		width := 0
+		for _, t := range r.Terms {
+			width = max(width, utf8.RuneCountInString(t.Word))
+		}
+		for j, t := range r.Terms {
+			trend := ""
+			if t.Trend != nil {
+				trend = "  " + sparkline(t.Trend)
+			}
+			if _, err := fmt.Fprintf(w, "%3d. %-*s %5.1f%%  %.4f%s\n", j+1, width, t.Word, 100*t.Share, t.TFIDF, trend); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeTermsCSV writes the terms as a CSV table with a header and a row per
+// term of each tag; with trends, the share of each bucket is in a column named
+// by its date.
+func writeTermsCSV(w io.Writer, results []tagTerms) error {
+	cw := csv.NewWriter(w)
+	header := []string{"tag", "rank", "word", "stem", "questions", "share", "tfidf"}
+	if len(results) > 0 {
+		header = append(header, results[0].Dates...)
+	}
+	cw.Write(header)
+	for _, r := range results {
+		for j, t := range r.Terms {
+			row := []string{r.Tag, strconv.Itoa(j + 1), t.Word, t.Stem, strconv.Itoa(t.Texts),
+				strconv.FormatFloat(t.Share, 'f', 4, 64), strconv.FormatFloat(t.TFIDF, 'f', 4, 64)}
+			for _, share := range t.Trend {
+				row = append(row, strconv.FormatFloat(share, 'f', 4, 64))
+			}
+			cw.Write(row)
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTermsJSON writes the results as a JSON array with an object per tag.
+func writeTermsJSON(w io.Writer, results []tagTerms) error {
+	if results == nil {
+		results = []tagTerms{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
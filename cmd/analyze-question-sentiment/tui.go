@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// runTUI implements the tui command, a full-screen explorer of the data in
+// -dir: pick a tag, browse its monthly table (toggling metric columns), and
+// drill down into the most negative questions of a month.
+func runTUI(args []string) {
+	fs := newFlagSet("tui")
+	var af analysisFlags
+	af.register(fs)
+	monthsFlag := fs.Int("months", 24, "number of months to browse, when -fromdate isn't provided")
+	parseFlags(fs, args)
+
+	if len(af.dir) == 0 {
+		log.Fatal("-dir must be provided and cannot be empty. Please use the folder where the data was fetched.")
+	}
+	af.setRecentMonths(*monthsFlag)
+	tags, err := af.analyzer().Dataset.Tags()
+	failonf(err, "reading tags in %q", af.dir)
+
+	m := &tuiModel{
+		af:      af,
+		tags:    tags,
+		series:  make(map[string]soanalysis.Series),
+		columns: []bool{true, true, true, true, true, true, af.sentiments != ""},
+	}
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// tuiColumns are the metric columns of the monthly table in the TUI, toggled
+// by the number keys.
+var tuiColumns = []struct {
+	name   string
+	format func(tr soanalysis.Result) string
+}{
+	{"questions", func(tr soanalysis.Result) string { return strconv.Itoa(tr.Total) }},
+	{"negative", func(tr soanalysis.Result) string { return fmt.Sprintf("%.3f", tr.NegativeRatio()) }},
+	{"closed", func(tr soanalysis.Result) string { return fmt.Sprintf("%.3f", tr.ClosedRatio()) }},
+	{"closed&neg", func(tr soanalysis.Result) string { return fmt.Sprintf("%.3f", tr.ClosedAndNegativeRatio()) }},
+	{"unanswered", func(tr soanalysis.Result) string { return fmt.Sprintf("%.3f", tr.UnansweredRatio()) }},
+	{"neg/10kview", func(tr soanalysis.Result) string { return fmt.Sprintf("%.2f", tr.NegativePer10kViews()) }},
+	{"sentiment", func(tr soanalysis.Result) string {
+		if len(tr.Sentiments) == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.3f", tr.MeanSentiment())
+	}},
+}
+
+// tuiScreen is a screen of the TUI; each has its own list with a cursor.
+type tuiScreen int
+
+const (
+	screenTags tuiScreen = iota
+	screenMonths
+	screenQuestions
+)
+
+// tuiModel is the bubbletea model of the TUI.
+type tuiModel struct {
+	af     analysisFlags
+	height int
+
+	screen tuiScreen
+	// cursor and scroll offset per screen
+	cursor [3]int
+	offset [3]int
+
+	tags []string
+	// series caches the analysis of tags, which are analyzed when first opened
+	series    map[string]soanalysis.Series
+	columns   []bool
+	questions []soapi.Item
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *tuiModel) tag() string {
+	return m.tags[m.cursor[screenTags]]
+}
+
+// listLen returns the number of lines in the list of the current screen.
+func (m *tuiModel) listLen() int {
+	switch m.screen {
+	case screenTags:
+		return len(m.tags)
+	case screenMonths:
+		return len(m.series[m.tag()].Buckets)
+	default:
+		return len(m.questions)
+	}
+}
+
+// pageSize returns how many lines of a list fit on the screen, leaving room for
+// the header and help lines.
+func (m *tuiModel) pageSize() int {
+	return max(m.height-4, 1)
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		key := msg.String()
+		switch key {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "up", "k":
+			m.cursor[m.screen] = max(m.cursor[m.screen]-1, 0)
+		case "down", "j":
+			m.cursor[m.screen] = max(min(m.cursor[m.screen]+1, m.listLen()-1), 0)
+		case "esc", "backspace", "left", "h":
+			if m.screen > screenTags {
+				m.screen--
+			}
+		case "enter", "right", "l":
+			m.enter()
+		case "1", "2", "3", "4", "5", "6", "7":
+			i := int(key[0] - '1')
+			m.columns[i] = !m.columns[i]
+		}
+	}
+
+	// Keep the cursor within the visible part of the list
+	c, o := m.cursor[m.screen], m.offset[m.screen]
+	if c < o {
+		m.offset[m.screen] = c
+	} else if c >= o+m.pageSize() {
+		m.offset[m.screen] = c - m.pageSize() + 1
+	}
+	return m, nil
+}
+
+// enter moves to the screen selected by the cursor of the current one.
+func (m *tuiModel) enter() {
+	switch m.screen {
+	case screenTags:
+		if len(m.tags) == 0 {
+			return
+		}
+		if _, ok := m.series[m.tag()]; !ok {
+			af := m.af
+			af.tags = m.tag()
+			m.series[m.tag()] = af.run()[0]
+		}
+		m.screen = screenMonths
+		m.cursor[screenMonths], m.offset[screenMonths] = 0, 0
+	case screenMonths:
+		buckets := m.series[m.tag()].Buckets
+		if len(buckets) == 0 {
+			return
+		}
+		to := buckets[m.cursor[screenMonths]].Date
+		ctx, cancel := m.af.context()
+		questions, err := m.af.analyzer().MostNegative(ctx, m.tag(), to.AddDate(0, -1, 0), to, 100)
+		cancel()
+		failonf(err, "analyzing tag %q", m.tag())
+		m.questions = questions
+		m.screen = screenQuestions
+		m.cursor[screenQuestions], m.offset[screenQuestions] = 0, 0
+	}
+}
+
+func (m *tuiModel) View() string {
+	var sb strings.Builder
+	var lines []string
+	var help string
+
+	switch m.screen {
+	case screenTags:
+		fmt.Fprintf(&sb, "Tags in %s\n\n", m.af.dir)
+		lines = m.tags
+		help = "enter: open tag, q: quit"
+	case screenMonths:
+		fmt.Fprintf(&sb, "[%s] by month\n", m.tag())
+		header := fmt.Sprintf("%-10s", "month end")
+		for i, c := range tuiColumns {
+			if m.columns[i] {
+				header += fmt.Sprintf(" %11s", c.name)
+			}
+		}
+		sb.WriteString("  " + header + "\n")
+		for _, b := range m.series[m.tag()].Buckets {
+			line := b.Date.Format("2006-01-02")
+			for i, c := range tuiColumns {
+				if m.columns[i] {
+					line += fmt.Sprintf(" %11s", c.format(b.Result))
+				}
+			}
+			lines = append(lines, line)
+		}
+		help = "enter: negative questions, 1-7: toggle columns, esc: back, q: quit"
+	case screenQuestions:
+		to := m.series[m.tag()].Buckets[m.cursor[screenMonths]].Date
+		fmt.Fprintf(&sb, "[%s] most negative questions for the month ending %s\n\n", m.tag(), to.Format("2006-01-02"))
+		for _, item := range m.questions {
+			lines = append(lines, fmt.Sprintf("%4d  %s  %s", item.Score, item.Title, item.Link))
+		}
+		if len(lines) == 0 {
+			sb.WriteString("  (none)\n")
+		}
+		help = "esc: back, q: quit"
+	}
+
+	o := m.offset[m.screen]
+	for i := o; i < len(lines) && i < o+m.pageSize(); i++ {
+		cursor := "  "
+		if i == m.cursor[m.screen] {
+			cursor = "> "
+		}
+		sb.WriteString(cursor + lines[i] + "\n")
+	}
+	sb.WriteString("\n" + help)
+	return sb.String()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// runValidate implements the validate command, which checks the stored pages
+// of each tag in the data directory against the JSON Schema of the replies of
+// the API (see soapi.ReplySchema), to catch changes of the API that would
+// otherwise go unnoticed: fields being renamed, retyped or dropped decode as
+// zero values. It exits with an error if any page doesn't match.
+func runValidate(args []string) {
+	fs := newFlagSet("validate")
+	var af analysisFlags
+	fs.StringVar(&af.dir, "dir", "", "base directory with results")
+	fs.StringVar(&af.tags, "tags", "", "tags (or groups of tags from the config file) separated by commas; all tags by default")
+	maxFlag := fs.Int("max", 10, "maximal number of mismatches to print per page")
+	schemaFlag := fs.Bool("schema", false, "print the JSON Schema the pages are checked against and exit")
+	parseFlags(fs, args)
+
+	if *schemaFlag {
+		fmt.Print(string(soapi.ReplySchema))
+		return
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("validate requires a data directory, not -dir -")
+	}
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	ds := af.analyzer().Dataset
+	ctx, cancel := af.context()
+	defer cancel()
+	invalid := 0
+	for _, tag := range tags {
+		pages, tagInvalid := 0, 0
+		err := ds.ForEachPage(ctx, tag, func(path string, body []byte) error {
+			pages++
+			errs := soapi.ValidateReply(body)
+			if len(errs) == 0 {
+				return nil
+			}
+			tagInvalid++
+			for i, e := range errs {
+				if i == *maxFlag {
+					fmt.Printf("%s: %d more mismatches\n", path, len(errs)-i)
+					break
+				}
+				fmt.Printf("%s: %v\n", path, e)
+			}
+			return nil
+		})
+		failonf(err, "validating tag %q", tag)
+		fmt.Printf("%s: %d of %d pages don't match the schema\n", tag, tagInvalid, pages)
+		invalid += tagInvalid
+	}
+	if invalid > 0 {
+		log.Fatalf("%d pages don't match the schema", invalid)
+	}
+}
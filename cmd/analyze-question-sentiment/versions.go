@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// versionRow is the result of the questions of a version as presented in
+// -format json.
+type versionRow struct {
+	Version           string   `json:"version"`
+	Total             int      `json:"total"`
+	Negative          float64  `json:"negative"`
+	Closed            float64  `json:"closed"`
+	Unanswered        float64  `json:"unanswered"`
+	MeanScore         float64  `json:"meanScore"`
+	MeanSentiment     *float64 `json:"meanSentiment,omitempty"`
+	SentimentCoverage float64  `json:"sentimentCoverage,omitempty"`
+}
+
+type versionFamily struct {
+	Family   string       `json:"family"`
+	Tags     []string     `json:"tags"`
+	All      versionRow   `json:"all"`
+	Versions []versionRow `json:"versions"`
+}
+
+// versionSplit is the split of a family along with the tags its questions were
+// read from.
+type versionSplit struct {
+	tags  []string
+	split soanalysis.VersionSplit
+}
+
+// runVersions implements the versions command, which groups the tags with
+// versions (python-3.x, java-8, angular2) with the tag of their family, and
+// reports the metrics of the questions of each family rolled up and split by
+// the versioned tags on them; see soanalysis.Analyzer.VersionSplit.
+func runVersions(args []string) {
+	fs := newFlagSet("versions")
+	var af analysisFlags
+	af.register(fs)
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("versions requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+	known, err := (&soanalysis.Dataset{Dir: af.dir}).Tags()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	families, members := soanalysis.VersionFamilies(tags, known)
+	var results []versionSplit
+	for _, family := range families {
+		vs, err := an.VersionSplit(ctx, family, members[family], fromDate, toDate)
+		failonf(err, "analyzing family %q", family)
+		results = append(results, versionSplit{tags: members[family], split: vs})
+	}
+
+	if *formatFlag == "json" {
+		err = writeVersionsJSON(os.Stdout, results, an.Sentiments != nil)
+	} else {
+		err = writeVersionsCSV(os.Stdout, results, an.Sentiments != nil)
+	}
+	failonf(err, "writing results")
+}
+
+// versionName returns the name of a version in the output: "all" for the
+// rolled up questions and "none" for the questions without a version.
+func versionName(version string) string {
+	if version == "" {
+		return "none"
+	}
+	return version
+}
+
+// writeVersionsCSV writes the results of each family as a line with the family
+// followed by a CSV table with a row for all its questions, named "all", and
+// then a row per version (and one named "none" for the questions without a
+// version): the number of questions, their negative, closed and unanswered
+// ratios and their mean score, along with their mean sentiment and its
+// coverage with sentiments.
+func writeVersionsCSV(w io.Writer, results []versionSplit, sentiments bool) error {
+	writeRow := func(name string, r soanalysis.Result) error {
+		_, err := fmt.Fprintf(w, "%s,%d,%.3f,%.3f,%.3f,%.2f", name, r.Total, r.NegativeRatio(), r.ClosedRatio(),
+			r.UnansweredRatio(), r.MeanScore())
+		if err != nil {
+			return err
+		}
+		if sentiments {
+			if _, err := fmt.Fprintf(w, ",%.3f,%.3f", r.MeanSentiment(), r.SentimentCoverage()); err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintln(w)
+		return err
+	}
+	for _, s := range results {
+		if _, err := fmt.Fprintf(w, "\n%s\n", s.split.Family); err != nil {
+			return err
+		}
+		if err := writeRow("all", s.split.All); err != nil {
+			return err
+		}
+		for _, v := range s.split.Versions {
+			if err := writeRow(versionName(v.Version), v.Result); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeVersionsJSON writes the results as a JSON array with an object per
+// family.
+func writeVersionsJSON(w io.Writer, results []versionSplit, sentiments bool) error {
+	newRow := func(name string, r soanalysis.Result) versionRow {
+		row := versionRow{
+			Version:    name,
+			Total:      r.Total,
+			Negative:   r.NegativeRatio(),
+			Closed:     r.ClosedRatio(),
+			Unanswered: r.UnansweredRatio(),
+			MeanScore:  r.MeanScore(),
+		}
+		if sentiments && len(r.Sentiments) > 0 {
+			mean := r.MeanSentiment()
+			row.MeanSentiment = &mean
+			row.SentimentCoverage = r.SentimentCoverage()
+		}
+		return row
+	}
+	vfs := []versionFamily{}
+	for _, s := range results {
+		vf := versionFamily{
+			Family:   s.split.Family,
+			Tags:     s.tags,
+			All:      newRow("all", s.split.All),
+			Versions: []versionRow{},
+		}
+		for _, v := range s.split.Versions {
+			vf.Versions = append(vf.Versions, newRow(versionName(v.Version), v.Result))
+		}
+		vfs = append(vfs, vf)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vfs)
+}
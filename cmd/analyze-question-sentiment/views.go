@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+)
+
+// viewGrowthWeek is a week of the age of the questions of a group as presented
+// in -format json.
+type viewGrowthWeek struct {
+	Week        int     `json:"week"`
+	Snapshots   int     `json:"snapshots"`
+	MeanViews   float64 `json:"meanViews"`
+	ViewsPerDay float64 `json:"viewsPerDay"`
+}
+
+type viewGrowthGroup struct {
+	Questions        int              `json:"questions"`
+	MedianDaysToHalf float64          `json:"medianDaysToHalfViews"`
+	Weeks            []viewGrowthWeek `json:"weeks"`
+}
+
+type viewGrowthTag struct {
+	Tag      string          `json:"tag"`
+	Negative viewGrowthGroup `json:"negative"`
+	Other    viewGrowthGroup `json:"other"`
+}
+
+// runViews implements the views command, which reports how the view counts of
+// the questions of each tag grow in the weeks after they're asked, from the
+// view counts logged by successive fetches, separately for negative questions
+// and the others; see soanalysis.Analyzer.ViewGrowth.
+func runViews(args []string) {
+	fs := newFlagSet("views")
+	var af analysisFlags
+	af.register(fs)
+	weeksFlag := fs.Int("weeks", 8, "number of weeks after asking to report the view counts of")
+	formatFlag := fs.String("format", "csv", "output format: csv or json")
+	parseFlags(fs, args)
+
+	if *formatFlag != "csv" && *formatFlag != "json" {
+		log.Fatalf("unknown -format %q", *formatFlag)
+	}
+	if cli.IsStream(af.dir) {
+		log.Fatal("views requires a data directory, not -dir -")
+	}
+	fromDate, toDate, err := af.dates()
+	failonf(err, "parsing dates")
+	tags, err := af.tagList()
+	failonf(err, "listing tags")
+
+	an := af.analyzer()
+	ctx, cancel := af.context()
+	defer cancel()
+	var results []soanalysis.ViewGrowth
+	for _, tag := range tags {
+		vg, err := an.ViewGrowth(ctx, tag, fromDate, toDate, *weeksFlag)
+		failonf(err, "analyzing tag %q", tag)
+		if vg.Negative.Questions+vg.Other.Questions == 0 {
+			log.Printf("no view counts of the questions of %q logged by several fetches; fetch them again with fetch-all-questions -refresh", tag)
+		}
+		results = append(results, vg)
+	}
+
+	if *formatFlag == "json" {
+		err = writeViewsJSON(os.Stdout, results)
+	} else {
+		err = writeViewsCSV(os.Stdout, results)
+	}
+	failonf(err, "writing results")
+}
+
+// writeViewsCSV writes the results of each tag as a line with the tag name and
+// the median number of days its negative and other questions took to get half
+// of their views, followed by a CSV table with a row per group and week.
+func writeViewsCSV(w io.Writer, results []soanalysis.ViewGrowth) error {
+	for _, vg := range results {
+		_, err := fmt.Fprintf(w, "\n%s (half views in %.1f days when negative, %.1f days otherwise)\n", vg.Tag,
+			vg.Negative.MedianTimeToHalfViews.Hours()/24, vg.Other.MedianTimeToHalfViews.Hours()/24)
+		if err != nil {
+			return err
+		}
+		for i, g := range []soanalysis.ViewGrowthGroup{vg.Negative, vg.Other} {
+			for _, wk := range g.Weeks {
+				_, err := fmt.Fprintf(w, "%s,%d,%d,%.1f,%.2f\n", []string{"negative", "other"}[i], wk.Week, wk.Snapshots, wk.MeanViews, wk.ViewsPerDay)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeViewsJSON writes the results as a JSON array with an object per tag.
+func writeViewsJSON(w io.Writer, results []soanalysis.ViewGrowth) error {
+	newGroup := func(g soanalysis.ViewGrowthGroup) viewGrowthGroup {
+		vg := viewGrowthGroup{
+			Questions:        g.Questions,
+			MedianDaysToHalf: g.MedianTimeToHalfViews.Hours() / 24,
+			Weeks:            []viewGrowthWeek{},
+		}
+		for _, wk := range g.Weeks {
+			vg.Weeks = append(vg.Weeks, viewGrowthWeek{
+				Week:        wk.Week,
+				Snapshots:   wk.Snapshots,
+				MeanViews:   wk.MeanViews,
+				ViewsPerDay: wk.ViewsPerDay,
+			})
+		}
+		return vg
+	}
+	vts := []viewGrowthTag{}
+	for _, vg := range results {
+		vts = append(vts, viewGrowthTag{Tag: vg.Tag, Negative: newGroup(vg.Negative), Other: newGroup(vg.Other)})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(vts)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/fsnotify/fsnotify"
+)
+
+// rerunOnChange calls fn, and then if watch is true keeps watching dir and its
+// subdirectories for changes (such as pages written by a fetch running in
+// parallel), calling fn again after each batch of changes. While watching, the
+// config file is reloaded (see reloadConfig) when it changes or on SIGHUP, and
+// fn called again with the new flags. It never returns if watch is true.
+func rerunOnChange(watch bool, dir string, fn func()) {
+	if watch && cli.IsStream(dir) {
+		log.Fatal("-watch requires a data directory, not -dir -")
+	}
+	fn()
+	if !watch {
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	failonf(err, "creating watcher")
+	defer watcher.Close()
+
+	err = watcher.Add(dir)
+	failonf(err, "watching %q", dir)
+	ds := &soanalysis.Dataset{Dir: dir}
+	tags, err := ds.Tags()
+	failonf(err, "reading tags in %q", dir)
+	for _, tag := range tags {
+		err = watcher.Add(ds.TagDir(tag))
+		failonf(err, "watching %q", tag)
+	}
+	// Watch the directory of the config file rather than the file, which
+	// editors replace when saving it, and which may not exist yet.
+	config := configPath()
+	if config != "" {
+		if err := watcher.Add(filepath.Dir(config)); err != nil {
+			log.Printf("not watching the config file: %v", err)
+		}
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	// Changes come in bursts while fetching, so wait for a quiet period before
+	// rerunning.
+	const quietPeriod = 2 * time.Second
+	timer := time.NewTimer(quietPeriod)
+	timer.Stop()
+	// Saving the config file may take several writes too.
+	const configQuietPeriod = 200 * time.Millisecond
+	configTimer := time.NewTimer(configQuietPeriod)
+	configTimer.Stop()
+
+	log.Println("Watching", dir, "for changes")
+	for {
+		select {
+		case event := <-watcher.Events:
+			if event.Name == config {
+				if !event.Has(fsnotify.Chmod) {
+					configTimer.Reset(configQuietPeriod)
+				}
+				continue
+			}
+			if config != "" && filepath.Dir(event.Name) == filepath.Dir(config) && filepath.Dir(config) != filepath.Clean(dir) {
+				// Another file next to the config file
+				continue
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					// A new tag directory
+					err = watcher.Add(event.Name)
+					failonf(err, "watching %q", event.Name)
+				}
+			}
+			timer.Reset(quietPeriod)
+		case err := <-watcher.Errors:
+			log.Println("watch error:", err)
+		case <-timer.C:
+			log.Println("Data changed; rerunning")
+			fn()
+		case <-hup:
+			configTimer.Reset(0)
+		case <-configTimer.C:
+			if err := reloadConfig(); err != nil {
+				log.Println("not reloading the config:", err)
+				continue
+			}
+			log.Println("Config reloaded; rerunning")
+			fn()
+		}
+	}
+}
@@ -0,0 +1,795 @@
+// StackOverflow analysis using its API in Go.
+//
+// This program just fetches data from the StackOverflow API. The idea is that
+// you run it once to fetch all the data you need, and can then analyze this
+// data locally by repeatedly invoking analyze-question-sentiment with different
+// parameters.
+//
+// To get the increased API quota, get a key from stackapps.com and run with the
+// env var STACK_KEY=<key>
+//
+// The defaults of the flags can be set with environment variables, like those
+// of analyze-question-sentiment, e.g. SOTRENDS_DIR or SOTRENDS_SITE; see
+// cli.EnvVar. Flags given on the command line override them.
+//
+// Run "fetch-all-questions -help" for the flags, which fetch e.g. the answers
+// (-answers), bodies (-bodies) or related tags (-related) of the questions too,
+// for the analyses of analyze-question-sentiment needing them. A tag that fails
+// to be fetched doesn't stop the fetching of the others; fetching stops with a
+// checkpoint to resume from on Ctrl-C, after -timeout, when the API keeps
+// failing (see -breaker) or when the disk is nearly full (see -minfree), and
+// the exit status tells these apart; see fetchResults and exitStatus.
+//
+// Eli Bendersky [https://eli.thegreenplace.net]
+// This code is in the public domain.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/internal/cli"
+	"github.com/eliben/so-tag-sentiment-analysis/internal/telemetry"
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer traces the fetching of each tag (with -otel-endpoint), along with the
+// requests made for it.
+var tracer = otel.Tracer("github.com/eliben/so-tag-sentiment-analysis/cmd/fetch-all-questions")
+
+// fetchOptions are the options of fetching questions into a data directory,
+// given by the flags of the same names.
+type fetchOptions struct {
+	// erase removes the previous contents of the directories of the tags.
+	erase bool
+
+	// refresh requests the stored pages conditionally with their validators,
+	// keeping the ones that didn't change.
+	refresh bool
+
+	// answers also fetches the answers to the questions of each tag, and
+	// fulltext builds the full-text index of each tag after fetching it.
+	answers  bool
+	fulltext bool
+
+	// validate checks the fetched pages against the schema of the replies of
+	// the API.
+	validate bool
+
+	// minFree is the number of bytes of disk space to keep available; pages
+	// aren't stored once fewer are.
+	minFree int64
+}
+
+// fetchResults fetches the questions of each of tags into ds, as set by opts. A
+// tag that fails to be fetched doesn't stop the fetching of the other tags;
+// the errors of the failed tags are returned in a *cli.PartialError. If
+// fetching stops before all the tags are fetched (when the circuit breaker of
+// fetcher opens, fewer than opts.minFree bytes are available on the disk or
+// ctx is done), a checkpoint is written into ds, from which the next fetch of
+// the same window resumes.
+func fetchResults(ctx context.Context, fetcher *soapi.Fetcher, ds *soanalysis.Dataset, tags []string, fromDate time.Time, toDate time.Time, opts fetchOptions) error {
+	done, resumeTag, resumePage, err := resume(ds, fromDate, toDate)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, tag := range tags {
+		if slices.Contains(done, tag) {
+			fmt.Printf("Skipping tag '%s', fetched before the checkpoint\n", tag)
+			continue
+		}
+		startPage := 1
+		if tag == resumeTag {
+			startPage = resumePage
+		}
+		if err := ctx.Err(); err != nil {
+			// Stopped right after fetching the previous tag, e.g. by a signal
+			// arriving as it finished.
+			err = fmt.Errorf("fetching tag %q: %w", tag, err)
+			log.Println(err)
+			errs = append(errs, err)
+			if err := writeCheckpoint(ds, fromDate, toDate, done, tag, startPage, err); err != nil {
+				errs = append(errs, err)
+			}
+			return cli.Partial(errs)
+		}
+
+		ctx, span := tracer.Start(ctx, "fetch tag", trace.WithAttributes(attribute.String("tag", tag)))
+		page, err := fetchTagDir(ctx, fetcher, ds, tag, fromDate, toDate, startPage, opts)
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+			err = fmt.Errorf("fetching tag %q: %w", tag, err)
+			log.Println(err)
+			errs = append(errs, err)
+		} else {
+			done = append(done, tag)
+		}
+		span.End()
+
+		if stopsFetch(ctx, err) {
+			// The other tags would fail too.
+			if err := writeCheckpoint(ds, fromDate, toDate, done, tag, page, err); err != nil {
+				errs = append(errs, err)
+			}
+			return cli.Partial(errs)
+		}
+	}
+	if err := ds.RemoveCheckpoint(); err != nil {
+		errs = append(errs, err)
+	}
+	return cli.Partial(errs)
+}
+
+// writeCheckpoint records in ds that fetching the window between fromDate and
+// toDate stopped with err at page of tag, after fetching the tags in done.
+func writeCheckpoint(ds *soanalysis.Dataset, fromDate time.Time, toDate time.Time, done []string, tag string, page int, err error) error {
+	c := soanalysis.Checkpoint{Time: time.Now().UTC(), FromDate: fromDate, ToDate: toDate, Done: done, Tag: tag, Page: page, Err: err.Error()}
+	if err := ds.WriteCheckpoint(c); err != nil {
+		return err
+	}
+	fmt.Printf("Stopped at page %d of tag '%s'; fetch the same window again to resume from there\n", page, tag)
+	return nil
+}
+
+// resume returns where to resume fetching the window between fromDate and
+// toDate into ds from, according to its checkpoint: the tags already fetched,
+// and the tag and page fetching stopped at. Without a checkpoint of the same
+// window, there's nothing to resume.
+func resume(ds *soanalysis.Dataset, fromDate time.Time, toDate time.Time) ([]string, string, int, error) {
+	c, err := ds.Checkpoint()
+	if err != nil || c == nil {
+		return nil, "", 0, err
+	}
+	if !c.FromDate.Equal(fromDate) || !c.ToDate.Equal(toDate) {
+		fmt.Printf("Ignoring the checkpoint of the fetch of %s to %s\n", c.FromDate.Format(cli.DateLayout), c.ToDate.Format(cli.DateLayout))
+		return nil, "", 0, nil
+	}
+	fmt.Printf("Resuming the fetch stopped at %s (%s)\n", c.Time.Format(time.RFC3339), c.Err)
+	return c.Done, c.Tag, c.Page, nil
+}
+
+// fetchTagDir fetches the questions of tag into its directory in ds from
+// startPage on (keeping the pages before it, as stored by a fetch that
+// stopped), along with the data requested by opts; see fetchResults. On
+// errors, it returns the page to resume from.
+func fetchTagDir(ctx context.Context, fetcher *soapi.Fetcher, ds *soanalysis.Dataset, tag string, fromDate time.Time, toDate time.Time, startPage int, opts fetchOptions) (int, error) {
+	dirName := ds.TagDir(tag)
+
+	if opts.erase && startPage == 1 {
+		// Clear out subdirectory if it already exists
+		fmt.Println("Erasing directory", dirName)
+		os.RemoveAll(dirName)
+	}
+	os.Mkdir(dirName, 0777)
+
+	// Load the cached validators even without refresh, to keep the ones of
+	// other windows fetched into the directory.
+	validators, err := loadValidators(ds.ValidatorsPath(tag))
+	if err != nil {
+		return startPage, err
+	}
+
+	// The questions of the window as fetched before, to tell which ones were
+	// deleted since.
+	before, err := ds.WindowItems(tag, fromDate, toDate)
+	if err != nil {
+		return startPage, err
+	}
+
+	fmt.Println("")
+	fmt.Printf("Fetching tag '%s' to dir '%s'\n", tag, dirName)
+	lastPage, err := fetchTag(ctx, fetcher, ds, tag, fromDate, toDate, startPage, validators, opts)
+	if err != nil {
+		return lastPage, err
+	}
+
+	// Remove pages left over from a previous fetch with more pages.
+	if err := ds.TruncatePages(tag, fromDate, toDate, lastPage); err != nil {
+		return lastPage, err
+	}
+	if err := logDeletions(ctx, ds, tag, before); err != nil {
+		return lastPage, err
+	}
+	if err := logScores(ds, tag, fromDate, toDate); err != nil {
+		return lastPage, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(validators); err != nil {
+		return lastPage, err
+	}
+	if err := os.WriteFile(ds.ValidatorsPath(tag), buf.Bytes(), 0644); err != nil {
+		return lastPage, err
+	}
+
+	if opts.answers {
+		if err := fetchAnswers(ctx, fetcher, ds, tag, opts.minFree); err != nil {
+			return lastPage, err
+		}
+	}
+
+	if opts.fulltext {
+		n, err := ds.BuildFullTextIndex(ctx, tag)
+		if err != nil {
+			return lastPage, err
+		}
+		fmt.Printf("Indexed %d questions of tag '%s'\n", n, tag)
+	}
+	return lastPage, nil
+}
+
+// fetchAnswers fetches the answers to all the questions of tag in ds that have
+// any into the answers directory of tag, replacing the answers fetched before,
+// for the analyses of answering, such as the time questions wait for an
+// answer. It stops once fewer than minFree bytes are available on the disk.
+func fetchAnswers(ctx context.Context, fetcher *soapi.Fetcher, ds *soanalysis.Dataset, tag string, minFree int64) error {
+	var ids []int
+	err := ds.ForEachItem(ctx, tag, time.Time{}, time.Time{}, func(item *soapi.Item) {
+		if item.AnswerCount > 0 {
+			ids = append(ids, item.QuestionID)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Fetch into a temporary directory and then replace the answers with it, so
+	// that answers are never partially fetched.
+	tmpDir := ds.AnswersDir(tag) + ".tmp"
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	}
+	if err := os.Mkdir(tmpDir, 0777); err != nil {
+		return err
+	}
+
+	fmt.Printf("Fetching answers to %d questions of tag '%s'\n", len(ids), tag)
+	n := 0
+	for start := 0; start < len(ids); start += soapi.MaxAnswerQuestions {
+		if start > 0 {
+			if err := fetcher.Pause(ctx); err != nil {
+				return err
+			}
+		}
+		end := min(start+soapi.MaxAnswerQuestions, len(ids))
+		err := fetcher.FetchAnswers(ctx, ids[start:end], func(page int, body []byte, reply *soapi.AnswerReply) error {
+			n++
+			fmt.Printf("Fetched %d answers, quota remaining: %d\n", len(reply.Items), reply.QuotaRemaining)
+			if err := logQuota(ds, tag, reply.QuotaRemaining); err != nil {
+				return err
+			}
+			if err := ds.CheckFreeSpace(minFree); err != nil {
+				return err
+			}
+			return os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("%04d.json", n)), body, 0644)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.RemoveAll(ds.AnswersDir(tag)); err != nil {
+		return err
+	}
+	return os.Rename(tmpDir, ds.AnswersDir(tag))
+}
+
+// fetchTag fetches the pages of tag into ds from startPage on, and returns the
+// number of the last page, or of the page it failed at, as set by opts (see
+// fetchOptions). validators is updated with the validators of the responses.
+// With opts.refresh, pages are requested conditionally with them (with
+// If-None-Match and If-Modified-Since), and the ones that didn't change are
+// kept, saving quota when fetching a recent window again.
+func fetchTag(ctx context.Context, fetcher *soapi.Fetcher, ds *soanalysis.Dataset, tag string, fromDate time.Time, toDate time.Time, startPage int, validators map[string]soapi.Validators, opts fetchOptions) (int, error) {
+	for page := startPage; ; page++ {
+		key := soapi.PageKey(page, tag, fromDate, toDate)
+
+		var v soapi.Validators
+		stored, err := ds.LoadPage(tag, fromDate, toDate, page)
+		if opts.refresh && err == nil {
+			// Only request conditionally if there's a page to keep
+			v = validators[key]
+		}
+
+		body, reply, v, err := fetcher.FetchPageIf(ctx, page, tag, fromDate, toDate, v)
+		if errors.Is(err, soapi.ErrNotModified) {
+			fmt.Printf("Page %d not modified\n", page)
+			reply = &soapi.Reply{}
+			if err := json.Unmarshal(stored, reply); err != nil {
+				return page, fmt.Errorf("unmarshalling page %d: %w", page, err)
+			}
+		} else if err != nil {
+			return page, err
+		} else {
+			fmt.Printf("Fetched page %d, quota remaining: %d\n", page, reply.QuotaRemaining)
+			if err := logQuota(ds, tag, reply.QuotaRemaining); err != nil {
+				return page, err
+			}
+			if opts.validate {
+				warnInvalid(tag, page, body)
+			}
+			if err := ds.CheckFreeSpace(opts.minFree); err != nil {
+				return page, err
+			}
+			isNew, err := ds.StorePage(tag, fromDate, toDate, page, body)
+			if err != nil {
+				return page, err
+			}
+			if isNew {
+				fmt.Printf("Stored page %d\n", page)
+			} else {
+				fmt.Printf("Page %d is identical to a stored page\n", page)
+			}
+		}
+		validators[key] = v
+
+		if !reply.HasMore {
+			return page, nil
+		}
+		if err := fetcher.Pause(ctx); err != nil {
+			return page, err
+		}
+	}
+}
+
+// logDeletions records the questions of before (the questions of a window of
+// tag before fetching it again) that are no longer in ds in the deletions log
+// of tag: they were deleted (or retagged) in the meantime, which
+// analyze-question-sentiment deletions reports the rates of.
+func logDeletions(ctx context.Context, ds *soanalysis.Dataset, tag string, before []soapi.Item) error {
+	if len(before) == 0 {
+		return nil
+	}
+	present := make(map[int]bool)
+	err := ds.ForEachItem(ctx, tag, time.Time{}, time.Time{}, func(item *soapi.Item) {
+		present[item.QuestionID] = true
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var deletions []soanalysis.Deletion
+	for _, item := range before {
+		if !present[item.QuestionID] {
+			deletions = append(deletions, soanalysis.Deletion{Detected: now, Item: soanalysis.NewDeletedItem(&item)})
+			present[item.QuestionID] = true
+		}
+	}
+	if len(deletions) > 0 {
+		fmt.Printf("Detected %d deleted questions\n", len(deletions))
+		return ds.LogDeletions(tag, deletions)
+	}
+	return nil
+}
+
+// logScores records the scores of the questions of the window of tag just
+// fetched in the scores log of tag, since fetching the window again replaces
+// its pages; analyze-question-sentiment drift compares the scores of
+// successive fetches.
+func logScores(ds *soanalysis.Dataset, tag string, fromDate time.Time, toDate time.Time) error {
+	items, err := ds.WindowItems(tag, fromDate, toDate)
+	if err != nil {
+		return err
+	}
+	return ds.LogScores(tag, time.Now().UTC(), items)
+}
+
+// run identifies this run of the program in the quota log.
+var run = time.Now().UTC().Format(time.RFC3339)
+
+// logQuota records the quota remaining after a request made while fetching tag
+// in the quota log of ds, for analyze-question-sentiment quota to report the
+// quota spent per tag, run and day.
+func logQuota(ds *soanalysis.Dataset, tag string, remaining int) error {
+	rec := soanalysis.QuotaRecord{Time: time.Now().UTC(), Run: run, Tag: tag, Remaining: remaining}
+	return ds.LogQuota(rec)
+}
+
+// fetchSiteCounts fetches the number of questions asked on the whole site
+// between fromDate and toDate, as well as in each month from fromDate on (the
+// buckets of analyze-question-sentiment -bymonth), into the data directory,
+// for reporting the share of each tag out of all the questions of the site.
+func fetchSiteCounts(ctx context.Context, fetcher *soapi.Fetcher, baseDir string, fromDate time.Time, toDate time.Time) error {
+	ds := &soanalysis.Dataset{Dir: baseDir}
+
+	periods := [][2]time.Time{{fromDate, toDate}}
+	for d := fromDate; d.Before(toDate); d = d.AddDate(0, 1, 0) {
+		periods = append(periods, [2]time.Time{d, d.AddDate(0, 1, 0)})
+	}
+
+	var counts []soanalysis.SiteCount
+	for _, period := range periods {
+		total, err := fetcher.CountQuestions(ctx, "", period[0], period[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s to %s: %d questions on the site\n", period[0].Format("2006-01-02"), period[1].Format("2006-01-02"), total)
+		counts = append(counts, soanalysis.SiteCount{FromDate: period[0], ToDate: period[1], Total: total})
+		if err := fetcher.Pause(ctx); err != nil {
+			return err
+		}
+	}
+	return ds.AddSiteCounts(counts)
+}
+
+// preflight counts the questions of each of tags asked between fromDate and
+// toDate, and estimates the disk space fetching them into ds takes, with their
+// bodies if bodies is set. It returns an error wrapping
+// soanalysis.ErrDiskFull if fewer than minFree bytes would be left available.
+// It runs before fetching with -estimate, and instead of fetching with -dryrun.
+func preflight(ctx context.Context, fetcher *soapi.Fetcher, ds *soanalysis.Dataset, tags []string, fromDate time.Time, toDate time.Time, bodies bool, minFree int64) error {
+	total := 0
+	for _, tag := range tags {
+		n, err := fetcher.CountQuestions(ctx, tag, fromDate, toDate)
+		if err != nil {
+			return fmt.Errorf("counting the questions of tag %q: %w", tag, err)
+		}
+		fmt.Printf("Tag '%s': %d questions to fetch\n", tag, n)
+		total += n
+		if err := fetcher.Pause(ctx); err != nil {
+			return err
+		}
+	}
+
+	need, err := ds.EstimateSize(total, bodies)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d questions to fetch, taking about %s on disk\n", total, soanalysis.FormatBytes(need))
+	free, err := ds.FreeSpace()
+	if errors.Is(err, errors.ErrUnsupported) {
+		log.Println("warning: can't tell the free disk space on this platform")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	fmt.Printf("%s available in '%s'\n", soanalysis.FormatBytes(free), ds.Dir)
+	if free-need < minFree {
+		return fmt.Errorf("%w: fetching takes about %s, and only %s is available in %q, keeping at least %s", soanalysis.ErrDiskFull,
+			soanalysis.FormatBytes(need), soanalysis.FormatBytes(free), ds.Dir, soanalysis.FormatBytes(minFree))
+	}
+	return nil
+}
+
+// fetchRelatedTags fetches up to n related tags of each of tags, as reported by
+// the API, and returns them by tag, for analyze-question-sentiment benchmark to
+// compare each tag with.
+func fetchRelatedTags(ctx context.Context, fetcher *soapi.Fetcher, tags []string, n int) (map[string][]string, error) {
+	related := make(map[string][]string)
+	for _, tag := range tags {
+		var err error
+		related[tag], err = fetcher.RelatedTags(ctx, tag, n)
+		if err != nil {
+			return nil, fmt.Errorf("fetching tags related to %q: %w", tag, err)
+		}
+		fmt.Printf("Tags related to '%s': %s\n", tag, strings.Join(related[tag], ", "))
+		if err := fetcher.Pause(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return related, nil
+}
+
+// loadValidators loads the validators cached at path, if any.
+func loadValidators(path string) (map[string]soapi.Validators, error) {
+	validators := make(map[string]soapi.Validators)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return validators, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &validators); err != nil {
+		return nil, fmt.Errorf("unmarshalling %q: %w", path, err)
+	}
+	return validators, nil
+}
+
+// streamResults writes the pages of the given tags to w, one per line, e.g. to
+// pipe them into analyze-question-sentiment -dir -. With validate, the pages
+// are checked against the schema of the replies of the API. As with
+// fetchResults, the errors of tags that fail to be fetched are returned
+// in a *cli.PartialError after fetching the other tags.
+func streamResults(ctx context.Context, fetcher *soapi.Fetcher, w io.Writer, tags []string, fromDate time.Time, toDate time.Time, validate bool) error {
+	var errs []error
+	for _, tag := range tags {
+		log.Printf("Fetching tag '%s'", tag)
+		err := fetcher.FetchTag(ctx, tag, fromDate, toDate, func(page int, body []byte, reply *soapi.Reply) error {
+			log.Printf("Fetched page %d, quota remaining: %d", page, reply.QuotaRemaining)
+			if validate {
+				warnInvalid(tag, page, body)
+			}
+			_, err := fmt.Fprintf(w, "%s\n", bytes.TrimSpace(body))
+			return err
+		})
+		if err != nil {
+			err = fmt.Errorf("fetching tag %q: %w", tag, err)
+			log.Println(err)
+			errs = append(errs, err)
+		}
+		if errors.Is(err, soapi.ErrCircuitOpen) || ctx.Err() != nil {
+			break
+		}
+	}
+	return cli.Partial(errs)
+}
+
+// warnInvalid logs the mismatches between a fetched page of tag and the schema
+// of the replies of the API; see soapi.ValidateReply.
+func warnInvalid(tag string, page int, body []byte) {
+	for _, e := range soapi.ValidateReply(body) {
+		log.Printf("warning: page %d of tag %q doesn't match the schema: %v", page, tag, e)
+	}
+}
+
+// newFetcher returns a copy of base fetching the questions with their bodies
+// (for the full-text index) and the details of their closing (such as the
+// questions duplicates were closed as duplicates of, and the dates questions
+// were protected and locked), if requested.
+func newFetcher(ctx context.Context, base *soapi.Fetcher, bodies bool, closedDetails bool) *soapi.Fetcher {
+	fetcher := *base
+	fetcher.Bodies = bodies
+	if closedDetails {
+		include := []string{"question.closed_details", "question.closed_reason", "question.protected_date", "question.locked_date"}
+		if bodies {
+			include = append(include, "question.body")
+		}
+		var err error
+		fetcher.Filter, err = fetcher.CreateFilter(ctx, include...)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	return &fetcher
+}
+
+// stopsFetch reports whether err, returned while fetching a tag (nil if the tag
+// was fetched), stops the fetching of the other tags as well.
+func stopsFetch(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	return errors.Is(err, soapi.ErrCircuitOpen) || soanalysis.IsDiskFull(err) || ctx.Err() != nil
+}
+
+// The exit statuses when fetching stops because the circuit breaker opened or
+// the disk is full, to tell them from tags failing to be fetched.
+const (
+	exitCircuitOpen = 3
+	exitDiskFull    = 4
+)
+
+// exitStatus returns the exit status of a fetch that returned err.
+func exitStatus(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, soapi.ErrCircuitOpen):
+		return exitCircuitOpen
+	case soanalysis.IsDiskFull(err):
+		return exitDiskFull
+	default:
+		return 1
+	}
+}
+
+// envOr returns the value of the environment variable name, or def if it's
+// empty.
+func envOr(name string, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+func mustParseTime(date string) time.Time {
+	if len(strings.TrimSpace(date)) == 0 {
+		log.Fatal("empty time string")
+	}
+
+	t, err := cli.ParseDate(date, time.UTC)
+	cli.Exit(err)
+	return t
+}
+
+func main() {
+	dirFlag := flag.String("dir", "", "base directory to store results, or - to write them to stdout")
+	fromDate := flag.String("fromdate", "", "start date in 2006-01-02 format")
+	toDate := flag.String("todate", "", "end date in 2006-01-02 format")
+	tagsFlag := flag.String("tags", "", "tags separated by commas")
+	siteFlag := flag.String("site", soapi.DefaultSite, "Stack Exchange site to fetch from, e.g. serverfault or devops")
+	eraseFlag := flag.Bool("erase", false, "erase previous contents of fetched directories")
+	refreshFlag := flag.Bool("refresh", false, "update previously fetched directories, skipping pages that didn't change")
+	answersFlag := flag.Bool("answers", false, "also fetch the answers to all the questions of the tags in -dir")
+	relatedFlag := flag.Int("related", 0, "also fetch this many related tags of each tag, to benchmark the tag against")
+	bodiesFlag := flag.Bool("bodies", false, "also fetch the bodies of the questions, for the full-text index")
+	fullTextFlag := flag.Bool("fulltext", false, "build the full-text index of the questions of each tag after fetching it")
+	closedDetailsFlag := flag.Bool("closeddetails", false, "also fetch the details of closed questions, such as the questions duplicates were closed as duplicates of")
+	siteCountsFlag := flag.Bool("sitecounts", false, "also fetch the number of questions on the whole site in the period and each month of it; -tags may be omitted to only fetch these")
+	validateFlag := flag.Bool("validate", false, "warn about fetched pages that don't match the schema of the replies of the API")
+	timeoutFlag := flag.Duration("timeout", 0, "maximal duration of the whole run, e.g. 1h; 0 for no limit")
+	requestTimeoutFlag := flag.Duration("requesttimeout", time.Minute, "maximal duration of each request to the API; 0 for no limit")
+	userAgentFlag := flag.String("useragent", envOr("STACK_USER_AGENT", soapi.DefaultUserAgent), "User-Agent header of the requests to the API")
+	appIDFlag := flag.String("appid", os.Getenv("STACK_APP_ID"), "identifier of the application sent with the requests to the API, e.g. its name or a contact URL")
+	delayFlag := flag.Duration("delay", 300*time.Millisecond, "time to wait between consecutive requests to the API, not to get throttled")
+	retriesFlag := flag.Int("retries", 2, "number of times to retry requests to the API that fail with network or server errors")
+	breakerFlag := flag.Int("breaker", 5, "stop making requests after this many consecutive failed ones (after their retries); 0 to never stop")
+	cooldownFlag := flag.Duration("cooldown", 0, "with -breaker, wait this long after stopping and then try again, instead of exiting")
+	otelEndpointFlag := flag.String("otel-endpoint", "", "OTLP/HTTP collector to export traces and metrics to, e.g. http://localhost:4318")
+	logHeadersFlag := flag.Bool("logheaders", false, "log the headers of the requests to the API and of their responses, e.g. to debug throttling")
+	minFreeFlag := flag.Int64("minfree", 100<<20, "bytes of disk space to keep available in -dir, stopping the fetch before going below it; 0 not to check")
+	compressionFlag := flag.String("compression", soanalysis.CodecNames[0], "codec to compress the stored pages with: "+strings.Join(soanalysis.CodecNames, " or "))
+	compressionLevelFlag := flag.Int("compressionlevel", 0, "level of -compression, from 1 (fastest) up to 9 for gzip and 22 for zstd; 0 for the default level of the codec")
+	estimateFlag := flag.Bool("estimate", false, "before fetching, count the questions to fetch (a request per tag) and check the disk space they take against -minfree")
+	dryRunFlag := flag.Bool("dryrun", false, "only count the questions to fetch and estimate the disk space they take")
+
+	cli.Exit(cli.SetEnvDefaults(flag.CommandLine, ""))
+	flag.Parse()
+
+	fDate := mustParseTime(*fromDate)
+	tDate := mustParseTime(*toDate)
+	tags := cli.SplitList(*tagsFlag)
+
+	if len(*dirFlag) == 0 {
+		log.Fatal("-dir must be provided and cannot be empty")
+	}
+
+	if len(tags) == 0 && !*siteCountsFlag {
+		log.Fatal("provide at least one tag with -tags")
+	}
+
+	// Stop cleanly on Ctrl-C, or once -timeout passes.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if *timeoutFlag > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeoutFlag)
+		defer cancel()
+	}
+
+	flushTelemetry := func() {}
+	if *otelEndpointFlag != "" {
+		shutdown, err := telemetry.Setup(*otelEndpointFlag, "fetch-all-questions")
+		cli.Exit(cli.Wrapf(err, "setting up telemetry"))
+		flushTelemetry = func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := shutdown(ctx); err != nil {
+				log.Println("flushing telemetry:", err)
+			}
+		}
+	}
+
+	started := time.Now().UTC()
+	base := soapi.NewFetcher(os.Getenv("STACK_KEY"))
+	base.Site = *siteFlag
+	base.Delay = *delayFlag
+	base.Timeout = *requestTimeoutFlag
+	base.Retries = *retriesFlag
+	if *breakerFlag > 0 {
+		base.Breaker = &soapi.Breaker{Threshold: *breakerFlag, Cooldown: *cooldownFlag, OnOpen: func(err error) {
+			log.Printf("%d consecutive requests failed, the last with: %v", *breakerFlag, err)
+			if *cooldownFlag > 0 {
+				log.Printf("Waiting %v before trying again", *cooldownFlag)
+			}
+		}}
+	}
+	// Stack Exchange asks API consumers to identify themselves and the
+	// application on whose behalf they make requests.
+	base.UserAgent = *userAgentFlag
+	base.AppID = *appIDFlag
+	if *logHeadersFlag {
+		base.Logger = log.Default()
+	}
+	if *otelEndpointFlag != "" {
+		base.Client = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	}
+
+	if cli.IsStream(*dirFlag) {
+		if *answersFlag || *siteCountsFlag || *relatedFlag > 0 || *fullTextFlag || *dryRunFlag {
+			log.Fatal("-answers, -sitecounts, -related, -fulltext and -dryrun require a data directory, not -dir -")
+		}
+		err := streamResults(ctx, newFetcher(ctx, base, *bodiesFlag, *closedDetailsFlag), os.Stdout, tags, fDate, tDate, *validateFlag)
+		log.Printf("Made %v", base.Stats)
+		flushTelemetry()
+		os.Exit(exitStatus(err))
+		return
+	}
+
+	codec, err := soanalysis.NewCodec(*compressionFlag, *compressionLevelFlag)
+	cli.Exit(cli.Wrapf(err, "parsing -compression"))
+	// Try to create the directory; ignore error (if it already exists, etc.)
+	_ = os.Mkdir(*dirFlag, 0777)
+	ds := &soanalysis.Dataset{Dir: *dirFlag, Codec: codec}
+	site, err := ds.Site()
+	cli.Exit(err)
+	if site != *siteFlag {
+		if existing, _ := ds.Tags(); len(existing) > 0 {
+			log.Fatalf("%s was fetched from site %q; fetch %q into a directory of its own", *dirFlag, site, *siteFlag)
+		}
+	}
+	if *siteCountsFlag && !*dryRunFlag {
+		cli.Exit(cli.Wrapf(fetchSiteCounts(ctx, base, *dirFlag, fDate, tDate), "fetching site counts"))
+	}
+	if len(tags) > 0 {
+		var related map[string][]string
+		if *relatedFlag > 0 {
+			related, err = fetchRelatedTags(ctx, base, tags, *relatedFlag)
+			cli.Exit(err)
+			for _, tag := range tags {
+				for _, r := range related[tag] {
+					if !slices.Contains(tags, r) {
+						tags = append(tags, r)
+					}
+				}
+			}
+		}
+		fetcher := newFetcher(ctx, base, *bodiesFlag, *closedDetailsFlag)
+		if *minFreeFlag > 0 && !*estimateFlag && !*dryRunFlag {
+			err := ds.CheckFreeSpace(*minFreeFlag)
+			if err != nil {
+				log.Println(err)
+				flushTelemetry()
+				os.Exit(exitStatus(err))
+			}
+		}
+		if *estimateFlag || *dryRunFlag {
+			err := preflight(ctx, fetcher, ds, tags, fDate, tDate, *bodiesFlag, *minFreeFlag)
+			if *dryRunFlag || err != nil {
+				if err != nil {
+					log.Println(err)
+				}
+				flushTelemetry()
+				os.Exit(exitStatus(err))
+			}
+		}
+		opts := fetchOptions{
+			erase:    *eraseFlag,
+			refresh:  *refreshFlag,
+			answers:  *answersFlag,
+			fulltext: *fullTextFlag,
+			validate: *validateFlag,
+			minFree:  *minFreeFlag,
+		}
+		err = fetchResults(ctx, fetcher, ds, tags, fDate, tDate, opts)
+
+		for tag, r := range related {
+			cli.Exit(ds.WriteRelatedTags(tag, r))
+		}
+	}
+
+	// The statistics of the requests help tune -delay and diagnose slow
+	// fetches.
+	fmt.Println("")
+	fmt.Printf("Made %v\n", base.Stats)
+	fetchRun := soanalysis.FetchRun{Started: started, Finished: time.Now().UTC(), Site: *siteFlag, Stats: *base.Stats}
+	cli.Exit(ds.RecordFetch(fetchRun))
+	flushTelemetry()
+	// The errors were logged as the tags failed.
+	os.Exit(exitStatus(err))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soanalysis"
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// roundTripFunc is an http.RoundTripper answering requests with a function.
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestStopsFetch(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"fetched", context.Background(), nil, false},
+		{"fetched, then cancelled", cancelled, nil, false},
+		{"failed", context.Background(), errors.New("bad tag"), false},
+		{"failed on cancel", cancelled, context.Canceled, true},
+		{"circuit open", context.Background(), soapi.ErrCircuitOpen, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stopsFetch(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("stopsFetch(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFetchResultsCancelledAfterTag checks that a fetch cancelled just as a tag
+// is fetched checkpoints before the next tag, rather than fetching it.
+func TestFetchResultsCancelledAfterTag(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var requested []string
+	fetcher := soapi.NewFetcher("")
+	fetcher.Delay = 0
+	fetcher.Client = &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		tag := req.URL.Query().Get("tagged")
+		requested = append(requested, tag)
+		// The signal arrives as the last page of the first tag is fetched.
+		cancel()
+		body := `{"items":[{"question_id":1,"creation_date":1577836800,"score":1}],"has_more":false,"quota_max":300,"quota_remaining":299}`
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})}
+
+	ds := &soanalysis.Dataset{Dir: t.TempDir()}
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	err := fetchResults(ctx, fetcher, ds, []string{"go", "rust"}, from, to, fetchOptions{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+	if want := []string{"go"}; !slices.Equal(requested, want) {
+		t.Errorf("requested tags %v, want %v", requested, want)
+	}
+
+	c, err := ds.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c == nil {
+		t.Fatal("no checkpoint written")
+	}
+	if !slices.Equal(c.Done, []string{"go"}) || c.Tag != "rust" || c.Page != 1 {
+		t.Errorf("got checkpoint with done %v at page %d of %q, want done [go] at page 1 of \"rust\"", c.Done, c.Page, c.Tag)
+	}
+}
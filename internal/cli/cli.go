@@ -0,0 +1,155 @@
+// Package cli holds the conventions shared by the command-line programs of
+// this module: the format of dates and lists in flags, the meaning of special
+// data directories, the environment variables setting the defaults of flags and
+// the reporting of errors.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// DateLayout is the format of the dates given in flags, e.g. -fromdate.
+const DateLayout = "2006-01-02"
+
+// StreamDir is the data directory meaning standard input or output: the pages
+// fetched with -dir - are written to stdout, and analyzing with -dir - reads
+// them from stdin.
+const StreamDir = "-"
+
+// IsStream reports whether dir is StreamDir, i.e. pages are streamed rather
+// than stored in a data directory.
+func IsStream(dir string) bool {
+	return dir == StreamDir
+}
+
+// ParseDate parses date in DateLayout as the start of that day in loc. An
+// empty date is the zero time, which means an unbounded period.
+func ParseDate(date string, loc *time.Location) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation(DateLayout, date, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expecting %s format", date, DateLayout)
+	}
+	return t, nil
+}
+
+// SplitList splits a list of values separated by commas, e.g. -tags go,rust,
+// ignoring spaces around the values and empty values.
+func SplitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// EnvVar returns the name of the environment variable setting the default of
+// a flag: SOTRENDS_ followed by the name of the flag in upper case with dashes
+// replaced by underscores, e.g. SOTRENDS_DIR or SOTRENDS_TOLERANCE_NEGATIVE. If
+// command isn't empty, the command's name is added after SOTRENDS_, e.g.
+// SOTRENDS_SERVE_ADDR.
+func EnvVar(command string, flagName string) string {
+	name := "SOTRENDS_"
+	if command != "" {
+		name += strings.ToUpper(command) + "_"
+	}
+	return name + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// VisitEnv calls fn for each flag in fs whose default is set in the
+// environment, with the name and the value of the variable (see EnvVar): first
+// for the variable for all commands, and then for the one for command, if
+// command isn't empty, so the latter takes precedence when fn sets the default.
+func VisitEnv(fs *flag.FlagSet, command string, fn func(f *flag.Flag, name string, value string)) {
+	fs.VisitAll(func(f *flag.Flag) {
+		names := []string{EnvVar("", f.Name)}
+		if command != "" {
+			names = append(names, EnvVar(command, f.Name))
+		}
+		for _, name := range names {
+			if value, ok := os.LookupEnv(name); ok {
+				fn(f, name, value)
+			}
+		}
+	})
+}
+
+// SetEnvDefaults sets the defaults of the flags in fs from the environment, as
+// VisitEnv finds them, before parsing the command line, which overrides them.
+func SetEnvDefaults(fs *flag.FlagSet, command string) error {
+	var errs []error
+	VisitEnv(fs, command, func(f *flag.Flag, name string, value string) {
+		if err := fs.Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("$%s: -%s: %w", name, f.Name, err))
+			return
+		}
+		f.DefValue = value
+	})
+	return errors.Join(errs...)
+}
+
+// Wrapf returns err annotated with a message formatted from pattern and args,
+// or nil if err is nil.
+func Wrapf(err error, pattern string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%s: %w", fmt.Sprintf(pattern, args...), err)
+}
+
+// PartialError is returned by operations on several tags (or files) that go
+// on with the others when some of them fail, so that one bad tag doesn't lose
+// the results of all the others. Errs holds the errors of the failed ones.
+type PartialError struct {
+	Errs []error
+}
+
+// Partial returns a *PartialError holding errs, or nil if errs is empty.
+func Partial(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &PartialError{Errs: errs}
+}
+
+func (e *PartialError) Error() string {
+	msgs := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e *PartialError) Unwrap() []error {
+	return e.Errs
+}
+
+// Warn logs err, each of the errors of a *PartialError on a line of its own.
+func Warn(err error) {
+	var pe *PartialError
+	if errors.As(err, &pe) {
+		for _, err := range pe.Errs {
+			log.Println(err)
+		}
+	} else if err != nil {
+		log.Println(err)
+	}
+}
+
+// Exit logs err as Warn does and exits with status 1 if err is not nil.
+func Exit(err error) {
+	if err != nil {
+		Warn(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		date string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"", time.UTC, time.Time{}},
+		{"2020-01-31", time.UTC, time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{"2020-02-29", ny, time.Date(2020, 2, 29, 0, 0, 0, 0, ny)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.date, tt.loc)
+		if err != nil {
+			t.Errorf("ParseDate(%q) failed: %v", tt.date, err)
+		} else if !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+
+	for _, date := range []string{"2020-02-30", "2020/01/01", "01-01-2020", "2020-01-01T00:00:00Z", " "} {
+		if got, err := ParseDate(date, time.UTC); err == nil {
+			t.Errorf("ParseDate(%q) = %v, want an error", date, got)
+		}
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"", nil},
+		{"go", []string{"go"}},
+		{"go,rust", []string{"go", "rust"}},
+		{" go , rust ,", []string{"go", "rust"}},
+		{",,", nil},
+	}
+	for _, tt := range tests {
+		if got := SplitList(tt.s); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitList(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestIsStream(t *testing.T) {
+	for dir, want := range map[string]bool{"-": true, "": false, "data": false, "./-": false} {
+		if got := IsStream(dir); got != want {
+			t.Errorf("IsStream(%q) = %v, want %v", dir, got, want)
+		}
+	}
+}
+
+func TestWrapf(t *testing.T) {
+	if err := Wrapf(nil, "reading %q", "data"); err != nil {
+		t.Errorf("Wrapf(nil) = %v, want nil", err)
+	}
+
+	err := Wrapf(os.ErrNotExist, "reading tag %q", "go")
+	if want := `reading tag "go": file does not exist`; err.Error() != want {
+		t.Errorf("Wrapf() = %q, want %q", err, want)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Wrapf() = %v, doesn't wrap os.ErrNotExist", err)
+	}
+}
+
+func TestPartial(t *testing.T) {
+	if err := Partial(nil); err != nil {
+		t.Errorf("Partial(nil) = %v, want nil", err)
+	}
+
+	errGo := Wrapf(os.ErrNotExist, "analyzing tag %q", "go")
+	errRust := errors.New("analyzing tag \"rust\": bad page")
+	err := Partial([]error{errGo, errRust})
+	var pe *PartialError
+	if !errors.As(err, &pe) || len(pe.Errs) != 2 {
+		t.Fatalf("Partial() = %#v, want a *PartialError of 2 errors", err)
+	}
+	if want := errGo.Error() + "\n" + errRust.Error(); err.Error() != want {
+		t.Errorf("Partial().Error() = %q, want %q", err, want)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Partial() = %v, doesn't wrap os.ErrNotExist", err)
+	}
+}
+
+func TestSetEnvDefaults(t *testing.T) {
+	t.Setenv("SOTRENDS_DIR", "data")
+	t.Setenv("SOTRENDS_FETCH_DIR", "fetched")
+	t.Setenv("SOTRENDS_MIN_SCORE", "3")
+	t.Setenv("SOTRENDS_RELATED", "many")
+
+	fs := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	dir := fs.String("dir", "", "")
+	site := fs.String("site", "stackoverflow", "")
+	minScore := fs.Int("min-score", 0, "")
+	related := fs.Int("related", 0, "")
+	err := SetEnvDefaults(fs, "fetch")
+	if want := `$SOTRENDS_RELATED: -related: parse error`; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("SetEnvDefaults() = %v, want an error starting with %q", err, want)
+	}
+	if *dir != "fetched" || *site != "stackoverflow" || *minScore != 3 || *related != 0 {
+		t.Errorf("got -dir %q, -site %q, -min-score %d and -related %d, want fetched, stackoverflow, 3 and 0", *dir, *site, *minScore, *related)
+	}
+	if def := fs.Lookup("dir").DefValue; def != "fetched" {
+		t.Errorf("got default %q of -dir, want fetched", def)
+	}
+
+	if err := fs.Parse([]string{"-dir", "other"}); err != nil {
+		t.Fatal(err)
+	}
+	if *dir != "other" {
+		t.Errorf("got -dir %q after parsing, want other", *dir)
+	}
+}
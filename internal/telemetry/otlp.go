@@ -0,0 +1,429 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// This file exports spans and metrics with the JSON encoding of OTLP/HTTP
+// (https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding), which
+// every collector accepts, without depending on the generated protobuf types
+// of OTLP.
+
+// otlpClient posts OTLP/HTTP requests to a collector.
+type otlpClient struct {
+	endpoint *url.URL
+	client   *http.Client
+}
+
+func newExporter(endpoint *url.URL) *otlpClient {
+	return &otlpClient{endpoint: endpoint, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// post posts the JSON encoding of req to path (such as /v1/traces) under the
+// endpoint.
+func (c *otlpClient) post(ctx context.Context, path string, req any) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	u := c.endpoint.JoinPath(path)
+	hreq, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(hreq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("exporting to %s: %s: %s", u, resp.Status, body)
+	}
+	return nil
+}
+
+// ExportSpans implements sdktrace.SpanExporter.
+func (c *otlpClient) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	// Group the spans by resource and scope, as OTLP does.
+	type scopeKey struct {
+		res   *resource.Resource
+		scope instrumentation.Scope
+	}
+	var keys []scopeKey
+	byScope := make(map[scopeKey][]otlpSpan)
+	for _, s := range spans {
+		k := scopeKey{s.Resource(), s.InstrumentationScope()}
+		if _, ok := byScope[k]; !ok {
+			keys = append(keys, k)
+		}
+		byScope[k] = append(byScope[k], newOTLPSpan(s))
+	}
+
+	var req struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	for _, k := range keys {
+		req.ResourceSpans = append(req.ResourceSpans, otlpResourceSpans{
+			Resource:   otlpResource{Attributes: otlpAttributes(k.res.Attributes())},
+			ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: k.scope.Name, Version: k.scope.Version}, Spans: byScope[k]}},
+		})
+	}
+	return c.post(ctx, "v1/traces", req)
+}
+
+// Shutdown implements sdktrace.SpanExporter and sdkmetric.Exporter; there's
+// nothing to release.
+func (c *otlpClient) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// Temporality implements sdkmetric.Exporter.
+func (c *otlpClient) Temporality(kind sdkmetric.InstrumentKind) metricdata.Temporality {
+	return sdkmetric.DefaultTemporalitySelector(kind)
+}
+
+// Aggregation implements sdkmetric.Exporter.
+func (c *otlpClient) Aggregation(kind sdkmetric.InstrumentKind) sdkmetric.Aggregation {
+	return sdkmetric.DefaultAggregationSelector(kind)
+}
+
+// ForceFlush implements sdkmetric.Exporter; metrics aren't buffered.
+func (c *otlpClient) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+// Export implements sdkmetric.Exporter.
+func (c *otlpClient) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	var sms []otlpScopeMetrics
+	for _, sm := range rm.ScopeMetrics {
+		var ms []otlpMetric
+		for _, m := range sm.Metrics {
+			if om, ok := newOTLPMetric(m); ok {
+				ms = append(ms, om)
+			}
+		}
+		if len(ms) > 0 {
+			sms = append(sms, otlpScopeMetrics{Scope: otlpScope{Name: sm.Scope.Name, Version: sm.Scope.Version}, Metrics: ms})
+		}
+	}
+	if len(sms) == 0 {
+		return nil
+	}
+
+	var req struct {
+		ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+	}
+	req.ResourceMetrics = []otlpResourceMetrics{{
+		Resource:     otlpResource{Attributes: otlpAttributes(rm.Resource.Attributes())},
+		ScopeMetrics: sms,
+	}}
+	return c.post(ctx, "v1/metrics", req)
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+// otlpAnyValue is a value of an attribute; only one of its fields is set.
+// 64-bit integers are encoded as strings, as in the JSON mapping of protobuf.
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+func otlpAttributes(kvs []attribute.KeyValue) []otlpKeyValue {
+	var out []otlpKeyValue
+	for _, kv := range kvs {
+		out = append(out, otlpKeyValue{Key: string(kv.Key), Value: otlpValue(kv.Value)})
+	}
+	return out
+}
+
+func otlpValue(v attribute.Value) otlpAnyValue {
+	var av otlpAnyValue
+	switch v.Type() {
+	case attribute.BOOL:
+		b := v.AsBool()
+		av.BoolValue = &b
+	case attribute.INT64:
+		i := strconv.FormatInt(v.AsInt64(), 10)
+		av.IntValue = &i
+	case attribute.FLOAT64:
+		f := v.AsFloat64()
+		av.DoubleValue = &f
+	case attribute.BOOLSLICE, attribute.INT64SLICE, attribute.FLOAT64SLICE, attribute.STRINGSLICE:
+		av.ArrayValue = &otlpArrayValue{}
+		switch v.Type() {
+		case attribute.BOOLSLICE:
+			for _, b := range v.AsBoolSlice() {
+				av.ArrayValue.Values = append(av.ArrayValue.Values, otlpValue(attribute.BoolValue(b)))
+			}
+		case attribute.INT64SLICE:
+			for _, i := range v.AsInt64Slice() {
+				av.ArrayValue.Values = append(av.ArrayValue.Values, otlpValue(attribute.Int64Value(i)))
+			}
+		case attribute.FLOAT64SLICE:
+			for _, f := range v.AsFloat64Slice() {
+				av.ArrayValue.Values = append(av.ArrayValue.Values, otlpValue(attribute.Float64Value(f)))
+			}
+		case attribute.STRINGSLICE:
+			for _, s := range v.AsStringSlice() {
+				av.ArrayValue.Values = append(av.ArrayValue.Values, otlpValue(attribute.StringValue(s)))
+			}
+		}
+	default:
+		s := v.Emit()
+		av.StringValue = &s
+	}
+	return av
+}
+
+// otlpTime encodes t in nanoseconds since the epoch.
+func otlpTime(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Events            []otlpEvent    `json:"events,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpEvent struct {
+	TimeUnixNano string         `json:"timeUnixNano"`
+	Name         string         `json:"name"`
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpStatus struct {
+	Message string `json:"message,omitempty"`
+	Code    int    `json:"code"`
+}
+
+// The status codes of OTLP, which differ from the ones of codes.
+const (
+	otlpStatusUnset = 0
+	otlpStatusOk    = 1
+	otlpStatusError = 2
+)
+
+func newOTLPSpan(s sdktrace.ReadOnlySpan) otlpSpan {
+	span := otlpSpan{
+		TraceID:           s.SpanContext().TraceID().String(),
+		SpanID:            s.SpanContext().SpanID().String(),
+		Name:              s.Name(),
+		Kind:              int(s.SpanKind()), // the same values as in OTLP
+		StartTimeUnixNano: otlpTime(s.StartTime()),
+		EndTimeUnixNano:   otlpTime(s.EndTime()),
+		Attributes:        otlpAttributes(s.Attributes()),
+		Status:            otlpStatus{Message: s.Status().Description, Code: otlpStatusUnset},
+	}
+	if s.Parent().HasSpanID() {
+		span.ParentSpanID = s.Parent().SpanID().String()
+	}
+	switch s.Status().Code {
+	case codes.Ok:
+		span.Status.Code = otlpStatusOk
+	case codes.Error:
+		span.Status.Code = otlpStatusError
+	}
+	for _, e := range s.Events() {
+		span.Events = append(span.Events, otlpEvent{TimeUnixNano: otlpTime(e.Time), Name: e.Name, Attributes: otlpAttributes(e.Attributes)})
+	}
+	return span
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+// otlpMetric is a metric; only one of Sum, Gauge and Histogram is set.
+type otlpMetric struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Unit        string         `json:"unit,omitempty"`
+	Sum         *otlpSum       `json:"sum,omitempty"`
+	Gauge       *otlpGauge     `json:"gauge,omitempty"`
+	Histogram   *otlpHistogram `json:"histogram,omitempty"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                      `json:"aggregationTemporality"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	AsInt             *string        `json:"asInt,omitempty"`
+	AsDouble          *float64       `json:"asDouble,omitempty"`
+}
+
+type otlpHistogramDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	TimeUnixNano      string         `json:"timeUnixNano"`
+	Count             string         `json:"count"`
+	Sum               *float64       `json:"sum,omitempty"`
+	BucketCounts      []string       `json:"bucketCounts"`
+	ExplicitBounds    []float64      `json:"explicitBounds"`
+	Min               *float64       `json:"min,omitempty"`
+	Max               *float64       `json:"max,omitempty"`
+}
+
+// otlpTemporality returns the OTLP aggregation temporality of t.
+func otlpTemporality(t metricdata.Temporality) int {
+	switch t {
+	case metricdata.DeltaTemporality:
+		return 1
+	case metricdata.CumulativeTemporality:
+		return 2
+	}
+	return 0
+}
+
+// newOTLPMetric converts m, reporting false for the kinds of data the
+// instruments of the module don't produce.
+func newOTLPMetric(m metricdata.Metrics) (otlpMetric, bool) {
+	om := otlpMetric{Name: m.Name, Description: m.Description, Unit: m.Unit}
+	switch data := m.Data.(type) {
+	case metricdata.Sum[int64]:
+		om.Sum = &otlpSum{DataPoints: numberDataPoints(data.DataPoints), AggregationTemporality: otlpTemporality(data.Temporality), IsMonotonic: data.IsMonotonic}
+	case metricdata.Sum[float64]:
+		om.Sum = &otlpSum{DataPoints: numberDataPoints(data.DataPoints), AggregationTemporality: otlpTemporality(data.Temporality), IsMonotonic: data.IsMonotonic}
+	case metricdata.Gauge[int64]:
+		om.Gauge = &otlpGauge{DataPoints: numberDataPoints(data.DataPoints)}
+	case metricdata.Gauge[float64]:
+		om.Gauge = &otlpGauge{DataPoints: numberDataPoints(data.DataPoints)}
+	case metricdata.Histogram[int64]:
+		om.Histogram = &otlpHistogram{DataPoints: histogramDataPoints(data.DataPoints), AggregationTemporality: otlpTemporality(data.Temporality)}
+	case metricdata.Histogram[float64]:
+		om.Histogram = &otlpHistogram{DataPoints: histogramDataPoints(data.DataPoints), AggregationTemporality: otlpTemporality(data.Temporality)}
+	default:
+		return om, false
+	}
+	return om, true
+}
+
+func numberDataPoints[N int64 | float64](dps []metricdata.DataPoint[N]) []otlpNumberDataPoint {
+	var out []otlpNumberDataPoint
+	for _, dp := range dps {
+		odp := otlpNumberDataPoint{
+			Attributes:        otlpAttributes(dp.Attributes.ToSlice()),
+			StartTimeUnixNano: otlpTime(dp.StartTime),
+			TimeUnixNano:      otlpTime(dp.Time),
+		}
+		switch v := any(dp.Value).(type) {
+		case int64:
+			i := strconv.FormatInt(v, 10)
+			odp.AsInt = &i
+		case float64:
+			odp.AsDouble = &v
+		}
+		out = append(out, odp)
+	}
+	return out
+}
+
+func histogramDataPoints[N int64 | float64](dps []metricdata.HistogramDataPoint[N]) []otlpHistogramDataPoint {
+	var out []otlpHistogramDataPoint
+	for _, dp := range dps {
+		sum := float64(dp.Sum)
+		odp := otlpHistogramDataPoint{
+			Attributes:        otlpAttributes(dp.Attributes.ToSlice()),
+			StartTimeUnixNano: otlpTime(dp.StartTime),
+			TimeUnixNano:      otlpTime(dp.Time),
+			Count:             strconv.FormatUint(dp.Count, 10),
+			Sum:               &sum,
+			ExplicitBounds:    dp.Bounds,
+		}
+		for _, c := range dp.BucketCounts {
+			odp.BucketCounts = append(odp.BucketCounts, strconv.FormatUint(c, 10))
+		}
+		if v, ok := dp.Min.Value(); ok {
+			f := float64(v)
+			odp.Min = &f
+		}
+		if v, ok := dp.Max.Value(); ok {
+			f := float64(v)
+			odp.Max = &f
+		}
+		out = append(out, odp)
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+// Package telemetry exports the OpenTelemetry traces and metrics of the
+// programs to a collector, for running them inside observable infrastructure.
+//
+// The packages of the module record their spans and metrics with the global
+// providers of go.opentelemetry.io/otel, which do nothing until Setup installs
+// providers exporting them.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// metricInterval is how often metrics are exported.
+const metricInterval = 15 * time.Second
+
+// Setup installs global tracer and meter providers exporting to the OTLP/HTTP
+// collector at endpoint (e.g. http://localhost:4318) as the given service, and
+// the W3C trace context propagator, so that traces continue across the
+// requests of clients. It returns a function flushing the remaining spans and
+// metrics, to call before exiting.
+func Setup(endpoint string, service string) (func(context.Context) error, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing OTLP endpoint %q: %w", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("OTLP endpoint %q must be an http or https URL", endpoint)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", service)))
+	if err != nil {
+		return nil, err
+	}
+
+	exp := newExporter(u)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exp, sdkmetric.WithInterval(metricInterval))),
+		sdkmetric.WithResource(res))
+	otel.SetTracerProvider(tp)
+	otel.SetMeterProvider(mp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		return errors.Join(tp.Shutdown(ctx), mp.Shutdown(ctx))
+	}, nil
+}
@@ -0,0 +1,127 @@
+package soanalysis
+
+import (
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Aggregator accumulates the questions of a tag into a Series, one question at
+// a time; it's useful for analyzing questions as they are fetched, without
+// storing them in a Dataset first.
+type Aggregator struct {
+	series Series
+
+	// starts[i] is the start of the period of series.Buckets[i]
+	starts []time.Time
+
+	// Robust is set on the results of Series.
+	Robust Robust
+
+	// Sentiments, if not nil, are the external sentiment scores of the
+	// questions, added to the buckets along with them.
+	Sentiments Sentiments
+}
+
+// NewAggregator creates a new Aggregator of questions with the given tag into a
+// series with the same buckets as Analyzer.Series.
+func NewAggregator(tag string, fromDate time.Time, toDate time.Time, byMonth bool) (*Aggregator, error) {
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return nil, err
+	}
+	return newAggregator(tag, starts, ends), nil
+}
+
+// NewWindowAggregator creates a new Aggregator of questions with the given tag
+// into a series with the same buckets as Analyzer.WindowSeries.
+func NewWindowAggregator(tag string, fromDate time.Time, toDate time.Time, size time.Duration, step time.Duration) (*Aggregator, error) {
+	starts, ends, err := windows(fromDate, toDate, size, step)
+	if err != nil {
+		return nil, err
+	}
+	return newAggregator(tag, starts, ends), nil
+}
+
+func newAggregator(tag string, starts []time.Time, ends []time.Time) *Aggregator {
+	ag := &Aggregator{series: Series{Tag: tag}, starts: starts}
+	for i, end := range ends {
+		ag.series.Buckets = append(ag.series.Buckets, Bucket{Start: starts[i], Date: end})
+	}
+	return ag
+}
+
+// periods returns the starts and ends of the periods of the buckets of a
+// series between fromDate and toDate: a single period without byMonth, and a
+// period per month starting at fromDate with it.
+func periods(fromDate time.Time, toDate time.Time, byMonth bool) ([]time.Time, []time.Time, error) {
+	if !byMonth {
+		return []time.Time{fromDate}, []time.Time{toDate}, nil
+	}
+
+	if fromDate.IsZero() || toDate.IsZero() {
+		return nil, nil, ErrMonthlyDates
+	}
+	var starts, ends []time.Time
+	for d := fromDate; d.Before(toDate); {
+		endDate := d.AddDate(0, 1, 0) // add a month
+		starts = append(starts, d)
+		ends = append(ends, endDate)
+		d = endDate
+	}
+	return starts, ends, nil
+}
+
+// windows returns the starts and ends of sliding windows of the given size
+// between fromDate and toDate, a step apart: the first starts at fromDate, and
+// the last is the last one ending by toDate (or, if size is larger than the
+// whole period, a single window of the period).
+func windows(fromDate time.Time, toDate time.Time, size time.Duration, step time.Duration) ([]time.Time, []time.Time, error) {
+	if fromDate.IsZero() || toDate.IsZero() {
+		return nil, nil, ErrWindowDates
+	}
+	if size <= 0 || step <= 0 {
+		return nil, nil, ErrWindowSize
+	}
+	var starts, ends []time.Time
+	for d := fromDate; !d.Add(size).After(toDate); d = d.Add(step) {
+		starts = append(starts, d)
+		ends = append(ends, d.Add(size))
+	}
+	if len(starts) == 0 {
+		return []time.Time{fromDate}, []time.Time{toDate}, nil
+	}
+	return starts, ends, nil
+}
+
+// inPeriod reports whether date is in the period between start and end
+// (inclusive of both); a zero start or end leaves the period open.
+func inPeriod(date time.Time, start time.Time, end time.Time) bool {
+	return (start.IsZero() || !date.Before(start)) && (end.IsZero() || !date.After(end))
+}
+
+// Add adds a question to the buckets whose periods include it (inclusive of
+// both ends); questions outside of all periods are ignored.
+func (ag *Aggregator) Add(item *soapi.Item) {
+	itemDate := time.Unix(int64(item.CreationDate), 0)
+	for i := range ag.series.Buckets {
+		b := &ag.series.Buckets[i]
+		if inPeriod(itemDate, ag.starts[i], b.Date) {
+			b.Result.addItem(item, ag.Sentiments)
+		}
+	}
+}
+
+// Series returns the series of the questions added so far. Without byMonth, if
+// toDate was zero the date of its bucket is the date of the latest question.
+func (ag *Aggregator) Series() Series {
+	series := Series{Tag: ag.series.Tag, Buckets: append([]Bucket(nil), ag.series.Buckets...)}
+	for i := range series.Buckets {
+		series.Buckets[i].Result.Robust = ag.Robust
+	}
+	if len(ag.starts) == 1 && series.Buckets[0].Date.IsZero() {
+		// if not explicit date, consider the max encountered date
+		series.Buckets[0].Date = series.Buckets[0].Result.MaxDate
+	}
+	return series
+}
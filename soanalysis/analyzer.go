@@ -0,0 +1,453 @@
+package soanalysis
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Result holds the metrics of a set of questions.
+type Result struct {
+	Total             int
+	Negative          int
+	Closed            int
+	ClosedAndNegative int
+	Unanswered        int
+
+	// Views is the total number of views of the questions.
+	Views int
+
+	// Scores and ViewCounts are the scores and view counts of the questions,
+	// for their means.
+	Scores     []int
+	ViewCounts []int
+
+	// Sentiments are the external sentiment scores of the questions that
+	// have one (see Sentiments), added with AddSentiment.
+	Sentiments []float64
+
+	// Robust selects how the extreme scores and view counts are handled by
+	// the means and NegativePer10kViews.
+	Robust Robust
+
+	// Licenses maps content licenses (such as "CC BY-SA 4.0") to the number of
+	// questions under them.
+	Licenses map[string]int
+
+	// OwnerTypes maps the types of the owners of the questions (see
+	// soapi.Item: "registered", "unregistered", "does_not_exist" for deleted
+	// accounts, etc.) to the counts of their questions.
+	OwnerTypes map[string]OwnerTypeCounts
+
+	// min and max dates of actual items
+	MinDate time.Time
+	MaxDate time.Time
+}
+
+// OwnerTypeCounts holds the counts of the questions of a type of owners.
+type OwnerTypeCounts struct {
+	Total    int
+	Negative int
+	Closed   int
+}
+
+// ratio returns n/total, or 0 if total is 0 (an empty period), since NaN can't
+// be encoded to JSON.
+func ratio(n int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// NegativeRatio returns the ratio of questions with a negative score.
+func (r Result) NegativeRatio() float64 {
+	return ratio(r.Negative, r.Total)
+}
+
+// ClosedRatio returns the ratio of closed questions.
+func (r Result) ClosedRatio() float64 {
+	return ratio(r.Closed, r.Total)
+}
+
+// ClosedAndNegativeRatio returns the ratio of closed questions with a negative
+// score.
+func (r Result) ClosedAndNegativeRatio() float64 {
+	return ratio(r.ClosedAndNegative, r.Total)
+}
+
+// UnansweredRatio returns the ratio of questions without an accepted or
+// upvoted answer.
+func (r Result) UnansweredRatio() float64 {
+	return ratio(r.Unanswered, r.Total)
+}
+
+// NegativePer10kViews returns the number of questions with a negative score
+// per 10,000 views of the questions. Unlike NegativeRatio, it accounts for the
+// amount of traffic (and thus of votes) questions get, which varies between
+// tags. With Robust set, the views are estimated from the robust mean of the
+// view counts instead.
+func (r Result) NegativePer10kViews() float64 {
+	if r.Robust == (Robust{}) {
+		return 10000 * ratio(r.Negative, r.Views)
+	}
+	views := r.MeanViews() * float64(r.Total)
+	if views == 0 {
+		return 0
+	}
+	return 10000 * float64(r.Negative) / views
+}
+
+// MeanScore returns the mean score of the questions, trimmed and winsorized as
+// selected by Robust.
+func (r Result) MeanScore() float64 {
+	return r.Robust.Mean(r.Scores)
+}
+
+// MeanViews returns the mean number of views of the questions, trimmed and
+// winsorized as selected by Robust.
+func (r Result) MeanViews() float64 {
+	return r.Robust.Mean(r.ViewCounts)
+}
+
+// MeanSentiment returns the mean of the external sentiment scores of the
+// questions that have one, trimmed and winsorized as selected by Robust, or 0
+// if none has.
+func (r Result) MeanSentiment() float64 {
+	return r.Robust.MeanFloat(r.Sentiments)
+}
+
+// SentimentCoverage returns the ratio of the questions that have an external
+// sentiment score.
+func (r Result) SentimentCoverage() float64 {
+	return ratio(len(r.Sentiments), r.Total)
+}
+
+// AddSentiment adds the external sentiment score of a question added with Add.
+func (r *Result) AddSentiment(score float64) {
+	r.Sentiments = append(r.Sentiments, score)
+}
+
+// addItem adds a question to r along with its score in sentiments, if it has
+// one.
+func (r *Result) addItem(item *soapi.Item, sentiments Sentiments) {
+	r.Add(item)
+	if score, ok := sentiments[item.QuestionID]; ok {
+		r.AddSentiment(score)
+	}
+}
+
+// Add adds a question to the result.
+func (r *Result) Add(item *soapi.Item) {
+	itemDate := time.Unix(int64(item.CreationDate), 0)
+
+	r.Total++
+	r.Views += item.ViewCount
+	r.Scores = append(r.Scores, item.Score)
+	r.ViewCounts = append(r.ViewCounts, item.ViewCount)
+
+	if item.Score < 0 {
+		r.Negative++
+	}
+
+	if item.ClosedDate > 0 {
+		r.Closed++
+
+		if item.Score < 0 {
+			r.ClosedAndNegative++
+		}
+	}
+
+	if !item.IsAnswered {
+		r.Unanswered++
+	}
+
+	if r.Licenses == nil {
+		r.Licenses = make(map[string]int)
+	}
+	r.Licenses[item.ContentLicense]++
+
+	if r.OwnerTypes == nil {
+		r.OwnerTypes = make(map[string]OwnerTypeCounts)
+	}
+	oc := r.OwnerTypes[item.Owner.UserType]
+	oc.Total++
+	if item.Score < 0 {
+		oc.Negative++
+	}
+	if item.ClosedDate > 0 {
+		oc.Closed++
+	}
+	r.OwnerTypes[item.Owner.UserType] = oc
+
+	if r.MinDate.IsZero() || itemDate.Before(r.MinDate) {
+		r.MinDate = itemDate
+	}
+	if r.MaxDate.IsZero() || itemDate.After(r.MaxDate) {
+		r.MaxDate = itemDate
+	}
+}
+
+// Merge adds the counts of other into r, and widens its date range to include
+// the dates of other.
+func (r *Result) Merge(other Result) {
+	r.Total += other.Total
+	r.Negative += other.Negative
+	r.Closed += other.Closed
+	r.ClosedAndNegative += other.ClosedAndNegative
+	r.Unanswered += other.Unanswered
+	r.Views += other.Views
+	r.Scores = append(r.Scores, other.Scores...)
+	r.ViewCounts = append(r.ViewCounts, other.ViewCounts...)
+	r.Sentiments = append(r.Sentiments, other.Sentiments...)
+	for license, n := range other.Licenses {
+		if r.Licenses == nil {
+			r.Licenses = make(map[string]int)
+		}
+		r.Licenses[license] += n
+	}
+	for userType, other := range other.OwnerTypes {
+		if r.OwnerTypes == nil {
+			r.OwnerTypes = make(map[string]OwnerTypeCounts)
+		}
+		oc := r.OwnerTypes[userType]
+		oc.Total += other.Total
+		oc.Negative += other.Negative
+		oc.Closed += other.Closed
+		r.OwnerTypes[userType] = oc
+	}
+	if r.MinDate.IsZero() || (!other.MinDate.IsZero() && other.MinDate.Before(r.MinDate)) {
+		r.MinDate = other.MinDate
+	}
+	if other.MaxDate.After(r.MaxDate) {
+		r.MaxDate = other.MaxDate
+	}
+}
+
+// Bucket is the result for a single period of a tag; Date is the end of the
+// period, and Start its start (zero if the period has no explicit start).
+type Bucket struct {
+	Start  time.Time
+	Date   time.Time
+	Result Result
+
+	// SiteTotal is the number of questions asked on the whole site in the
+	// period, if known (see Dataset.SiteCounts), and 0 otherwise.
+	SiteTotal int
+}
+
+// SiteShare returns the share of the questions of the bucket out of all the
+// questions asked on the site in its period, or 0 if SiteTotal isn't known.
+func (b Bucket) SiteShare() float64 {
+	return ratio(b.Result.Total, b.SiteTotal)
+}
+
+// QuestionsPerDay returns the average number of questions asked per day in the
+// period of the bucket. Without an explicit start, the period starts at the
+// first question.
+func (b Bucket) QuestionsPerDay() float64 {
+	start := b.Start
+	if start.IsZero() {
+		start = b.Result.MinDate
+	}
+	days := b.Date.Sub(start).Hours() / 24
+	return float64(b.Result.Total) / max(days, 1)
+}
+
+// Series is the analysis of a single tag: one bucket for a whole period, or
+// one per month.
+type Series struct {
+	Tag     string
+	Buckets []Bucket
+}
+
+// QuestionsPerDay returns the questions per day of each bucket of the series,
+// smoothed with a trailing moving average over window buckets (over fewer for
+// the first buckets); a window of 1 doesn't smooth.
+func (s Series) QuestionsPerDay(window int) []float64 {
+	window = max(window, 1)
+	perDay := make([]float64, len(s.Buckets))
+	sum := 0.0
+	for i, b := range s.Buckets {
+		sum += b.QuestionsPerDay()
+		if i >= window {
+			sum -= s.Buckets[i-window].QuestionsPerDay()
+		}
+		perDay[i] = sum / float64(min(i+1, window))
+	}
+	return perDay
+}
+
+// Cumulative returns the series with each bucket holding the questions of its
+// period and of the periods of all the buckets before it, i.e. the questions
+// from the start of the first bucket to the end of each, e.g. for the total
+// number of questions or closures to date. The site totals are summed as well,
+// as long as they are known for all the buckets so far.
+func (s Series) Cumulative() Series {
+	cs := Series{Tag: s.Tag}
+	var r Result
+	siteTotal := 0
+	for i, b := range s.Buckets {
+		if i == 0 {
+			r.Robust = b.Result.Robust
+		}
+		r.Merge(b.Result)
+		if b.SiteTotal == 0 {
+			siteTotal = -1
+		} else if siteTotal >= 0 {
+			siteTotal += b.SiteTotal
+		}
+		cb := Bucket{Start: s.Buckets[0].Start, Date: b.Date, Result: r, SiteTotal: max(siteTotal, 0)}
+		// copy the slices and maps of r, which the next buckets add to
+		cb.Result.Scores = slices.Clone(r.Scores)
+		cb.Result.ViewCounts = slices.Clone(r.ViewCounts)
+		cb.Result.Sentiments = slices.Clone(r.Sentiments)
+		cb.Result.Licenses = maps.Clone(r.Licenses)
+		cb.Result.OwnerTypes = maps.Clone(r.OwnerTypes)
+		cs.Buckets = append(cs.Buckets, cb)
+	}
+	return cs
+}
+
+// ErrMonthlyDates is returned by Series when asked for a monthly series without
+// both dates.
+var ErrMonthlyDates = errors.New("analysis by month requires from and to dates")
+
+// ErrWindowDates is returned by WindowSeries when asked for a series without
+// both dates, and ErrWindowSize when the size or step of the windows isn't
+// positive.
+var (
+	ErrWindowDates = errors.New("analysis by sliding windows requires from and to dates")
+	ErrWindowSize  = errors.New("sliding windows require a positive size and step")
+)
+
+// Analyzer analyzes the questions of a Dataset.
+type Analyzer struct {
+	Dataset *Dataset
+
+	// Filter, if not nil, selects the questions to analyze: questions for which
+	// it returns false are ignored.
+	Filter func(item *soapi.Item) bool
+
+	// Robust is set on the results of Analyze and Series.
+	Robust Robust
+
+	// Sentiments, if not nil, are the external sentiment scores of the
+	// questions, added to the results along with them.
+	Sentiments Sentiments
+}
+
+// NewAnalyzer creates a new Analyzer of the data directory dir.
+func NewAnalyzer(dir string) *Analyzer {
+	return &Analyzer{Dataset: &Dataset{Dir: dir}}
+}
+
+// ForEachItem is like Dataset.ForEachItem, but only calls fn for the questions
+// selected by the Filter of the analyzer.
+func (a *Analyzer) ForEachItem(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, fn func(item *soapi.Item)) error {
+	ctx, span := tracer.Start(ctx, "soanalysis.analyze", trace.WithAttributes(
+		attribute.String("tag", tag),
+		attribute.String("fromdate", fromDate.Format(time.DateOnly)),
+		attribute.String("todate", toDate.Format(time.DateOnly))))
+	defer span.End()
+
+	n := 0
+	err := a.Dataset.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		if a.Filter == nil || a.Filter(item) {
+			n++
+			fn(item)
+		}
+	})
+	span.SetAttributes(attribute.Int("questions", n))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// Analyze analyzes the questions with the given tag. If fromDate and toDate are
+// non-zero, then only questions between fromDate and toDate (inclusive) are
+// considered.
+func (a *Analyzer) Analyze(ctx context.Context, tag string, fromDate time.Time, toDate time.Time) (Result, error) {
+	r := Result{Robust: a.Robust}
+	err := a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		r.addItem(item, a.Sentiments)
+	})
+	return r, err
+}
+
+// Series analyzes the questions with the given tag between fromDate and toDate.
+// Without byMonth, the series has a single bucket dated toDate (or the date of
+// the latest question if toDate is zero). With byMonth, it has a bucket per
+// month starting at fromDate, and both dates have to be non-zero. The site
+// totals of the buckets are set from the site counts of the dataset fetched for
+// the same periods.
+func (a *Analyzer) Series(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool) (Series, error) {
+	ag, err := NewAggregator(tag, fromDate, toDate, byMonth)
+	if err != nil {
+		return Series{Tag: tag}, err
+	}
+	return a.aggregate(ctx, tag, fromDate, toDate, ag)
+}
+
+// WindowSeries is like Series, but with a bucket per sliding window of the
+// given size from fromDate, a step apart; windows overlapping each other (with
+// a step smaller than the size) make for smoother series than disjoint months.
+// Buckets are dated by the ends of their windows. Both dates have to be
+// non-zero.
+func (a *Analyzer) WindowSeries(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, size time.Duration, step time.Duration) (Series, error) {
+	ag, err := NewWindowAggregator(tag, fromDate, toDate, size, step)
+	if err != nil {
+		return Series{Tag: tag}, err
+	}
+	return a.aggregate(ctx, tag, fromDate, toDate, ag)
+}
+
+// aggregate adds the questions with the given tag between fromDate and toDate
+// to ag, and returns its series with the site totals of its buckets.
+func (a *Analyzer) aggregate(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, ag *Aggregator) (Series, error) {
+	ag.Robust = a.Robust
+	ag.Sentiments = a.Sentiments
+	if err := a.ForEachItem(ctx, tag, fromDate, toDate, ag.Add); err != nil {
+		return Series{Tag: tag}, err
+	}
+	counts, err := a.Dataset.SiteCounts()
+	if err != nil {
+		return Series{Tag: tag}, err
+	}
+	ts := ag.Series()
+	for i := range ts.Buckets {
+		ts.Buckets[i].SiteTotal = siteTotal(counts, ts.Buckets[i].Start, ts.Buckets[i].Date)
+	}
+	return ts, nil
+}
+
+// MostNegative returns up to n of the lowest scored questions with a negative
+// score for the given tag and period, lowest first.
+func (a *Analyzer) MostNegative(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, n int) ([]soapi.Item, error) {
+	var items []soapi.Item
+	err := a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		if item.Score < 0 {
+			items = append(items, *item)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Score < items[j].Score
+	})
+	if len(items) > n {
+		items = items[:n]
+	}
+	return items, nil
+}
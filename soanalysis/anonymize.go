@@ -0,0 +1,28 @@
+package soanalysis
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"strconv"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Anonymize removes the personal data of the owner of item: the display name,
+// profile image and link are cleared, and the user ID is replaced by a
+// pseudonym derived from it with key (an HMAC), so that the questions of the
+// same user can still be told apart without revealing who they are. The same
+// key gives the same pseudonyms; keep it secret, since anyone with the key can
+// match pseudonyms to known user IDs. None of this affects the analyses.
+func Anonymize(item *soapi.Item, key []byte) {
+	item.Owner.DisplayName = ""
+	item.Owner.ProfileImage = ""
+	item.Owner.Link = ""
+	if item.Owner.UserID != 0 {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(strconv.Itoa(item.Owner.UserID)))
+		// A positive int32, to remain a plausible user ID.
+		item.Owner.UserID = int(binary.BigEndian.Uint32(mac.Sum(nil)) >> 1)
+	}
+}
@@ -0,0 +1,75 @@
+package soanalysis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// AnswersDir returns the directory holding the pages of answers to the
+// questions with the given tag, as returned by the API; the names of the pages
+// don't matter, as long as they end with .json.
+func (ds *Dataset) AnswersDir(tag string) string {
+	return filepath.Join(ds.TagDir(tag), "answers")
+}
+
+// HasAnswers reports whether the answers to the questions with the given tag
+// were fetched.
+func (ds *Dataset) HasAnswers(tag string) bool {
+	info, err := os.Stat(ds.AnswersDir(tag))
+	return err == nil && info.IsDir()
+}
+
+// AnswersFetched returns the time the answers to the questions with the given
+// tag were fetched (the modification time of their directory): questions
+// without answers are known to have had none by then.
+func (ds *Dataset) AnswersFetched(tag string) (time.Time, error) {
+	info, err := os.Stat(ds.AnswersDir(tag))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// ForEachAnswer calls fn for each answer to the questions with the given tag in
+// the dataset. If the answers weren't fetched, it returns an error wrapping
+// os.ErrNotExist.
+func (ds *Dataset) ForEachAnswer(tag string, fn func(answer *soapi.Answer)) error {
+	dirName := ds.AnswersDir(tag)
+	entries, err := os.ReadDir(dirName)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no answers to the questions of %q: %w", tag, err)
+	} else if err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool)
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dirName, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var reply soapi.AnswerReply
+		if err := json.Unmarshal(data, &reply); err != nil {
+			return fmt.Errorf("unmarshalling %q: %w", path, err)
+		}
+		for i := range reply.Items {
+			if !seen[reply.Items[i].AnswerID] {
+				seen[reply.Items[i].AnswerID] = true
+				fn(&reply.Items[i])
+			}
+		}
+	}
+	return nil
+}
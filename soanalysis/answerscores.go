@@ -0,0 +1,140 @@
+package soanalysis
+
+import (
+	"context"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// AnswerScoreBucket describes the scores of the answers to the questions asked
+// in a period; Date is the end of the period.
+type AnswerScoreBucket struct {
+	Date time.Time
+
+	// Questions is the number of questions, Answered the number of them with
+	// answers and Answers the number of answers to them.
+	Questions int
+	Answered  int
+	Answers   int
+
+	// TopScores is the sum of the scores of the best answers of the answered
+	// questions, and NegativeBest the number of answered questions whose best
+	// answer has a negative score.
+	TopScores    int
+	NegativeBest int
+
+	// Accepted is the number of questions with an accepted answer, and
+	// AcceptedNotTop the number of them whose accepted answer has a lower score
+	// than another answer.
+	Accepted       int
+	AcceptedNotTop int
+}
+
+// MeanTopScore returns the mean score of the best answers of the answered
+// questions.
+func (b AnswerScoreBucket) MeanTopScore() float64 {
+	return ratio(b.TopScores, b.Answered)
+}
+
+// NegativeBestRatio returns the ratio of the answered questions whose best
+// answer has a negative score.
+func (b AnswerScoreBucket) NegativeBestRatio() float64 {
+	return ratio(b.NegativeBest, b.Answered)
+}
+
+// AnswersPerQuestion returns the mean number of answers of the questions,
+// answered or not.
+func (b AnswerScoreBucket) AnswersPerQuestion() float64 {
+	return ratio(b.Answers, b.Questions)
+}
+
+// AcceptedNotTopRatio returns the ratio of the questions with an accepted
+// answer whose accepted answer isn't the highest-voted one; a rising ratio
+// means that askers and the community increasingly disagree on the best
+// answers.
+func (b AnswerScoreBucket) AcceptedNotTopRatio() float64 {
+	return ratio(b.AcceptedNotTop, b.Accepted)
+}
+
+// AnswerScoreSeries is the answer scores of a single tag over time, with the
+// same buckets as Series.
+type AnswerScoreSeries struct {
+	Tag     string
+	Buckets []AnswerScoreBucket
+}
+
+// AnswerScores computes the scores of the answers to the questions with the
+// given tag (the ones selected by the Filter of the analyzer) in each of the
+// buckets of Series, by the dates of the questions: how good the best answers
+// are, how often even the best answer is downvoted, how many answers the
+// questions get, and how often the accepted answer isn't the highest-voted
+// one. Accepted answers tied with the best score count as highest-voted. The
+// answers to the questions of tag have to be fetched.
+func (a *Analyzer) AnswerScores(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool) (AnswerScoreSeries, error) {
+	as := AnswerScoreSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return as, err
+	}
+
+	// The number of answers to each question, the score of its best one and
+	// the score of its accepted one, if any.
+	answers := make(map[int]int)
+	topScores := make(map[int]int)
+	acceptedScores := make(map[int]int)
+	err = a.Dataset.ForEachAnswer(tag, func(answer *soapi.Answer) {
+		if top, ok := topScores[answer.QuestionID]; !ok || answer.Score > top {
+			topScores[answer.QuestionID] = answer.Score
+		}
+		if answer.IsAccepted {
+			acceptedScores[answer.QuestionID] = answer.Score
+		}
+		answers[answer.QuestionID]++
+	})
+	if err != nil {
+		return as, err
+	}
+
+	as.Buckets = make([]AnswerScoreBucket, len(starts))
+	var maxDate time.Time
+	err = a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		itemDate := time.Unix(int64(item.CreationDate), 0)
+		if itemDate.After(maxDate) {
+			maxDate = itemDate
+		}
+		for i := range starts {
+			if !inPeriod(itemDate, starts[i], ends[i]) {
+				continue
+			}
+			b := &as.Buckets[i]
+			b.Questions++
+			if n := answers[item.QuestionID]; n > 0 {
+				b.Answered++
+				b.Answers += n
+				b.TopScores += topScores[item.QuestionID]
+				if topScores[item.QuestionID] < 0 {
+					b.NegativeBest++
+				}
+				if score, ok := acceptedScores[item.QuestionID]; ok {
+					b.Accepted++
+					if score < topScores[item.QuestionID] {
+						b.AcceptedNotTop++
+					}
+				}
+			}
+		}
+	})
+	if err != nil {
+		return as, err
+	}
+
+	for i := range as.Buckets {
+		as.Buckets[i].Date = ends[i]
+		if as.Buckets[i].Date.IsZero() {
+			// if not explicit date, consider the max encountered date
+			as.Buckets[i].Date = maxDate
+		}
+	}
+	return as, nil
+}
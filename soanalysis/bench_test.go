@@ -0,0 +1,182 @@
+package soanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// benchStart is the start of the questions of the generated datasets; each
+// page holds the questions of a day.
+var benchStart = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// benchPageSize is the number of questions per generated page, the maximum the
+// API returns.
+const benchPageSize = 100
+
+// genReply returns a page of questions asked on the given day after
+// benchStart, with plausible distributions of scores, views and closures.
+func genReply(day int) soapi.Reply {
+	rng := rand.New(rand.NewPCG(uint64(day), 1))
+	reply := soapi.Reply{HasMore: true, QuotaMax: 10000, QuotaRemaining: 9000}
+	date := benchStart.AddDate(0, 0, day)
+	for i := range benchPageSize {
+		item := soapi.Item{
+			Tags:             []string{"go", "concurrency"},
+			IsAnswered:       rng.IntN(3) > 0,
+			ViewCount:        rng.IntN(5000),
+			AnswerCount:      rng.IntN(4),
+			Score:            rng.IntN(8) - 2,
+			CreationDate:     int(date.Unix()) + i*800,
+			LastActivityDate: int(date.Unix()) + i*800 + rng.IntN(86400*30),
+			QuestionID:       day*benchPageSize + i,
+			ContentLicense:   "CC BY-SA 4.0",
+			Link:             fmt.Sprintf("https://stackoverflow.com/questions/%d", day*benchPageSize+i),
+			Title:            "How do I stop a goroutine that is blocked on a channel receive?",
+		}
+		item.Owner.UserID = rng.IntN(100000)
+		item.Owner.UserType = "registered"
+		item.Owner.Reputation = rng.IntN(20000)
+		item.Owner.DisplayName = fmt.Sprintf("user%d", item.Owner.UserID)
+		if rng.IntN(5) == 0 {
+			item.ClosedDate = int64(item.CreationDate + 3600)
+			item.ClosedReason = "duplicate"
+		}
+		reply.Items = append(reply.Items, item)
+	}
+	return reply
+}
+
+// genPage returns genReply(day) as returned by the API.
+func genPage(tb testing.TB, day int) []byte {
+	body, err := json.Marshal(genReply(day))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return body
+}
+
+// genDataset returns a dataset in a temporary directory with the given number
+// of pages of questions with the "go" tag, stored as fetched by
+// fetch-all-questions in monthly windows.
+func genDataset(b *testing.B, pages int) *Dataset {
+	ds := &Dataset{Dir: b.TempDir()}
+	for day := range pages {
+		date := benchStart.AddDate(0, 0, day)
+		from := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if _, err := ds.StorePage("go", from, from.AddDate(0, 1, 0), date.Day(), genPage(b, day)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return ds
+}
+
+// benchPages are the sizes of the generated datasets, in pages.
+var benchPages = []int{10, 100, 1000}
+
+func BenchmarkDecodePage(b *testing.B) {
+	body := genPage(b, 0)
+	b.SetBytes(int64(len(body)))
+	for b.Loop() {
+		var reply soapi.Reply
+		if err := json.Unmarshal(body, &reply); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkForEachItem(b *testing.B) {
+	for _, pages := range benchPages {
+		ds := genDataset(b, pages)
+		for _, parallelism := range []int{1, 0} {
+			b.Run(fmt.Sprintf("pages=%d/parallelism=%d", pages, parallelism), func(b *testing.B) {
+				ds.Parallelism = parallelism
+				for b.Loop() {
+					n := 0
+					err := ds.ForEachItem(b.Context(), "go", time.Time{}, time.Time{}, func(item *soapi.Item) { n++ })
+					if err != nil {
+						b.Fatal(err)
+					}
+					if n != pages*benchPageSize {
+						b.Fatalf("got %d questions, want %d", n, pages*benchPageSize)
+					}
+				}
+			})
+		}
+	}
+}
+
+// benchItems returns the questions of the given number of generated pages.
+func benchItems(pages int) []soapi.Item {
+	var items []soapi.Item
+	for day := range pages {
+		items = append(items, genReply(day).Items...)
+	}
+	return items
+}
+
+func BenchmarkAggregatorAdd(b *testing.B) {
+	items := benchItems(365)
+	from, to := benchStart, benchStart.AddDate(1, 0, 0)
+	for _, bc := range []struct {
+		name string
+		new  func() (*Aggregator, error)
+	}{
+		{"total", func() (*Aggregator, error) { return NewAggregator("go", from, to, false) }},
+		{"bymonth", func() (*Aggregator, error) { return NewAggregator("go", from, to, true) }},
+		{"window=90d/step=7d", func() (*Aggregator, error) {
+			return NewWindowAggregator("go", from, to, 90*24*time.Hour, 7*24*time.Hour)
+		}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for b.Loop() {
+				ag, err := bc.new()
+				if err != nil {
+					b.Fatal(err)
+				}
+				for i := range items {
+					ag.Add(&items[i])
+				}
+				ag.Series()
+			}
+		})
+	}
+}
+
+func BenchmarkPeriods(b *testing.B) {
+	for _, years := range []int{1, 10} {
+		from, to := benchStart, benchStart.AddDate(years, 0, 0)
+		b.Run(fmt.Sprintf("bymonth/years=%d", years), func(b *testing.B) {
+			for b.Loop() {
+				if _, _, err := periods(from, to, true); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("window/years=%d", years), func(b *testing.B) {
+			for b.Loop() {
+				if _, _, err := windows(from, to, 90*24*time.Hour, 24*time.Hour); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSeries(b *testing.B) {
+	for _, pages := range benchPages {
+		an := &Analyzer{Dataset: genDataset(b, pages)}
+		from, to := benchStart, benchStart.AddDate(0, 0, pages)
+		b.Run(fmt.Sprintf("pages=%d", pages), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := an.Series(b.Context(), "go", from, to, true); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package soanalysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RelatedTagsPath returns the path of the file listing the tags related to tag
+// (its peer group for Benchmark), one per line.
+func (ds *Dataset) RelatedTagsPath(tag string) string {
+	return filepath.Join(ds.TagDir(tag), "related-tags.txt")
+}
+
+// RelatedTags returns the tags related to tag, as written by WriteRelatedTags.
+func (ds *Dataset) RelatedTags(tag string) ([]string, error) {
+	data, err := os.ReadFile(ds.RelatedTagsPath(tag))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// WriteRelatedTags writes the tags related to tag into the dataset.
+func (ds *Dataset) WriteRelatedTags(tag string, related []string) error {
+	return os.WriteFile(ds.RelatedTagsPath(tag), []byte(strings.Join(related, "\n")+"\n"), 0644)
+}
+
+// PercentileRank returns the percentile rank (0 to 100) of value among the
+// values of peers: the percentage of peers with a lower value, counting equal
+// values as half lower. Without peers, it's 0.
+func PercentileRank(value float64, peers []float64) float64 {
+	if len(peers) == 0 {
+		return 0
+	}
+	below := 0.0
+	for _, p := range peers {
+		if p < value {
+			below++
+		} else if p == value {
+			below += 0.5
+		}
+	}
+	return 100 * below / float64(len(peers))
+}
+
+// BenchmarkBucket holds the percentile ranks of the metrics of a tag among its
+// peers in a bucket; Date is the end of the period, and Peers is the number of
+// peers with questions in it.
+type BenchmarkBucket struct {
+	Date  time.Time
+	Peers int
+
+	Negative            float64
+	Closed              float64
+	ClosedAndNegative   float64
+	Unanswered          float64
+	NegativePer10kViews float64
+}
+
+// Benchmark is the comparison of the metrics of a tag with the ones of its
+// related tags, with the same buckets as Series.
+type Benchmark struct {
+	Tag string
+
+	// Peers are the related tags of Tag in the dataset.
+	Peers []string
+
+	Buckets []BenchmarkBucket
+}
+
+// Benchmark ranks the metrics of the questions with the given tag between
+// fromDate and toDate among the ones of its related tags, in each of the
+// buckets of Series. The related tags have to be written into the dataset
+// with WriteRelatedTags, and only the ones that are in the dataset are
+// compared with.
+func (a *Analyzer) Benchmark(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool) (Benchmark, error) {
+	bm := Benchmark{Tag: tag}
+	related, err := a.Dataset.RelatedTags(tag)
+	if err != nil {
+		return bm, err
+	}
+
+	ts, err := a.Series(ctx, tag, fromDate, toDate, byMonth)
+	if err != nil {
+		return bm, err
+	}
+	var peers []Series
+	for _, peer := range related {
+		if ok, err := a.Dataset.HasTag(peer); err != nil {
+			return bm, err
+		} else if !ok {
+			continue
+		}
+		ps, err := a.Series(ctx, peer, fromDate, toDate, byMonth)
+		if err != nil {
+			return bm, err
+		}
+		bm.Peers = append(bm.Peers, peer)
+		peers = append(peers, ps)
+	}
+
+	for i, b := range ts.Buckets {
+		var peerResults []Result
+		for _, ps := range peers {
+			if ps.Buckets[i].Result.Total > 0 {
+				peerResults = append(peerResults, ps.Buckets[i].Result)
+			}
+		}
+		rank := func(metric func(Result) float64) float64 {
+			var values []float64
+			for _, r := range peerResults {
+				values = append(values, metric(r))
+			}
+			return PercentileRank(metric(b.Result), values)
+		}
+		bm.Buckets = append(bm.Buckets, BenchmarkBucket{
+			Date:                b.Date,
+			Peers:               len(peerResults),
+			Negative:            rank(Result.NegativeRatio),
+			Closed:              rank(Result.ClosedRatio),
+			ClosedAndNegative:   rank(Result.ClosedAndNegativeRatio),
+			Unanswered:          rank(Result.UnansweredRatio),
+			NegativePer10kViews: rank(Result.NegativePer10kViews),
+		})
+	}
+	return bm, nil
+}
@@ -0,0 +1,107 @@
+package soanalysis
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// BountyGroup describes how the questions of a group were answered.
+type BountyGroup struct {
+	Questions int
+
+	// Answered is the number of questions with answers, and Accepted the
+	// number of them with an accepted answer.
+	Answered int
+	Accepted int
+
+	// MedianTimeToAccept is the median time between asking the questions and
+	// posting their accepted answers (the API doesn't tell when answers are
+	// accepted), over the TimedAccepted questions whose accepted answers were
+	// fetched; it's 0 if there are none.
+	MedianTimeToAccept time.Duration
+	TimedAccepted      int
+}
+
+// AnswerRate returns the ratio of the questions with answers.
+func (g BountyGroup) AnswerRate() float64 {
+	return ratio(g.Answered, g.Questions)
+}
+
+// AcceptRate returns the ratio of the questions with an accepted answer.
+func (g BountyGroup) AcceptRate() float64 {
+	return ratio(g.Accepted, g.Questions)
+}
+
+// BountyStats compares the questions of a tag with a bounty to the other
+// questions of the tag.
+type BountyStats struct {
+	Tag string
+
+	Bountied BountyGroup
+	Other    BountyGroup
+
+	// MeanAmount is the mean amount of reputation offered by the bounties.
+	MeanAmount float64
+}
+
+// Bounties compares the questions with the given tag between fromDate and
+// toDate that have a bounty with the other questions, to tell whether bounties
+// get struggling questions answered. The API only reports the bounties open
+// when the questions were fetched, so questions whose bounties had ended by
+// then count as other questions; recently fetched periods give the most
+// faithful picture. The times to accept require the answers to the questions
+// of tag to be fetched; without them, they're left at 0.
+func (a *Analyzer) Bounties(ctx context.Context, tag string, fromDate time.Time, toDate time.Time) (BountyStats, error) {
+	bs := BountyStats{Tag: tag}
+	answerDates := make(map[int]int)
+	if a.Dataset.HasAnswers(tag) {
+		err := a.Dataset.ForEachAnswer(tag, func(answer *soapi.Answer) {
+			answerDates[answer.AnswerID] = answer.CreationDate
+		})
+		if err != nil {
+			return bs, err
+		}
+	}
+
+	var bountiedTimes, otherTimes []time.Duration
+	amounts := 0
+	err := a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		g, times := &bs.Other, &otherTimes
+		if item.BountyAmount > 0 {
+			g, times = &bs.Bountied, &bountiedTimes
+			amounts += item.BountyAmount
+		}
+		g.Questions++
+		if item.AnswerCount > 0 {
+			g.Answered++
+		}
+		if item.AcceptedAnswerID != 0 {
+			g.Accepted++
+			if answered, ok := answerDates[item.AcceptedAnswerID]; ok {
+				// Answers may predate questions merged into others.
+				*times = append(*times, max(time.Duration(answered-item.CreationDate)*time.Second, 0))
+			}
+		}
+	})
+	if err != nil {
+		return bs, err
+	}
+
+	bs.MeanAmount = ratio(amounts, bs.Bountied.Questions)
+	bs.Bountied.MedianTimeToAccept, bs.Bountied.TimedAccepted = medianDuration(bountiedTimes), len(bountiedTimes)
+	bs.Other.MedianTimeToAccept, bs.Other.TimedAccepted = medianDuration(otherTimes), len(otherTimes)
+	return bs, nil
+}
+
+// medianDuration returns the median of times, or 0 if times is empty.
+func medianDuration(times []time.Duration) time.Duration {
+	n := len(times)
+	if n == 0 {
+		return 0
+	}
+	slices.Sort(times)
+	return (times[(n-1)/2] + times[n/2]) / 2
+}
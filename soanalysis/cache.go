@@ -0,0 +1,269 @@
+package soanalysis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Cache keeps the questions of the tags of a Dataset in memory, for
+// long-running servers to analyze them without reading and decoding their
+// pages for every query. A tag is loaded when its questions are first read,
+// and Refresh reloads the tags whose files changed since.
+//
+// A Cache is safe for concurrent use.
+type Cache struct {
+	// MaxBytes, if positive, bounds the approximate memory taken by the
+	// cached questions: the least recently used tags are evicted to stay
+	// within it, and tags taking more than MaxBytes on their own are read
+	// from their files every time instead of being cached.
+	MaxBytes int64
+
+	mu    sync.Mutex
+	tags  map[string]*cachedTag
+	bytes int64
+	// clock orders the uses of the cached tags, for evicting the least
+	// recently used one.
+	clock int64
+	stats CacheStats
+}
+
+// cachedTag holds the questions of a tag, as read by Dataset.ForEachItem, in
+// the order it reads them in.
+type cachedTag struct {
+	items []soapi.Item
+	// version identifies the files the questions were read from; see
+	// Dataset.tagVersion.
+	version string
+	size    int64
+	used    int64
+}
+
+// CacheStats are the statistics of a Cache.
+type CacheStats struct {
+	// Tags is the number of cached tags, and Bytes the approximate memory
+	// they take.
+	Tags  int   `json:"tags"`
+	Bytes int64 `json:"bytes"`
+
+	// Hits and Misses count the reads of the questions of a tag that were
+	// served from memory and from the files of the tag.
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+
+	// Evictions counts the tags evicted to stay within MaxBytes, and
+	// Reloads the tags reloaded by Refresh.
+	Evictions int `json:"evictions"`
+	Reloads   int `json:"reloads"`
+}
+
+// Stats returns the statistics of the cache.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Tags = len(c.tags)
+	stats.Bytes = c.bytes
+	return stats
+}
+
+// forEachItem implements Dataset.ForEachItem for a dataset with a cache.
+func (c *Cache) forEachItem(ctx context.Context, ds *Dataset, tag string, fromDate time.Time, toDate time.Time, fn func(item *soapi.Item)) error {
+	items, err := c.items(ctx, ds, tag)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		itemDate := time.Unix(int64(items[i].CreationDate), 0)
+		if !fromDate.IsZero() && itemDate.Before(fromDate) {
+			continue
+		}
+		if !toDate.IsZero() && itemDate.After(toDate) {
+			continue
+		}
+		fn(&items[i])
+	}
+	return nil
+}
+
+// items returns the questions of tag, loading them into the cache if they
+// aren't cached yet.
+func (c *Cache) items(ctx context.Context, ds *Dataset, tag string) ([]soapi.Item, error) {
+	c.mu.Lock()
+	if ct, ok := c.tags[tag]; ok {
+		c.clock++
+		ct.used = c.clock
+		c.stats.Hits++
+		c.mu.Unlock()
+		return ct.items, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	ct, err := c.load(ctx, ds, tag)
+	if err != nil {
+		return nil, err
+	}
+	c.store(tag, ct)
+	return ct.items, nil
+}
+
+// load reads the questions of tag from its files.
+func (c *Cache) load(ctx context.Context, ds *Dataset, tag string) (*cachedTag, error) {
+	// Read the version first, so that files changing while loading make the
+	// next Refresh load them again.
+	version, err := ds.tagVersion(tag)
+	if err != nil {
+		return nil, err
+	}
+	ct := &cachedTag{version: version}
+	err = ds.forEachStoredItem(ctx, tag, time.Time{}, time.Time{}, func(item *soapi.Item) {
+		ct.items = append(ct.items, *item)
+		ct.size += itemSize(item)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+// store caches ct as the questions of tag, evicting the least recently used
+// tags as needed to stay within MaxBytes.
+func (c *Cache) store(tag string, ct *cachedTag) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.MaxBytes > 0 && ct.size > c.MaxBytes {
+		return
+	}
+	if c.tags == nil {
+		c.tags = make(map[string]*cachedTag)
+	}
+	if old, ok := c.tags[tag]; ok {
+		c.bytes -= old.size
+	}
+	c.clock++
+	ct.used = c.clock
+	c.tags[tag] = ct
+	c.bytes += ct.size
+
+	for c.MaxBytes > 0 && c.bytes > c.MaxBytes {
+		lru := ""
+		for t, other := range c.tags {
+			if t != tag && (lru == "" || other.used < c.tags[lru].used) {
+				lru = t
+			}
+		}
+		c.bytes -= c.tags[lru].size
+		delete(c.tags, lru)
+		c.stats.Evictions++
+	}
+}
+
+// Refresh reloads the cached tags of ds whose files changed since they were
+// loaded, and drops the ones that were removed.
+func (c *Cache) Refresh(ctx context.Context, ds *Dataset) error {
+	c.mu.Lock()
+	versions := make(map[string]string, len(c.tags))
+	for tag, ct := range c.tags {
+		versions[tag] = ct.version
+	}
+	c.mu.Unlock()
+
+	for tag, version := range versions {
+		if _, err := os.Stat(ds.TagDir(tag)); errors.Is(err, os.ErrNotExist) {
+			c.drop(tag)
+			continue
+		}
+		current, err := ds.tagVersion(tag)
+		if err != nil {
+			return err
+		}
+		if current == version {
+			continue
+		}
+		ct, err := c.load(ctx, ds, tag)
+		if err != nil {
+			return fmt.Errorf("reloading tag %q: %w", tag, err)
+		}
+		c.store(tag, ct)
+		c.mu.Lock()
+		c.stats.Reloads++
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+// RefreshEvery calls Refresh every interval until ctx is done, logging its
+// errors.
+func (c *Cache) RefreshEvery(ctx context.Context, ds *Dataset, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx, ds); err != nil {
+				log.Println("refreshing the cache:", err)
+			}
+		}
+	}
+}
+
+// drop removes tag from the cache.
+func (c *Cache) drop(tag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ct, ok := c.tags[tag]; ok {
+		c.bytes -= ct.size
+		delete(c.tags, tag)
+	}
+}
+
+// tagVersion returns a hash of the paths, sizes and modification times of the
+// files holding the questions of tag, which changes when they're fetched again.
+func (ds *Dataset) tagVersion(tag string) (string, error) {
+	paths, err := ds.pageFiles(tag)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %d %d\n", path, fi.Size(), fi.ModTime().UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// itemSize returns the approximate memory taken by item.
+func itemSize(item *soapi.Item) int64 {
+	size := int64(unsafe.Sizeof(*item))
+	for _, tag := range item.Tags {
+		size += int64(unsafe.Sizeof(tag) + uintptr(len(tag)))
+	}
+	size += int64(len(item.Owner.UserType) + len(item.Owner.ProfileImage) + len(item.Owner.DisplayName) + len(item.Owner.Link))
+	size += int64(len(item.ContentLicense) + len(item.Link) + len(item.Title) + len(item.Body) + len(item.ClosedReason))
+	if item.ClosedDetails != nil {
+		for _, q := range item.ClosedDetails.OriginalQuestions {
+			size += int64(unsafe.Sizeof(q) + uintptr(len(q.Title)))
+		}
+	}
+	return size
+}
@@ -0,0 +1,70 @@
+package soanalysis
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Checkpoint records where a fetch into a dataset stopped before fetching all
+// its tags, e.g. because the API kept failing, so that running it again
+// resumes from there.
+type Checkpoint struct {
+	// Time is when the fetch stopped.
+	Time time.Time `json:"time"`
+
+	// FromDate and ToDate are the window being fetched.
+	FromDate time.Time `json:"fromdate"`
+	ToDate   time.Time `json:"todate"`
+
+	// Done are the tags fetched before stopping, which are skipped when
+	// resuming.
+	Done []string `json:"done"`
+
+	// Tag is the tag the fetch stopped at, which is resumed from Page.
+	Tag  string `json:"tag"`
+	Page int    `json:"page"`
+
+	// Err is the error the fetch stopped on.
+	Err string `json:"error"`
+}
+
+// CheckpointPath returns the path of the checkpoint of the dataset, in its base
+// directory.
+func (ds *Dataset) CheckpointPath() string {
+	return filepath.Join(ds.Dir, "checkpoint.json")
+}
+
+// Checkpoint returns the checkpoint of the dataset, or nil if there's none.
+func (ds *Dataset) Checkpoint() (*Checkpoint, error) {
+	data, err := os.ReadFile(ds.CheckpointPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var c Checkpoint
+	err = json.Unmarshal(data, &c)
+	return &c, err
+}
+
+// WriteCheckpoint records c as the checkpoint of the dataset.
+func (ds *Dataset) WriteCheckpoint(c Checkpoint) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ds.CheckpointPath(), append(data, '\n'), 0644)
+}
+
+// RemoveCheckpoint removes the checkpoint of the dataset, once the fetch it
+// recorded is complete.
+func (ds *Dataset) RemoveCheckpoint() error {
+	err := os.Remove(ds.CheckpointPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,142 @@
+package soanalysis
+
+import (
+	"context"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// ChurnBucket holds the moderation events of the questions asked in a period;
+// Date is the end of the period.
+type ChurnBucket struct {
+	Date time.Time
+
+	// Questions is the number of questions asked in the period, including the
+	// deleted ones.
+	Questions int
+
+	// Closed is the number of questions closed at some point, Reopened the
+	// number of them seen closed and then open again, and Deleted the number
+	// of deleted questions.
+	Closed   int
+	Reopened int
+	Deleted  int
+}
+
+// Churn returns the moderation churn of the bucket: the number of moderation
+// events (closing, reopening and deletion) per question, a single measure of
+// how contested the moderation of the questions is.
+func (b ChurnBucket) Churn() float64 {
+	return ratio(b.Closed+b.Reopened+b.Deleted, b.Questions)
+}
+
+// ChurnSeries is the moderation churn of a tag, with the same buckets as
+// Series.
+type ChurnSeries struct {
+	Tag     string
+	Buckets []ChurnBucket
+}
+
+// Smoothed returns the churn of each bucket averaged with the churn of the
+// window-1 buckets before it (or as many as there are), to show its trend.
+func (cs ChurnSeries) Smoothed(window int) []float64 {
+	window = max(window, 1)
+	smoothed := make([]float64, len(cs.Buckets))
+	sum := 0.0
+	for i, b := range cs.Buckets {
+		sum += b.Churn()
+		if i >= window {
+			sum -= cs.Buckets[i-window].Churn()
+		}
+		smoothed[i] = sum / float64(min(i+1, window))
+	}
+	return smoothed
+}
+
+// Churn computes the moderation churn of the questions with the given tag
+// between fromDate and toDate, in each of the buckets of Series. Reopening
+// isn't reported by the API, so it's detected from the state of the questions
+// in the scores log (see ScoreSnapshot) followed by their latest stored state:
+// a question closed in a snapshot and open in a later one was reopened.
+// Deletions are the ones in the deletions log (see Deletion).
+func (a *Analyzer) Churn(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool) (ChurnSeries, error) {
+	cs := ChurnSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return cs, err
+	}
+	for _, end := range ends {
+		cs.Buckets = append(cs.Buckets, ChurnBucket{Date: end})
+	}
+
+	snapshots, err := a.Dataset.ScoreSnapshots(tag)
+	if err != nil {
+		return cs, err
+	}
+
+	var latest time.Time
+	add := func(item *soapi.Item, deleted bool) {
+		if a.Filter != nil && !a.Filter(item) {
+			return
+		}
+		itemDate := time.Unix(int64(item.CreationDate), 0)
+		if !inPeriod(itemDate, fromDate, toDate) {
+			return
+		}
+		if itemDate.After(latest) {
+			latest = itemDate
+		}
+
+		closed, reopened := false, false
+		for _, s := range snapshots[item.QuestionID] {
+			reopened = reopened || (closed && !s.Closed)
+			closed = closed || s.Closed
+		}
+		reopened = reopened || (closed && item.ClosedDate == 0)
+		closed = closed || item.ClosedDate > 0
+
+		for i := range cs.Buckets {
+			b := &cs.Buckets[i]
+			if !inPeriod(itemDate, starts[i], b.Date) {
+				continue
+			}
+			b.Questions++
+			if closed {
+				b.Closed++
+			}
+			if reopened {
+				b.Reopened++
+			}
+			if deleted {
+				b.Deleted++
+			}
+		}
+	}
+
+	present := make(map[int]bool)
+	err = a.Dataset.ForEachItem(ctx, tag, time.Time{}, time.Time{}, func(item *soapi.Item) {
+		present[item.QuestionID] = true
+		add(item, false)
+	})
+	if err != nil {
+		return cs, err
+	}
+	deletions, err := a.Dataset.Deletions(tag)
+	if err != nil {
+		return cs, err
+	}
+	for i := range deletions {
+		item := deletions[i].Item.item()
+		if !present[item.QuestionID] {
+			present[item.QuestionID] = true
+			add(item, true)
+		}
+	}
+
+	if toDate.IsZero() {
+		// if not explicit date, consider the max encountered date
+		cs.Buckets[0].Date = latest
+	}
+	return cs, nil
+}
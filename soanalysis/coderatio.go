@@ -0,0 +1,191 @@
+package soanalysis
+
+import (
+	"context"
+	"math"
+	"regexp"
+	"slices"
+	"time"
+	"unicode"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+var codeBlock = regexp.MustCompile(`(?is)<pre[^>]*>.*?</pre>`)
+
+// textLength returns the number of characters of the text of s, not counting
+// whitespace.
+func textLength(s string) int {
+	n := 0
+	for _, r := range plainText(s) {
+		if !unicode.IsSpace(r) {
+			n++
+		}
+	}
+	return n
+}
+
+// CodeRatio returns the fraction of the text of the HTML body of a question
+// that's in code blocks (<pre>), as opposed to prose and inline code; ok is
+// false if the body has no text, e.g. because bodies weren't fetched.
+func CodeRatio(body string) (r float64, ok bool) {
+	code := 0
+	for _, block := range codeBlock.FindAllString(body, -1) {
+		code += textLength(block)
+	}
+	prose := textLength(codeBlock.ReplaceAllString(body, " "))
+	if code+prose == 0 {
+		return 0, false
+	}
+	return float64(code) / float64(code+prose), true
+}
+
+// CodeRatioBands are the upper bounds of the bands of code ratios of
+// CodeRatioStats, after the band of questions without code.
+var CodeRatioBands = []float64{0.25, 0.5, 0.75, 1}
+
+// CodeRatioBand is the result of the questions whose code ratio is above Min
+// and at most Max; the band of questions without code has both at 0.
+type CodeRatioBand struct {
+	Min    float64
+	Max    float64
+	Result Result
+}
+
+// CodeRatioStats relates the share of code in the bodies of the questions of
+// a tag to how they're received, as a proxy for the effort put into them.
+type CodeRatioStats struct {
+	Tag string
+
+	// Questions is the number of questions with bodies, and WithoutBodies the
+	// number of questions skipped for not having one.
+	Questions     int
+	WithoutBodies int
+
+	// MeanRatio is the mean code ratio of the questions, and WithCode the share
+	// of the questions with any code block.
+	MeanRatio float64
+	WithCode  float64
+
+	// ScoreCorrelation and ClosedCorrelation are the Spearman rank
+	// correlations of the code ratio with the score of the questions and with
+	// their being closed, between -1 and 1.
+	ScoreCorrelation  float64
+	ClosedCorrelation float64
+
+	// Bands are the results of the questions by their code ratio: without
+	// code, and then up to each of CodeRatioBands.
+	Bands []CodeRatioBand
+}
+
+// CodeRatios computes the code ratios (see CodeRatio) of the questions with
+// the given tag between fromDate and toDate, and how they correlate with the
+// scores and closing of the questions. It requires the bodies of the questions
+// to be fetched (with fetch-all-questions -bodies); questions without them are
+// only counted.
+func (a *Analyzer) CodeRatios(ctx context.Context, tag string, fromDate time.Time, toDate time.Time) (CodeRatioStats, error) {
+	cs := CodeRatioStats{Tag: tag}
+	cs.Bands = append(cs.Bands, CodeRatioBand{Result: Result{Robust: a.Robust}})
+	lower := 0.0
+	for _, upper := range CodeRatioBands {
+		cs.Bands = append(cs.Bands, CodeRatioBand{Min: lower, Max: upper, Result: Result{Robust: a.Robust}})
+		lower = upper
+	}
+
+	var ratios, scores, closed []float64
+	withCode := 0
+	err := a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		r, ok := CodeRatio(item.Body)
+		if !ok {
+			cs.WithoutBodies++
+			return
+		}
+		ratios = append(ratios, r)
+		scores = append(scores, float64(item.Score))
+		closed = append(closed, 0)
+		if item.ClosedDate != 0 {
+			closed[len(closed)-1] = 1
+		}
+
+		band := 0
+		if r > 0 {
+			withCode++
+			band = 1 + slices.IndexFunc(CodeRatioBands, func(upper float64) bool { return r <= upper })
+		}
+		cs.Bands[band].Result.addItem(item, a.Sentiments)
+	})
+	if err != nil {
+		return cs, err
+	}
+
+	cs.Questions = len(ratios)
+	cs.WithCode = ratio(withCode, cs.Questions)
+	for _, r := range ratios {
+		cs.MeanRatio += r / float64(cs.Questions)
+	}
+	cs.ScoreCorrelation = spearman(ratios, scores)
+	cs.ClosedCorrelation = spearman(ratios, closed)
+	return cs, nil
+}
+
+// spearman returns the Spearman rank correlation of x and y, or 0 if either
+// is constant.
+func spearman(x []float64, y []float64) float64 {
+	return pearson(ranks(x), ranks(y))
+}
+
+// ranks returns the ranks of values from 1, with tied values getting the mean
+// of their ranks.
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(i, j int) int {
+		switch {
+		case values[i] < values[j]:
+			return -1
+		case values[i] > values[j]:
+			return 1
+		}
+		return 0
+	})
+	rs := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+		rank := float64(start+end+1) / 2
+		for _, i := range order[start:end] {
+			rs[i] = rank
+		}
+		start = end
+	}
+	return rs
+}
+
+// pearson returns the Pearson correlation of x and y, or 0 if either is
+// constant.
+func pearson(x []float64, y []float64) float64 {
+	n := float64(len(x))
+	if n == 0 {
+		return 0
+	}
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i] / n
+		meanY += y[i] / n
+	}
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
@@ -0,0 +1,122 @@
+package soanalysis
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Cohort is the askers of a tag whose first question in the analyzed period was
+// asked in the same month, and how many of them asked again in the following
+// months.
+type Cohort struct {
+	// Month is the start of the month of the cohort.
+	Month time.Time
+
+	Askers int
+
+	// Retained[i] is the number of askers who asked again i+1 months after the
+	// month of the cohort; it only covers the months in the analyzed period.
+	Retained []int
+
+	// NegativeAskers and NegativeRetained are like Askers and Retained, for
+	// the askers whose first question had a negative score.
+	NegativeAskers   int
+	NegativeRetained []int
+}
+
+// RetentionRatio returns the ratio of the askers of the cohort who asked again
+// i+1 months after the month of the cohort.
+func (c Cohort) RetentionRatio(i int) float64 {
+	return ratio(c.Retained[i], c.Askers)
+}
+
+// NegativeRetentionRatio is like RetentionRatio, for the askers whose first
+// question had a negative score.
+func (c Cohort) NegativeRetentionRatio(i int) float64 {
+	return ratio(c.NegativeRetained[i], c.NegativeAskers)
+}
+
+// monthIndex returns the number of months since year 0 of t in loc.
+func monthIndex(t time.Time, loc *time.Location) int {
+	t = t.In(loc)
+	return t.Year()*12 + int(t.Month()) - 1
+}
+
+// Cohorts groups the askers of questions with the given tag between fromDate
+// and toDate by the month of their first question, and follows each cohort for
+// up to the given number of months after it, as long as they end by toDate (or
+// the latest question, if toDate is zero). Months start in the location of
+// fromDate. Questions of deleted users (without a user ID) are ignored.
+func (a *Analyzer) Cohorts(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, months int) ([]Cohort, error) {
+	loc := fromDate.Location()
+
+	type asker struct {
+		first  soapi.Item
+		months map[int]bool
+	}
+	askers := make(map[int]*asker)
+	lastMonth := 0
+	err := a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		if item.Owner.UserID == 0 {
+			return
+		}
+		ask, ok := askers[item.Owner.UserID]
+		if !ok {
+			ask = &asker{first: *item, months: make(map[int]bool)}
+			askers[item.Owner.UserID] = ask
+		} else if item.CreationDate < ask.first.CreationDate {
+			ask.first = *item
+		}
+		month := monthIndex(time.Unix(int64(item.CreationDate), 0), loc)
+		ask.months[month] = true
+		lastMonth = max(lastMonth, month)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !toDate.IsZero() {
+		// The last month entirely in the period
+		lastMonth = monthIndex(toDate.AddDate(0, 0, 1), loc) - 1
+	}
+
+	cohorts := make(map[int]*Cohort)
+	for _, ask := range askers {
+		month := monthIndex(time.Unix(int64(ask.first.CreationDate), 0), loc)
+		c, ok := cohorts[month]
+		if !ok {
+			followed := max(min(months, lastMonth-month), 0)
+			c = &Cohort{
+				Month:            time.Date(month/12, time.Month(month%12+1), 1, 0, 0, 0, 0, loc),
+				Retained:         make([]int, followed),
+				NegativeRetained: make([]int, followed),
+			}
+			cohorts[month] = c
+		}
+
+		negative := ask.first.Score < 0
+		c.Askers++
+		if negative {
+			c.NegativeAskers++
+		}
+		for i := range c.Retained {
+			if ask.months[month+i+1] {
+				c.Retained[i]++
+				if negative {
+					c.NegativeRetained[i]++
+				}
+			}
+		}
+	}
+
+	var result []Cohort
+	for _, c := range cohorts {
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Month.Before(result[j].Month)
+	})
+	return result, nil
+}
@@ -0,0 +1,101 @@
+package soanalysis
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// compactName is the format of the names of the files written by Compact.
+const compactName = "compact%04d.json"
+
+// Compact merges all the pages of tag into files of up to itemsPerFile
+// questions each, sorted by creation date and with each question only once (as
+// found by ForEachItem). The merged files replace the pages and the index of
+// fetched windows, so the next fetch of a window stores its pages anew. Only
+// the fields of soapi.Item are kept. Compact returns the number of files
+// before and after merging.
+func (ds *Dataset) Compact(ctx context.Context, tag string, itemsPerFile int) (int, int, error) {
+	if itemsPerFile <= 0 {
+		return 0, 0, fmt.Errorf("bad number of questions per file: %d", itemsPerFile)
+	}
+	paths, err := ds.pageFiles(tag)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var items []soapi.Item
+	err = ds.ForEachItem(ctx, tag, time.Time{}, time.Time{}, func(item *soapi.Item) {
+		items = append(items, *item)
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	slices.SortStableFunc(items, func(a, b soapi.Item) int {
+		return cmp.Compare(a.CreationDate, b.CreationDate)
+	})
+
+	// Until the old pages are removed below, the questions are found in both
+	// them and the merged files, which ForEachItem only considers once.
+	merged, err := ds.WriteItems(tag, items, itemsPerFile)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, path := range paths {
+		if slices.Contains(merged, path) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, 0, err
+		}
+	}
+	if err := os.Remove(ds.indexPath(tag)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, 0, err
+	}
+	if err := os.RemoveAll(filepath.Join(ds.TagDir(tag), objectsDir)); err != nil {
+		return 0, 0, err
+	}
+	return len(paths), len(merged), nil
+}
+
+// WriteItems writes items into the directory of tag, in files of up to
+// itemsPerFile questions each named like the ones merged by Compact, and
+// returns their paths. Files by the same names are replaced.
+func (ds *Dataset) WriteItems(tag string, items []soapi.Item, itemsPerFile int) ([]string, error) {
+	if itemsPerFile <= 0 {
+		return nil, fmt.Errorf("bad number of questions per file: %d", itemsPerFile)
+	}
+	if err := os.MkdirAll(ds.TagDir(tag), 0777); err != nil {
+		return nil, err
+	}
+
+	// Write the files under temporary names first, so that they aren't read
+	// as pages until complete.
+	var paths []string
+	for start := 0; start < len(items); start += itemsPerFile {
+		end := min(start+itemsPerFile, len(items))
+		data, err := json.Marshal(soapi.Reply{Items: items[start:end]})
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(ds.TagDir(tag), fmt.Sprintf(compactName, len(paths)+1))
+		if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	for _, path := range paths {
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,124 @@
+package soanalysis
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Concentration measures how concentrated the answers of a period are among
+// the users answering them.
+type Concentration struct {
+	Answers   int
+	Answerers int
+
+	// Gini is the Gini coefficient of the numbers of answers of the answerers:
+	// 0 if they all answered the same number of questions, approaching 1 as
+	// fewer of them give most of the answers.
+	Gini float64
+
+	// TopShare is the ratio of the answers given by the top 10% of the
+	// answerers (rounded up), by number of answers.
+	TopShare float64
+}
+
+// newConcentration computes the concentration of answers given the number of
+// answers of each answerer.
+func newConcentration(counts []int) Concentration {
+	slices.Sort(counts)
+	c := Concentration{Answerers: len(counts)}
+	weighted := 0
+	for i, n := range counts {
+		c.Answers += n
+		weighted += (i + 1) * n
+	}
+	if c.Answers == 0 {
+		return c
+	}
+
+	n := float64(len(counts))
+	c.Gini = 2*float64(weighted)/(n*float64(c.Answers)) - (n+1)/n
+
+	top := 0
+	for _, n := range counts[len(counts)-(len(counts)+9)/10:] {
+		top += n
+	}
+	c.TopShare = float64(top) / float64(c.Answers)
+	return c
+}
+
+// ConcentrationBucket is the concentration of the answers of a period; Date is
+// the end of the period.
+type ConcentrationBucket struct {
+	Date          time.Time
+	Concentration Concentration
+}
+
+// ConcentrationSeries is the concentration of the answers of a single tag over
+// time, with the same buckets as Series.
+type ConcentrationSeries struct {
+	Tag     string
+	Buckets []ConcentrationBucket
+}
+
+// Concentration computes the concentration of the answers to the questions
+// with the given tag (the ones selected by the Filter of the analyzer) in each
+// of the buckets of Series, by the dates of the answers. Answers of deleted
+// users (without a user ID) are ignored. The answers to the questions of tag
+// have to be fetched.
+func (a *Analyzer) Concentration(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool) (ConcentrationSeries, error) {
+	ts := ConcentrationSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return ts, err
+	}
+
+	questions := make(map[int]bool)
+	err = a.ForEachItem(ctx, tag, time.Time{}, time.Time{}, func(item *soapi.Item) {
+		questions[item.QuestionID] = true
+	})
+	if err != nil {
+		return ts, err
+	}
+
+	// counts[i] maps the answerers of the answers in bucket i to their number
+	// of answers.
+	counts := make([]map[int]int, len(starts))
+	for i := range counts {
+		counts[i] = make(map[int]int)
+	}
+	var maxDate time.Time
+	err = a.Dataset.ForEachAnswer(tag, func(answer *soapi.Answer) {
+		if !questions[answer.QuestionID] || answer.Owner.UserID == 0 {
+			return
+		}
+		answerDate := time.Unix(int64(answer.CreationDate), 0)
+		for i := range starts {
+			if inPeriod(answerDate, starts[i], ends[i]) {
+				counts[i][answer.Owner.UserID]++
+				if answerDate.After(maxDate) {
+					maxDate = answerDate
+				}
+			}
+		}
+	})
+	if err != nil {
+		return ts, err
+	}
+
+	for i, answerers := range counts {
+		var n []int
+		for _, count := range answerers {
+			n = append(n, count)
+		}
+		b := ConcentrationBucket{Date: ends[i], Concentration: newConcentration(n)}
+		if b.Date.IsZero() {
+			// if not explicit date, consider the max encountered date
+			b.Date = maxDate
+		}
+		ts.Buckets = append(ts.Buckets, b)
+	}
+	return ts, nil
+}
@@ -0,0 +1,177 @@
+// Package soanalysis analyzes the questions fetched with soapi into a data
+// directory.
+//
+// A data directory has a subdirectory per tag, holding the pages of questions
+// with that tag as returned by the API. Pages are stored by the hash of their
+// contents with an index of the fetched windows (see StorePage); directories
+// fetched by older versions have files named so001.json, so002.json and so on
+// instead, which are read as well, like the files merged by Compact. The
+// validators of the pages for refreshing them with conditional requests may be
+// cached alongside, in validators.cache.
+package soanalysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Dataset is a data directory.
+type Dataset struct {
+	Dir string
+
+	// Parallelism is the number of pages decoded concurrently when reading
+	// the questions of a tag; if it's not positive, it's GOMAXPROCS.
+	Parallelism int
+
+	// Cache, if not nil, keeps the questions read by ForEachItem in memory.
+	Cache *Cache
+
+	// Codec, if not nil, compresses the pages stored in FormatCompressed;
+	// they're compressed with gzip otherwise. It doesn't matter for reading
+	// pages, which are decompressed with the codec they were stored with.
+	Codec Codec
+}
+
+// Tags returns the tags in the dataset (the names of its subdirectories).
+func (ds *Dataset) Tags() ([]string, error) {
+	entries, err := os.ReadDir(ds.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			tags = append(tags, entry.Name())
+		}
+	}
+	return tags, nil
+}
+
+// HasTag reports whether tag is one of the tags in the dataset.
+func (ds *Dataset) HasTag(tag string) (bool, error) {
+	tags, err := ds.Tags()
+	if err != nil {
+		return false, err
+	}
+	for _, t := range tags {
+		if t == tag {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// TagDir returns the path of the directory holding the pages of tag.
+func (ds *Dataset) TagDir(tag string) string {
+	return filepath.Join(ds.Dir, tag)
+}
+
+// ValidatorsPath returns the path of the file caching the validators of the
+// pages of tag.
+func (ds *Dataset) ValidatorsPath(tag string) string {
+	return filepath.Join(ds.TagDir(tag), "validators.cache")
+}
+
+// pageFiles returns the paths of the files holding the pages of tag: the ones
+// stored with StorePage, latest first, followed by the other JSON files in the
+// tag directory (pages fetched by older versions or merged by Compact).
+func (ds *Dataset) pageFiles(tag string) ([]string, error) {
+	paths, err := ds.pagePaths(tag)
+	if err != nil {
+		return nil, err
+	}
+	unindexed, err := ds.unindexedFiles(tag)
+	if err != nil {
+		return nil, err
+	}
+	return append(paths, unindexed...), nil
+}
+
+// unindexedFiles returns the paths of the JSON files in the tag directory.
+func (ds *Dataset) unindexedFiles(tag string) ([]string, error) {
+	dirName := ds.TagDir(tag)
+	entries, err := os.ReadDir(dirName)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), "json") {
+			paths = append(paths, filepath.Join(dirName, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// ForEachPage calls fn with the path and the contents of each file holding
+// pages of tag, in the order they are analyzed in: the stored pages latest
+// first, followed by the other files.
+func (ds *Dataset) ForEachPage(ctx context.Context, tag string, fn func(path string, body []byte) error) error {
+	paths, err := ds.pageFiles(tag)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		body, err := readPage(path)
+		if err != nil {
+			return err
+		}
+		if err := fn(path, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForEachItem calls fn for each question with the given tag in the dataset. If
+// fromDate and toDate are non-zero, then only questions between fromDate and
+// toDate (inclusive) are considered. A question found in several pages (e.g.
+// from fetching overlapping windows) is only considered once, as found in the
+// latest stored page. The pages are decoded in parallel (see Parallelism), but
+// fn is called from the calling goroutine. With a Cache, the questions are
+// read from memory once cached, and fn must not modify them.
+func (ds *Dataset) ForEachItem(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, fn func(item *soapi.Item)) error {
+	if ds.Cache != nil {
+		return ds.Cache.forEachItem(ctx, ds, tag, fromDate, toDate, fn)
+	}
+	return ds.forEachStoredItem(ctx, tag, fromDate, toDate, fn)
+}
+
+// forEachStoredItem is ForEachItem reading the questions from the files of the
+// tag.
+func (ds *Dataset) forEachStoredItem(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, fn func(item *soapi.Item)) error {
+	paths, err := ds.pageFiles(tag)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool)
+	return decodePages(ctx, paths, ds.Parallelism, func(path string, reply *soapi.Reply) error {
+		for i := range reply.Items {
+			if seen[reply.Items[i].QuestionID] {
+				continue
+			}
+			seen[reply.Items[i].QuestionID] = true
+
+			itemDate := time.Unix(int64(reply.Items[i].CreationDate), 0)
+			if !fromDate.IsZero() && itemDate.Before(fromDate) {
+				continue
+			}
+			if !toDate.IsZero() && itemDate.After(toDate) {
+				continue
+			}
+			fn(&reply.Items[i])
+		}
+		return nil
+	})
+}
@@ -0,0 +1,232 @@
+package soanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// storeItems stores a page of questions of tag with the given IDs and scores,
+// as the only page of the window starting at from, and fails t on errors.
+func storeItems(t *testing.T, ds *Dataset, tag string, from time.Time, ids []int, score int) {
+	t.Helper()
+	reply := soapi.Reply{}
+	for _, id := range ids {
+		reply.Items = append(reply.Items, soapi.Item{QuestionID: id, Score: score, CreationDate: int(benchStart.Unix()) + id*60, Title: "How do I stop a goroutine?"})
+	}
+	body, err := json.Marshal(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.StorePage(tag, from, from.AddDate(0, 1, 0), 1, body); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readScores returns the scores of the questions of tag by their IDs, failing
+// t if a question is read twice.
+func readScores(t *testing.T, ds *Dataset, tag string) map[int]int {
+	t.Helper()
+	scores := make(map[int]int)
+	err := ds.ForEachItem(context.Background(), tag, time.Time{}, time.Time{}, func(item *soapi.Item) {
+		if _, ok := scores[item.QuestionID]; ok {
+			t.Errorf("question %d read twice", item.QuestionID)
+		}
+		scores[item.QuestionID] = item.Score
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scores
+}
+
+func TestForEachItemNewestWins(t *testing.T) {
+	tests := []struct {
+		name        string
+		parallelism int
+		cache       bool
+	}{
+		{"sequential", 1, false},
+		{"parallel", 4, false},
+		{"more workers than pages", 64, false},
+		{"default", 0, false},
+		{"cached", 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := &Dataset{Dir: t.TempDir(), Parallelism: tt.parallelism}
+			if tt.cache {
+				ds.Cache = &Cache{}
+			}
+			// Overlapping windows fetched one after another: each has question
+			// 1 with the score of its fetch, and a question of its own.
+			const fetches = 10
+			for i := range fetches {
+				storeItems(t, ds, "go", benchStart.AddDate(0, 0, i), []int{1, 100 + i}, i)
+			}
+
+			scores := readScores(t, ds, "go")
+			if len(scores) != fetches+1 {
+				t.Errorf("got %d questions, want %d", len(scores), fetches+1)
+			}
+			if scores[1] != fetches-1 {
+				t.Errorf("question 1 has score %d, want %d of the newest page", scores[1], fetches-1)
+			}
+		})
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	ds := &Dataset{Dir: t.TempDir()}
+	for _, tag := range []string{"go", "rust", "zig"} {
+		storeItems(t, ds, tag, benchStart, []int{1, 2, 3}, 0)
+	}
+	ct, err := (&Cache{}).load(context.Background(), ds, "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Room for two of the tags, which take the same memory.
+	ds.Cache = &Cache{MaxBytes: 2 * ct.size}
+	for _, tag := range []string{"go", "rust", "go", "zig"} {
+		readScores(t, ds, tag)
+	}
+
+	var cached []string
+	for tag := range ds.Cache.tags {
+		cached = append(cached, tag)
+	}
+	slices.Sort(cached)
+	if want := []string{"go", "zig"}; !slices.Equal(cached, want) {
+		t.Errorf("cached tags %v, want %v", cached, want)
+	}
+	stats := ds.Cache.Stats()
+	if stats.Evictions != 1 || stats.Hits != 1 || stats.Misses != 3 {
+		t.Errorf("got stats %+v, want 1 eviction, 1 hit and 3 misses", stats)
+	}
+}
+
+func TestCacheRefresh(t *testing.T) {
+	ds := &Dataset{Dir: t.TempDir(), Cache: &Cache{}}
+	storeItems(t, ds, "go", benchStart, []int{1, 2}, 0)
+	readScores(t, ds, "go")
+
+	storeItems(t, ds, "go", benchStart.AddDate(0, 0, 1), []int{2, 3}, 5)
+	if err := ds.Cache.Refresh(context.Background(), ds); err != nil {
+		t.Fatal(err)
+	}
+	if reloads := ds.Cache.Stats().Reloads; reloads != 1 {
+		t.Errorf("got %d reloads, want 1", reloads)
+	}
+	scores := readScores(t, ds, "go")
+	if want := map[int]int{1: 0, 2: 5, 3: 5}; !maps.Equal(scores, want) {
+		t.Errorf("got scores %v after refreshing, want %v", scores, want)
+	}
+}
+
+func TestDeletionsLogKeepsNoPersonalData(t *testing.T) {
+	ds := &Dataset{Dir: t.TempDir()}
+	storeItems(t, ds, "go", benchStart, []int{1}, 0)
+
+	item := soapi.Item{QuestionID: 2, CreationDate: int(benchStart.Unix()), Score: -3, ContentLicense: "CC BY-SA 4.0", Title: "Why is my map nil"}
+	item.Owner.UserID = 42
+	item.Owner.DisplayName = "alice"
+	if err := ds.LogDeletions("go", []Deletion{{Detected: benchStart, Item: NewDeletedItem(&item)}}); err != nil {
+		t.Fatal(err)
+	}
+	// An entry as logged before DeletedItem, with the whole question.
+	old := `{"detected":"2020-01-02T00:00:00Z","item":{"question_id":3,"creation_date":1577836800,"score":1,"owner":{"user_id":43,"display_name":"bob"},"title":"How do I stop a goroutine?"}}` + "\n"
+	f, err := os.OpenFile(ds.DeletionsPath("go"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(old); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := ds.Purge(context.Background(), "go", func(item *soapi.Item) bool { return item.Owner.UserID == 43 }, nil); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(ds.DeletionsPath("go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, personal := range []string{"alice", "bob", "user_id", "title"} {
+		if strings.Contains(string(data), personal) {
+			t.Errorf("deletions log has %q after purging:\n%s", personal, data)
+		}
+	}
+
+	deletions, err := ds.Deletions("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DeletedItem{
+		{QuestionID: 2, CreationDate: int(benchStart.Unix()), Score: -3, ContentLicense: "CC BY-SA 4.0"},
+		{QuestionID: 3, CreationDate: 1577836800, Score: 1},
+	}
+	var got []DeletedItem
+	for _, d := range deletions {
+		got = append(got, d.Item)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got deleted questions %+v, want %+v", got, want)
+	}
+}
+
+func TestPurgeRemovesAnswers(t *testing.T) {
+	ds := &Dataset{Dir: t.TempDir()}
+	storeItems(t, ds, "go", benchStart, []int{1, 2}, 0)
+
+	reply := soapi.AnswerReply{}
+	for i, owner := range []int{42, 43, 42} {
+		answer := soapi.Answer{AnswerID: 10 + i, QuestionID: 1 + i%2, Score: i}
+		answer.Owner.UserID = owner
+		reply.Items = append(reply.Items, answer)
+	}
+	body, err := json.Marshal(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(ds.AnswersDir("go"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(ds.AnswersDir("go"), "0001.json"), body, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	questions, answers, err := ds.Purge(context.Background(), "go",
+		func(item *soapi.Item) bool { return item.Owner.UserID == 42 },
+		func(answer *soapi.Answer) bool { return answer.Owner.UserID == 42 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if questions != 0 || answers != 2 {
+		t.Errorf("removed %d questions and %d answers, want 0 and 2", questions, answers)
+	}
+
+	var kept []int
+	err = ds.ForEachAnswer("go", func(answer *soapi.Answer) {
+		kept = append(kept, answer.AnswerID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{11}; !slices.Equal(kept, want) {
+		t.Errorf("kept answers %v, want %v", kept, want)
+	}
+	if scores := readScores(t, ds, "go"); len(scores) != 2 {
+		t.Errorf("got %d questions after purging, want 2", len(scores))
+	}
+}
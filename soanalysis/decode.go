@@ -0,0 +1,121 @@
+package soanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// decodedPage is a page decoded by a worker of decodePages.
+type decodedPage struct {
+	path  string
+	reply soapi.Reply
+	err   error
+}
+
+// The instruments of the reading of datasets, which record with the global
+// OpenTelemetry providers (doing nothing unless they're set up).
+var (
+	tracer = otel.Tracer("github.com/eliben/so-tag-sentiment-analysis/soanalysis")
+	meter  = otel.Meter("github.com/eliben/so-tag-sentiment-analysis/soanalysis")
+
+	decodeHistogram, _ = meter.Float64Histogram("soanalysis.page.decode.duration", metric.WithUnit("s"),
+		metric.WithDescription("Duration of reading and decoding a stored page."))
+)
+
+// decodePage reads and decodes the page at path.
+func decodePage(ctx context.Context, path string) decodedPage {
+	start := time.Now()
+	defer func() { decodeHistogram.Record(ctx, time.Since(start).Seconds()) }()
+	page := decodedPage{path: path}
+	data, err := readPage(path)
+	if err != nil {
+		page.err = err
+		return page
+	}
+	if err := json.Unmarshal(data, &page.reply); err != nil {
+		page.err = fmt.Errorf("unmarshalling %q: %w", path, err)
+	}
+	return page
+}
+
+// decodePages reads and decodes the pages at paths in a pool of parallelism
+// workers (GOMAXPROCS if it's not positive), and calls fn with each page in the
+// order of paths from the calling goroutine, so that fn aggregates them without
+// locking. Decoding is what dominates the analysis of large datasets, and it
+// can't run more than parallelism pages ahead of fn, which bounds the memory
+// used regardless of the number of pages. It stops at the first error, from
+// decoding or from fn, or when ctx is done.
+func decodePages(ctx context.Context, paths []string, parallelism int, fn func(path string, reply *soapi.Reply) error) (err error) {
+	ctx, span := tracer.Start(ctx, "soanalysis.decodePages", trace.WithAttributes(attribute.Int("pages", len(paths))))
+	defer func() {
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+
+	type job struct {
+		path string
+		out  chan decodedPage
+	}
+	jobs := make(chan job)
+	// pending holds the outputs of the jobs in the order of paths, which fn
+	// is called in.
+	pending := make(chan chan decodedPage, parallelism)
+	done := make(chan struct{})
+	defer close(done)
+
+	for range parallelism {
+		go func() {
+			for j := range jobs {
+				j.out <- decodePage(ctx, j.path)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		defer close(pending)
+		for _, path := range paths {
+			// buffered, so that workers never wait for fn
+			out := make(chan decodedPage, 1)
+			select {
+			case pending <- out:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- job{path, out}:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for out := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		page := <-out
+		if page.err != nil {
+			return page.err
+		}
+		if err := fn(page.path, &page.reply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
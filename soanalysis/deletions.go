@@ -0,0 +1,270 @@
+package soanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Deletion is an entry of the deletions log of a tag: a question that was in
+// a stored window of the tag but was missing from it when the window was
+// fetched again, which is how deletions show through the API. Questions that
+// were retagged so they no longer have the tag look the same.
+type Deletion struct {
+	// Detected is when the window was fetched again.
+	Detected time.Time `json:"detected"`
+
+	// Item is what the log keeps of the question as last stored.
+	Item DeletedItem `json:"item"`
+}
+
+// DeletedItem is what the deletions log keeps of a deleted question: the fields
+// its analyses use, without the personal data of its owner or its contents.
+// Logs written before kept whole questions, which decode into it as well.
+type DeletedItem struct {
+	QuestionID   int   `json:"question_id"`
+	CreationDate int   `json:"creation_date"`
+	Score        int   `json:"score"`
+	ClosedDate   int64 `json:"closed_date,omitempty"`
+
+	// ContentLicense is kept for filtering the deleted questions by license,
+	// like the others.
+	ContentLicense string `json:"content_license,omitempty"`
+}
+
+// NewDeletedItem returns what the deletions log keeps of item.
+func NewDeletedItem(item *soapi.Item) DeletedItem {
+	return DeletedItem{
+		QuestionID:     item.QuestionID,
+		CreationDate:   item.CreationDate,
+		Score:          item.Score,
+		ClosedDate:     item.ClosedDate,
+		ContentLicense: item.ContentLicense,
+	}
+}
+
+// item returns the deleted question as a soapi.Item, with only the fields kept
+// in the log set, for analyzing it like the stored questions.
+func (d DeletedItem) item() *soapi.Item {
+	return &soapi.Item{
+		QuestionID:     d.QuestionID,
+		CreationDate:   d.CreationDate,
+		Score:          d.Score,
+		ClosedDate:     d.ClosedDate,
+		ContentLicense: d.ContentLicense,
+	}
+}
+
+// DeletionsPath returns the path of the deletions log of tag, in its
+// directory. It has a JSON object per line.
+func (ds *Dataset) DeletionsPath(tag string) string {
+	return filepath.Join(ds.TagDir(tag), "deletions.log")
+}
+
+// WindowItems returns the questions in the stored pages of the window of tag
+// between fromDate and toDate, as fetched last; there are none if the window
+// wasn't stored with StorePage.
+func (ds *Dataset) WindowItems(tag string, fromDate time.Time, toDate time.Time) ([]soapi.Item, error) {
+	entries, err := ds.readIndex(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []soapi.Item
+	for _, e := range entries {
+		if !e.ref.fromDate.Equal(fromDate) || !e.ref.toDate.Equal(toDate) {
+			continue
+		}
+		data, err := readPage(ds.objectPath(tag, e.hash))
+		if err != nil {
+			return nil, err
+		}
+		var reply soapi.Reply
+		if err := json.Unmarshal(data, &reply); err != nil {
+			return nil, err
+		}
+		items = append(items, reply.Items...)
+	}
+	return items, nil
+}
+
+// LogDeletions appends the deletions to the deletions log of tag.
+func (ds *Dataset) LogDeletions(tag string, deletions []Deletion) error {
+	f, err := os.OpenFile(ds.DeletionsPath(tag), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := writeDeletions(f, deletions); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeDeletions(w io.Writer, deletions []Deletion) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	for _, d := range deletions {
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteDeletions rewrites the deletions log of tag, if any, with only what
+// DeletedItem keeps of the questions, dropping the personal data that logs
+// written before kept.
+func (ds *Dataset) rewriteDeletions(tag string) error {
+	deletions, err := ds.Deletions(tag)
+	if err != nil || deletions == nil {
+		return err
+	}
+	tmp := ds.DeletionsPath(tag) + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := writeDeletions(f, deletions); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ds.DeletionsPath(tag))
+}
+
+// Deletions returns the entries of the deletions log of tag, oldest first;
+// there are none if no deletions were detected.
+func (ds *Dataset) Deletions(tag string) ([]Deletion, error) {
+	f, err := os.Open(ds.DeletionsPath(tag))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Logs written before DeletedItem have whole questions, with possibly long
+	// bodies, so they are decoded as a stream rather than scanned by lines.
+	var deletions []Deletion
+	dec := json.NewDecoder(f)
+	for {
+		var d Deletion
+		if err := dec.Decode(&d); err == io.EOF {
+			return deletions, nil
+		} else if err != nil {
+			return nil, err
+		}
+		deletions = append(deletions, d)
+	}
+}
+
+// DeletionBucket holds the deletions of the questions asked in a period; Date
+// is the end of the period.
+type DeletionBucket struct {
+	Date time.Time
+
+	// Questions is the number of questions asked in the period, including
+	// the deleted ones, and Deleted the number of the deleted ones.
+	Questions int
+	Deleted   int
+
+	// DeletedNegative is the number of deleted questions that had a negative
+	// score when last stored.
+	DeletedNegative int
+}
+
+// DeletedRatio returns the ratio of the questions that were deleted.
+func (b DeletionBucket) DeletedRatio() float64 {
+	return ratio(b.Deleted, b.Questions)
+}
+
+// DeletionSeries is the analysis of the deletions of the questions of a tag,
+// with the same buckets as Series.
+type DeletionSeries struct {
+	Tag     string
+	Buckets []DeletionBucket
+}
+
+// Deletions analyzes the deletions of the questions with the given tag between
+// fromDate and toDate, in each of the buckets of Series by the dates the
+// questions were asked. Deleted questions are missing from the pages fetched
+// after their deletion, biasing the other analyses towards the questions that
+// survive; deletions are only detected when windows are fetched again (see
+// Deletion), so the deletions of the questions of windows fetched once are
+// unknown. A question that reappears in the stored pages (e.g. undeleted) isn't
+// considered deleted.
+func (a *Analyzer) Deletions(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool) (DeletionSeries, error) {
+	ds := DeletionSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return ds, err
+	}
+	for _, end := range ends {
+		ds.Buckets = append(ds.Buckets, DeletionBucket{Date: end})
+	}
+
+	var latest time.Time
+	add := func(item *soapi.Item, deleted bool) {
+		if a.Filter != nil && !a.Filter(item) {
+			return
+		}
+		itemDate := time.Unix(int64(item.CreationDate), 0)
+		if !inPeriod(itemDate, fromDate, toDate) {
+			return
+		}
+		if itemDate.After(latest) {
+			latest = itemDate
+		}
+		for i := range ds.Buckets {
+			b := &ds.Buckets[i]
+			if !inPeriod(itemDate, starts[i], b.Date) {
+				continue
+			}
+			b.Questions++
+			if deleted {
+				b.Deleted++
+				if item.Score < 0 {
+					b.DeletedNegative++
+				}
+			}
+		}
+	}
+
+	present := make(map[int]bool)
+	err = a.Dataset.ForEachItem(ctx, tag, time.Time{}, time.Time{}, func(item *soapi.Item) {
+		present[item.QuestionID] = true
+		add(item, false)
+	})
+	if err != nil {
+		return ds, err
+	}
+	deletions, err := a.Dataset.Deletions(tag)
+	if err != nil {
+		return ds, err
+	}
+	for i := range deletions {
+		item := deletions[i].Item.item()
+		if present[item.QuestionID] {
+			continue
+		}
+		// a question may be detected as deleted more than once, e.g. from
+		// overlapping windows
+		present[item.QuestionID] = true
+		add(item, true)
+	}
+
+	if toDate.IsZero() {
+		// if not explicit date, consider the max encountered date
+		ds.Buckets[0].Date = latest
+	}
+	return ds, nil
+}
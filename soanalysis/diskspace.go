@@ -0,0 +1,70 @@
+package soanalysis
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// ErrDiskFull is returned when the file system of a dataset is (nearly) full.
+var ErrDiskFull = errors.New("not enough free disk space")
+
+// The approximate sizes of questions as stored, uncompressed, without and with
+// their bodies, and how much smaller FormatCompressed stores them; see
+// EstimateSize.
+const (
+	questionSize     = 1 << 10
+	questionBodySize = 4 << 10
+	compressionRatio = 4
+)
+
+// EstimateSize estimates the disk space n questions fetched into ds take, with
+// their bodies if bodies is set. It doesn't account for questions stored
+// already, so it overestimates the space re-fetching windows takes.
+func (ds *Dataset) EstimateSize(n int, bodies bool) (int64, error) {
+	format, err := ds.Format()
+	if err != nil {
+		return 0, err
+	}
+	size := int64(n) * questionSize
+	if bodies {
+		size = int64(n) * questionBodySize
+	}
+	if format >= FormatCompressed {
+		size /= compressionRatio
+	}
+	return size, nil
+}
+
+// FreeSpace returns the number of bytes available in the file system of ds, or
+// an error wrapping errors.ErrUnsupported on platforms where it can't be told.
+func (ds *Dataset) FreeSpace() (int64, error) {
+	return freeSpace(ds.Dir)
+}
+
+// CheckFreeSpace returns an error wrapping ErrDiskFull if fewer than min bytes
+// are available in the file system of ds. It passes on platforms where the
+// available space can't be told.
+func (ds *Dataset) CheckFreeSpace(min int64) error {
+	free, err := ds.FreeSpace()
+	if errors.Is(err, errors.ErrUnsupported) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if free < min {
+		return fmt.Errorf("%w: %s available in %q, keeping at least %s", ErrDiskFull, FormatBytes(free), ds.Dir, FormatBytes(min))
+	}
+	return nil
+}
+
+// IsDiskFull reports whether err is ErrDiskFull or an error writing to a full
+// file system.
+func IsDiskFull(err error) bool {
+	return errors.Is(err, ErrDiskFull) || errors.Is(err, syscall.ENOSPC)
+}
+
+// FormatBytes formats a number of bytes in MB, as soapi.Stats does.
+func FormatBytes(n int64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
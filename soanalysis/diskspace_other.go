@@ -0,0 +1,12 @@
+//go:build !(linux || darwin || freebsd)
+
+package soanalysis
+
+import (
+	"errors"
+	"fmt"
+)
+
+func freeSpace(dir string) (int64, error) {
+	return 0, fmt.Errorf("telling the free space of %q: %w", dir, errors.ErrUnsupported)
+}
@@ -0,0 +1,231 @@
+package soanalysis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// ScoreSnapshot is an entry of the scores log of a tag: the score and view
+// count of a question when it was fetched, and whether it was closed. Pages fetched again
+// replace the pages stored before, so the log is what keeps the state of the
+// questions in earlier fetches.
+type ScoreSnapshot struct {
+	QuestionID int `json:"id"`
+
+	// Asked is the creation date of the question, in seconds since the epoch
+	// like soapi.Item.CreationDate.
+	Asked int `json:"asked"`
+
+	Fetched time.Time `json:"fetched"`
+	Score   int       `json:"score"`
+	Closed  bool      `json:"closed,omitempty"`
+
+	// Views is the view count of the question; it's 0 in snapshots logged
+	// before view counts were.
+	Views int `json:"views,omitempty"`
+}
+
+// ScoresPath returns the path of the scores log of tag, in its directory. It
+// has a JSON object per line.
+func (ds *Dataset) ScoresPath(tag string) string {
+	return filepath.Join(ds.TagDir(tag), "scores.log")
+}
+
+// LogScores appends snapshots of the scores of items, fetched at the given
+// time, to the scores log of tag.
+func (ds *Dataset) LogScores(tag string, fetched time.Time, items []soapi.Item) error {
+	f, err := os.OpenFile(ds.ScoresPath(tag), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		s := ScoreSnapshot{
+			QuestionID: item.QuestionID,
+			Asked:      item.CreationDate,
+			Fetched:    fetched,
+			Score:      item.Score,
+			Closed:     item.ClosedDate > 0,
+			Views:      item.ViewCount,
+		}
+		if err := enc.Encode(s); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ScoreSnapshots returns the snapshots in the scores log of tag by question
+// ID, each oldest first; there are none if no scores were logged.
+func (ds *Dataset) ScoreSnapshots(tag string) (map[int][]ScoreSnapshot, error) {
+	f, err := os.Open(ds.ScoresPath(tag))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	snapshots := make(map[int][]ScoreSnapshot)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var s ScoreSnapshot
+		if err := json.Unmarshal(scanner.Bytes(), &s); err != nil {
+			return nil, err
+		}
+		snapshots[s.QuestionID] = append(snapshots[s.QuestionID], s)
+	}
+	for _, ss := range snapshots {
+		slices.SortStableFunc(ss, func(a, b ScoreSnapshot) int { return a.Fetched.Compare(b.Fetched) })
+	}
+	return snapshots, scanner.Err()
+}
+
+// DriftBucket holds the drift of the scores of the questions asked in a
+// period; Date is the end of the period.
+type DriftBucket struct {
+	Date time.Time
+
+	// Tracked is the number of questions with snapshots from at least two
+	// fetches, and MeanChange the mean change of their scores from the first
+	// snapshot to the last one.
+	Tracked    int
+	MeanChange float64
+
+	// Negative is the number of tracked questions whose first snapshot had a
+	// negative score, and Recovered the number of them whose last one
+	// doesn't.
+	Negative  int
+	Recovered int
+}
+
+// RecoveryRatio returns the ratio of the initially negative questions that
+// recovered.
+func (b DriftBucket) RecoveryRatio() float64 {
+	return ratio(b.Recovered, b.Negative)
+}
+
+// DriftWeek holds the snapshots taken in a week of the age of the questions:
+// week 0 is the first week after asking.
+type DriftWeek struct {
+	Week      int
+	Snapshots int
+	MeanScore float64
+
+	// Negative is the number of snapshots with a negative score.
+	Negative int
+}
+
+// NegativeRatio returns the ratio of the snapshots with a negative score.
+func (w DriftWeek) NegativeRatio() float64 {
+	return ratio(w.Negative, w.Snapshots)
+}
+
+// DriftSeries is the analysis of the drift of the scores of a tag, with the
+// same buckets as Series, and the scores of all its questions by age in weeks,
+// up to the oldest snapshot.
+type DriftSeries struct {
+	Tag     string
+	Buckets []DriftBucket
+	Weeks   []DriftWeek
+}
+
+// ScoreDrift analyzes how the scores of the questions with the given tag
+// between fromDate and toDate drift after they are asked, from the snapshots
+// of their scores logged when fetching (see ScoreSnapshot): in each of the
+// buckets of Series, how much the scores of the questions fetched more than
+// once changed and how many of the initially negative ones recovered, and the
+// scores of the snapshots by the age of the questions. Only the questions
+// still stored (and selected by the Filter of the analyzer) are considered.
+func (a *Analyzer) ScoreDrift(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool) (DriftSeries, error) {
+	dr := DriftSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return dr, err
+	}
+	for _, end := range ends {
+		dr.Buckets = append(dr.Buckets, DriftBucket{Date: end})
+	}
+
+	snapshots, err := a.Dataset.ScoreSnapshots(tag)
+	if err != nil {
+		return dr, err
+	}
+
+	const week = 7 * 24 * time.Hour
+	var latest time.Time
+	err = a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		ss := snapshots[item.QuestionID]
+		if len(ss) == 0 {
+			return
+		}
+		itemDate := time.Unix(int64(item.CreationDate), 0)
+		if itemDate.After(latest) {
+			latest = itemDate
+		}
+
+		for _, s := range ss {
+			w := max(int(s.Fetched.Sub(itemDate)/week), 0)
+			for len(dr.Weeks) <= w {
+				dr.Weeks = append(dr.Weeks, DriftWeek{Week: len(dr.Weeks)})
+			}
+			dr.Weeks[w].Snapshots++
+			dr.Weeks[w].MeanScore += float64(s.Score)
+			if s.Score < 0 {
+				dr.Weeks[w].Negative++
+			}
+		}
+
+		first, last := ss[0], ss[len(ss)-1]
+		if !last.Fetched.After(first.Fetched) {
+			return
+		}
+		for i := range dr.Buckets {
+			b := &dr.Buckets[i]
+			if !inPeriod(itemDate, starts[i], b.Date) {
+				continue
+			}
+			b.Tracked++
+			b.MeanChange += float64(last.Score - first.Score)
+			if first.Score < 0 {
+				b.Negative++
+				if last.Score >= 0 {
+					b.Recovered++
+				}
+			}
+		}
+	})
+	if err != nil {
+		return dr, err
+	}
+
+	for i := range dr.Buckets {
+		if dr.Buckets[i].Tracked > 0 {
+			dr.Buckets[i].MeanChange /= float64(dr.Buckets[i].Tracked)
+		}
+	}
+	for i := range dr.Weeks {
+		if dr.Weeks[i].Snapshots > 0 {
+			dr.Weeks[i].MeanScore /= float64(dr.Weeks[i].Snapshots)
+		}
+	}
+	if toDate.IsZero() {
+		// if not explicit date, consider the max encountered date
+		dr.Buckets[0].Date = latest
+	}
+	return dr, nil
+}
@@ -0,0 +1,135 @@
+package soanalysis
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// DuplicateCluster is the questions closed as duplicates of the same original
+// question.
+type DuplicateCluster struct {
+	Original int
+	Title    string
+
+	// Questions are the IDs of the questions closed as duplicates of the
+	// original.
+	Questions []int
+}
+
+// DuplicateBucket holds the clusters of the duplicates asked in a period; Date
+// is the end of the period.
+type DuplicateBucket struct {
+	Date time.Time
+
+	// Closed is the number of closed questions, and Duplicates the number of
+	// them closed as duplicates.
+	Closed     int
+	Duplicates int
+
+	// Clusters are the clusters of the duplicates, largest first.
+	Clusters []DuplicateCluster
+}
+
+// MeanClusterSize returns the average number of duplicates per original
+// question.
+func (b DuplicateBucket) MeanClusterSize() float64 {
+	n := 0
+	for _, c := range b.Clusters {
+		n += len(c.Questions)
+	}
+	return ratio(n, len(b.Clusters))
+}
+
+// DuplicateSeries is the analysis of the duplicates of a tag, with the same
+// buckets as Series; Total holds the clusters of the whole period, dated like
+// the bucket of Series without byMonth.
+type DuplicateSeries struct {
+	Tag     string
+	Total   DuplicateBucket
+	Buckets []DuplicateBucket
+}
+
+// Duplicates clusters the questions with the given tag between fromDate and
+// toDate that were closed as duplicates by their original questions, in each
+// of the buckets of Series and for the whole period. A question closed as a
+// duplicate of several questions is in the cluster of each. The details of
+// closing have to be fetched with the questions (see soapi.ClosedDetails), or
+// there are no duplicates.
+func (a *Analyzer) Duplicates(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool) (DuplicateSeries, error) {
+	ds := DuplicateSeries{Tag: tag, Total: DuplicateBucket{Date: toDate}}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return ds, err
+	}
+
+	total := make(map[int]*DuplicateCluster)
+	clusters := make([]map[int]*DuplicateCluster, len(ends))
+	for i, end := range ends {
+		ds.Buckets = append(ds.Buckets, DuplicateBucket{Date: end})
+		clusters[i] = make(map[int]*DuplicateCluster)
+	}
+	add := func(b *DuplicateBucket, bc map[int]*DuplicateCluster, item *soapi.Item) {
+		if item.ClosedDate == 0 {
+			return
+		}
+		b.Closed++
+		if item.ClosedDetails == nil || len(item.ClosedDetails.OriginalQuestions) == 0 {
+			return
+		}
+		b.Duplicates++
+		for _, orig := range item.ClosedDetails.OriginalQuestions {
+			c, ok := bc[orig.QuestionID]
+			if !ok {
+				c = &DuplicateCluster{Original: orig.QuestionID, Title: orig.Title}
+				bc[orig.QuestionID] = c
+			}
+			c.Questions = append(c.Questions, item.QuestionID)
+		}
+	}
+	var latest time.Time
+	err = a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		itemDate := time.Unix(int64(item.CreationDate), 0)
+		if itemDate.After(latest) {
+			latest = itemDate
+		}
+		add(&ds.Total, total, item)
+		for i := range ds.Buckets {
+			if inPeriod(itemDate, starts[i], ds.Buckets[i].Date) {
+				add(&ds.Buckets[i], clusters[i], item)
+			}
+		}
+	})
+	if err != nil {
+		return ds, err
+	}
+
+	ds.Total.Clusters = sortedClusters(total)
+	for i := range ds.Buckets {
+		ds.Buckets[i].Clusters = sortedClusters(clusters[i])
+	}
+	if toDate.IsZero() {
+		// if not explicit date, consider the max encountered date
+		ds.Total.Date = latest
+		ds.Buckets[0].Date = latest
+	}
+	return ds, nil
+}
+
+// sortedClusters returns the clusters, largest first and then by the ID of the
+// original question.
+func sortedClusters(clusters map[int]*DuplicateCluster) []DuplicateCluster {
+	var sorted []DuplicateCluster
+	for _, c := range clusters {
+		sorted = append(sorted, *c)
+	}
+	slices.SortFunc(sorted, func(a, b DuplicateCluster) int {
+		if len(a.Questions) != len(b.Questions) {
+			return len(b.Questions) - len(a.Questions)
+		}
+		return a.Original - b.Original
+	})
+	return sorted
+}
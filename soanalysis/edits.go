@@ -0,0 +1,173 @@
+package soanalysis
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// ForEachHistory is like ForEachItem, but calls fn with all the distinct
+// copies of each question stored in the dataset, oldest first: pages fetched
+// at different times (e.g. overlapping windows, or refreshes of pages of other
+// windows) hold snapshots of the question at those times. The last copy is the
+// one ForEachItem considers.
+func (ds *Dataset) ForEachHistory(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, fn func(copies []*soapi.Item)) error {
+	paths, err := ds.pageFiles(tag)
+	if err != nil {
+		return err
+	}
+
+	histories := make(map[int][]*soapi.Item)
+	var ids []int
+	slices.Reverse(paths)
+	err = decodePages(ctx, paths, ds.Parallelism, func(path string, reply *soapi.Reply) error {
+		for j := range reply.Items {
+			item := &reply.Items[j]
+			itemDate := time.Unix(int64(item.CreationDate), 0)
+			if !inPeriod(itemDate, fromDate, toDate) {
+				continue
+			}
+			history, ok := histories[item.QuestionID]
+			if !ok {
+				ids = append(ids, item.QuestionID)
+			}
+			if n := len(history); n > 0 && sameSnapshot(history[n-1], item) {
+				continue
+			}
+			histories[item.QuestionID] = append(history, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		fn(histories[id])
+	}
+	return nil
+}
+
+// sameSnapshot reports whether a and b are copies of a question in the same
+// state, as far as the analyses are concerned.
+func sameSnapshot(a *soapi.Item, b *soapi.Item) bool {
+	return a.Score == b.Score && a.LastEditDate == b.LastEditDate && a.LastActivityDate == b.LastActivityDate &&
+		a.ClosedDate == b.ClosedDate && a.ViewCount == b.ViewCount
+}
+
+// EditBucket holds the edit activity of the questions asked in a period; Date
+// is the end of the period.
+type EditBucket struct {
+	Date time.Time
+
+	Questions int
+
+	// Edited is the number of questions edited after being asked.
+	Edited int
+
+	// MedianTimeToEdit is the median time between asking and editing the
+	// edited questions. The API only tells the time of the last edit, so the
+	// earliest one in the snapshots of a question is used.
+	MedianTimeToEdit time.Duration
+
+	// NegativeEdited is the number of questions that had a negative score in
+	// a snapshot and were edited after it, and Recovered the number of them
+	// whose score isn't negative anymore.
+	NegativeEdited int
+	Recovered      int
+}
+
+// EditedRatio returns the ratio of the questions that were edited.
+func (b EditBucket) EditedRatio() float64 {
+	return ratio(b.Edited, b.Questions)
+}
+
+// RecoveryRatio returns the ratio of the negative questions edited after being
+// downvoted that recovered.
+func (b EditBucket) RecoveryRatio() float64 {
+	return ratio(b.Recovered, b.NegativeEdited)
+}
+
+// EditSeries is the analysis of the edit activity of a tag, with the same
+// buckets as Series.
+type EditSeries struct {
+	Tag     string
+	Buckets []EditBucket
+}
+
+// Edits analyzes the edit activity of the questions with the given tag between
+// fromDate and toDate, in each of the buckets of Series, as a measure of the
+// effort of the community to curate them. Telling whether negative questions
+// recover after being edited requires several snapshots of them; see
+// Dataset.ForEachHistory.
+func (a *Analyzer) Edits(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool) (EditSeries, error) {
+	es := EditSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return es, err
+	}
+	times := make([][]time.Duration, len(ends))
+	for _, end := range ends {
+		es.Buckets = append(es.Buckets, EditBucket{Date: end})
+	}
+
+	var latest time.Time
+	err = a.Dataset.ForEachHistory(ctx, tag, fromDate, toDate, func(copies []*soapi.Item) {
+		last := copies[len(copies)-1]
+		if a.Filter != nil && !a.Filter(last) {
+			return
+		}
+		itemDate := time.Unix(int64(last.CreationDate), 0)
+		if itemDate.After(latest) {
+			latest = itemDate
+		}
+
+		firstEdit := 0
+		negativeEdited := false
+		for i, c := range copies {
+			if c.LastEditDate > 0 && (firstEdit == 0 || c.LastEditDate < firstEdit) {
+				firstEdit = c.LastEditDate
+			}
+			if c.Score < 0 && slices.ContainsFunc(copies[i+1:], func(later *soapi.Item) bool {
+				return later.LastEditDate > c.LastEditDate
+			}) {
+				negativeEdited = true
+			}
+		}
+
+		for i := range es.Buckets {
+			b := &es.Buckets[i]
+			if !inPeriod(itemDate, starts[i], b.Date) {
+				continue
+			}
+			b.Questions++
+			if firstEdit > 0 {
+				b.Edited++
+				times[i] = append(times[i], time.Duration(firstEdit-last.CreationDate)*time.Second)
+			}
+			if negativeEdited {
+				b.NegativeEdited++
+				if last.Score >= 0 {
+					b.Recovered++
+				}
+			}
+		}
+	})
+	if err != nil {
+		return es, err
+	}
+
+	for i := range es.Buckets {
+		if n := len(times[i]); n > 0 {
+			slices.Sort(times[i])
+			es.Buckets[i].MedianTimeToEdit = (times[i][(n-1)/2] + times[i][n/2]) / 2
+		}
+	}
+	if toDate.IsZero() {
+		// if not explicit date, consider the max encountered date
+		es.Buckets[0].Date = latest
+	}
+	return es, nil
+}
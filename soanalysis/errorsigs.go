@@ -0,0 +1,204 @@
+package soanalysis
+
+import (
+	"cmp"
+	"context"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+var (
+	// quotedText matches text in double quotes, backquotes, typographic quotes,
+	// or single quotes that aren't apostrophes.
+	quotedText = regexp.MustCompile("\"([^\"]{3,})\"|`([^`]{3,})`|“([^”]{3,})”|‘([^’]{3,})’|(?:^|\\W)'([^']{3,})'(?:\\W|$)")
+
+	// errorWords are the words telling quoted text is an error message.
+	errorWords = regexp.MustCompile(`(?i)\b(error|exception|cannot|can't|could not|couldn't|failed|failure|not found|undefined|unexpected|invalid|denied|refused|no such|unable|missing|fatal|panic|segmentation fault|not defined|not recognized|mismatch|timed out)\b`)
+
+	// errorPrefix matches a message after a prefix like "error:",
+	// "error[E0382]:", "TypeError:" or "panic:", up to the end of the title or
+	// a closing quote.
+	errorPrefix = regexp.MustCompile("(?i)\\b(\\w*error|\\w*exception|panic|fatal|warning)(?:\\[\\w+\\])?\\s*:\\s*([^\"`”]{3,})")
+
+	// knownErrors matches common error messages that titles often give
+	// without quotes.
+	knownErrors = regexp.MustCompile(`(?i)\b(segmentation fault|stack overflow|out of memory|deadlock|(?:nil|null) pointer dereference|index out of range|undefined reference to|permission denied|access denied|connection refused|no such file or directory|command not found)\b`)
+
+	// exceptionName matches the names of exceptions and error types, such as
+	// NullPointerException or ModuleNotFoundError, without their packages.
+	exceptionName = regexp.MustCompile(`\b(?:[a-z_]\w*\.)*([A-Z]\w*(?:Exception|Error))\b`)
+
+	// exitCode matches exit codes, and statusCode HTTP status codes.
+	exitCode   = regexp.MustCompile(`(?i)\b(?:exit(?:ed)? (?:with )?(?:code|status)|exit code|return(?:ed)? code|non-zero exit status)\s*[:=]?\s*(-?\d+)`)
+	statusCode = regexp.MustCompile(`(?i)\b(?:http )?status(?: code)?\s*[:=]?\s*([1-5]\d\d)\b`)
+
+	// errorCode matches the codes of compiler and database errors, such as
+	// E0382 (Rust), CS1061 (C#), TS2322 (TypeScript) or ORA-00942.
+	errorCode = regexp.MustCompile(`\b((?:E|CS|TS|C|LNK)\d{4}|ORA-\d{5})\b`)
+
+	// messageEnd matches where the message of a title ends and its context
+	// begins, as in "cannot find module X when running tests".
+	messageEnd = regexp.MustCompile(`(?i)\s+(?:when|while|after|using|on|in) .*$|\s+[-–—|]\s+.*$|[?.!\s]+$`)
+
+	// The parts of messages varying from question to question.
+	messageQuoted  = regexp.MustCompile("'[^']*'|\"[^\"]*\"|`[^`]*`|‘[^’]*’|“[^”]*”")
+	messageAddress = regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`)
+	messagePath    = regexp.MustCompile(`(?:[a-zA-Z]:)?(?:[/\\][\w.@-]+){2,}`)
+	messageNumber  = regexp.MustCompile(`\b\d+\b`)
+	messageSpace   = regexp.MustCompile(`\s+`)
+)
+
+// ErrorSignatures returns the normalized error signatures in a title: quoted
+// error messages and messages after prefixes like "error:" or "panic:"
+// (lowercased, with the quoted names, numbers, addresses and paths in them
+// replaced by placeholders so that the same error is counted once), common
+// messages like "segmentation fault" even without quotes, names of
+// exceptions (NullPointerException), exit and HTTP status codes ("exit code
+// 137", "status code 404") and codes of compiler errors (E0382). Each
+// signature is returned once.
+func ErrorSignatures(title string) []string {
+	title = strings.ReplaceAll(html.UnescapeString(title), "’", "'")
+	var sigs []string
+	add := func(sig string) {
+		if sig != "" && !slices.Contains(sigs, sig) {
+			sigs = append(sigs, sig)
+		}
+	}
+
+	for _, m := range quotedText.FindAllStringSubmatch(title, -1) {
+		for _, quoted := range m[1:] {
+			if quoted != "" && errorWords.MatchString(quoted) {
+				add(normalizeMessage(quoted))
+			}
+		}
+	}
+	if m := errorPrefix.FindStringSubmatch(title); m != nil {
+		add(strings.ToLower(m[1]) + ": " + normalizeMessage(m[2]))
+	}
+	for _, m := range knownErrors.FindAllString(title, -1) {
+		add(strings.ToLower(m))
+	}
+	for _, m := range exceptionName.FindAllStringSubmatch(title, -1) {
+		add(m[1])
+	}
+	for _, m := range exitCode.FindAllStringSubmatch(title, -1) {
+		add("exit code " + m[1])
+	}
+	for _, m := range statusCode.FindAllStringSubmatch(title, -1) {
+		add("status code " + m[1])
+	}
+	for _, m := range errorCode.FindAllStringSubmatch(title, -1) {
+		add(m[1])
+	}
+	return sigs
+}
+
+// normalizeMessage returns an error message without the context following it,
+// lowercased and with its varying parts replaced by placeholders.
+func normalizeMessage(msg string) string {
+	msg = messageEnd.ReplaceAllString(msg, "")
+	msg = messageQuoted.ReplaceAllString(msg, "'…'")
+	msg = messageAddress.ReplaceAllString(msg, "ADDR")
+	msg = messagePath.ReplaceAllString(msg, "PATH")
+	msg = messageNumber.ReplaceAllString(msg, "N")
+	msg = messageSpace.ReplaceAllString(msg, " ")
+	return strings.ToLower(strings.Trim(msg, " :;,.-"))
+}
+
+// ErrorCount is the number of questions with an error signature.
+type ErrorCount struct {
+	Signature string
+	Questions int
+}
+
+// ErrorBucket holds the most frequent error signatures of the questions of a
+// period; Date is the end of the period. Questions is the number of questions
+// of the period, and WithErrors the number of them with an error signature.
+type ErrorBucket struct {
+	Date       time.Time
+	Questions  int
+	WithErrors int
+	Top        []ErrorCount
+}
+
+// Share returns the share of the questions of the bucket with the error
+// signature of ec.
+func (b ErrorBucket) Share(ec ErrorCount) float64 {
+	return ratio(ec.Questions, b.Questions)
+}
+
+// ErrorSeries is the most frequent error signatures of a single tag over time,
+// with the same buckets as Series.
+type ErrorSeries struct {
+	Tag     string
+	Buckets []ErrorBucket
+}
+
+// TopErrors computes the n most frequent error signatures (see
+// ErrorSignatures) in the titles of the questions with the given tag, in each
+// of the buckets of Series, most frequent first.
+func (a *Analyzer) TopErrors(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool, n int) (ErrorSeries, error) {
+	es := ErrorSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return es, err
+	}
+
+	// counts[i] maps the signatures of bucket i to their number of questions.
+	counts := make([]map[string]int, len(starts))
+	for i := range counts {
+		counts[i] = make(map[string]int)
+	}
+	buckets := make([]ErrorBucket, len(starts))
+	var maxDate time.Time
+	err = a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		sigs := ErrorSignatures(item.Title)
+		itemDate := time.Unix(int64(item.CreationDate), 0)
+		for i := range starts {
+			if !inPeriod(itemDate, starts[i], ends[i]) {
+				continue
+			}
+			buckets[i].Questions++
+			if len(sigs) > 0 {
+				buckets[i].WithErrors++
+			}
+			for _, sig := range sigs {
+				counts[i][sig]++
+			}
+			if itemDate.After(maxDate) {
+				maxDate = itemDate
+			}
+		}
+	})
+	if err != nil {
+		return es, err
+	}
+
+	for i, sigs := range counts {
+		b := buckets[i]
+		b.Date = ends[i]
+		if b.Date.IsZero() {
+			// if not explicit date, consider the max encountered date
+			b.Date = maxDate
+		}
+		for sig, questions := range sigs {
+			b.Top = append(b.Top, ErrorCount{Signature: sig, Questions: questions})
+		}
+		slices.SortFunc(b.Top, func(x, y ErrorCount) int {
+			if x.Questions != y.Questions {
+				return cmp.Compare(y.Questions, x.Questions)
+			}
+			return strings.Compare(x.Signature, y.Signature)
+		})
+		if len(b.Top) > n {
+			b.Top = b.Top[:n]
+		}
+		es.Buckets = append(es.Buckets, b)
+	}
+	return es, nil
+}
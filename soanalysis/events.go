@@ -0,0 +1,46 @@
+package soanalysis
+
+import (
+	"context"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Event is a named date that may have changed the reception of questions, such
+// as a language release, the launch of a chatbot or a policy change of the
+// site.
+type Event struct {
+	Name string
+	Date time.Time
+}
+
+// EventImpact compares the questions of a tag asked shortly before an event
+// with the ones asked shortly after it.
+type EventImpact struct {
+	Event Event
+
+	// Before holds the questions asked in the days before the event, and After
+	// the ones asked from its date on.
+	Before Result
+	After  Result
+}
+
+// EventImpact compares the questions with the given tag asked in the given
+// number of days before ev with the ones asked in as many days from its date.
+func (a *Analyzer) EventImpact(ctx context.Context, tag string, ev Event, days int) (EventImpact, error) {
+	impact := EventImpact{Event: ev}
+	impact.Before.Robust = a.Robust
+	impact.After.Robust = a.Robust
+
+	fromDate, toDate := ev.Date.AddDate(0, 0, -days), ev.Date.AddDate(0, 0, days)
+	err := a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		date := time.Unix(int64(item.CreationDate), 0)
+		if date.Before(ev.Date) {
+			impact.Before.addItem(item, a.Sentiments)
+		} else if date.Before(toDate) {
+			impact.After.addItem(item, a.Sentiments)
+		}
+	})
+	return impact, err
+}
@@ -0,0 +1,148 @@
+package soanalysis
+
+import (
+	"errors"
+	"math"
+)
+
+// Prediction is a forecast value with its 95% prediction interval.
+type Prediction struct {
+	Value float64
+	Lower float64
+	Upper float64
+}
+
+// ErrForecastLength is returned by Forecast for series too short to fit a
+// trend to.
+var ErrForecastLength = errors.New("forecasting requires at least three values")
+
+// smoothingSteps are the values tried for each smoothing parameter of Forecast.
+var smoothingSteps = []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9}
+
+// Forecast projects values (e.g. a metric of the monthly buckets of a Series)
+// n steps forward with exponential smoothing: Holt-Winters with additive
+// seasons of period values if there are at least two periods of values, and
+// Holt's linear trend method otherwise. The smoothing parameters are the ones
+// minimizing the squared errors of the one-step forecasts of values, and the
+// prediction intervals assume these errors are normal. Forecasts aren't
+// clamped, so ratios may be projected below 0 or above 1.
+func Forecast(values []float64, n int, period int) ([]Prediction, error) {
+	if len(values) < 3 {
+		return nil, ErrForecastLength
+	}
+	if period < 2 || len(values) < 2*period {
+		period = 0
+	}
+
+	var best *holtWinters
+	gammas := []float64{0}
+	if period > 0 {
+		gammas = smoothingSteps
+	}
+	for _, alpha := range smoothingSteps {
+		for _, beta := range smoothingSteps {
+			for _, gamma := range gammas {
+				hw := newHoltWinters(values, period, alpha, beta, gamma)
+				if best == nil || hw.sse < best.sse {
+					best = hw
+				}
+			}
+		}
+	}
+	return best.forecast(n), nil
+}
+
+// holtWinters is exponential smoothing of a series fitted with given
+// parameters; without seasons, period is 0.
+type holtWinters struct {
+	alpha, beta, gamma float64
+	period             int
+
+	level    float64
+	trend    float64
+	seasonal []float64
+
+	// sse is the sum of the squared errors of the one-step forecasts, and
+	// steps their number.
+	sse   float64
+	steps int
+
+	// length is the number of fitted values.
+	length int
+}
+
+func newHoltWinters(values []float64, period int, alpha, beta, gamma float64) *holtWinters {
+	hw := &holtWinters{alpha: alpha, beta: beta, gamma: gamma, period: period, length: len(values)}
+	start := 0
+	if period > 0 {
+		first, second := mean(values[:period]), mean(values[period:2*period])
+		hw.level = first
+		hw.trend = (second - first) / float64(period)
+		hw.seasonal = make([]float64, period)
+		for k := range hw.seasonal {
+			hw.seasonal[k] = values[k] - first
+		}
+		start = period
+	} else {
+		hw.level = values[0]
+		hw.trend = values[1] - values[0]
+		start = 1
+	}
+
+	for t := start; t < len(values); t++ {
+		season := 0.0
+		if period > 0 {
+			season = hw.seasonal[t%period]
+		}
+		e := values[t] - (hw.level + hw.trend + season)
+		hw.sse += e * e
+		hw.steps++
+
+		level := alpha*(values[t]-season) + (1-alpha)*(hw.level+hw.trend)
+		hw.trend = beta*(level-hw.level) + (1-beta)*hw.trend
+		hw.level = level
+		if period > 0 {
+			hw.seasonal[t%period] = gamma*(values[t]-level) + (1-gamma)*season
+		}
+	}
+	return hw
+}
+
+// forecast returns the forecasts of the n steps after the fitted values.
+func (hw *holtWinters) forecast(n int) []Prediction {
+	sigma2 := hw.sse / float64(hw.steps)
+	variance := sigma2
+
+	var predictions []Prediction
+	for h := 1; h <= n; h++ {
+		v := hw.level + float64(h)*hw.trend
+		if hw.period > 0 {
+			v += hw.seasonal[(hw.length+h-1)%hw.period]
+		}
+		if h > 1 {
+			// the variance of the h-step forecast errors of additive
+			// Holt-Winters
+			j := h - 1
+			c := hw.alpha * (1 + float64(j)*hw.beta)
+			if hw.period > 0 && j%hw.period == 0 {
+				c += hw.gamma
+			}
+			variance += sigma2 * c * c
+		}
+		half := 1.96 * math.Sqrt(variance)
+		predictions = append(predictions, Prediction{Value: v, Lower: v - half, Upper: v + half})
+	}
+	return predictions
+}
+
+// mean returns the mean of values, or 0 if there are none.
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
@@ -0,0 +1,150 @@
+package soanalysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/char/html"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/mapping"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// FullTextIndexPath returns the path of the full-text index of the titles and
+// bodies of the questions of tag, a directory in the tag directory.
+func (ds *Dataset) FullTextIndexPath(tag string) string {
+	return filepath.Join(ds.TagDir(tag), "fulltext.bleve")
+}
+
+// HasFullTextIndex reports whether the full-text index of the questions with
+// the given tag was built.
+func (ds *Dataset) HasFullTextIndex(tag string) bool {
+	info, err := os.Stat(ds.FullTextIndexPath(tag))
+	return err == nil && info.IsDir()
+}
+
+// fullTextDoc is the document indexed for a question.
+type fullTextDoc struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// fullTextMapping returns the mapping of the full-text index: titles and bodies
+// are indexed (but not stored) as English text, with the HTML markup of the
+// bodies stripped.
+func fullTextMapping() (mapping.IndexMapping, error) {
+	im := bleve.NewIndexMapping()
+	err := im.AddCustomAnalyzer("html", map[string]any{
+		"type":          custom.Name,
+		"char_filters":  []string{html.Name},
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	title := bleve.NewTextFieldMapping()
+	title.Analyzer = en.AnalyzerName
+	title.Store = false
+	body := bleve.NewTextFieldMapping()
+	body.Analyzer = "html"
+	body.Store = false
+
+	doc := bleve.NewDocumentMapping()
+	doc.AddFieldMappingsAt("title", title)
+	doc.AddFieldMappingsAt("body", body)
+	im.DefaultMapping = doc
+	return im, nil
+}
+
+// BuildFullTextIndex builds the full-text index of the titles and bodies of the
+// questions with the given tag, replacing the one built before, and returns
+// the number of questions indexed. Bodies are only indexed if they were
+// fetched.
+func (ds *Dataset) BuildFullTextIndex(ctx context.Context, tag string) (int, error) {
+	im, err := fullTextMapping()
+	if err != nil {
+		return 0, err
+	}
+
+	// Build into a temporary directory and then replace the index with it, so
+	// that searches never see a partially built index.
+	tmpPath := ds.FullTextIndexPath(tag) + ".tmp"
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return 0, err
+	}
+	index, err := bleve.New(tmpPath, im)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	batch := index.NewBatch()
+	err = ds.ForEachItem(ctx, tag, time.Time{}, time.Time{}, func(item *soapi.Item) {
+		if err != nil {
+			return
+		}
+		n++
+		err = batch.Index(strconv.Itoa(item.QuestionID), fullTextDoc{Title: item.Title, Body: item.Body})
+		if err == nil && batch.Size() >= 1000 {
+			err = index.Batch(batch)
+			batch.Reset()
+		}
+	})
+	if err == nil {
+		err = index.Batch(batch)
+	}
+	if closeErr := index.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if err := os.RemoveAll(ds.FullTextIndexPath(tag)); err != nil {
+		return 0, err
+	}
+	return n, os.Rename(tmpPath, ds.FullTextIndexPath(tag))
+}
+
+// SearchFullText returns the IDs of the questions with the given tag matching
+// query in the full-text index, in no particular order. The query is in the
+// query string syntax of bleve: e.g. "panic" matches questions with the word
+// in their titles or bodies, "+panic -goroutine" ones without "goroutine",
+// and "title:panic" only matches titles.
+func (ds *Dataset) SearchFullText(tag string, query string) ([]int, error) {
+	index, err := bleve.OpenUsing(ds.FullTextIndexPath(tag), map[string]any{"read_only": true})
+	if err != nil {
+		return nil, err
+	}
+	defer index.Close()
+
+	total, err := index.DocCount()
+	if err != nil {
+		return nil, err
+	}
+	req := bleve.NewSearchRequestOptions(bleve.NewQueryStringQuery(query), int(total), 0, false)
+	res, err := index.Search(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for _, hit := range res.Hits {
+		id, err := strconv.Atoi(hit.ID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
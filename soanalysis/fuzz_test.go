@@ -0,0 +1,127 @@
+package soanalysis
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// addPageSeeds adds pages like the ones real fetches produce to the corpus of
+// f: a generated page, a page cut short, an error reply of the API (as returned
+// when throttled) and pages with missing or mistyped fields.
+func addPageSeeds(f *testing.F) {
+	page := genPage(f, 0)
+	f.Add(page)
+	f.Add(page[:len(page)/2])
+	f.Add([]byte(`{"error_id":502,"error_message":"too many requests from this IP","error_name":"throttle_violation"}`))
+	f.Add([]byte(`{"items":null,"has_more":false}`))
+	f.Add([]byte(`{"items":[{}]}`))
+	f.Add([]byte(`{"items":[{"question_id":1,"creation_date":-1,"score":-5,"view_count":-3,"closed_date":1}]}`))
+	f.Add([]byte(`{"items":[{"question_id":"1","tags":"go"}]}`))
+}
+
+// checkResult fails t if the metrics of tr are inconsistent with each other.
+func checkResult(t *testing.T, tr Result) {
+	if tr.Negative > tr.Total || tr.Closed > tr.Total || tr.Unanswered > tr.Total || tr.ClosedAndNegative > tr.Closed || tr.ClosedAndNegative > tr.Negative {
+		t.Fatalf("inconsistent counts: %+v", tr)
+	}
+	for _, r := range []float64{tr.NegativeRatio(), tr.ClosedRatio(), tr.ClosedAndNegativeRatio(), tr.UnansweredRatio()} {
+		if math.IsNaN(r) || r < 0 || r > 1 {
+			t.Fatalf("ratio %v out of [0, 1]: %+v", r, tr)
+		}
+	}
+	for _, m := range []float64{tr.MeanScore(), tr.MeanViews()} {
+		if math.IsNaN(m) {
+			t.Fatalf("mean is NaN: %+v", tr)
+		}
+	}
+}
+
+// FuzzAggregate feeds the questions of arbitrary pages through the aggregation
+// of monthly and sliding-window series and of robust means.
+func FuzzAggregate(f *testing.F) {
+	addPageSeeds(f)
+	from := time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 3, 0)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var reply soapi.Reply
+		if err := json.Unmarshal(data, &reply); err != nil {
+			return
+		}
+
+		monthly, err := NewAggregator("go", from, to, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		windowed, err := NewWindowAggregator("go", from, to, 30*24*time.Hour, 7*24*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total := Result{Robust: Robust{Trim: 0.1, Winsorize: 0.1}}
+		for i := range reply.Items {
+			monthly.Add(&reply.Items[i])
+			windowed.Add(&reply.Items[i])
+			total.Add(&reply.Items[i])
+		}
+
+		checkResult(t, total)
+		if total.Total != len(reply.Items) {
+			t.Fatalf("got %d questions, want %d", total.Total, len(reply.Items))
+		}
+		// cumulative buckets aren't bounded by the total: questions asked at
+		// the end of a month are in both of the periods sharing it
+		for _, b := range monthly.Series().Cumulative().Buckets {
+			checkResult(t, b.Result)
+		}
+		for _, ts := range []Series{monthly.Series(), windowed.Series()} {
+			for _, b := range ts.Buckets {
+				checkResult(t, b.Result)
+				if b.Result.Total > total.Total {
+					t.Fatalf("bucket %v has %d questions out of %d", b.Date, b.Result.Total, total.Total)
+				}
+			}
+		}
+	})
+}
+
+// FuzzForEachItem stores an arbitrary page in a data directory and reads it
+// back, as the analyses do: malformed pages have to fail with an error rather
+// than a panic, and each question has to be seen once.
+func FuzzForEachItem(f *testing.F) {
+	addPageSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ds := &Dataset{Dir: t.TempDir(), Parallelism: 2}
+		if err := os.Mkdir(ds.TagDir("go"), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(ds.TagDir("go"), "so001.json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		seen := make(map[int]bool)
+		err := ds.ForEachItem(t.Context(), "go", time.Time{}, time.Time{}, func(item *soapi.Item) {
+			if seen[item.QuestionID] {
+				t.Fatalf("question %d seen twice", item.QuestionID)
+			}
+			seen[item.QuestionID] = true
+		})
+		if err != nil {
+			return
+		}
+
+		an := &Analyzer{Dataset: ds}
+		ts, err := an.Series(t.Context(), "go", time.Time{}, time.Time{}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkResult(t, ts.Buckets[0].Result)
+		if ts.Buckets[0].Result.Total != len(seen) {
+			t.Fatalf("got %d questions in the series, want %d", ts.Buckets[0].Result.Total, len(seen))
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package soanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Occurrence is a copy of a question stored in the dataset.
+type Occurrence struct {
+	Tag string
+
+	// Path is the file holding the page the question is in.
+	Path string
+
+	// Raw is the question as returned by the API, including the fields soapi
+	// doesn't know.
+	Raw json.RawMessage
+}
+
+// FindQuestion returns all the copies of the question with the given ID stored
+// in the dataset, under any tag. The copies of each tag are in the order
+// ForEachItem reads them, so the first one is the one analyzed.
+func (ds *Dataset) FindQuestion(id int) ([]Occurrence, error) {
+	tags, err := ds.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	var found []Occurrence
+	for _, tag := range tags {
+		paths, err := ds.pageFiles(tag)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			data, err := readPage(path)
+			if err != nil {
+				return nil, err
+			}
+			var page struct {
+				Items []json.RawMessage `json:"items"`
+			}
+			if err := json.Unmarshal(data, &page); err != nil {
+				return nil, fmt.Errorf("unmarshalling %q: %w", path, err)
+			}
+			for _, raw := range page.Items {
+				var item soapi.Item
+				if err := json.Unmarshal(raw, &item); err != nil {
+					return nil, fmt.Errorf("unmarshalling %q: %w", path, err)
+				}
+				if item.QuestionID == id {
+					found = append(found, Occurrence{Tag: tag, Path: path, Raw: raw})
+				}
+			}
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,112 @@
+package soanalysis
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Intent is what the asker of a question is after, as told by its title.
+type Intent string
+
+const (
+	IntentHowTo      Intent = "how-to"
+	IntentDebugging  Intent = "debugging"
+	IntentConceptual Intent = "conceptual"
+	IntentOpinion    Intent = "opinion"
+	IntentResource   Intent = "resource"
+	IntentOther      Intent = "other"
+)
+
+// Intents are all the intents of questions, in the order they're reported.
+var Intents = []Intent{IntentHowTo, IntentDebugging, IntentConceptual, IntentOpinion, IntentResource, IntentOther}
+
+// intentPatterns are the patterns of the titles of each intent, tried in order
+// so that e.g. "Why does this panic?" is debugging rather than conceptual, and
+// "How does defer work?" is conceptual rather than how-to.
+var intentPatterns = []struct {
+	intent  Intent
+	pattern *regexp.Regexp
+}{
+	{IntentResource, anyOf(
+		`(recommend(ed|ation)?s?|looking for|suggest) (an? |any )?(good )?(library|package|tool|book|tutorial|resource|course|framework|ide)`,
+		`is there (an?|any) (good )?(library|package|tool|module|framework|crate|gem)`,
+		`(good|best) (library|package|tool|book|tutorial|resource|course|framework|ide)s?`,
+		`(book|tutorial|resource|course)s? (for|to learn)`,
+	)},
+	{IntentOpinion, anyOf(
+		`should (i|we)`, `which (one )?is (better|faster|preferred)`, `(good|bad) (idea|practice)`,
+		`best practices?`, `idiomatic`, `(preferred|recommended) way`, `pros and cons`, `is it worth`,
+		`vs\.?`, `versus`, `better (to|way)`, `opinions?`,
+	)},
+	{IntentDebugging, anyOf(
+		`errors?`, `exceptions?`, `fail(s|ed|ing|ure)?`, `crash(es|ed|ing)?`, `panic(s|ked)?`, `segfault`,
+		`segmentation fault`, `(not|isn't|doesn't|does not) work(ing)?`, `won't`, `undefined`, `cannot`,
+		`can't`, `unable`, `traceback`, `stack ?trace`, `warnings?`, `bug`, `wrong`, `unexpected(ly)?`,
+		`incorrect`, `broken`, `deadlock`, `hangs?`, `stuck`, `leaks?`, `time(out|d out)`,
+		`returns? (nil|null|none|empty|undefined)`, `(nil|null) pointer`,
+	)},
+	{IntentConceptual, anyOf(
+		`^why`, `^what('s| is| are| does| do)`, `^when (should|to|is|are|does)`, `^is it (true|necessary)`,
+		`^how (does|do) .*work`, `differences? between`, `(meaning|purpose|semantics) of`, `explain`,
+		`explanation`, `understand(ing)?`, `under the hood`, `why (does|is|are|do)`,
+	)},
+	{IntentHowTo, anyOf(
+		`^how`, `^can i`, `^is (it|this) possible`, `^is there (an?|any) (way|option)`, `how (to|do|can|would|should) i`,
+		`ways? to`, `convert`, `implement`, `create`, `add`, `get`, `set`, `read`, `write`, `parse`, `generate`,
+		`iterate`, `check (if|whether)`, `find`, `remove`, `replace`, `sort`,
+	)},
+}
+
+// anyOf returns a pattern matching any of the given alternatives as whole
+// words.
+func anyOf(alternatives ...string) *regexp.Regexp {
+	return regexp.MustCompile(`\b(` + strings.Join(alternatives, "|") + `)\b`)
+}
+
+// ClassifyIntent returns the intent of a question by heuristics on its title:
+// whether it asks how to do something (how-to), about an error or unexpected
+// behavior (debugging), why something is the way it is (conceptual), which
+// option is better (opinion), or for a library, tool or learning resource
+// (resource). Titles matching none of these are IntentOther.
+func ClassifyIntent(title string) Intent {
+	title = strings.ToLower(strings.ReplaceAll(html.UnescapeString(title), "’", "'"))
+	for _, ip := range intentPatterns {
+		if ip.pattern.MatchString(title) {
+			return ip.intent
+		}
+	}
+	return IntentOther
+}
+
+// IntentResult is the result of the questions of an intent, and Share their
+// share of all the questions.
+type IntentResult struct {
+	Intent Intent
+	Result Result
+	Share  float64
+}
+
+// IntentResults computes the results of the questions with the given tag in
+// the period, by their intent (see ClassifyIntent), in the order of Intents.
+func (a *Analyzer) IntentResults(ctx context.Context, tag string, fromDate time.Time, toDate time.Time) ([]IntentResult, error) {
+	results := make([]IntentResult, len(Intents))
+	index := make(map[Intent]int)
+	for i, intent := range Intents {
+		results[i] = IntentResult{Intent: intent, Result: Result{Robust: a.Robust}}
+		index[intent] = i
+	}
+	total := 0
+	err := a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		results[index[ClassifyIntent(item.Title)]].Result.addItem(item, a.Sentiments)
+		total++
+	})
+	for i := range results {
+		results[i].Share = ratio(results[i].Result.Total, total)
+	}
+	return results, err
+}
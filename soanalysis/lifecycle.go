@@ -0,0 +1,99 @@
+package soanalysis
+
+import (
+	"context"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Stage is a stage of the lifecycle of a tag, by the trend of its number of
+// questions.
+type Stage string
+
+const (
+	StageGrowing   Stage = "growing"
+	StagePlateau   Stage = "plateau"
+	StageDeclining Stage = "declining"
+)
+
+// lifecycleChange is the relative change of the monthly number of questions
+// beyond which a tag is considered growing or declining.
+const lifecycleChange = 0.1
+
+// Lifecycle summarizes the history of the volume of questions of a tag.
+type Lifecycle struct {
+	Tag string
+
+	// First is the creation date of the first question.
+	First time.Time
+
+	// PeakMonth is the start of the month with the most questions, and
+	// PeakQuestions their number.
+	PeakMonth     time.Time
+	PeakQuestions int
+
+	// Trailing is the average number of questions per month in the last
+	// complete months of the period, and Previous the average in as many
+	// months before them.
+	Trailing float64
+	Previous float64
+
+	Stage Stage
+}
+
+// Lifecycle summarizes the history of the questions with the given tag between
+// fromDate and toDate, classifying its stage by comparing the volume of
+// questions of the last window complete months with the one of the window
+// months before them. Months end by toDate or, if it's zero, before the month
+// of the latest question, which is likely incomplete. Months start in the
+// location of fromDate.
+func (a *Analyzer) Lifecycle(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, window int) (Lifecycle, error) {
+	lc := Lifecycle{Tag: tag}
+	loc := fromDate.Location()
+
+	counts := make(map[int]int)
+	lastMonth := 0
+	err := a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		date := time.Unix(int64(item.CreationDate), 0)
+		if lc.First.IsZero() || date.Before(lc.First) {
+			lc.First = date
+		}
+		month := monthIndex(date, loc)
+		counts[month]++
+		lastMonth = max(lastMonth, month)
+	})
+	if err != nil || len(counts) == 0 {
+		return lc, err
+	}
+
+	for month, n := range counts {
+		if n > lc.PeakQuestions || (n == lc.PeakQuestions && month < monthIndex(lc.PeakMonth, loc)) {
+			lc.PeakMonth = time.Date(month/12, time.Month(month%12+1), 1, 0, 0, 0, 0, loc)
+			lc.PeakQuestions = n
+		}
+	}
+
+	// endMonth is the month after the last complete month
+	endMonth := lastMonth
+	if !toDate.IsZero() {
+		endMonth = monthIndex(toDate.AddDate(0, 0, 1), loc)
+	}
+	window = max(window, 1)
+	for i := 1; i <= window; i++ {
+		lc.Trailing += float64(counts[endMonth-i])
+		lc.Previous += float64(counts[endMonth-window-i])
+	}
+	lc.Trailing /= float64(window)
+	lc.Previous /= float64(window)
+
+	switch {
+	case lc.Trailing > lc.Previous*(1+lifecycleChange):
+		lc.Stage = StageGrowing
+	case lc.Trailing < lc.Previous*(1-lifecycleChange) || lc.Trailing == 0:
+		lc.Stage = StageDeclining
+	default:
+		lc.Stage = StagePlateau
+	}
+	return lc, nil
+}
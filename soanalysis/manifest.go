@@ -0,0 +1,369 @@
+package soanalysis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// The versions of the format of data directories. Each version can read the
+// layouts of the previous ones, so data directories keep working after
+// upgrading the programs, and Migrate upgrades them to get the improvements of
+// the current version.
+const (
+	// FormatLegacy has the pages of each tag in files named so001.json,
+	// so002.json and so on, as written by the first versions of
+	// fetch-all-questions.
+	FormatLegacy = 1
+
+	// FormatIndexed has the pages stored by the hash of their contents, with an
+	// index of the fetched windows; see StorePage.
+	FormatIndexed = 2
+
+	// FormatCompressed is FormatIndexed with the pages compressed, with gzip
+	// or another Codec; versions of the programs before codecs could be chosen
+	// only read the pages compressed with gzip.
+	FormatCompressed = 3
+
+	// CurrentFormat is the format new data directories are created in.
+	CurrentFormat = FormatCompressed
+)
+
+// ErrNewerFormat is returned when writing to or migrating a data directory of
+// a format newer than CurrentFormat, written by a newer version of the
+// programs.
+var ErrNewerFormat = errors.New("data directory is of a newer format; upgrade the programs")
+
+// Manifest describes a data directory; it's stored in its manifest.json file.
+type Manifest struct {
+	// Format is the version of the format of the data directory.
+	Format int `json:"format"`
+
+	// Updated is when the manifest was last written.
+	Updated time.Time `json:"updated"`
+
+	// LastFetch describes the last run of fetch-all-questions into the data
+	// directory, if recorded.
+	LastFetch *FetchRun `json:"last_fetch,omitempty"`
+}
+
+// FetchRun describes a run of fetch-all-questions, with the statistics of the
+// requests it made.
+type FetchRun struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+
+	// Site is the site the run fetched from; it's empty for runs recorded
+	// before the site could be chosen, which fetched from stackoverflow.
+	Site string `json:"site,omitempty"`
+
+	soapi.Stats
+}
+
+// ManifestPath returns the path of the manifest of the dataset.
+func (ds *Dataset) ManifestPath() string {
+	return filepath.Join(ds.Dir, "manifest.json")
+}
+
+// Manifest returns the manifest of the dataset. Data directories written
+// before manifests get one with the format detected from their contents.
+func (ds *Dataset) Manifest() (*Manifest, error) {
+	data, err := os.ReadFile(ds.ManifestPath())
+	if errors.Is(err, os.ErrNotExist) {
+		format, err := ds.detectFormat()
+		if err != nil {
+			return nil, err
+		}
+		return &Manifest{Format: format}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("unmarshalling %q: %w", ds.ManifestPath(), err)
+	}
+	return &m, nil
+}
+
+// Format returns the version of the format of the dataset: the one recorded in
+// its manifest or, for data directories written before manifests, the one
+// detected from their contents. A dataset without data is in CurrentFormat.
+func (ds *Dataset) Format() (int, error) {
+	m, err := ds.Manifest()
+	if err != nil {
+		return 0, err
+	}
+	return m.Format, nil
+}
+
+// Site returns the Stack Exchange site the dataset was last fetched from, as
+// recorded in its manifest; it's soapi.DefaultSite if no site was recorded.
+func (ds *Dataset) Site() (string, error) {
+	m, err := ds.Manifest()
+	if err != nil {
+		return "", err
+	}
+	if m.LastFetch == nil || m.LastFetch.Site == "" {
+		return soapi.DefaultSite, nil
+	}
+	return m.LastFetch.Site, nil
+}
+
+// detectFormat returns the format of a dataset without a manifest, from its
+// contents.
+func (ds *Dataset) detectFormat() (int, error) {
+	tags, err := ds.Tags()
+	if errors.Is(err, os.ErrNotExist) {
+		return CurrentFormat, nil
+	} else if err != nil {
+		return 0, err
+	}
+	format := CurrentFormat
+	for _, tag := range tags {
+		legacy, err := ds.legacyFiles(tag)
+		if err != nil {
+			return 0, err
+		}
+		if len(legacy) > 0 {
+			return FormatLegacy, nil
+		}
+		if _, err := os.Stat(ds.indexPath(tag)); err == nil {
+			format = FormatIndexed
+		}
+	}
+	return format, nil
+}
+
+// writeManifest records format as the format of the dataset.
+func (ds *Dataset) writeManifest(format int) error {
+	return ds.updateManifest(func(m *Manifest) { m.Format = format })
+}
+
+// RecordFetch records run as the last fetch into the dataset in its manifest.
+func (ds *Dataset) RecordFetch(run FetchRun) error {
+	return ds.updateManifest(func(m *Manifest) { m.LastFetch = &run })
+}
+
+// updateManifest writes the manifest of the dataset as changed by update.
+func (ds *Dataset) updateManifest(update func(m *Manifest)) error {
+	m, err := ds.Manifest()
+	if err != nil {
+		return err
+	}
+	update(m)
+	m.Updated = time.Now().UTC()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ds.Dir, 0777); err != nil {
+		return err
+	}
+	tmp := ds.ManifestPath() + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ds.ManifestPath())
+}
+
+// writeFormat returns the format to store pages in, recording it in the
+// manifest if there's none yet.
+func (ds *Dataset) writeFormat() (int, error) {
+	format, err := ds.Format()
+	if err != nil {
+		return 0, err
+	}
+	if format > CurrentFormat {
+		return 0, ErrNewerFormat
+	}
+	if _, err := os.Stat(ds.ManifestPath()); errors.Is(err, os.ErrNotExist) {
+		if err := ds.writeManifest(format); err != nil {
+			return 0, err
+		}
+	}
+	return format, nil
+}
+
+// migrations upgrade a tag of a data directory from the format they are keyed
+// by to the next one. They can be run again on a tag that was partially
+// migrated, e.g. if a migration was interrupted.
+var migrations = map[int]func(ds *Dataset, tag string) error{
+	FormatLegacy:  (*Dataset).indexLegacyFiles,
+	FormatIndexed: (*Dataset).compressObjects,
+}
+
+// Migrate upgrades the dataset to CurrentFormat, calling progress (if not nil)
+// before each step of each tag, and returns the format it was in. The manifest
+// is updated after each step, so an interrupted migration resumes from the
+// last complete step when run again.
+func (ds *Dataset) Migrate(progress func(tag string, from int)) (int, error) {
+	from, err := ds.Format()
+	if err != nil {
+		return 0, err
+	}
+	if from > CurrentFormat {
+		return from, ErrNewerFormat
+	}
+	tags, err := ds.Tags()
+	if err != nil {
+		return from, err
+	}
+
+	for format := from; format < CurrentFormat; format++ {
+		for _, tag := range tags {
+			if progress != nil {
+				progress(tag, format)
+			}
+			if err := migrations[format](ds, tag); err != nil {
+				return from, fmt.Errorf("migrating tag %q from format %d: %w", tag, format, err)
+			}
+		}
+		if err := ds.writeManifest(format + 1); err != nil {
+			return from, err
+		}
+	}
+	if from == CurrentFormat {
+		// Record the format of directories written before manifests.
+		return from, ds.writeManifest(from)
+	}
+	return from, nil
+}
+
+// legacyName matches the names of the files of FormatLegacy, capturing the
+// number of the page.
+var legacyName = regexp.MustCompile(`^so(\d+)\.json$`)
+
+// legacyFiles returns the paths of the files of tag in FormatLegacy, by the
+// number of their pages.
+func (ds *Dataset) legacyFiles(tag string) ([]string, error) {
+	paths, err := ds.unindexedFiles(tag)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(paths, func(path string) bool {
+		return !legacyName.MatchString(filepath.Base(path))
+	}), nil
+}
+
+// indexLegacyFiles stores the pages of tag in FormatLegacy by the hash of their
+// contents, as StorePage does. Their windows weren't recorded, so they are
+// indexed with zero dates, before the pages of known windows: fetching their
+// windows again stores the pages anew, which are analyzed rather than them.
+func (ds *Dataset) indexLegacyFiles(tag string) error {
+	paths, err := ds.legacyFiles(tag)
+	if err != nil || len(paths) == 0 {
+		return err
+	}
+	entries, err := ds.readIndex(tag)
+	if err != nil {
+		return err
+	}
+
+	// The index lists the latest pages last, and legacy files were read in
+	// the order of their names, so the first one goes last.
+	var legacy []indexEntry
+	for _, path := range slices.Backward(paths) {
+		page, _ := strconv.Atoi(legacyName.FindStringSubmatch(filepath.Base(path))[1])
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		hash, _, err := ds.storeObject(tag, body)
+		if err != nil {
+			return err
+		}
+		if err := keepModTime(path, ds.objectPath(tag, hash)); err != nil {
+			return err
+		}
+		ref := pageRef{page: page}
+		entries = removeEntries(entries, func(e indexEntry) bool { return e.ref.equal(ref) })
+		legacy = append(legacy, indexEntry{ref: ref, hash: hash})
+	}
+	if err := ds.writeIndex(tag, append(legacy, entries...)); err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compressObjects compresses the pages of tag stored by hash, with the codec of
+// the dataset.
+func (ds *Dataset) compressObjects(tag string) error {
+	codec := ds.codec()
+	dir := filepath.Join(ds.TagDir(tag), objectsDir)
+	objects, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if !strings.HasSuffix(object.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, object.Name())
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		compressed, err := codec.Compress(body)
+		if err != nil {
+			return err
+		}
+		tmp := path + codec.Ext() + ".tmp"
+		if err := os.WriteFile(tmp, compressed, 0644); err != nil {
+			return err
+		}
+		if err := keepModTime(path, tmp); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path+codec.Ext()); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keepModTime sets the modification time of the file at path to the one of the
+// file at original, which tells when the page was fetched (see Stats).
+func keepModTime(original string, path string) error {
+	info, err := os.Stat(original)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(path, time.Time{}, info.ModTime())
+}
+
+// readPage reads a file holding pages, decompressing it with the codec it was
+// compressed with in FormatCompressed, if any.
+func readPage(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	codec := detectCodec(data)
+	if codec == nil {
+		return data, nil
+	}
+	data, err = codec.Decompress(data)
+	if err != nil {
+		return nil, fmt.Errorf("decompressing %q: %w", path, err)
+	}
+	return data, nil
+}
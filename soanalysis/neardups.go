@@ -0,0 +1,263 @@
+package soanalysis
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"html"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// The MinHash signatures of titles have minHashes hashes, split into
+// minHashBands bands for locality-sensitive hashing: two titles are compared
+// if all the hashes of any band are equal, which is likely when their Jaccard
+// similarity is well above (1/bands)^(1/rows), about 0.42.
+const (
+	minHashes    = 128
+	minHashBands = 32
+)
+
+// NearDuplicateCluster is a group of questions with similar titles.
+type NearDuplicateCluster struct {
+	// Questions are the IDs of the questions, oldest first, and Title the
+	// title of the oldest.
+	Questions []int
+	Title     string
+}
+
+// NearDuplicateBucket holds the near-duplicate questions asked in a period;
+// Date is the end of the period.
+type NearDuplicateBucket struct {
+	Date time.Time
+
+	// Questions is the number of questions, and NearDuplicates the number of
+	// them whose title is similar to an older question of the analyzed period.
+	Questions      int
+	NearDuplicates int
+}
+
+// NearDuplicateRatio returns the ratio of the questions of the bucket that are
+// near-duplicates of older questions.
+func (b NearDuplicateBucket) NearDuplicateRatio() float64 {
+	return ratio(b.NearDuplicates, b.Questions)
+}
+
+// NearDuplicateSeries is the analysis of the near-duplicate questions of a tag,
+// with the same buckets as Series, and the clusters of near-duplicates of the
+// whole period, largest first.
+type NearDuplicateSeries struct {
+	Tag      string
+	Buckets  []NearDuplicateBucket
+	Clusters []NearDuplicateCluster
+}
+
+// titleShingles is a question whose title is split into the hashes of its
+// terms.
+type titleShingles struct {
+	id     int
+	date   time.Time
+	title  string
+	hashes []uint64
+}
+
+// NearDuplicates clusters the questions with the given tag between fromDate
+// and toDate by the similarity of their titles: the Jaccard similarity of the
+// sets of their stemmed words without stop words, found by MinHash and
+// locality-sensitive hashing. Questions with a similarity of at least
+// threshold are in the same cluster, along with the questions similar to
+// them. In each of the buckets of Series, it counts the questions similar to
+// an older question of the period, an estimate of the volume of repeated
+// questions that complements the questions closed as duplicates by
+// moderators (see Duplicates). Titles with fewer than two words are left out.
+func (a *Analyzer) NearDuplicates(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool, threshold float64) (NearDuplicateSeries, error) {
+	ns := NearDuplicateSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return ns, err
+	}
+	ta, err := NewTermAnalyzer(TermOptions{Language: "en", Stem: true})
+	if err != nil {
+		return ns, err
+	}
+
+	var titles []titleShingles
+	var latest time.Time
+	for _, end := range ends {
+		ns.Buckets = append(ns.Buckets, NearDuplicateBucket{Date: end})
+	}
+	err = a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		itemDate := time.Unix(int64(item.CreationDate), 0)
+		if itemDate.After(latest) {
+			latest = itemDate
+		}
+		for i := range ns.Buckets {
+			if inPeriod(itemDate, starts[i], ns.Buckets[i].Date) {
+				ns.Buckets[i].Questions++
+			}
+		}
+		title := html.UnescapeString(item.Title)
+		if hashes := termHashes(ta, title); len(hashes) >= 2 {
+			titles = append(titles, titleShingles{id: item.QuestionID, date: itemDate, title: title, hashes: hashes})
+		}
+	})
+	if err != nil {
+		return ns, err
+	}
+	if toDate.IsZero() {
+		// if not explicit date, consider the max encountered date
+		ns.Buckets[0].Date = latest
+	}
+
+	for _, cluster := range clusterTitles(titles, threshold) {
+		slices.SortFunc(cluster, func(x, y titleShingles) int {
+			if c := x.date.Compare(y.date); c != 0 {
+				return c
+			}
+			return cmp.Compare(x.id, y.id)
+		})
+		c := NearDuplicateCluster{Title: cluster[0].title}
+		for j, t := range cluster {
+			c.Questions = append(c.Questions, t.id)
+			if j == 0 {
+				continue
+			}
+			for i := range ns.Buckets {
+				if inPeriod(t.date, starts[i], ns.Buckets[i].Date) {
+					ns.Buckets[i].NearDuplicates++
+				}
+			}
+		}
+		ns.Clusters = append(ns.Clusters, c)
+	}
+	slices.SortFunc(ns.Clusters, func(x, y NearDuplicateCluster) int {
+		if len(x.Questions) != len(y.Questions) {
+			return len(y.Questions) - len(x.Questions)
+		}
+		return x.Questions[0] - y.Questions[0]
+	})
+	return ns, nil
+}
+
+// termHashes returns the sorted hashes of the distinct terms of a title.
+func termHashes(ta *TermAnalyzer, title string) []uint64 {
+	var hashes []uint64
+	for _, t := range ta.Terms(title) {
+		h := fnv.New64a()
+		h.Write([]byte(t.Stem))
+		hashes = append(hashes, h.Sum64())
+	}
+	slices.Sort(hashes)
+	return slices.Compact(hashes)
+}
+
+// clusterTitles returns the clusters of the titles with a Jaccard similarity of
+// at least threshold with another title of the cluster, of at least two
+// titles each. Candidate pairs are the titles whose MinHash signatures share a
+// band, and their similarity is computed exactly.
+func clusterTitles(titles []titleShingles, threshold float64) [][]titleShingles {
+	parent := make([]int, len(titles))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	// Titles with the same terms are united directly, and only the first of
+	// them is hashed and compared with the others.
+	rows := minHashes / minHashBands
+	buckets := make(map[[2]uint64][]int)
+	exact := make(map[string]int)
+	for i, t := range titles {
+		var key []byte
+		for _, h := range t.hashes {
+			key = binary.LittleEndian.AppendUint64(key, h)
+		}
+		if j, ok := exact[string(key)]; ok {
+			parent[find(i)] = find(j)
+			continue
+		}
+		exact[string(key)] = i
+
+		sig := minHash(t.hashes)
+		for band := range minHashBands {
+			h := fnv.New64a()
+			var buf []byte
+			for _, v := range sig[band*rows : (band+1)*rows] {
+				buf = binary.LittleEndian.AppendUint64(buf, v)
+			}
+			h.Write(buf)
+			key := [2]uint64{uint64(band), h.Sum64()}
+			for _, j := range buckets[key] {
+				if find(i) != find(j) && jaccard(t.hashes, titles[j].hashes) >= threshold {
+					parent[find(i)] = find(j)
+				}
+			}
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	members := make(map[int][]titleShingles)
+	for i, t := range titles {
+		root := find(i)
+		members[root] = append(members[root], t)
+	}
+	var clusters [][]titleShingles
+	for _, root := range slices.Sorted(maps.Keys(members)) {
+		if len(members[root]) > 1 {
+			clusters = append(clusters, members[root])
+		}
+	}
+	return clusters
+}
+
+// minHash returns the MinHash signature of a set of hashes: for each of
+// minHashes hash functions, the minimum over the set.
+func minHash(hashes []uint64) []uint64 {
+	sig := make([]uint64, minHashes)
+	for i := range sig {
+		sig[i] = math.MaxUint64
+		seed := splitMix64(uint64(i) + 1)
+		for _, h := range hashes {
+			sig[i] = min(sig[i], splitMix64(h^seed))
+		}
+	}
+	return sig
+}
+
+// splitMix64 is the finalizer of the SplitMix64 generator, a fast hash of
+// 64-bit integers.
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// jaccard returns the Jaccard similarity of two sorted sets of hashes.
+func jaccard(a []uint64, b []uint64) float64 {
+	common := 0
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			common++
+			i++
+			j++
+		case a[i] < b[j]:
+			i++
+		default:
+			j++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
@@ -0,0 +1,91 @@
+package soanalysis
+
+import (
+	"context"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// ProtectionBucket holds the protected and locked questions among the
+// questions asked in a period; Date is the end of the period.
+type ProtectionBucket struct {
+	Date      time.Time
+	Questions int
+
+	// Protected and Locked are the numbers of protected and locked questions.
+	Protected int
+	Locked    int
+
+	// MedianTimeToProtect is the median time between asking and protecting the
+	// protected questions.
+	MedianTimeToProtect time.Duration
+}
+
+// ProtectedRatio returns the ratio of the questions that are protected.
+func (b ProtectionBucket) ProtectedRatio() float64 {
+	return ratio(b.Protected, b.Questions)
+}
+
+// LockedRatio returns the ratio of the questions that are locked.
+func (b ProtectionBucket) LockedRatio() float64 {
+	return ratio(b.Locked, b.Questions)
+}
+
+// ProtectionSeries is the protection and locking of the questions of a single
+// tag over time, with the same buckets as Series.
+type ProtectionSeries struct {
+	Tag     string
+	Buckets []ProtectionBucket
+}
+
+// Protection computes how many of the questions with the given tag in each of
+// the buckets of Series are protected (typically for attracting low-quality
+// answers from new users) or locked (typically for disputes), as signals of
+// moderation pressure beyond closing. The API only reports the protection and
+// locks in place when the questions were fetched, so lifted ones aren't
+// counted.
+func (a *Analyzer) Protection(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool) (ProtectionSeries, error) {
+	ps := ProtectionSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return ps, err
+	}
+	ps.Buckets = make([]ProtectionBucket, len(starts))
+	times := make([][]time.Duration, len(starts))
+
+	var maxDate time.Time
+	err = a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		itemDate := time.Unix(int64(item.CreationDate), 0)
+		if itemDate.After(maxDate) {
+			maxDate = itemDate
+		}
+		for i := range starts {
+			if !inPeriod(itemDate, starts[i], ends[i]) {
+				continue
+			}
+			b := &ps.Buckets[i]
+			b.Questions++
+			if item.ProtectedDate != 0 {
+				b.Protected++
+				times[i] = append(times[i], max(time.Duration(item.ProtectedDate-int64(item.CreationDate))*time.Second, 0))
+			}
+			if item.LockedDate != 0 {
+				b.Locked++
+			}
+		}
+	})
+	if err != nil {
+		return ps, err
+	}
+
+	for i := range ps.Buckets {
+		ps.Buckets[i].MedianTimeToProtect = medianDuration(times[i])
+		ps.Buckets[i].Date = ends[i]
+		if ps.Buckets[i].Date.IsZero() {
+			// if not explicit date, consider the max encountered date
+			ps.Buckets[i].Date = maxDate
+		}
+	}
+	return ps, nil
+}
@@ -0,0 +1,185 @@
+package soanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Purge removes the questions of tag for which match returns true from all the
+// pages they are stored in, and the answers to the questions of tag for which
+// matchAnswer (if not nil) returns true from the pages of answers, and returns
+// the numbers of questions (counting each stored copy) and answers removed. The
+// rest of the pages is kept as is. Pages stored by hash are stored anew with
+// their new contents, and the index points to them instead; their validators
+// are kept, so refreshing a page that didn't change since doesn't bring the
+// removed questions back. The full-text index of tag, if any, is rebuilt
+// without the removed questions, and the deletions log of tag is rewritten
+// without the personal data logs written before DeletedItem kept.
+func (ds *Dataset) Purge(ctx context.Context, tag string, match func(item *soapi.Item) bool, matchAnswer func(answer *soapi.Answer) bool) (int, int, error) {
+	removed := 0
+
+	entries, err := ds.readIndex(tag)
+	if err != nil {
+		return 0, 0, err
+	}
+	rehashed := make(map[string]string)
+	for i, e := range entries {
+		hash, ok := rehashed[e.hash]
+		if !ok {
+			path := ds.objectPath(tag, e.hash)
+			data, err := readPage(path)
+			if err != nil {
+				return 0, 0, err
+			}
+			data, n, err := purgeItems(data, match)
+			if err != nil {
+				return 0, 0, fmt.Errorf("purging %q: %w", path, err)
+			}
+			removed += n
+			hash = e.hash
+			if n > 0 {
+				hash, _, err = ds.storeObject(tag, data)
+				if err != nil {
+					return 0, 0, err
+				}
+			}
+			rehashed[e.hash] = hash
+		}
+		entries[i].hash = hash
+	}
+	if len(entries) > 0 {
+		if err := ds.writeIndex(tag, entries); err != nil {
+			return 0, 0, err
+		}
+		if err := ds.removeUnusedObjects(tag, entries); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	paths, err := ds.unindexedFiles(tag)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, path := range paths {
+		data, err := readPage(path)
+		if err != nil {
+			return 0, 0, err
+		}
+		data, n, err := purgeItems(data, match)
+		if err != nil {
+			return 0, 0, fmt.Errorf("purging %q: %w", path, err)
+		}
+		if n == 0 {
+			continue
+		}
+		removed += n
+		if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+			return 0, 0, err
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	answers, err := ds.purgeAnswers(tag, matchAnswer)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := ds.rewriteDeletions(tag); err != nil {
+		return 0, 0, err
+	}
+
+	if removed > 0 && ds.HasFullTextIndex(tag) {
+		if _, err := ds.BuildFullTextIndex(ctx, tag); err != nil {
+			return 0, 0, err
+		}
+	}
+	return removed, answers, nil
+}
+
+// purgeAnswers removes the answers for which match returns true from the pages
+// of answers to the questions of tag, if they were fetched, and returns the
+// number of answers removed; with a nil match, none are.
+func (ds *Dataset) purgeAnswers(tag string, match func(answer *soapi.Answer) bool) (int, error) {
+	if match == nil {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(ds.AnswersDir(tag))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(ds.AnswersDir(tag), entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, err
+		}
+		data, n, err := purgeItems(data, match)
+		if err != nil {
+			return 0, fmt.Errorf("purging %q: %w", path, err)
+		}
+		if n == 0 {
+			continue
+		}
+		removed += n
+		if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+			return 0, err
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return 0, err
+		}
+	}
+	return removed, nil
+}
+
+// purgeItems returns the page data without the items (questions or answers)
+// for which match returns true, and the number of items removed. The other
+// fields of the page and of the items are kept, even ones soapi doesn't know.
+func purgeItems[T any](data []byte, match func(item *T) bool) ([]byte, int, error) {
+	var page map[string]json.RawMessage
+	if err := json.Unmarshal(data, &page); err != nil {
+		return nil, 0, err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(page["items"], &items); err != nil {
+		return nil, 0, err
+	}
+
+	kept := []json.RawMessage{}
+	for _, raw := range items {
+		var item T
+		if err := json.Unmarshal(raw, &item); err != nil {
+			return nil, 0, err
+		}
+		if !match(&item) {
+			kept = append(kept, raw)
+		}
+	}
+	removed := len(items) - len(kept)
+	if removed == 0 {
+		return data, 0, nil
+	}
+
+	var err error
+	page["items"], err = json.Marshal(kept)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err = json.Marshal(page)
+	return data, removed, err
+}
@@ -0,0 +1,113 @@
+package soanalysis
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// QuotaRecord is an entry of the quota log of a dataset: the API quota
+// remaining after a request made while fetching tag.
+type QuotaRecord struct {
+	Time time.Time `json:"time"`
+
+	// Run identifies the run of the fetcher that made the request, e.g. by
+	// the time it started.
+	Run string `json:"run"`
+
+	Tag       string `json:"tag"`
+	Remaining int    `json:"remaining"`
+}
+
+// QuotaLogPath returns the path of the quota log of the dataset, in its base
+// directory. It has a JSON object per line.
+func (ds *Dataset) QuotaLogPath() string {
+	return filepath.Join(ds.Dir, "quota.log")
+}
+
+// LogQuota appends rec to the quota log of the dataset.
+func (ds *Dataset) LogQuota(rec QuotaRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(ds.QuotaLogPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// QuotaLog returns the records of the quota log of the dataset, oldest first;
+// there are none if nothing was logged.
+func (ds *Dataset) QuotaLog() ([]QuotaRecord, error) {
+	f, err := os.Open(ds.QuotaLogPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []QuotaRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec QuotaRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// QuotaUsage is the quota spent by a group of requests.
+type QuotaUsage struct {
+	Key      string
+	Requests int
+
+	// Spent is the quota spent by the requests, as told by the drops of the
+	// remaining quota. It includes the requests that weren't logged (such as
+	// the ones for pages that weren't modified) and the ones made with the
+	// same key by others in the meantime.
+	Spent int
+
+	// MinRemaining is the lowest quota remaining after the requests.
+	MinRemaining int
+}
+
+// QuotaUsageBy groups records by key and returns the quota spent by each
+// group, in the order of their first records. A request is taken to have
+// spent the drop of the remaining quota since the previous request of the same
+// run, or 1 if there's no drop (e.g. when the quota was reset in between).
+func QuotaUsageBy(records []QuotaRecord, key func(rec QuotaRecord) string) []QuotaUsage {
+	var usages []QuotaUsage
+	index := make(map[string]int)
+	lastRemaining := make(map[string]int)
+	for _, rec := range records {
+		spent := 1
+		if last, ok := lastRemaining[rec.Run]; ok && last > rec.Remaining {
+			spent = last - rec.Remaining
+		}
+		lastRemaining[rec.Run] = rec.Remaining
+
+		k := key(rec)
+		i, ok := index[k]
+		if !ok {
+			i = len(usages)
+			index[k] = i
+			usages = append(usages, QuotaUsage{Key: k, MinRemaining: rec.Remaining})
+		}
+		usages[i].Requests++
+		usages[i].Spent += spent
+		usages[i].MinRemaining = min(usages[i].MinRemaining, rec.Remaining)
+	}
+	return usages
+}
@@ -0,0 +1,136 @@
+package soanalysis
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// Reputation describes the reputation of the users whose answers were
+// accepted in a period. The API reports the reputation of users at the time
+// of fetching, not at the time of answering, so older periods are skewed
+// towards higher reputations of users that stayed active since.
+type Reputation struct {
+	// Accepted is the number of accepted answers, and Answerers the number of
+	// distinct users that gave them.
+	Accepted  int
+	Answerers int
+
+	// Quartiles of the reputation of the answerers, each counted once, and its
+	// 90th percentile.
+	P25    int
+	Median int
+	P75    int
+	P90    int
+
+	// VeteranShare is the ratio of the accepted answers given by veterans:
+	// users with at least the veteran reputation.
+	VeteranShare float64
+}
+
+// quantile returns the q-quantile of sorted by the nearest-rank method, or 0
+// if sorted is empty.
+func quantile(sorted []int, q float64) int {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(q*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+// newReputation computes the reputation of the answerers given the number of
+// accepted answers of each answerer, by user ID, and their reputation.
+func newReputation(counts map[int]int, reputations map[int]int, veteran int) Reputation {
+	r := Reputation{Answerers: len(counts)}
+	var reps []int
+	veterans := 0
+	for user, n := range counts {
+		r.Accepted += n
+		reps = append(reps, reputations[user])
+		if reputations[user] >= veteran {
+			veterans += n
+		}
+	}
+	slices.Sort(reps)
+	r.P25 = quantile(reps, 0.25)
+	r.Median = quantile(reps, 0.5)
+	r.P75 = quantile(reps, 0.75)
+	r.P90 = quantile(reps, 0.9)
+	r.VeteranShare = ratio(veterans, r.Accepted)
+	return r
+}
+
+// ReputationBucket is the reputation of the accepted answerers of a period;
+// Date is the end of the period.
+type ReputationBucket struct {
+	Date       time.Time
+	Reputation Reputation
+}
+
+// ReputationSeries is the reputation of the accepted answerers of a single tag
+// over time, with the same buckets as Series.
+type ReputationSeries struct {
+	Tag     string
+	Buckets []ReputationBucket
+}
+
+// AcceptedReputation computes the reputation of the users whose answers to the
+// questions with the given tag (the ones selected by the Filter of the
+// analyzer) were accepted, in each of the buckets of Series, by the dates of
+// the answers; a rising share of veterans means that the tag increasingly
+// relies on a few experienced users. Answers of deleted users (without a user
+// ID) are ignored. The answers to the questions of tag have to be fetched.
+func (a *Analyzer) AcceptedReputation(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool, veteran int) (ReputationSeries, error) {
+	rs := ReputationSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return rs, err
+	}
+
+	questions := make(map[int]bool)
+	err = a.ForEachItem(ctx, tag, time.Time{}, time.Time{}, func(item *soapi.Item) {
+		questions[item.QuestionID] = true
+	})
+	if err != nil {
+		return rs, err
+	}
+
+	// counts[i] maps the answerers of the accepted answers in bucket i to their
+	// number of accepted answers.
+	counts := make([]map[int]int, len(starts))
+	for i := range counts {
+		counts[i] = make(map[int]int)
+	}
+	reputations := make(map[int]int)
+	var maxDate time.Time
+	err = a.Dataset.ForEachAnswer(tag, func(answer *soapi.Answer) {
+		if !answer.IsAccepted || !questions[answer.QuestionID] || answer.Owner.UserID == 0 {
+			return
+		}
+		reputations[answer.Owner.UserID] = max(reputations[answer.Owner.UserID], answer.Owner.Reputation)
+		answerDate := time.Unix(int64(answer.CreationDate), 0)
+		for i := range starts {
+			if inPeriod(answerDate, starts[i], ends[i]) {
+				counts[i][answer.Owner.UserID]++
+				if answerDate.After(maxDate) {
+					maxDate = answerDate
+				}
+			}
+		}
+	})
+	if err != nil {
+		return rs, err
+	}
+
+	for i, answerers := range counts {
+		b := ReputationBucket{Date: ends[i], Reputation: newReputation(answerers, reputations, veteran)}
+		if b.Date.IsZero() {
+			// if not explicit date, consider the max encountered date
+			b.Date = maxDate
+		}
+		rs.Buckets = append(rs.Buckets, b)
+	}
+	return rs, nil
+}
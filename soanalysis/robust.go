@@ -0,0 +1,52 @@
+package soanalysis
+
+import (
+	"slices"
+)
+
+// Robust selects how the extreme values are handled when computing the means
+// of a Result, so that e.g. a single viral question doesn't dominate the mean
+// views of a small tag. Trim is the fraction of the lowest and of the highest
+// values (each) that are dropped, and Winsorize the fraction of them that are
+// replaced by the nearest remaining value; both are between 0 and 0.5, and 0
+// leaves the values alone. If both are set, the values are trimmed first.
+type Robust struct {
+	Trim      float64
+	Winsorize float64
+}
+
+// Mean returns the mean of values after trimming and winsorizing them, or 0 if
+// there are no values left.
+func (rb Robust) Mean(values []int) float64 {
+	return robustMean(rb, values)
+}
+
+// MeanFloat is like Mean, for fractional values.
+func (rb Robust) MeanFloat(values []float64) float64 {
+	return robustMean(rb, values)
+}
+
+func robustMean[T int | float64](rb Robust, values []T) float64 {
+	values = slices.Clone(values)
+	slices.Sort(values)
+
+	if k := int(rb.Trim * float64(len(values))); k > 0 {
+		values = values[k : len(values)-k]
+	}
+	if k := int(rb.Winsorize * float64(len(values))); k > 0 {
+		n := len(values)
+		for i := 0; i < k; i++ {
+			values[i] = values[k]
+			values[n-1-i] = values[n-1-k]
+		}
+	}
+
+	if len(values) == 0 {
+		return 0
+	}
+	var sum T
+	for _, v := range values {
+		sum += v
+	}
+	return float64(sum) / float64(len(values))
+}
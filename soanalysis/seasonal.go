@@ -0,0 +1,92 @@
+package soanalysis
+
+import "errors"
+
+// Decomposition is the decomposition of a series of values into components,
+// such that each value is the sum of its trend, seasonal and residual
+// components.
+type Decomposition struct {
+	Trend    []float64
+	Seasonal []float64
+	Residual []float64
+}
+
+// ErrSeasonalLength is returned by Decompose for series shorter than two
+// periods, whose seasonality can't be told from their trend.
+var ErrSeasonalLength = errors.New("seasonal decomposition requires at least two periods")
+
+// decomposeIterations is the number of times the trend is reestimated from the
+// values without the seasonal component, as STL does.
+const decomposeIterations = 3
+
+// Decompose decomposes values (e.g. a metric of the monthly buckets of a
+// Series) into trend, seasonal and residual components, with seasons of period
+// values (12 for the yearly seasonality of monthly values, such as the one of
+// the academic calendar). It's an additive decomposition in the style of STL,
+// with moving averages instead of loess smoothing: the trend is the centered
+// moving average over a period, and the seasonal component is the mean
+// deviation from the trend at each position of the period, reestimating the
+// trend from the deseasonalized values a few times.
+func Decompose(values []float64, period int) (Decomposition, error) {
+	n := len(values)
+	if period < 2 || n < 2*period {
+		return Decomposition{}, ErrSeasonalLength
+	}
+
+	d := Decomposition{
+		Trend:    make([]float64, n),
+		Seasonal: make([]float64, n),
+		Residual: make([]float64, n),
+	}
+	deseasonalized := make([]float64, n)
+	for iter := 0; iter < decomposeIterations; iter++ {
+		for i := range values {
+			deseasonalized[i] = values[i] - d.Seasonal[i]
+		}
+		d.Trend = movingAverage(deseasonalized, period)
+
+		sums := make([]float64, period)
+		counts := make([]int, period)
+		for i := range values {
+			sums[i%period] += values[i] - d.Trend[i]
+			counts[i%period]++
+		}
+		mean := 0.0
+		for k := range sums {
+			sums[k] /= float64(counts[k])
+			mean += sums[k] / float64(period)
+		}
+		for i := range values {
+			d.Seasonal[i] = sums[i%period] - mean
+		}
+	}
+
+	for i := range values {
+		d.Residual[i] = values[i] - d.Trend[i] - d.Seasonal[i]
+	}
+	return d, nil
+}
+
+// movingAverage returns the centered moving average of values over window
+// values; for an even window, the values at both ends of the window count
+// half, so it stays centered. Near the ends of values, where the window
+// doesn't fit, the window is shrunk symmetrically.
+func movingAverage(values []float64, window int) []float64 {
+	n := len(values)
+	half := window / 2
+	avg := make([]float64, n)
+	for i := range values {
+		h := min(half, i, n-1-i)
+		sum, weight := 0.0, 0.0
+		for j := i - h; j <= i+h; j++ {
+			w := 1.0
+			if window%2 == 0 && h == half && (j == i-h || j == i+h) {
+				w = 0.5
+			}
+			sum += w * values[j]
+			weight += w
+		}
+		avg[i] = sum / weight
+	}
+	return avg
+}
@@ -0,0 +1,100 @@
+package soanalysis
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Sentiments maps question IDs to sentiment scores computed outside of this
+// package, e.g. by a language model over the titles and bodies of the
+// questions. The scores are aggregated as they are; their scale is up to
+// whatever produced them.
+type Sentiments map[int]float64
+
+// ReadSentiments reads the sentiment scores of questions from a sidecar file.
+// A .json file holds either an object mapping question IDs to scores, or an
+// array of objects with question_id and sentiment fields; any other file is
+// CSV, with rows of a question ID and a score, and an optional header row.
+func ReadSentiments(path string) (Sentiments, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return decodeSentimentsJSON(f)
+	}
+	return decodeSentimentsCSV(f)
+}
+
+func decodeSentimentsJSON(r io.Reader) (Sentiments, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var byID map[string]float64
+	if err := json.Unmarshal(data, &byID); err == nil {
+		s := make(Sentiments, len(byID))
+		for id, score := range byID {
+			n, err := strconv.Atoi(id)
+			if err != nil {
+				return nil, fmt.Errorf("invalid question ID %q", id)
+			}
+			s[n] = score
+		}
+		return s, nil
+	}
+
+	var rows []struct {
+		QuestionID *int     `json:"question_id"`
+		Sentiment  *float64 `json:"sentiment"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, errors.New("want an object of question IDs to scores, or an array of objects with question_id and sentiment")
+	}
+	s := make(Sentiments, len(rows))
+	for i, row := range rows {
+		if row.QuestionID == nil || row.Sentiment == nil {
+			return nil, fmt.Errorf("element %d: missing question_id or sentiment", i)
+		}
+		s[*row.QuestionID] = *row.Sentiment
+	}
+	return s, nil
+}
+
+func decodeSentimentsCSV(r io.Reader) (Sentiments, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = 2
+	cr.TrimLeadingSpace = true
+	s := make(Sentiments)
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return s, nil
+		} else if err != nil {
+			return nil, err
+		}
+		id, err := strconv.Atoi(record[0])
+		if err != nil {
+			if line == 1 {
+				// The header
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid question ID %q", line, record[0])
+		}
+		score, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid score %q", line, record[1])
+		}
+		s[id] = score
+	}
+}
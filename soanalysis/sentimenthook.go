@@ -0,0 +1,133 @@
+package soanalysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// SentimentHook scores the sentiment of questions with an external service
+// over HTTP, without tying this package to any vendor: each question is posted
+// to URL as a JSON object with its question_id, title, body (empty unless the
+// bodies were fetched) and tags, and the service replies with a JSON object
+// with its score in a sentiment field.
+type SentimentHook struct {
+	URL string
+
+	// Header holds extra headers of the requests, such as Authorization.
+	Header http.Header
+
+	// Client is used to issue requests; if nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// sentimentRequest is the body of the requests of a SentimentHook.
+type sentimentRequest struct {
+	QuestionID int      `json:"question_id"`
+	Title      string   `json:"title"`
+	Body       string   `json:"body"`
+	Tags       []string `json:"tags"`
+}
+
+// Score returns the sentiment score of item from the service.
+func (h *SentimentHook) Score(ctx context.Context, item *soapi.Item) (float64, error) {
+	payload, err := json.Marshal(sentimentRequest{QuestionID: item.QuestionID, Title: item.Title, Body: item.Body, Tags: item.Tags})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	for name, values := range h.Header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("sentiment service responded with %s", resp.Status)
+	}
+
+	var reply struct {
+		Sentiment *float64 `json:"sentiment"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&reply); err != nil {
+		return 0, fmt.Errorf("decoding the reply of the sentiment service: %w", err)
+	}
+	if reply.Sentiment == nil {
+		return 0, errors.New("the reply of the sentiment service has no sentiment")
+	}
+	return *reply.Sentiment, nil
+}
+
+// SentimentCache is a CSV file of sentiment scores, in the format read by
+// ReadSentiments, that scores are appended to as they are obtained, so that
+// questions are only scored once, even across interrupted runs.
+type SentimentCache struct {
+	// Scores are the scores in the file.
+	Scores Sentiments
+
+	f *os.File
+	w *csv.Writer
+}
+
+// OpenSentimentCache opens the cache of sentiment scores at path, creating it
+// if it doesn't exist.
+func OpenSentimentCache(path string) (*SentimentCache, error) {
+	scores, err := ReadSentiments(path)
+	if errors.Is(err, os.ErrNotExist) {
+		scores = make(Sentiments)
+	} else if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	sc := &SentimentCache{Scores: scores, f: f, w: csv.NewWriter(f)}
+	if info, err := f.Stat(); err == nil && info.Size() == 0 {
+		if err := sc.write("question_id", "sentiment"); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return sc, nil
+}
+
+// Add records the score of a question.
+func (sc *SentimentCache) Add(id int, score float64) error {
+	sc.Scores[id] = score
+	return sc.write(strconv.Itoa(id), strconv.FormatFloat(score, 'g', -1, 64))
+}
+
+func (sc *SentimentCache) write(record ...string) error {
+	if err := sc.w.Write(record); err != nil {
+		return err
+	}
+	sc.w.Flush()
+	return sc.w.Error()
+}
+
+// Close closes the file of the cache.
+func (sc *SentimentCache) Close() error {
+	return sc.f.Close()
+}
@@ -0,0 +1,134 @@
+package soanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// ErrNoONNX is returned by LoadSentimentModel in programs built without the
+// onnx build tag, which links ONNX Runtime through cgo.
+var ErrNoONNX = errors.New("running sentiment models requires building with -tags onnx")
+
+// SentimentModel is a sentiment classifier in ONNX format, run locally with
+// ONNX Runtime: a BERT-family text classification model (such as a DistilBERT
+// fine-tuned on SST-2) exported to a directory with its model.onnx and the
+// vocab.txt of its WordPiece tokenizer (and optionally its
+// tokenizer_config.json), as exported by Hugging Face Optimum. The score of a
+// text is the probability of its last label (positive) minus that of its
+// first one (negative), between -1 and 1.
+type SentimentModel struct {
+	// BatchSize is the number of texts run through the model at once, and
+	// MaxTokens the number of tokens texts are truncated to.
+	BatchSize int
+	MaxTokens int
+
+	tokenizer *wordPiece
+	session   modelSession
+}
+
+// modelSession runs a model on batches of token IDs with their attention
+// masks, returning the logits of each text.
+type modelSession interface {
+	run(ids [][]int64, masks [][]int64) ([][]float32, error)
+	close() error
+}
+
+// LoadSentimentModel loads the sentiment model in the directory dir. The ONNX
+// Runtime shared library is loaded from the path in the ONNXRUNTIME_LIB
+// environment variable, or else from the default search path of the system.
+func LoadSentimentModel(dir string) (*SentimentModel, error) {
+	var tc struct {
+		DoLowerCase *bool `json:"do_lower_case"`
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "tokenizer_config.json")); err == nil {
+		if err := json.Unmarshal(data, &tc); err != nil {
+			return nil, err
+		}
+	}
+	lower := tc.DoLowerCase == nil || *tc.DoLowerCase
+	tokenizer, err := loadWordPiece(filepath.Join(dir, "vocab.txt"), lower)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := newModelSession(filepath.Join(dir, "model.onnx"), os.Getenv("ONNXRUNTIME_LIB"))
+	if err != nil {
+		return nil, err
+	}
+	return &SentimentModel{BatchSize: 32, MaxTokens: 128, tokenizer: tokenizer, session: session}, nil
+}
+
+// Close releases the resources of the model.
+func (m *SentimentModel) Close() error {
+	return m.session.close()
+}
+
+// ItemText returns the text of a question scored by sentiment models: its title
+// followed by its body (if it was fetched), without HTML markup.
+func ItemText(item *soapi.Item) string {
+	return plainText(item.Title + "\n" + item.Body)
+}
+
+// Score returns the sentiment scores of texts, running them through the model
+// in batches of BatchSize.
+func (m *SentimentModel) Score(ctx context.Context, texts []string) ([]float64, error) {
+	scores := make([]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += m.BatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch := texts[start:min(start+m.BatchSize, len(texts))]
+
+		// Pad the token IDs of all the texts to the longest of the batch.
+		ids := make([][]int64, len(batch))
+		width := 0
+		for i, text := range batch {
+			ids[i] = m.tokenizer.encode(text, m.MaxTokens)
+			width = max(width, len(ids[i]))
+		}
+		masks := make([][]int64, len(batch))
+		for i := range ids {
+			masks[i] = make([]int64, width)
+			for j := range ids[i] {
+				masks[i][j] = 1
+			}
+			for len(ids[i]) < width {
+				ids[i] = append(ids[i], m.tokenizer.pad)
+			}
+		}
+
+		logits, err := m.session.run(ids, masks)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range logits {
+			scores = append(scores, polarity(l))
+		}
+	}
+	return scores, nil
+}
+
+// polarity returns the probability of the last label minus that of the first
+// one for the logits of a text.
+func polarity(logits []float32) float64 {
+	if len(logits) < 2 {
+		return 0
+	}
+	top := math.Inf(-1)
+	for _, l := range logits {
+		top = max(top, float64(l))
+	}
+	sum := 0.0
+	for _, l := range logits {
+		sum += math.Exp(float64(l) - top)
+	}
+	first := math.Exp(float64(logits[0])-top) / sum
+	last := math.Exp(float64(logits[len(logits)-1])-top) / sum
+	return last - first
+}
@@ -0,0 +1,125 @@
+//go:build onnx
+
+package soanalysis
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+// ortInit initializes the ONNX Runtime environment, once per process.
+var ortInit struct {
+	once sync.Once
+	err  error
+}
+
+// onnxSession is a modelSession running an ONNX model with ONNX Runtime.
+type onnxSession struct {
+	session *ort.DynamicAdvancedSession
+
+	// inputs are the names of the inputs of the model; tokenTypes is set if
+	// it takes token_type_ids along with input_ids and attention_mask.
+	inputs     []string
+	tokenTypes bool
+}
+
+func newModelSession(path string, lib string) (modelSession, error) {
+	ortInit.once.Do(func() {
+		if lib != "" {
+			ort.SetSharedLibraryPath(lib)
+		}
+		ortInit.err = ort.InitializeEnvironment()
+	})
+	if ortInit.err != nil {
+		return nil, fmt.Errorf("initializing ONNX Runtime: %w", ortInit.err)
+	}
+
+	inputInfo, outputInfo, err := ort.GetInputOutputInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &onnxSession{}
+	for _, in := range inputInfo {
+		s.inputs = append(s.inputs, in.Name)
+	}
+	for _, name := range []string{"input_ids", "attention_mask"} {
+		if !slices.Contains(s.inputs, name) {
+			return nil, fmt.Errorf("model %s has no %s input", path, name)
+		}
+	}
+	s.tokenTypes = slices.Contains(s.inputs, "token_type_ids")
+	if len(outputInfo) == 0 {
+		return nil, fmt.Errorf("model %s has no outputs", path)
+	}
+
+	s.session, err = ort.NewDynamicAdvancedSession(path, s.inputs, []string{outputInfo[0].Name}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *onnxSession) run(ids [][]int64, masks [][]int64) ([][]float32, error) {
+	batch, width := int64(len(ids)), int64(len(ids[0]))
+	shape := ort.NewShape(batch, width)
+	flat := func(rows [][]int64) []int64 {
+		var data []int64
+		for _, row := range rows {
+			data = append(data, row...)
+		}
+		return data
+	}
+
+	var inputs []ort.Value
+	defer func() {
+		for _, in := range inputs {
+			in.Destroy()
+		}
+	}()
+	for _, name := range s.inputs {
+		var data []int64
+		switch name {
+		case "input_ids":
+			data = flat(ids)
+		case "attention_mask":
+			data = flat(masks)
+		case "token_type_ids":
+			data = make([]int64, batch*width)
+		default:
+			return nil, fmt.Errorf("unknown model input %s", name)
+		}
+		t, err := ort.NewTensor(shape, data)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, t)
+	}
+
+	outputs := []ort.Value{nil}
+	if err := s.session.Run(inputs, outputs); err != nil {
+		return nil, err
+	}
+	defer outputs[0].Destroy()
+	out, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, fmt.Errorf("model output is %T, want float32 logits", outputs[0])
+	}
+	shape = out.GetShape()
+	if len(shape) != 2 || shape[0] != batch {
+		return nil, fmt.Errorf("model output has shape %v, want [%d labels]", shape, batch)
+	}
+	data := out.GetData()
+	logits := make([][]float32, batch)
+	labels := int(shape[1])
+	for i := range logits {
+		logits[i] = slices.Clone(data[i*labels : (i+1)*labels])
+	}
+	return logits, nil
+}
+
+func (s *onnxSession) close() error {
+	return s.session.Destroy()
+}
@@ -0,0 +1,7 @@
+//go:build !onnx
+
+package soanalysis
+
+func newModelSession(path string, lib string) (modelSession, error) {
+	return nil, ErrNoONNX
+}
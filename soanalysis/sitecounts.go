@@ -0,0 +1,73 @@
+package soanalysis
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// SiteCount is the number of questions asked on the whole site in a period,
+// regardless of their tags.
+type SiteCount struct {
+	FromDate time.Time `json:"from"`
+	ToDate   time.Time `json:"to"`
+	Total    int       `json:"total"`
+}
+
+// SiteCountsPath returns the path of the file holding the site counts of the
+// dataset, in its base directory.
+func (ds *Dataset) SiteCountsPath() string {
+	return filepath.Join(ds.Dir, "site-counts.json")
+}
+
+// SiteCounts returns the site counts of the dataset; there are none if they
+// weren't fetched.
+func (ds *Dataset) SiteCounts() ([]SiteCount, error) {
+	data, err := os.ReadFile(ds.SiteCountsPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var counts []SiteCount
+	err = json.Unmarshal(data, &counts)
+	return counts, err
+}
+
+// AddSiteCounts adds counts to the site counts of the dataset, replacing the
+// ones of the same periods.
+func (ds *Dataset) AddSiteCounts(counts []SiteCount) error {
+	existing, err := ds.SiteCounts()
+	if err != nil {
+		return err
+	}
+	for _, c := range counts {
+		existing = slices.DeleteFunc(existing, func(e SiteCount) bool {
+			return e.FromDate.Equal(c.FromDate) && e.ToDate.Equal(c.ToDate)
+		})
+		existing = append(existing, c)
+	}
+	slices.SortFunc(existing, func(a, b SiteCount) int {
+		return a.FromDate.Compare(b.FromDate)
+	})
+
+	data, err := json.MarshalIndent(existing, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(ds.SiteCountsPath(), data, 0644)
+}
+
+// siteTotal returns the site count of the period between fromDate and toDate,
+// or 0 if there's none.
+func siteTotal(counts []SiteCount, fromDate time.Time, toDate time.Time) int {
+	for _, c := range counts {
+		if c.FromDate.Equal(fromDate) && c.ToDate.Equal(toDate) {
+			return c.Total
+		}
+	}
+	return 0
+}
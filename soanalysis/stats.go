@@ -0,0 +1,124 @@
+package soanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// TagStats is a summary of the data of a tag in a dataset, for checking its
+// health.
+type TagStats struct {
+	Tag string
+
+	// Pages is the number of files holding pages of questions, and
+	// FetchedPages the number of pages of the fetched windows stored with
+	// StorePage; identical pages are stored once, so the ratio of the two
+	// tells how much storage is saved.
+	Pages        int
+	FetchedPages int
+
+	// Questions is the number of questions in all the pages, counting the
+	// questions found in several pages for each, and UniqueQuestions the
+	// number of distinct questions.
+	Questions       int
+	UniqueQuestions int
+
+	// MinDate and MaxDate are the creation dates of the first and the last
+	// questions.
+	MinDate time.Time
+	MaxDate time.Time
+
+	// Size is the total size in bytes of the files in the tag directory.
+	Size int64
+
+	// LastFetch is the latest modification time of the pages.
+	LastFetch time.Time
+
+	// Gaps are the periods without questions between MinDate and MaxDate
+	// longer than asked for, oldest first.
+	Gaps []Gap
+}
+
+// Gap is a period without questions, between the creation dates of two
+// consecutive questions.
+type Gap struct {
+	From time.Time
+	To   time.Time
+}
+
+// Stats summarizes the data of tag in the dataset, reporting the periods of at
+// least minGap without questions as gaps, which are often windows that weren't
+// fetched.
+func (ds *Dataset) Stats(tag string, minGap time.Duration) (TagStats, error) {
+	st := TagStats{Tag: tag}
+	paths, err := ds.pageFiles(tag)
+	if err != nil {
+		return st, err
+	}
+	st.Pages = len(paths)
+	entries, err := ds.readIndex(tag)
+	if err != nil {
+		return st, err
+	}
+	st.FetchedPages = len(entries)
+
+	seen := make(map[int]bool)
+	var dates []time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return st, err
+		}
+		if info.ModTime().After(st.LastFetch) {
+			st.LastFetch = info.ModTime()
+		}
+
+		data, err := readPage(path)
+		if err != nil {
+			return st, err
+		}
+		var reply soapi.Reply
+		if err := json.Unmarshal(data, &reply); err != nil {
+			return st, fmt.Errorf("unmarshalling %q: %w", path, err)
+		}
+		st.Questions += len(reply.Items)
+		for _, item := range reply.Items {
+			if !seen[item.QuestionID] {
+				seen[item.QuestionID] = true
+				dates = append(dates, time.Unix(int64(item.CreationDate), 0))
+			}
+		}
+	}
+	st.UniqueQuestions = len(seen)
+
+	slices.SortFunc(dates, time.Time.Compare)
+	if len(dates) > 0 {
+		st.MinDate = dates[0]
+		st.MaxDate = dates[len(dates)-1]
+	}
+	for i := 1; i < len(dates); i++ {
+		if dates[i].Sub(dates[i-1]) >= minGap {
+			st.Gaps = append(st.Gaps, Gap{From: dates[i-1], To: dates[i]})
+		}
+	}
+
+	err = filepath.WalkDir(ds.TagDir(tag), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		st.Size += info.Size()
+		return nil
+	})
+	return st, err
+}
@@ -0,0 +1,251 @@
+package soanalysis
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pages are stored by the hash of their contents in the objects subdirectory
+// of the tag directory, so identical pages (e.g. from fetching overlapping
+// windows) are stored once; in FormatCompressed, they are compressed with the
+// Codec of the dataset and named with its extension, like <hash>.json.gz for
+// gzip rather than <hash>.json. The index file of the tag maps each page of
+// each fetched window to the hash of its contents, a line per page:
+//
+//	<from date> <to date> <page> <hash>
+//
+// with the dates in RFC 3339 format. Lines are kept in the order the pages were
+// stored in, so the latest fetch of a page comes last.
+const (
+	indexName  = "pages.index"
+	objectsDir = "objects"
+)
+
+// pageRef identifies a page of the questions of a window.
+type pageRef struct {
+	fromDate time.Time
+	toDate   time.Time
+	page     int
+}
+
+type indexEntry struct {
+	ref  pageRef
+	hash string
+}
+
+func (ds *Dataset) indexPath(tag string) string {
+	return filepath.Join(ds.TagDir(tag), indexName)
+}
+
+// objectPath returns the path of the stored contents with hash, compressed if
+// they were stored in FormatCompressed.
+func (ds *Dataset) objectPath(tag string, hash string) string {
+	path := filepath.Join(ds.TagDir(tag), objectsDir, hash+".json")
+	for _, codec := range codecs {
+		if _, err := os.Stat(path + codec.Ext()); err == nil {
+			return path + codec.Ext()
+		}
+	}
+	return path
+}
+
+// readIndex reads the index of tag; a missing index is empty.
+func (ds *Dataset) readIndex(tag string) ([]indexEntry, error) {
+	f, err := os.Open(ds.indexPath(tag))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []indexEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("%s: bad line %q", ds.indexPath(tag), scanner.Text())
+		}
+		var e indexEntry
+		e.ref.fromDate, err = time.Parse(time.RFC3339, fields[0])
+		if err == nil {
+			e.ref.toDate, err = time.Parse(time.RFC3339, fields[1])
+		}
+		if err == nil {
+			e.ref.page, err = strconv.Atoi(fields[2])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ds.indexPath(tag), err)
+		}
+		e.hash = fields[3]
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// writeIndex replaces the index of tag with entries.
+func (ds *Dataset) writeIndex(tag string, entries []indexEntry) error {
+	var sb strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&sb, "%s %s %d %s\n", e.ref.fromDate.UTC().Format(time.RFC3339), e.ref.toDate.UTC().Format(time.RFC3339), e.ref.page, e.hash)
+	}
+
+	// Write to a temporary file and rename it, so a reader never sees a
+	// partially written index.
+	tmp := ds.indexPath(tag) + ".tmp"
+	if err := os.WriteFile(tmp, []byte(sb.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, ds.indexPath(tag))
+}
+
+func (r pageRef) equal(other pageRef) bool {
+	return r.fromDate.Equal(other.fromDate) && r.toDate.Equal(other.toDate) && r.page == other.page
+}
+
+// StorePage stores the body of the given page of questions with tag asked
+// between fromDate and toDate, replacing the page stored for the same window
+// before, if any. It reports whether the contents are new, i.e. no identical
+// page of tag was stored before.
+func (ds *Dataset) StorePage(tag string, fromDate time.Time, toDate time.Time, page int, body []byte) (bool, error) {
+	hash, isNew, err := ds.storeObject(tag, body)
+	if err != nil {
+		return false, err
+	}
+
+	entries, err := ds.readIndex(tag)
+	if err != nil {
+		return false, err
+	}
+	ref := pageRef{fromDate: fromDate, toDate: toDate, page: page}
+	entries = removeEntries(entries, func(e indexEntry) bool { return e.ref.equal(ref) })
+	entries = append(entries, indexEntry{ref: ref, hash: hash})
+	return isNew, ds.writeIndex(tag, entries)
+}
+
+// storeObject stores body by its hash, unless it's stored already, and returns
+// the hash and whether it wasn't stored before. The hash is of the
+// uncompressed body, so it doesn't depend on the format of the dataset.
+func (ds *Dataset) storeObject(tag string, body []byte) (string, bool, error) {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	path := ds.objectPath(tag, hash)
+	_, err := os.Stat(path)
+	isNew := errors.Is(err, os.ErrNotExist)
+	if isNew {
+		format, err := ds.writeFormat()
+		if err != nil {
+			return "", false, err
+		}
+		if format >= FormatCompressed {
+			codec := ds.codec()
+			if body, err = codec.Compress(body); err != nil {
+				return "", false, err
+			}
+			path += codec.Ext()
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return "", false, err
+		}
+		if err := os.WriteFile(path, body, 0644); err != nil {
+			return "", false, err
+		}
+	}
+	return hash, isNew, nil
+}
+
+// LoadPage returns the body of the given page stored with StorePage, or an
+// error wrapping os.ErrNotExist if there is none.
+func (ds *Dataset) LoadPage(tag string, fromDate time.Time, toDate time.Time, page int) ([]byte, error) {
+	entries, err := ds.readIndex(tag)
+	if err != nil {
+		return nil, err
+	}
+	ref := pageRef{fromDate: fromDate, toDate: toDate, page: page}
+	for _, e := range entries {
+		if e.ref.equal(ref) {
+			return readPage(ds.objectPath(tag, e.hash))
+		}
+	}
+	return nil, fmt.Errorf("page %d of %q: %w", page, tag, os.ErrNotExist)
+}
+
+// TruncatePages removes the pages of the window between fromDate and toDate
+// numbered after lastPage, e.g. left over from a previous fetch of the window
+// with more pages, and deletes the stored contents no page refers to anymore.
+func (ds *Dataset) TruncatePages(tag string, fromDate time.Time, toDate time.Time, lastPage int) error {
+	entries, err := ds.readIndex(tag)
+	if err != nil {
+		return err
+	}
+	entries = removeEntries(entries, func(e indexEntry) bool {
+		return e.ref.fromDate.Equal(fromDate) && e.ref.toDate.Equal(toDate) && e.ref.page > lastPage
+	})
+	if err := ds.writeIndex(tag, entries); err != nil {
+		return err
+	}
+	return ds.removeUnusedObjects(tag, entries)
+}
+
+// removeUnusedObjects deletes the stored contents none of entries refers to.
+func (ds *Dataset) removeUnusedObjects(tag string, entries []indexEntry) error {
+	used := make(map[string]bool)
+	for _, e := range entries {
+		used[e.hash+".json"] = true
+		for _, codec := range codecs {
+			used[e.hash+".json"+codec.Ext()] = true
+		}
+	}
+	objects, err := os.ReadDir(filepath.Join(ds.TagDir(tag), objectsDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if !used[object.Name()] {
+			if err := os.Remove(filepath.Join(ds.TagDir(tag), objectsDir, object.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pagePaths returns the paths of the files holding the pages of tag, the
+// latest stored first and without duplicates.
+func (ds *Dataset) pagePaths(tag string) ([]string, error) {
+	entries, err := ds.readIndex(tag)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	seen := make(map[string]bool)
+	for i := len(entries) - 1; i >= 0; i-- {
+		if hash := entries[i].hash; !seen[hash] {
+			seen[hash] = true
+			paths = append(paths, ds.objectPath(tag, hash))
+		}
+	}
+	return paths, nil
+}
+
+func removeEntries(entries []indexEntry, remove func(e indexEntry) bool) []indexEntry {
+	var kept []indexEntry
+	for _, e := range entries {
+		if !remove(e) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,147 @@
+package soanalysis
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// SurvivalPoint is a point of the curve of a SurvivalBucket.
+type SurvivalPoint struct {
+	Hours float64
+
+	// Answered is the estimated ratio of questions answered within Hours of
+	// being asked.
+	Answered float64
+}
+
+// SurvivalBucket is the estimate of the time the questions of a period wait
+// for their first answer; Date is the end of the period.
+type SurvivalBucket struct {
+	Date      time.Time
+	Questions int
+	Answered  int
+	Points    []SurvivalPoint
+}
+
+// SurvivalSeries is the survival analysis of a single tag, with the same
+// buckets as Series.
+type SurvivalSeries struct {
+	Tag     string
+	Buckets []SurvivalBucket
+}
+
+// observation is the time a question waited for its first answer in hours; if
+// not answered, the time it was known to be unanswered for (censored).
+type observation struct {
+	hours    float64
+	answered bool
+}
+
+// Survival estimates the time the questions with the given tag wait for their
+// first answer, with the Kaplan–Meier estimator: for each of the buckets of
+// Series, it estimates the ratio of questions answered within each of the
+// given numbers of hours. Questions without answers are censored at the time
+// the answers were fetched (see Dataset.AnswersFetched), so the answers to the
+// questions of tag have to be fetched; questions asked after that are ignored.
+func (a *Analyzer) Survival(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, byMonth bool, hours []float64) (SurvivalSeries, error) {
+	ts := SurvivalSeries{Tag: tag}
+	starts, ends, err := periods(fromDate, toDate, byMonth)
+	if err != nil {
+		return ts, err
+	}
+	fetched, err := a.Dataset.AnswersFetched(tag)
+	if err != nil {
+		return ts, err
+	}
+
+	// The time of the first answer to each question.
+	firstAnswers := make(map[int]time.Time)
+	err = a.Dataset.ForEachAnswer(tag, func(answer *soapi.Answer) {
+		answerDate := time.Unix(int64(answer.CreationDate), 0)
+		if first, ok := firstAnswers[answer.QuestionID]; !ok || answerDate.Before(first) {
+			firstAnswers[answer.QuestionID] = answerDate
+		}
+	})
+	if err != nil {
+		return ts, err
+	}
+
+	observations := make([][]observation, len(starts))
+	var maxDate time.Time
+	err = a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		itemDate := time.Unix(int64(item.CreationDate), 0)
+		o := observation{hours: fetched.Sub(itemDate).Hours()}
+		if first, ok := firstAnswers[item.QuestionID]; ok {
+			// Answers may predate questions merged into others.
+			o = observation{hours: max(first.Sub(itemDate).Hours(), 0), answered: true}
+		} else if o.hours < 0 {
+			return
+		}
+
+		if itemDate.After(maxDate) {
+			maxDate = itemDate
+		}
+		for i := range starts {
+			if inPeriod(itemDate, starts[i], ends[i]) {
+				observations[i] = append(observations[i], o)
+			}
+		}
+	})
+	if err != nil {
+		return ts, err
+	}
+
+	for i, obs := range observations {
+		b := SurvivalBucket{Date: ends[i], Questions: len(obs), Points: kaplanMeier(obs, hours)}
+		if b.Date.IsZero() {
+			// if not explicit date, consider the max encountered date
+			b.Date = maxDate
+		}
+		for _, o := range obs {
+			if o.answered {
+				b.Answered++
+			}
+		}
+		ts.Buckets = append(ts.Buckets, b)
+	}
+	return ts, nil
+}
+
+// kaplanMeier returns the Kaplan–Meier estimate of the ratio of questions
+// answered within each of hours, given the observations of the questions.
+func kaplanMeier(obs []observation, hours []float64) []SurvivalPoint {
+	sort.Slice(obs, func(i, j int) bool {
+		return obs[i].hours < obs[j].hours
+	})
+	hours = slices.Sorted(slices.Values(hours))
+
+	var points []SurvivalPoint
+	survival := 1.0
+	atRisk := len(obs)
+	for i := 0; i < len(obs); {
+		t := obs[i].hours
+		for len(points) < len(hours) && hours[len(points)] < t {
+			points = append(points, SurvivalPoint{Hours: hours[len(points)], Answered: 1 - survival})
+		}
+
+		// All the questions observed for t hours leave the risk set; the
+		// answered ones lower the survival.
+		observed, answered := 0, 0
+		for ; i < len(obs) && obs[i].hours == t; i++ {
+			observed++
+			if obs[i].answered {
+				answered++
+			}
+		}
+		survival *= 1 - float64(answered)/float64(atRisk)
+		atRisk -= observed
+	}
+	for len(points) < len(hours) {
+		points = append(points, SurvivalPoint{Hours: hours[len(points)], Answered: 1 - survival})
+	}
+	return points
+}
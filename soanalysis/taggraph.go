@@ -0,0 +1,90 @@
+package soanalysis
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// TagNode is a tag of a TagGraph, with the result of its questions.
+type TagNode struct {
+	Tag    string
+	Result Result
+}
+
+// TagEdge is a pair of tags of a TagGraph, with the number of questions
+// tagged with both; From is before To in alphabetical order.
+type TagEdge struct {
+	From      string
+	To        string
+	Questions int
+}
+
+// TagGraph is the network of the tags that occur together on questions.
+type TagGraph struct {
+	// Nodes are sorted by tag, and Edges by their tags.
+	Nodes []TagNode
+	Edges []TagEdge
+}
+
+// TagGraph computes the co-occurrence network of the tags of the questions with
+// any of the given tags between fromDate and toDate: a node per tag of the
+// questions (including tags that weren't fetched themselves, like the "json"
+// of a question tagged "go" and "json") with the result of the questions with
+// it, and an edge per pair of tags with the number of questions with both.
+// Questions with several of the given tags are counted once. Nodes with fewer
+// than minQuestions questions and edges with fewer than minEdge questions are
+// left out, as are the edges of the nodes left out.
+func (a *Analyzer) TagGraph(ctx context.Context, tags []string, fromDate time.Time, toDate time.Time, minQuestions int, minEdge int) (TagGraph, error) {
+	var g TagGraph
+	nodes := make(map[string]*Result)
+	edges := make(map[[2]string]int)
+	seen := make(map[int]bool)
+	for _, tag := range tags {
+		err := a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+			if seen[item.QuestionID] {
+				return
+			}
+			seen[item.QuestionID] = true
+			itemTags := slices.Clone(item.Tags)
+			slices.Sort(itemTags)
+			itemTags = slices.Compact(itemTags)
+			for i, t := range itemTags {
+				r := nodes[t]
+				if r == nil {
+					r = &Result{Robust: a.Robust}
+					nodes[t] = r
+				}
+				r.addItem(item, a.Sentiments)
+				for _, other := range itemTags[i+1:] {
+					edges[[2]string{t, other}]++
+				}
+			}
+		})
+		if err != nil {
+			return g, err
+		}
+	}
+
+	for tag, r := range nodes {
+		if r.Total >= minQuestions {
+			g.Nodes = append(g.Nodes, TagNode{Tag: tag, Result: *r})
+		}
+	}
+	slices.SortFunc(g.Nodes, func(x, y TagNode) int {
+		return strings.Compare(x.Tag, y.Tag)
+	})
+	for pair, n := range edges {
+		if n >= minEdge && nodes[pair[0]].Total >= minQuestions && nodes[pair[1]].Total >= minQuestions {
+			g.Edges = append(g.Edges, TagEdge{From: pair[0], To: pair[1], Questions: n})
+		}
+	}
+	slices.SortFunc(g.Edges, func(x, y TagEdge) int {
+		return cmp.Or(strings.Compare(x.From, y.From), strings.Compare(x.To, y.To))
+	})
+	return g, nil
+}
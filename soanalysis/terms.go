@@ -0,0 +1,393 @@
+package soanalysis
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/token/stop"
+	unicodetokenizer "github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/registry"
+
+	// The stop lists and stemmers of termLanguages, registered by name
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/da"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/de"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/en"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/es"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/fi"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/fr"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/hu"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/it"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/nl"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/no"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/pt"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/ro"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/ru"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/sv"
+	_ "github.com/blevesearch/bleve/v2/analysis/lang/tr"
+)
+
+// termLanguages maps the languages with stop lists and stemmers for terms to
+// the names of their stemmers in the bleve registry; their stop lists are
+// named stop_<language>.
+var termLanguages = map[string]string{
+	"da": "stemmer_da_snowball",
+	"de": "stemmer_de_snowball",
+	"en": "stemmer_en_snowball",
+	"es": "stemmer_es_snowball",
+	"fi": "stemmer_fi_snowball",
+	"fr": "stemmer_fr_snowball",
+	"hu": "stemmer_hu_snowball",
+	"it": "stemmer_it_snowball",
+	"nl": "stemmer_nl_snowball",
+	"no": "stemmer_no_snowball",
+	"pt": "stemmer_pt_light",
+	"ro": "stemmer_ro_snowball",
+	"ru": "stemmer_ru_snowball",
+	"sv": "stemmer_sv_snowball",
+	"tr": "stemmer_tr_snowball",
+}
+
+// TermLanguages returns the languages supported by TermOptions.Language.
+func TermLanguages() []string {
+	return slices.Sorted(maps.Keys(termLanguages))
+}
+
+// QuestionStopwords are words that are common in the titles of programming
+// questions whatever they are about, and so tell little about a tag; they're
+// dropped along with the stop words of the language with
+// TermOptions.QuestionStopwords.
+var QuestionStopwords = []string{
+	"can't", "cannot", "doesn't", "don't", "error", "errors", "get", "getting",
+	"help", "how", "issue", "make", "need", "possible", "problem", "trying",
+	"use", "used", "using", "want", "way", "without", "work", "working", "works",
+}
+
+// TermOptions selects how texts are split into terms by a TermAnalyzer.
+type TermOptions struct {
+	// Language is the language of the texts (one of TermLanguages), whose stop
+	// words are dropped and whose stemmer is used; if empty, no words are
+	// dropped and none are stemmed.
+	Language string
+
+	// Stem selects whether the terms are the stems of words, so that e.g.
+	// "import", "imports" and "importing" count as one term.
+	Stem bool
+
+	// QuestionStopwords selects whether QuestionStopwords are dropped too,
+	// and Stopwords are more words to drop.
+	QuestionStopwords bool
+	Stopwords         []string
+
+	// MinWords and MaxWords are the numbers of consecutive words of the terms
+	// (n-grams), e.g. 2 and 3 for the bigrams and trigrams of the texts; 0 is
+	// 1. Terms of several words may have stop words inside them, but not at
+	// their ends, except for negations, so that "cannot import" and "pointer
+	// to struct" are terms but "how to" and "in python" aren't.
+	MinWords int
+	MaxWords int
+}
+
+// termNegations are the stop words kept at the ends of terms of several words,
+// as they change their meaning.
+var termNegations = map[string]bool{
+	"aren't": true, "can't": true, "cannot": true, "couldn't": true, "didn't": true,
+	"doesn't": true, "don't": true, "isn't": true, "never": true, "no": true,
+	"not": true, "shouldn't": true, "wasn't": true, "won't": true, "wouldn't": true,
+}
+
+// Term is a term of a text: the stems of its words separated by spaces, or the
+// words themselves if stemming is off.
+type Term struct {
+	Stem string
+	Word string
+}
+
+// TermAnalyzer splits texts into terms, for counting them.
+type TermAnalyzer struct {
+	tokenizer analysis.Tokenizer
+	lowercase analysis.TokenFilter
+
+	// stops drop stop words, and stemmer stems words if it's set.
+	stops   []analysis.TokenFilter
+	stemmer analysis.TokenFilter
+
+	minWords int
+	maxWords int
+}
+
+// NewTermAnalyzer returns a TermAnalyzer with the given options.
+func NewTermAnalyzer(opts TermOptions) (*TermAnalyzer, error) {
+	ta := &TermAnalyzer{
+		tokenizer: unicodetokenizer.NewUnicodeTokenizer(),
+		lowercase: lowercase.NewLowerCaseFilter(),
+		minWords:  max(opts.MinWords, 1),
+		maxWords:  max(opts.MaxWords, opts.MinWords, 1),
+	}
+	cache := registry.NewCache()
+	if opts.Language != "" {
+		stemmer, ok := termLanguages[opts.Language]
+		if !ok {
+			return nil, fmt.Errorf("unknown language %q, want one of %s", opts.Language, strings.Join(TermLanguages(), ", "))
+		}
+		filter, err := cache.TokenFilterNamed("stop_" + opts.Language)
+		if err != nil {
+			return nil, err
+		}
+		ta.stops = append(ta.stops, filter)
+		if opts.Stem {
+			if ta.stemmer, err = cache.TokenFilterNamed(stemmer); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	stopwords := analysis.NewTokenMap()
+	if opts.QuestionStopwords {
+		for _, w := range QuestionStopwords {
+			stopwords.AddToken(w)
+		}
+	}
+	for _, w := range opts.Stopwords {
+		stopwords.AddToken(strings.ToLower(w))
+	}
+	if len(stopwords) > 0 {
+		ta.stops = append(ta.stops, stop.NewStopTokensFilter(stopwords))
+	}
+	return ta, nil
+}
+
+// Terms returns the terms of text in order, leaving out stop words, numbers and
+// single characters.
+func (ta *TermAnalyzer) Terms(text string) []Term {
+	text = strings.ReplaceAll(text, "’", "'")
+	var words []string
+	for _, tok := range ta.lowercase.Filter(ta.tokenizer.Tokenize([]byte(text))) {
+		words = append(words, string(tok.Term))
+	}
+
+	var terms []Term
+	for i := range words {
+		for n := ta.minWords; n <= ta.maxWords && i+n <= len(words); n++ {
+			gram := words[i : i+n]
+			if !ta.isTerm(gram) {
+				continue
+			}
+			stems := make([]string, n)
+			for j, w := range gram {
+				stems[j] = ta.stem(w)
+			}
+			terms = append(terms, Term{Stem: strings.Join(stems, " "), Word: strings.Join(gram, " ")})
+		}
+	}
+	return terms
+}
+
+// isTerm reports whether the consecutive words make up a term: they're all
+// words (not numbers or single characters) and they don't start or end with a
+// stop word, other than a negation in a term of several words.
+func (ta *TermAnalyzer) isTerm(words []string) bool {
+	for _, w := range words {
+		if len([]rune(w)) < 2 || strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			return false
+		}
+	}
+	for _, w := range []string{words[0], words[len(words)-1]} {
+		if ta.isStop(w) && (len(words) == 1 || !termNegations[w]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isStop reports whether word is a stop word.
+func (ta *TermAnalyzer) isStop(word string) bool {
+	tokens := analysis.TokenStream{{Term: []byte(word)}}
+	for _, f := range ta.stops {
+		tokens = f.Filter(tokens)
+	}
+	return len(tokens) == 0
+}
+
+// stem returns the stem of word, or word itself if stemming is off.
+func (ta *TermAnalyzer) stem(word string) string {
+	if ta.stemmer == nil {
+		return word
+	}
+	return string(ta.stemmer.Filter(analysis.TokenStream{{Term: []byte(word)}})[0].Term)
+}
+
+// TermStats are the statistics of a term in a group of texts.
+type TermStats struct {
+	// Word is the most frequent phrasing of the term, whose stem is Stem.
+	Word string `json:"word"`
+	Stem string `json:"stem"`
+
+	// Texts is the number of texts of the group with the term, and Share
+	// their share of the texts of the group.
+	Texts int     `json:"texts"`
+	Share float64 `json:"share"`
+
+	// TFIDF is the share of the texts of the group with the term, weighted by
+	// the inverse of the share of all the texts (of all the groups) with it,
+	// which ranks the terms characteristic of the group above the ones
+	// common to all the texts.
+	TFIDF float64 `json:"tfidf"`
+
+	// Trend is the share of the texts of the group with the term in each
+	// bucket of a TermCounter by month, oldest first.
+	Trend []float64 `json:"trend,omitempty"`
+}
+
+// TermCounter counts the terms of texts in groups, such as the titles of the
+// questions of tags, ranking the terms of each group by TF-IDF.
+type TermCounter struct {
+	analyzer *TermAnalyzer
+
+	// starts and ends are the periods of the buckets of the trends of terms,
+	// if there are several.
+	starts []time.Time
+	ends   []time.Time
+
+	// texts is the number of texts of all the groups, and withTerm the number
+	// of them with each stem.
+	texts    int
+	withTerm map[string]int
+
+	groups map[string]*termGroup
+}
+
+type termGroup struct {
+	texts    int
+	withTerm map[string]int
+
+	// words counts the phrasings of each stem.
+	words map[string]map[string]int
+
+	// bucketTexts is the number of texts in each bucket, and bucketWithTerm
+	// the number of them with each stem.
+	bucketTexts    []int
+	bucketWithTerm map[string][]int
+}
+
+// NewTermCounter returns a TermCounter splitting texts into terms with ta.
+// With byMonth, it also follows the share of the texts with each term in each
+// month from fromDate to toDate, like the buckets of Analyzer.Series.
+func NewTermCounter(ta *TermAnalyzer, fromDate time.Time, toDate time.Time, byMonth bool) (*TermCounter, error) {
+	tc := &TermCounter{analyzer: ta, withTerm: make(map[string]int), groups: make(map[string]*termGroup)}
+	if byMonth {
+		var err error
+		if tc.starts, tc.ends, err = periods(fromDate, toDate, true); err != nil {
+			return nil, err
+		}
+	}
+	return tc, nil
+}
+
+// Dates returns the dates of the buckets of the trends of terms (the ends of
+// their periods, like Bucket.Date), or nil if the counter isn't by month.
+func (tc *TermCounter) Dates() []time.Time {
+	return tc.ends
+}
+
+// Add adds a text dated at date to the given group.
+func (tc *TermCounter) Add(group string, date time.Time, text string) {
+	g := tc.groups[group]
+	if g == nil {
+		g = &termGroup{
+			withTerm:       make(map[string]int),
+			words:          make(map[string]map[string]int),
+			bucketTexts:    make([]int, len(tc.starts)),
+			bucketWithTerm: make(map[string][]int),
+		}
+		tc.groups[group] = g
+	}
+	tc.texts++
+	g.texts++
+	var buckets []int
+	for i := range tc.starts {
+		if inPeriod(date, tc.starts[i], tc.ends[i]) {
+			buckets = append(buckets, i)
+			g.bucketTexts[i]++
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range tc.analyzer.Terms(text) {
+		if g.words[t.Stem] == nil {
+			g.words[t.Stem] = make(map[string]int)
+		}
+		g.words[t.Stem][t.Word]++
+		if seen[t.Stem] {
+			continue
+		}
+		seen[t.Stem] = true
+		tc.withTerm[t.Stem]++
+		g.withTerm[t.Stem]++
+		if len(buckets) > 0 && g.bucketWithTerm[t.Stem] == nil {
+			g.bucketWithTerm[t.Stem] = make([]int, len(tc.starts))
+		}
+		for _, i := range buckets {
+			g.bucketWithTerm[t.Stem][i]++
+		}
+	}
+}
+
+// Top returns the n terms of the group with the highest TF-IDF, highest first.
+func (tc *TermCounter) Top(group string, n int) []TermStats {
+	g := tc.groups[group]
+	if g == nil {
+		return nil
+	}
+	var stats []TermStats
+	for stem, texts := range g.withTerm {
+		share := float64(texts) / float64(g.texts)
+		stats = append(stats, TermStats{
+			Word:  mostFrequent(g.words[stem]),
+			Stem:  stem,
+			Texts: texts,
+			Share: share,
+			TFIDF: share * math.Log(float64(tc.texts)/float64(tc.withTerm[stem])),
+		})
+	}
+	slices.SortFunc(stats, func(a, b TermStats) int {
+		if a.TFIDF != b.TFIDF {
+			return cmp.Compare(b.TFIDF, a.TFIDF)
+		}
+		return strings.Compare(a.Stem, b.Stem)
+	})
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+
+	if tc.starts != nil {
+		for i := range stats {
+			stats[i].Trend = make([]float64, len(tc.starts))
+			for j, texts := range g.bucketWithTerm[stats[i].Stem] {
+				if g.bucketTexts[j] > 0 {
+					stats[i].Trend[j] = float64(texts) / float64(g.bucketTexts[j])
+				}
+			}
+		}
+	}
+	return stats
+}
+
+// mostFrequent returns the key of counts with the highest count, the first in
+// order among equals.
+func mostFrequent(counts map[string]int) string {
+	best := ""
+	for _, k := range slices.Sorted(maps.Keys(counts)) {
+		if best == "" || counts[k] > counts[best] {
+			best = k
+		}
+	}
+	return best
+}
@@ -0,0 +1,162 @@
+package soanalysis
+
+import (
+	"cmp"
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// versionPatterns are the patterns of versioned tags, with the family and the
+// version as their groups: a version after a dash (python-3.x, java-8,
+// windows-10) or right after the name (angular2, c++11, html5).
+var versionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^(.+)-(\d+(?:\.(?:\d+|x))*)$`),
+	regexp.MustCompile(`^(.*[^\d.-])(\d+(?:\.(?:\d+|x))*)$`),
+}
+
+// TagVersion splits a versioned tag into the tag of its family and its
+// version, e.g. "python-3.x" into "python" and "3.x", or "angular2" into
+// "angular" and "2"; ok is false if the tag has no version. Many tags ending
+// with numbers aren't versions (like base64 or ipv6), so callers should only
+// take the family of a tag as such if it's a tag itself.
+func TagVersion(tag string) (family string, version string, ok bool) {
+	for _, p := range versionPatterns {
+		if m := p.FindStringSubmatch(tag); m != nil {
+			return m[1], m[2], true
+		}
+	}
+	return "", "", false
+}
+
+// compareVersions compares versions like 2.7 and 3.x by their numbers, with x
+// after all the numbers.
+func compareVersions(a string, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range min(len(as), len(bs)) {
+		an, aerr := strconv.Atoi(as[i])
+		bn, berr := strconv.Atoi(bs[i])
+		switch {
+		case aerr == nil && berr == nil && an != bn:
+			return cmp.Compare(an, bn)
+		case aerr != nil && berr == nil:
+			return 1
+		case aerr == nil && berr != nil:
+			return -1
+		}
+	}
+	return cmp.Compare(len(as), len(bs))
+}
+
+// VersionResult is the result of the questions of a version of a family of
+// tags; Version is empty for the questions without a versioned tag.
+type VersionResult struct {
+	Version string
+	Result  Result
+}
+
+// VersionSplit is the result of the questions of a family of tags, such as
+// python with python-2.7 and python-3.x, rolled up and by version.
+type VersionSplit struct {
+	Family string
+
+	// All is the result of all the questions, each counted once.
+	All Result
+
+	// Versions are the results of the questions by version, oldest version
+	// first and the questions without a version last. Questions tagged with
+	// several versions are counted in each.
+	Versions []VersionResult
+}
+
+// VersionSplit computes the results of the questions of a family of tags
+// between fromDate and toDate, both rolled up and split by the versioned tags
+// of the family on the questions (see TagVersion). The questions are read from
+// the given tags of the dataset, typically the family itself along with any of
+// its versioned tags that were fetched separately; questions in several of
+// them are counted once.
+func (a *Analyzer) VersionSplit(ctx context.Context, family string, tags []string, fromDate time.Time, toDate time.Time) (VersionSplit, error) {
+	vs := VersionSplit{Family: family, All: Result{Robust: a.Robust}}
+	versions := make(map[string]*Result)
+	seen := make(map[int]bool)
+	for _, tag := range tags {
+		err := a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+			if seen[item.QuestionID] {
+				return
+			}
+			seen[item.QuestionID] = true
+			vs.All.addItem(item, a.Sentiments)
+
+			var itemVersions []string
+			for _, t := range item.Tags {
+				if f, v, ok := TagVersion(t); ok && f == family && !slices.Contains(itemVersions, v) {
+					itemVersions = append(itemVersions, v)
+				}
+			}
+			if len(itemVersions) == 0 {
+				itemVersions = []string{""}
+			}
+			for _, v := range itemVersions {
+				r := versions[v]
+				if r == nil {
+					r = &Result{Robust: a.Robust}
+					versions[v] = r
+				}
+				r.addItem(item, a.Sentiments)
+			}
+		})
+		if err != nil {
+			return vs, err
+		}
+	}
+
+	for v, r := range versions {
+		vs.Versions = append(vs.Versions, VersionResult{Version: v, Result: *r})
+	}
+	slices.SortFunc(vs.Versions, func(x, y VersionResult) int {
+		if x.Version == "" || y.Version == "" {
+			// The questions without a version last
+			return cmp.Compare(y.Version, x.Version)
+		}
+		return compareVersions(x.Version, y.Version)
+	})
+	return vs, nil
+}
+
+// VersionFamilies groups tags into families of versioned tags, given the known
+// tags of a dataset: each tag whose family (see TagVersion) is a known tag
+// belongs to that family, and the other tags are families of their own. It
+// returns the families of tags in the order of their first tag, and the known
+// tags of each family (the family itself and its versioned tags), to read the
+// questions of the family from.
+func VersionFamilies(tags []string, known []string) ([]string, map[string][]string) {
+	familyOf := func(tag string) string {
+		if f, _, ok := TagVersion(tag); ok && slices.Contains(known, f) {
+			return f
+		}
+		return tag
+	}
+	var families []string
+	members := make(map[string][]string)
+	for _, tag := range tags {
+		family := familyOf(tag)
+		if _, ok := members[family]; ok {
+			continue
+		}
+		families = append(families, family)
+		for _, t := range known {
+			if familyOf(t) == family {
+				members[family] = append(members[family], t)
+			}
+		}
+		if !slices.Contains(members[family], tag) {
+			members[family] = append(members[family], tag)
+		}
+	}
+	return families, members
+}
@@ -0,0 +1,140 @@
+package soanalysis
+
+import (
+	"context"
+	"time"
+
+	"github.com/eliben/so-tag-sentiment-analysis/soapi"
+)
+
+// ViewGrowthWeek holds the view counts of questions in a week of their age:
+// week 0 is the first week after asking.
+type ViewGrowthWeek struct {
+	Week int
+
+	// Snapshots is the number of snapshots taken in the week, and MeanViews
+	// their mean view count.
+	Snapshots int
+	MeanViews float64
+
+	// ViewsPerDay is the mean rate the questions gained views at, between
+	// their snapshots (or asking) and the following snapshots in the week.
+	ViewsPerDay float64
+}
+
+// ViewGrowthGroup is the growth of the view counts of a group of questions.
+type ViewGrowthGroup struct {
+	// Questions is the number of questions with view counts logged in at
+	// least two fetches.
+	Questions int
+
+	// MedianTimeToHalfViews is the median time the questions took to get half
+	// of the views of their last snapshot, interpolating linearly between the
+	// snapshots; the sooner it is, the sooner the questions stop accumulating
+	// views.
+	MedianTimeToHalfViews time.Duration
+
+	// Weeks are the view counts of the questions by their age in weeks, up to
+	// the weeks asked for.
+	Weeks []ViewGrowthWeek
+}
+
+// ViewGrowth is the growth of the view counts of the questions of a tag after
+// they're asked, separately for the questions with a negative score in their
+// last snapshot and the others.
+type ViewGrowth struct {
+	Tag      string
+	Negative ViewGrowthGroup
+	Other    ViewGrowthGroup
+}
+
+// viewPoint is the view count of a question at a time.
+type viewPoint struct {
+	at    time.Time
+	views int
+}
+
+// ViewGrowth analyzes how the view counts of the questions with the given tag
+// between fromDate and toDate grow in the given number of weeks after they're
+// asked, from the snapshots logged when fetching them again (see
+// ScoreSnapshot), e.g. with fetch-all-questions -refresh. Only the questions
+// still stored (and selected by the Filter of the analyzer) with view counts
+// logged in at least two fetches are considered.
+func (a *Analyzer) ViewGrowth(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, weeks int) (ViewGrowth, error) {
+	vg := ViewGrowth{Tag: tag}
+	for _, g := range []*ViewGrowthGroup{&vg.Negative, &vg.Other} {
+		for w := range weeks {
+			g.Weeks = append(g.Weeks, ViewGrowthWeek{Week: w})
+		}
+	}
+	snapshots, err := a.Dataset.ScoreSnapshots(tag)
+	if err != nil {
+		return vg, err
+	}
+
+	const week = 7 * 24 * time.Hour
+	var negativeTimes, otherTimes []time.Duration
+	err = a.ForEachItem(ctx, tag, fromDate, toDate, func(item *soapi.Item) {
+		asked := time.Unix(int64(item.CreationDate), 0)
+		points := []viewPoint{{at: asked}}
+		for _, s := range snapshots[item.QuestionID] {
+			if s.Views > 0 && s.Fetched.After(points[len(points)-1].at) {
+				points = append(points, viewPoint{at: s.Fetched, views: s.Views})
+			}
+		}
+		if len(points) < 3 {
+			return
+		}
+
+		g, times := &vg.Other, &otherTimes
+		if ss := snapshots[item.QuestionID]; ss[len(ss)-1].Score < 0 {
+			g, times = &vg.Negative, &negativeTimes
+		}
+		g.Questions++
+		*times = append(*times, timeToHalf(points))
+		for i, p := range points[1:] {
+			w := int(p.at.Sub(asked) / week)
+			if w >= weeks {
+				break
+			}
+			prev := points[i]
+			g.Weeks[w].Snapshots++
+			g.Weeks[w].MeanViews += float64(p.views)
+			g.Weeks[w].ViewsPerDay += float64(p.views-prev.views) / (p.at.Sub(prev.at).Hours() / 24)
+		}
+	})
+	if err != nil {
+		return vg, err
+	}
+
+	vg.Negative.MedianTimeToHalfViews = medianDuration(negativeTimes)
+	vg.Other.MedianTimeToHalfViews = medianDuration(otherTimes)
+	for _, g := range []*ViewGrowthGroup{&vg.Negative, &vg.Other} {
+		for i := range g.Weeks {
+			if n := float64(g.Weeks[i].Snapshots); n > 0 {
+				g.Weeks[i].MeanViews /= n
+				g.Weeks[i].ViewsPerDay /= n
+			}
+		}
+	}
+	return vg, nil
+}
+
+// timeToHalf returns the time from the first of points, the asking of a
+// question, until it got half of the views of the last of them, interpolating
+// linearly between them.
+func timeToHalf(points []viewPoint) time.Duration {
+	half := float64(points[len(points)-1].views) / 2
+	for i := 1; i < len(points); i++ {
+		prev, p := points[i-1], points[i]
+		if float64(p.views) >= half {
+			frac := 1.0
+			if p.views > prev.views {
+				frac = (half - float64(prev.views)) / float64(p.views-prev.views)
+			}
+			at := prev.at.Add(time.Duration(frac * float64(p.at.Sub(prev.at))))
+			return max(at.Sub(points[0].at), 0)
+		}
+	}
+	return points[len(points)-1].at.Sub(points[0].at)
+}
@@ -0,0 +1,135 @@
+package soanalysis
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// wordPiece is the WordPiece tokenizer of BERT-family models, splitting text
+// into the IDs of the tokens of their vocabulary.
+type wordPiece struct {
+	vocab map[string]int64
+	lower bool
+
+	unk, cls, sep, pad int64
+}
+
+// loadWordPiece loads the vocabulary of a WordPiece tokenizer from path, a
+// vocab.txt file with a token per line, the ID of a token being its line
+// number from 0.
+func loadWordPiece(path string, lower bool) (*wordPiece, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	wp := &wordPiece{vocab: make(map[string]int64), lower: lower}
+	sc := bufio.NewScanner(f)
+	for id := int64(0); sc.Scan(); id++ {
+		wp.vocab[strings.TrimRight(sc.Text(), "\r")] = id
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	for _, t := range []struct {
+		token string
+		id    *int64
+	}{{"[UNK]", &wp.unk}, {"[CLS]", &wp.cls}, {"[SEP]", &wp.sep}, {"[PAD]", &wp.pad}} {
+		id, ok := wp.vocab[t.token]
+		if !ok {
+			return nil, fmt.Errorf("vocabulary %s has no %s token", path, t.token)
+		}
+		*t.id = id
+	}
+	return wp, nil
+}
+
+// encode returns the IDs of the tokens of text between [CLS] and [SEP],
+// truncated to maxLen IDs in all.
+func (wp *wordPiece) encode(text string, maxLen int) []int64 {
+	ids := []int64{wp.cls}
+	for _, word := range wp.words(text) {
+		ids = append(ids, wp.wordIDs(word)...)
+		if len(ids) >= maxLen-1 {
+			ids = ids[:maxLen-1]
+			break
+		}
+	}
+	return append(ids, wp.sep)
+}
+
+// words splits text into words on white space, and around punctuation, which
+// makes words of its own.
+func (wp *wordPiece) words(text string) []string {
+	if wp.lower {
+		text = strings.ToLower(text)
+	}
+	var words []string
+	start := -1
+	for i, r := range text {
+		switch {
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			if start >= 0 {
+				words = append(words, text[start:i])
+				start = -1
+			}
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			if start >= 0 {
+				words = append(words, text[start:i])
+				start = -1
+			}
+			words = append(words, string(r))
+		case start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, text[start:])
+	}
+	return words
+}
+
+// wordIDs returns the IDs of the longest tokens of the vocabulary making up
+// word, the ones after the first prefixed with ##, or of [UNK] if it can't be
+// made up of tokens.
+func (wp *wordPiece) wordIDs(word string) []int64 {
+	const maxWordLen = 100
+	if len(word) > maxWordLen {
+		return []int64{wp.unk}
+	}
+	var ids []int64
+	for start := 0; start < len(word); {
+		end := len(word)
+		found := false
+		for ; end > start; end-- {
+			piece := word[start:end]
+			if start > 0 {
+				piece = "##" + piece
+			}
+			if id, ok := wp.vocab[piece]; ok {
+				ids = append(ids, id)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return []int64{wp.unk}
+		}
+		start = end
+	}
+	return ids
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// plainText returns the text of the HTML of titles and bodies of questions,
+// without markup and with entities unescaped.
+func plainText(s string) string {
+	return html.UnescapeString(htmlTag.ReplaceAllString(s, " "))
+}
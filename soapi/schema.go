@@ -0,0 +1,135 @@
+package soapi
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ReplySchema is the JSON Schema of the pages of questions returned by the
+// API, as expected by Reply: the fields the API returns, their types and the
+// ones that are always present. Pages that don't match it are a sign that the
+// API changed; see ValidateReply.
+//
+//go:embed reply.schema.json
+var ReplySchema []byte
+
+// SchemaError is a mismatch between a page and ReplySchema.
+type SchemaError struct {
+	// Path locates the mismatching value in the page, e.g.
+	// $.items[3].owner.user_id.
+	Path    string
+	Message string
+}
+
+func (e SchemaError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidateReply checks the body of a page of questions against ReplySchema,
+// returning all the mismatches: unknown fields, values of the wrong type and
+// missing required fields. A body that isn't JSON is a single mismatch.
+func ValidateReply(body []byte) []SchemaError {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return []SchemaError{{Path: "$", Message: fmt.Sprintf("invalid JSON: %v", err)}}
+	}
+	var errs []SchemaError
+	replySchema().validate("$", v, &errs)
+	return errs
+}
+
+// schema is the subset of JSON Schema used by ReplySchema.
+type schema struct {
+	Type                 schemaTypes        `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+}
+
+// schemaTypes are the types allowed by a schema, given as a single type or as
+// a list of types.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+var replySchema = sync.OnceValue(func() *schema {
+	var s schema
+	if err := json.Unmarshal(ReplySchema, &s); err != nil {
+		panic(fmt.Sprintf("parsing reply.schema.json: %v", err))
+	}
+	return &s
+})
+
+// jsonType returns the JSON Schema type of a value decoded with UseNumber.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// validate appends the mismatches between v, found at path, and s to errs.
+func (s *schema) validate(path string, v interface{}, errs *[]SchemaError) {
+	if len(s.Type) > 0 {
+		t := jsonType(v)
+		if !slices.Contains(s.Type, t) && !(t == "integer" && slices.Contains(s.Type, "number")) {
+			*errs = append(*errs, SchemaError{path, fmt.Sprintf("want %s, got %s", strings.Join(s.Type, " or "), t)})
+			return
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, SchemaError{path, fmt.Sprintf("missing required field %q", name)})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if p, ok := s.Properties[name]; ok {
+				p.validate(path+"."+name, v[name], errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*errs = append(*errs, SchemaError{path, fmt.Sprintf("unknown field %q", name)})
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	}
+}
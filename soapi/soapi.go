@@ -0,0 +1,784 @@
+// Package soapi is a client for the StackExchange API, fetching the questions
+// asked on StackOverflow with a given tag.
+//
+// Base query built with the explorer on
+// https://api.stackexchange.com/docs/questions
+//
+// "https://api.stackexchange.com/2.2/questions?page=2&pagesize=100&fromdate=1610409600&todate=1613088000&order=desc&sort=activity&tagged=go&site=stackoverflow"
+package soapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Reply is a single page of questions returned by the API.
+//
+// Struct generated with https://mholt.github.io/json-to-go/
+type Reply struct {
+	Items          []Item `json:"items"`
+	HasMore        bool   `json:"has_more"`
+	QuotaMax       int    `json:"quota_max"`
+	QuotaRemaining int    `json:"quota_remaining"`
+	Total          int    `json:"total"`
+}
+
+// Item is a single question in a Reply.
+type Item struct {
+	Tags  []string `json:"tags"`
+	Owner struct {
+		Reputation   int    `json:"reputation"`
+		UserID       int    `json:"user_id"`
+		UserType     string `json:"user_type"`
+		ProfileImage string `json:"profile_image"`
+		DisplayName  string `json:"display_name"`
+		Link         string `json:"link"`
+	} `json:"owner"`
+	IsAnswered       bool   `json:"is_answered"`
+	ClosedDate       int64  `json:"closed_date"`
+	ViewCount        int    `json:"view_count"`
+	AcceptedAnswerID int    `json:"accepted_answer_id,omitempty"`
+	AnswerCount      int    `json:"answer_count"`
+	Score            int    `json:"score"`
+	LastActivityDate int    `json:"last_activity_date"`
+	CreationDate     int    `json:"creation_date"`
+	LastEditDate     int    `json:"last_edit_date"`
+	QuestionID       int    `json:"question_id"`
+	ContentLicense   string `json:"content_license"`
+	Link             string `json:"link"`
+	Title            string `json:"title"`
+
+	// Body is the HTML of the question; it's only returned if requested, see
+	// Fetcher.Bodies.
+	Body string `json:"body,omitempty"`
+
+	ClosedReason string `json:"closed_reason,omitempty"`
+
+	// ProtectedDate and LockedDate are only set for questions protected (so
+	// that new users can't answer them) or locked (so that nobody can change
+	// them) at the time of fetching.
+	ProtectedDate int64 `json:"protected_date,omitempty"`
+	LockedDate    int64 `json:"locked_date,omitempty"`
+
+	// BountyAmount and BountyClosesDate are only set for questions with a
+	// bounty open at the time of fetching.
+	BountyAmount     int   `json:"bounty_amount,omitempty"`
+	BountyClosesDate int64 `json:"bounty_closes_date,omitempty"`
+
+	// ClosedDetails is only returned if requested with a filter, see
+	// Fetcher.Filter.
+	ClosedDetails *ClosedDetails `json:"closed_details,omitempty"`
+}
+
+// ClosedDetails are the details of the closing of a question.
+type ClosedDetails struct {
+	// OriginalQuestions are the questions a question was closed as a
+	// duplicate of.
+	OriginalQuestions []struct {
+		QuestionID int    `json:"question_id"`
+		Title      string `json:"title"`
+	} `json:"original_questions,omitempty"`
+}
+
+// AnswerReply is a single page of answers returned by the API.
+type AnswerReply struct {
+	Items          []Answer `json:"items"`
+	HasMore        bool     `json:"has_more"`
+	QuotaMax       int      `json:"quota_max"`
+	QuotaRemaining int      `json:"quota_remaining"`
+}
+
+// Answer is a single answer in an AnswerReply.
+type Answer struct {
+	Owner struct {
+		Reputation   int    `json:"reputation"`
+		UserID       int    `json:"user_id"`
+		UserType     string `json:"user_type"`
+		ProfileImage string `json:"profile_image"`
+		DisplayName  string `json:"display_name"`
+		Link         string `json:"link"`
+	} `json:"owner"`
+	IsAccepted       bool   `json:"is_accepted"`
+	Score            int    `json:"score"`
+	LastActivityDate int    `json:"last_activity_date"`
+	CreationDate     int    `json:"creation_date"`
+	AnswerID         int    `json:"answer_id"`
+	QuestionID       int    `json:"question_id"`
+	ContentLicense   string `json:"content_license"`
+}
+
+// DecodeReplies decodes a stream of replies from r, such as the pages written
+// one after another by fetch-all-questions -dir -, calling fn with each reply.
+func DecodeReplies(r io.Reader, fn func(reply *Reply) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var reply Reply
+		if err := dec.Decode(&reply); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(&reply); err != nil {
+			return err
+		}
+	}
+}
+
+// APIURL is the root of the API queried by Fetcher.
+const APIURL = "https://api.stackexchange.com/2.2"
+
+// BaseURL is the endpoint of the API for questions.
+const BaseURL = APIURL + "/questions"
+
+// Fetcher fetches questions from the API.
+type Fetcher struct {
+	// Client is used to issue requests; if nil, http.DefaultClient is used.
+	Client *http.Client
+
+	// Site is the Stack Exchange site to fetch from, as its API parameter
+	// (e.g. "serverfault" or "devops"); if empty, DefaultSite is used.
+	Site string
+
+	// Key is the API key, from stackapps.com. It's optional, but greatly
+	// increases the daily quota of queries.
+	Key string
+
+	// Delay is the time to wait between requests for consecutive pages, to try
+	// not to get throttled.
+	Delay time.Duration
+
+	// Timeout, if positive, is the deadline of each request, including reading
+	// its response, so that a stalled connection fails the request rather than
+	// hanging the fetch.
+	Timeout time.Duration
+
+	// Bodies requests the bodies of the questions too, which makes the pages
+	// much larger.
+	Bodies bool
+
+	// Filter, if not empty, is the filter selecting the fields of the
+	// questions returned, as created by CreateFilter; it overrides Bodies.
+	Filter string
+
+	// UserAgent is sent as the User-Agent header of requests, identifying the
+	// program to the API; if empty, DefaultUserAgent is used.
+	UserAgent string
+
+	// AppID, if not empty, identifies the application on whose behalf the
+	// requests are made (e.g. its name or a contact URL), as the API asks of
+	// its consumers. It's sent as a comment after UserAgent.
+	AppID string
+
+	// Logger, if not nil, logs the headers of each request and of its
+	// response, e.g. to debug throttling.
+	Logger *log.Logger
+
+	// Retries is the number of times a request that failed with a network
+	// error or a server error (5xx) is retried, waiting twice as long as the
+	// previous time from Delay on.
+	Retries int
+
+	// Stats, if not nil, counts the requests made by the fetcher; copies of the
+	// fetcher share it.
+	Stats *Stats
+
+	// Breaker, if not nil, stops the requests of the fetcher once the API keeps
+	// failing; copies of the fetcher share it.
+	Breaker *Breaker
+
+	// backoffUntil is when the backoff requested by the last reply ends; the
+	// API asks not to make requests until then.
+	backoffUntil time.Time
+}
+
+// Stats counts the requests made by a Fetcher, e.g. to tune Delay or diagnose
+// slow fetches. It isn't safe for concurrent use, as Fetcher isn't.
+type Stats struct {
+	// Requests is the number of requests made, including retries.
+	Requests int `json:"requests"`
+
+	// Bytes is the number of bytes of response bodies downloaded.
+	Bytes int64 `json:"bytes"`
+
+	// Retries is the number of requests retried after failing.
+	Retries int `json:"retries"`
+
+	// Backoffs is the number of times the fetcher waited for a backoff
+	// requested by the API.
+	Backoffs int `json:"backoffs"`
+
+	// Latency is the total time requests took, from sending them to reading
+	// their responses.
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// AverageLatency returns the average time requests took.
+func (s *Stats) AverageLatency() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Requests)
+}
+
+func (s *Stats) String() string {
+	return fmt.Sprintf("%d requests, %.1f MB downloaded, %d retries, %d backoffs, average latency %v",
+		s.Requests, float64(s.Bytes)/(1<<20), s.Retries, s.Backoffs, s.AverageLatency().Round(time.Millisecond))
+}
+
+// ErrCircuitOpen is returned for the requests of a fetcher whose Breaker is
+// open.
+var ErrCircuitOpen = errors.New("too many consecutive failed requests to the API; stopped making requests")
+
+// Breaker is a circuit breaker for the requests of a Fetcher: once Threshold
+// consecutive requests fail (after their retries) with network errors, server
+// errors or error replies of the API, it opens, so as not to keep hitting an
+// API that's down or throttling, which risks a ban.
+type Breaker struct {
+	// Threshold is the number of consecutive failed requests that open the
+	// breaker.
+	Threshold int
+
+	// Cooldown, if positive, is how long to wait once the breaker opens before
+	// making the failed request again to probe the API, until it succeeds and
+	// closes the breaker. Without Cooldown, the breaker stays open, and
+	// requests fail with ErrCircuitOpen instead of being made.
+	Cooldown time.Duration
+
+	// OnOpen, if not nil, is called when the breaker opens, with the error of
+	// the last failed request.
+	OnOpen func(err error)
+
+	failures int
+}
+
+// open reports whether the breaker is open.
+func (b *Breaker) open() bool {
+	return b.Threshold > 0 && b.failures >= b.Threshold
+}
+
+// record records the outcome of a request, which failed with failure if it's
+// not nil, and reports whether the breaker is open.
+func (b *Breaker) record(failure error) bool {
+	if failure == nil {
+		b.failures = 0
+		return false
+	}
+	b.failures++
+	if b.open() && b.OnOpen != nil {
+		b.OnOpen(failure)
+	}
+	return b.open()
+}
+
+// The instruments of fetchers, which record with the global OpenTelemetry
+// providers (doing nothing unless they're set up), as Stats does.
+var (
+	tracer = otel.Tracer("github.com/eliben/so-tag-sentiment-analysis/soapi")
+	meter  = otel.Meter("github.com/eliben/so-tag-sentiment-analysis/soapi")
+
+	requestCounter, _ = meter.Int64Counter("soapi.requests",
+		metric.WithDescription("Requests made to the API, including retries."))
+	byteCounter, _ = meter.Int64Counter("soapi.downloaded", metric.WithUnit("By"),
+		metric.WithDescription("Bytes of response bodies downloaded from the API."))
+	retryCounter, _ = meter.Int64Counter("soapi.retries",
+		metric.WithDescription("Requests to the API retried after failing."))
+	backoffCounter, _ = meter.Int64Counter("soapi.backoffs",
+		metric.WithDescription("Backoffs requested by the API waited for."))
+	latencyHistogram, _ = meter.Float64Histogram("soapi.request.duration", metric.WithUnit("s"),
+		metric.WithDescription("Duration of requests to the API, from sending them to reading their responses."))
+)
+
+// DefaultSite is the site of fetchers with no Site.
+const DefaultSite = "stackoverflow"
+
+// DefaultUserAgent is the User-Agent of requests of fetchers with no UserAgent.
+const DefaultUserAgent = "so-tag-sentiment-analysis"
+
+// NewFetcher creates a new Fetcher with the given API key (which may be empty),
+// the default delay between requests, timeout and retries of requests, and
+// Stats.
+func NewFetcher(key string) *Fetcher {
+	return &Fetcher{Key: key, Delay: 300 * time.Millisecond, Timeout: time.Minute, Retries: 2, Stats: &Stats{}}
+}
+
+// site returns the site parameter of requests.
+func (f *Fetcher) site() string {
+	if f.Site == "" {
+		return DefaultSite
+	}
+	return f.Site
+}
+
+// userAgent returns the User-Agent header of requests, made of UserAgent and
+// AppID.
+func (f *Fetcher) userAgent() string {
+	ua := f.UserAgent
+	if ua == "" {
+		ua = DefaultUserAgent
+	}
+	if f.AppID != "" {
+		ua += " (" + f.AppID + ")"
+	}
+	return ua
+}
+
+// logHeaders logs header with the line introducing it, if f has a Logger.
+func (f *Fetcher) logHeaders(line string, header http.Header) {
+	if f.Logger == nil {
+		return
+	}
+	f.Logger.Print(line)
+	for _, name := range slices.Sorted(maps.Keys(header)) {
+		for _, value := range header[name] {
+			f.Logger.Printf("  %s: %s", name, value)
+		}
+	}
+}
+
+// get requests url with the given headers (which may be nil), and returns the
+// response along with its body. Failed requests are retried up to Retries
+// times, and then recorded by the Breaker, if any.
+func (f *Fetcher) get(ctx context.Context, url string, header http.Header) (resp *http.Response, body []byte, err error) {
+	endpoint, _, _ := strings.Cut(url, "?")
+	ctx, span := tracer.Start(ctx, "soapi.get", trace.WithAttributes(attribute.String("url.full", endpoint)))
+	defer func() {
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		} else {
+			span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		}
+		span.End()
+	}()
+
+	if f.Breaker == nil {
+		return f.getRetrying(ctx, url, header)
+	}
+	if f.Breaker.open() {
+		return nil, nil, ErrCircuitOpen
+	}
+	for {
+		resp, body, err = f.getRetrying(ctx, url, header)
+		if ctx.Err() != nil {
+			// Interruptions aren't failures of the API.
+			return resp, body, err
+		}
+		var failure error
+		if failedRequest(resp, body, err) {
+			failure = requestError(resp, body, err)
+		}
+		if !f.Breaker.record(failure) {
+			return resp, body, err
+		}
+		if f.Breaker.Cooldown <= 0 {
+			return nil, nil, fmt.Errorf("%w; the last failed with: %v", ErrCircuitOpen, failure)
+		}
+		span.AddEvent("cooldown")
+		if err := sleep(ctx, f.Breaker.Cooldown); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// getRetrying is get without the Breaker. It waits for the backoff requested
+// by the previous reply first.
+func (f *Fetcher) getRetrying(ctx context.Context, url string, header http.Header) (*http.Response, []byte, error) {
+	span := trace.SpanFromContext(ctx)
+	if wait := time.Until(f.backoffUntil); wait > 0 {
+		if f.Stats != nil {
+			f.Stats.Backoffs++
+		}
+		backoffCounter.Add(ctx, 1)
+		span.AddEvent("backoff", trace.WithAttributes(attribute.Float64("duration", wait.Seconds())))
+		if f.Logger != nil {
+			f.Logger.Printf("Backing off for %v as requested by the API", wait.Round(time.Second))
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, body, err := f.getOnce(ctx, url, header)
+		failed := err != nil || resp.StatusCode >= 500
+		if !failed || attempt == f.Retries || ctx.Err() != nil {
+			if err == nil {
+				f.setBackoff(body)
+			}
+			return resp, body, err
+		}
+
+		if f.Stats != nil {
+			f.Stats.Retries++
+		}
+		retryCounter.Add(ctx, 1)
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("attempt", attempt+1)))
+		if f.Logger != nil {
+			if err == nil {
+				err = errors.New(resp.Status)
+			}
+			f.Logger.Printf("Retrying %s: %v", url, err)
+		}
+		if err := sleep(ctx, f.Delay<<attempt); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// failedRequest reports whether a request failed, with a network error, a server
+// error or an error reply of the API (which has an error_id).
+func failedRequest(resp *http.Response, body []byte, err error) bool {
+	if err != nil || resp.StatusCode >= 500 {
+		return true
+	}
+	return resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotModified && bytes.Contains(body, []byte(`"error_id"`))
+}
+
+// requestError returns the error of a failed request; see failedRequest.
+func requestError(resp *http.Response, body []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+}
+
+// setBackoff records the backoff requested by body, a reply of the API, if
+// any. The API may ask to back off from any method, so it's honored for all
+// the requests that follow.
+func (f *Fetcher) setBackoff(body []byte) {
+	if !bytes.Contains(body, []byte(`"backoff"`)) {
+		return
+	}
+	var reply struct {
+		Backoff int `json:"backoff"`
+	}
+	if json.Unmarshal(body, &reply) == nil && reply.Backoff > 0 {
+		f.backoffUntil = time.Now().Add(time.Duration(reply.Backoff) * time.Second)
+	}
+}
+
+// getOnce is getRetrying without backoff or retries, giving up after Timeout.
+func (f *Fetcher) getOnce(ctx context.Context, url string, header http.Header) (*http.Response, []byte, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", f.userAgent())
+	f.logHeaders(req.Method+" "+url, req.Header)
+
+	start := time.Now()
+	if f.Stats != nil {
+		f.Stats.Requests++
+		defer func() { f.Stats.Latency += time.Since(start) }()
+	}
+	requestCounter.Add(ctx, 1)
+	defer func() { latencyHistogram.Record(ctx, time.Since(start).Seconds()) }()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	f.logHeaders(resp.Proto+" "+resp.Status, resp.Header)
+	body, err := io.ReadAll(resp.Body)
+	if f.Stats != nil {
+		f.Stats.Bytes += int64(len(body))
+	}
+	byteCounter.Add(ctx, int64(len(body)))
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, body, nil
+}
+
+// Pause waits for Delay, as done between consecutive requests, returning
+// early with the error of ctx if it's done.
+func (f *Fetcher) Pause(ctx context.Context) error {
+	return sleep(ctx, f.Delay)
+}
+
+// sleep waits for d, returning early with the error of ctx if it's done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// PageURL returns the URL of the given page (numbered from 1) of questions with
+// tag asked between fromDate and toDate.
+func (f *Fetcher) PageURL(page int, tag string, fromDate time.Time, toDate time.Time) string {
+	v := url.Values{}
+	v.Set("page", strconv.Itoa(page))
+	v.Set("pagesize", strconv.Itoa(100))
+	v.Set("fromdate", strconv.FormatInt(fromDate.Unix(), 10))
+	v.Set("todate", strconv.FormatInt(toDate.Unix(), 10))
+	v.Set("order", "desc")
+	v.Set("sort", "activity")
+	v.Set("tagged", tag)
+	v.Set("site", f.site())
+	v.Set("key", f.Key)
+	if f.Filter != "" {
+		v.Set("filter", f.Filter)
+	} else if f.Bodies {
+		v.Set("filter", "withbody")
+	}
+	return BaseURL + "?" + v.Encode()
+}
+
+// FetchPage fetches a single page of questions. It returns the body of the
+// response as well as the decoded reply.
+func (f *Fetcher) FetchPage(ctx context.Context, page int, tag string, fromDate time.Time, toDate time.Time) ([]byte, *Reply, error) {
+	body, reply, _, err := f.FetchPageIf(ctx, page, tag, fromDate, toDate, Validators{})
+	return body, reply, err
+}
+
+// Validators are the validators of a response (its ETag and Last-Modified
+// headers), used to make conditional requests for the same page later.
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ErrNotModified is returned by FetchPageIf when the page didn't change since
+// the given validators were obtained.
+var ErrNotModified = errors.New("not modified")
+
+// PageKey returns a key identifying the query of a page regardless of the API
+// key, for caching the validators of pages.
+func PageKey(page int, tag string, fromDate time.Time, toDate time.Time) string {
+	return (&Fetcher{}).PageURL(page, tag, fromDate, toDate)
+}
+
+// FetchPageIf is like FetchPage, but makes a conditional request with the given
+// validators of a previous response for the same page (if they aren't empty),
+// returning ErrNotModified if the page didn't change. It also returns the
+// validators of the response.
+func (f *Fetcher) FetchPageIf(ctx context.Context, page int, tag string, fromDate time.Time, toDate time.Time, v Validators) ([]byte, *Reply, Validators, error) {
+	header := make(http.Header)
+	if v.ETag != "" {
+		header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, body, err := f.get(ctx, f.PageURL(page, tag, fromDate, toDate), header)
+	if err != nil {
+		return nil, nil, Validators{}, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil, v, ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, Validators{}, fmt.Errorf("fetching page %d of %q: %s: %s", page, tag, resp.Status, body)
+	}
+
+	var reply Reply
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return nil, nil, Validators{}, fmt.Errorf("fetching page %d of %q: %w", page, tag, err)
+	}
+	newValidators := Validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	return body, &reply, newValidators, nil
+}
+
+// FetchTag fetches all the pages of questions with tag asked between fromDate
+// and toDate, calling fn with each page in order. It stops at the first error,
+// either from fetching or returned by fn, or when ctx is done.
+func (f *Fetcher) FetchTag(ctx context.Context, tag string, fromDate time.Time, toDate time.Time, fn func(page int, body []byte, reply *Reply) error) error {
+	for page := 1; ; page++ {
+		body, reply, err := f.FetchPage(ctx, page, tag, fromDate, toDate)
+		if err != nil {
+			return err
+		}
+		if err := fn(page, body, reply); err != nil {
+			return err
+		}
+		if !reply.HasMore {
+			return nil
+		}
+		if err := f.Pause(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// CountQuestions returns the number of questions with tag asked between
+// fromDate and toDate, or of all the questions on the site if tag is empty.
+func (f *Fetcher) CountQuestions(ctx context.Context, tag string, fromDate time.Time, toDate time.Time) (int, error) {
+	v := url.Values{}
+	v.Set("fromdate", strconv.FormatInt(fromDate.Unix(), 10))
+	v.Set("todate", strconv.FormatInt(toDate.Unix(), 10))
+	if tag != "" {
+		v.Set("tagged", tag)
+	}
+	v.Set("site", f.site())
+	v.Set("filter", "total")
+	v.Set("key", f.Key)
+	resp, body, err := f.get(ctx, BaseURL+"?"+v.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("counting questions of %q: %s: %s", tag, resp.Status, body)
+	}
+	var reply struct {
+		Total int `json:"total"`
+	}
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return 0, fmt.Errorf("counting questions of %q: %w", tag, err)
+	}
+	return reply.Total, nil
+}
+
+// MaxAnswerQuestions is the maximal number of questions whose answers can be
+// fetched together by FetchAnswers.
+const MaxAnswerQuestions = 100
+
+// AnswersURL returns the URL of the given page (numbered from 1) of the answers
+// to the questions with the given IDs.
+func (f *Fetcher) AnswersURL(page int, questionIDs []int) string {
+	ids := make([]string, len(questionIDs))
+	for i, id := range questionIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+
+	v := url.Values{}
+	v.Set("page", strconv.Itoa(page))
+	v.Set("pagesize", strconv.Itoa(100))
+	v.Set("order", "asc")
+	v.Set("sort", "creation")
+	v.Set("site", f.site())
+	v.Set("key", f.Key)
+	return BaseURL + "/" + strings.Join(ids, ";") + "/answers?" + v.Encode()
+}
+
+// FetchAnswers fetches all the pages of answers to the questions with the given
+// IDs (up to MaxAnswerQuestions of them), calling fn with each page in order.
+// It stops at the first error, either from fetching or returned by fn, or when
+// ctx is done.
+func (f *Fetcher) FetchAnswers(ctx context.Context, questionIDs []int, fn func(page int, body []byte, reply *AnswerReply) error) error {
+	if len(questionIDs) > MaxAnswerQuestions {
+		return fmt.Errorf("fetching answers to %d questions; at most %d are allowed", len(questionIDs), MaxAnswerQuestions)
+	}
+
+	for page := 1; ; page++ {
+		resp, body, err := f.get(ctx, f.AnswersURL(page, questionIDs), nil)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("fetching page %d of answers: %s: %s", page, resp.Status, body)
+		}
+
+		var reply AnswerReply
+		if err := json.Unmarshal(body, &reply); err != nil {
+			return fmt.Errorf("fetching page %d of answers: %w", page, err)
+		}
+		if err := fn(page, body, &reply); err != nil {
+			return err
+		}
+		if !reply.HasMore {
+			return nil
+		}
+		if err := f.Pause(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// CreateFilter creates a filter returning the fields of the default filter as
+// well as the given ones, e.g. "question.closed_details", and returns its name
+// for Filter.
+func (f *Fetcher) CreateFilter(ctx context.Context, include ...string) (string, error) {
+	v := url.Values{}
+	v.Set("include", strings.Join(include, ";"))
+	v.Set("base", "default")
+	v.Set("unsafe", "false")
+	v.Set("key", f.Key)
+	resp, body, err := f.get(ctx, APIURL+"/filters/create?"+v.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("creating filter: %s: %s", resp.Status, body)
+	}
+	var reply struct {
+		Items []struct {
+			Filter string `json:"filter"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return "", fmt.Errorf("creating filter: %w", err)
+	}
+	if len(reply.Items) == 0 {
+		return "", errors.New("creating filter: no filter returned")
+	}
+	return reply.Items[0].Filter, nil
+}
+
+// RelatedTags returns up to n of the tags most often used together with tag,
+// most related first.
+func (f *Fetcher) RelatedTags(ctx context.Context, tag string, n int) ([]string, error) {
+	v := url.Values{}
+	// One more than n, since the tag itself may be among them.
+	v.Set("pagesize", strconv.Itoa(n+1))
+	v.Set("site", f.site())
+	v.Set("key", f.Key)
+	resp, body, err := f.get(ctx, APIURL+"/tags/"+url.PathEscape(tag)+"/related?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching tags related to %q: %s: %s", tag, resp.Status, body)
+	}
+	var reply struct {
+		Items []struct {
+			Name string `json:"name"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return nil, fmt.Errorf("fetching tags related to %q: %w", tag, err)
+	}
+
+	var tags []string
+	for _, item := range reply.Items {
+		if item.Name != tag && len(tags) < n {
+			tags = append(tags, item.Name)
+		}
+	}
+	return tags, nil
+}
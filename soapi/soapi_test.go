@@ -0,0 +1,92 @@
+package soapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// benchReplies returns the given number of pages of 100 questions, written one
+// after another as by fetch-all-questions -dir -.
+func benchReplies(b *testing.B, pages int) []byte {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for page := range pages {
+		reply := Reply{HasMore: page < pages-1}
+		for i := range 100 {
+			id := page*100 + i
+			reply.Items = append(reply.Items, Item{
+				Tags:         []string{"go"},
+				ViewCount:    id % 5000,
+				Score:        id%8 - 2,
+				CreationDate: 1577836800 + id*800,
+				QuestionID:   id,
+				Link:         fmt.Sprintf("https://stackoverflow.com/questions/%d", id),
+				Title:        "How do I stop a goroutine that is blocked on a channel receive?",
+			})
+		}
+		if err := enc.Encode(reply); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func BenchmarkDecodeReplies(b *testing.B) {
+	for _, pages := range []int{1, 10, 100} {
+		data := benchReplies(b, pages)
+		b.Run(fmt.Sprintf("pages=%d", pages), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				n := 0
+				err := DecodeReplies(bytes.NewReader(data), func(reply *Reply) error {
+					n += len(reply.Items)
+					return nil
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+				if n != pages*100 {
+					b.Fatalf("got %d questions, want %d", n, pages*100)
+				}
+			}
+		})
+	}
+}
+
+// FuzzDecodeReplies decodes arbitrary streams of replies, which have to fail
+// with an error rather than a panic, and checks that the replies decoded from
+// valid streams survive re-encoding.
+func FuzzDecodeReplies(f *testing.F) {
+	f.Add([]byte(`{"items":[{"question_id":1,"tags":["go"],"score":-1}],"has_more":true}` + "\n" + `{"items":[]}`))
+	f.Add([]byte(`{"error_id":502,"error_message":"too many requests from this IP","error_name":"throttle_violation"}`))
+	f.Add([]byte(`{"items":[{"question_id":1,"owner":{"user_type":"does_not_exist"},"closed_details":{"original_questions":[{"question_id":2}]}}]}`))
+	f.Add([]byte(`{"items":[{"question_id":1`))
+	f.Add([]byte(`{"items":[{"question_id":"1"}]}`))
+	f.Add([]byte(`[]`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var replies []*Reply
+		err := DecodeReplies(bytes.NewReader(data), func(reply *Reply) error {
+			replies = append(replies, reply)
+			return nil
+		})
+		if err != nil {
+			return
+		}
+
+		for _, reply := range replies {
+			encoded, err := json.Marshal(reply)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var decoded Reply
+			if err := json.Unmarshal(encoded, &decoded); err != nil {
+				t.Fatalf("decoding re-encoded reply %s: %v", encoded, err)
+			}
+			if len(decoded.Items) != len(reply.Items) {
+				t.Fatalf("got %d questions after re-encoding, want %d", len(decoded.Items), len(reply.Items))
+			}
+		}
+	})
+}